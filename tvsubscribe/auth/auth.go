@@ -0,0 +1,410 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"tvsubscribe/storage"
+)
+
+// Role 用户和API令牌的角色
+type Role string
+
+const (
+	RoleReadOnly Role = "readonly" // 只能查看配置、订阅和处理记录
+	RoleAdmin    Role = "admin"    // 可以修改配置和订阅，管理用户和令牌
+)
+
+// ParseRole 解析角色名称
+func ParseRole(value string) (Role, error) {
+	switch role := Role(value); role {
+	case RoleReadOnly, RoleAdmin:
+		return role, nil
+	}
+	return "", fmt.Errorf("无效的角色: %s，可选 %s 或 %s", value, RoleReadOnly, RoleAdmin)
+}
+
+// Allows 判断角色是否具有 required 角色的权限，管理员拥有全部权限
+func (r Role) Allows(required Role) bool {
+	return r == RoleAdmin || r == required
+}
+
+// TokenPrefix API令牌的前缀，便于识别泄露的令牌
+const TokenPrefix = "tvs_"
+
+var (
+	// ErrInvalidCredentials 用户名或密码错误
+	ErrInvalidCredentials = errors.New("用户名或密码错误")
+	// ErrNotFound 用户或令牌不存在
+	ErrNotFound = errors.New("不存在")
+	// ErrLastAdmin 不能删除最后一个管理员
+	ErrLastAdmin = errors.New("至少需要保留一个管理员")
+)
+
+// usernamePattern 用户名只能包含字母、数字和 ._-
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,32}$`)
+
+// User 可以登录Web界面的用户
+type User struct {
+	Username     string    `json:"username"`
+	Role         Role      `json:"role"`
+	PasswordHash string    `json:"password_hash,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Token 供命令行和其他程序使用的API令牌，只保存令牌的哈希
+type Token struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Role       Role       `json:"role"`
+	Hash       string     `json:"hash,omitempty"`
+	CreatedBy  string     `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// Identity 通过认证的请求身份
+type Identity struct {
+	Name    string `json:"name"`               // 用户名或令牌名称
+	Role    Role   `json:"role"`               // 角色
+	Method  string `json:"method"`             // 认证方式：session 或 token
+	TokenID string `json:"token_id,omitempty"` // 使用API令牌时的令牌ID
+}
+
+// 认证方式
+const (
+	MethodSession = "session"
+	MethodToken   = "token"
+)
+
+// authFile 用户和令牌文件的内容
+type authFile struct {
+	Users  []User  `json:"users"`
+	Tokens []Token `json:"tokens"`
+}
+
+// Store 保存用户和API令牌，文件只有所有者可读写
+type Store struct {
+	mu   sync.RWMutex
+	path string
+	data authFile
+}
+
+// NewStore 创建用户和令牌存储，path 为空时只保存在内存中
+func NewStore(path string) (*Store, error) {
+	s := &Store{path: path}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) || (err == nil && len(data) == 0) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取用户文件失败: %v", err)
+	}
+	if err := json.Unmarshal(data, &s.data); err != nil {
+		return nil, fmt.Errorf("解析用户文件失败: %v", err)
+	}
+	return s, nil
+}
+
+// save 保存用户和令牌到文件，调用方需持有写锁
+func (s *Store) save() error {
+	if s.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(s.data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化用户文件失败: %v", err)
+	}
+	if err := storage.WriteFile(s.path, data, 0600); err != nil {
+		return fmt.Errorf("写入用户文件失败: %v", err)
+	}
+	return nil
+}
+
+// EnsureAdmin 没有任何用户时创建管理员 admin，password 为空时生成随机密码。
+// 返回生成的密码，已有用户或使用了指定的密码时返回空字符串
+func (s *Store) EnsureAdmin(password string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.data.Users) > 0 {
+		return "", nil
+	}
+	generated := ""
+	if password == "" {
+		secret, err := randomString(12)
+		if err != nil {
+			return "", err
+		}
+		password, generated = secret, secret
+	}
+	if err := s.addUser("admin", password, RoleAdmin); err != nil {
+		return "", err
+	}
+	return generated, nil
+}
+
+// Users 返回全部用户，不包含密码哈希
+func (s *Store) Users() []User {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	users := make([]User, len(s.data.Users))
+	for i, user := range s.data.Users {
+		user.PasswordHash = ""
+		users[i] = user
+	}
+	return users
+}
+
+// AddUser 添加用户
+func (s *Store) AddUser(username, password string, role Role) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addUser(username, password, role)
+}
+
+// addUser 添加用户，调用方需持有写锁
+func (s *Store) addUser(username, password string, role Role) error {
+	if !usernamePattern.MatchString(username) {
+		return fmt.Errorf("无效的用户名: %q，只能包含字母、数字和 ._-，最长32个字符", username)
+	}
+	if _, err := ParseRole(string(role)); err != nil {
+		return err
+	}
+	if err := validatePassword(password); err != nil {
+		return err
+	}
+	if s.findUser(username) >= 0 {
+		return fmt.Errorf("用户 %s 已存在", username)
+	}
+	hash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+	s.data.Users = append(s.data.Users, User{Username: username, Role: role, PasswordHash: hash, CreatedAt: time.Now()})
+	if err := s.save(); err != nil {
+		s.data.Users = s.data.Users[:len(s.data.Users)-1]
+		return err
+	}
+	return nil
+}
+
+// RemoveUser 删除用户，不能删除最后一个管理员
+func (s *Store) RemoveUser(username string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.findUser(username)
+	if i < 0 {
+		return fmt.Errorf("用户 %s %w", username, ErrNotFound)
+	}
+	if s.data.Users[i].Role == RoleAdmin && s.countAdmins() == 1 {
+		return ErrLastAdmin
+	}
+	users := s.data.Users
+	s.data.Users = append(append([]User(nil), users[:i]...), users[i+1:]...)
+	if err := s.save(); err != nil {
+		s.data.Users = users
+		return err
+	}
+	return nil
+}
+
+// SetPassword 修改用户的密码
+func (s *Store) SetPassword(username, password string) error {
+	if err := validatePassword(password); err != nil {
+		return err
+	}
+	hash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.findUser(username)
+	if i < 0 {
+		return fmt.Errorf("用户 %s %w", username, ErrNotFound)
+	}
+	old := s.data.Users[i].PasswordHash
+	s.data.Users[i].PasswordHash = hash
+	if err := s.save(); err != nil {
+		s.data.Users[i].PasswordHash = old
+		return err
+	}
+	return nil
+}
+
+// Authenticate 校验用户名和密码，失败时返回 ErrInvalidCredentials
+func (s *Store) Authenticate(username, password string) (User, error) {
+	s.mu.RLock()
+	i := s.findUser(username)
+	var user User
+	if i >= 0 {
+		user = s.data.Users[i]
+	}
+	s.mu.RUnlock()
+
+	// 用户不存在时同样计算一次哈希，避免通过响应时间判断用户是否存在
+	hash := user.PasswordHash
+	if hash == "" {
+		hash = dummyHash()
+	}
+	if !checkPassword(hash, password) || i < 0 {
+		return User{}, ErrInvalidCredentials
+	}
+	user.PasswordHash = ""
+	return user, nil
+}
+
+// dummyHash 用户不存在时用于校验的密码哈希，第一次使用时生成
+var dummyHash = sync.OnceValue(func() string {
+	hash, _ := hashPassword("tvsubscribe-dummy-password")
+	return hash
+})
+
+// findUser 返回用户的序号，不存在时返回 -1，调用方需持有锁
+func (s *Store) findUser(username string) int {
+	for i, user := range s.data.Users {
+		if user.Username == username {
+			return i
+		}
+	}
+	return -1
+}
+
+// countAdmins 返回管理员的数量，调用方需持有锁
+func (s *Store) countAdmins() int {
+	count := 0
+	for _, user := range s.data.Users {
+		if user.Role == RoleAdmin {
+			count++
+		}
+	}
+	return count
+}
+
+// CreateToken 创建API令牌，返回只显示这一次的令牌和令牌信息
+func (s *Store) CreateToken(name string, role Role, createdBy string) (string, Token, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", Token{}, fmt.Errorf("令牌名称不能为空")
+	}
+	if _, err := ParseRole(string(role)); err != nil {
+		return "", Token{}, err
+	}
+	secret, err := randomString(24)
+	if err != nil {
+		return "", Token{}, err
+	}
+	id, err := randomHex(6)
+	if err != nil {
+		return "", Token{}, err
+	}
+	plain := TokenPrefix + secret
+	token := Token{ID: id, Name: name, Role: role, Hash: hashToken(plain), CreatedBy: createdBy, CreatedAt: time.Now()}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Tokens = append(s.data.Tokens, token)
+	if err := s.save(); err != nil {
+		s.data.Tokens = s.data.Tokens[:len(s.data.Tokens)-1]
+		return "", Token{}, err
+	}
+	token.Hash = ""
+	return plain, token, nil
+}
+
+// Tokens 返回全部API令牌，不包含令牌的哈希
+func (s *Store) Tokens() []Token {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tokens := make([]Token, len(s.data.Tokens))
+	for i, token := range s.data.Tokens {
+		token.Hash = ""
+		tokens[i] = token
+	}
+	return tokens
+}
+
+// RevokeToken 删除API令牌，删除后立即失效
+func (s *Store) RevokeToken(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, token := range s.data.Tokens {
+		if token.ID != id {
+			continue
+		}
+		tokens := s.data.Tokens
+		s.data.Tokens = append(append([]Token(nil), tokens[:i]...), tokens[i+1:]...)
+		if err := s.save(); err != nil {
+			s.data.Tokens = tokens
+			return err
+		}
+		return nil
+	}
+	return fmt.Errorf("令牌 %s %w", id, ErrNotFound)
+}
+
+// VerifyToken 校验API令牌，有效时返回令牌信息并记录使用时间（不立即写入文件）
+func (s *Store) VerifyToken(plain string) (Token, bool) {
+	if !strings.HasPrefix(plain, TokenPrefix) {
+		return Token{}, false
+	}
+	hash := []byte(hashToken(plain))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, token := range s.data.Tokens {
+		if subtle.ConstantTimeCompare([]byte(token.Hash), hash) == 1 {
+			now := time.Now()
+			s.data.Tokens[i].LastUsedAt = &now
+			token.Hash = ""
+			return token, true
+		}
+	}
+	return Token{}, false
+}
+
+// hashToken 返回API令牌的哈希，令牌本身是高强度的随机数，不需要加盐
+func hashToken(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomString 返回 n 个随机字节的 URL 安全 base64 编码
+func randomString(n int) (string, error) {
+	bytes := make([]byte, n)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", fmt.Errorf("生成随机数失败: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
+}
+
+// randomHex 返回 n 个随机字节的十六进制编码
+func randomHex(n int) (string, error) {
+	bytes := make([]byte, n)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", fmt.Errorf("生成随机数失败: %v", err)
+	}
+	return hex.EncodeToString(bytes), nil
+}
@@ -0,0 +1,170 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPBKDF2 使用 RFC 7914 中的测试向量验证密钥派生
+func TestPBKDF2(t *testing.T) {
+	key := pbkdf2(sha256.New, []byte("passwd"), []byte("salt"), 1, 64)
+	assert.Equal(t, "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783", hex.EncodeToString(key))
+
+	hash, err := hashPassword("correct horse")
+	require.NoError(t, err)
+	assert.True(t, checkPassword(hash, "correct horse"))
+	assert.False(t, checkPassword(hash, "wrong horse"))
+	assert.False(t, checkPassword("invalid", "correct horse"))
+}
+
+// TestStoreUsers 测试创建管理员、登录校验、修改密码和删除用户，并验证保存到文件
+func TestStoreUsers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "auth.json")
+	store, err := NewStore(path)
+	require.NoError(t, err)
+
+	generated, err := store.EnsureAdmin("")
+	require.NoError(t, err)
+	assert.NotEmpty(t, generated, "没有指定密码时生成随机密码")
+	again, err := store.EnsureAdmin("")
+	require.NoError(t, err)
+	assert.Empty(t, again, "已有用户时不再创建")
+
+	user, err := store.Authenticate("admin", generated)
+	require.NoError(t, err)
+	assert.Equal(t, RoleAdmin, user.Role)
+	assert.Empty(t, user.PasswordHash)
+	_, err = store.Authenticate("admin", "wrong-password")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	_, err = store.Authenticate("nobody", generated)
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	assert.Error(t, store.AddUser("viewer", "short", RoleReadOnly), "密码太短")
+	assert.Error(t, store.AddUser("bad name", "password123", RoleReadOnly), "用户名无效")
+	assert.Error(t, store.AddUser("viewer", "password123", Role("owner")), "角色无效")
+	require.NoError(t, store.AddUser("viewer", "password123", RoleReadOnly))
+	assert.Error(t, store.AddUser("viewer", "password123", RoleReadOnly), "用户已存在")
+	require.NoError(t, store.SetPassword("viewer", "password456"))
+
+	assert.ErrorIs(t, store.RemoveUser("admin"), ErrLastAdmin)
+	assert.True(t, errors.Is(store.RemoveUser("nobody"), ErrNotFound))
+
+	// 文件只有所有者可读写，不保存明文密码
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "password456")
+
+	reloaded, err := NewStore(path)
+	require.NoError(t, err)
+	_, err = reloaded.Authenticate("viewer", "password456")
+	require.NoError(t, err)
+	require.NoError(t, reloaded.RemoveUser("viewer"))
+	assert.Len(t, reloaded.Users(), 1)
+}
+
+// TestStoreTokens 测试创建、校验和删除API令牌
+func TestStoreTokens(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "auth.json")
+	store, err := NewStore(path)
+	require.NoError(t, err)
+
+	plain, token, err := store.CreateToken("cli", RoleReadOnly, "admin")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(plain, TokenPrefix))
+	assert.Empty(t, token.Hash)
+	_, _, err = store.CreateToken(" ", RoleAdmin, "admin")
+	assert.Error(t, err)
+
+	verified, ok := store.VerifyToken(plain)
+	require.True(t, ok)
+	assert.Equal(t, token.ID, verified.ID)
+	assert.Equal(t, RoleReadOnly, verified.Role)
+	_, ok = store.VerifyToken(plain + "x")
+	assert.False(t, ok)
+	require.NotNil(t, store.Tokens()[0].LastUsedAt)
+
+	// 文件中只保存令牌的哈希
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), plain)
+
+	require.NoError(t, store.RevokeToken(token.ID))
+	_, ok = store.VerifyToken(plain)
+	assert.False(t, ok)
+	assert.ErrorIs(t, store.RevokeToken(token.ID), ErrNotFound)
+}
+
+// TestSessions 测试会话过期和删除用户的全部会话
+func TestSessions(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	sessions := NewSessions(time.Hour)
+	sessions.now = func() time.Time { return now }
+
+	first, err := sessions.Create(User{Username: "admin", Role: RoleAdmin})
+	require.NoError(t, err)
+	second, err := sessions.Create(User{Username: "viewer", Role: RoleReadOnly})
+	require.NoError(t, err)
+
+	session, ok := sessions.Get(first.ID)
+	require.True(t, ok)
+	assert.Equal(t, RoleAdmin, session.Role)
+
+	sessions.DeleteUser("admin")
+	_, ok = sessions.Get(first.ID)
+	assert.False(t, ok)
+
+	now = now.Add(time.Hour)
+	_, ok = sessions.Get(second.ID)
+	assert.False(t, ok, "会话已过期")
+
+	assert.True(t, RoleAdmin.Allows(RoleReadOnly))
+	assert.False(t, RoleReadOnly.Allows(RoleAdmin))
+}
+
+// TestLoginLimiter 测试连续登录失败后锁定、锁定时长加倍、登录成功后清除和长时间没有失败后清除
+func TestLoginLimiter(t *testing.T) {
+	limiter := NewLoginLimiter(3, time.Minute, 5*time.Minute)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		limiter.Fail("ip:10.0.0.1", "user:admin")
+	}
+	assert.Zero(t, limiter.Wait("ip:10.0.0.1", "user:admin"), "允许的失败次数内不锁定")
+
+	limiter.Fail("ip:10.0.0.1", "user:admin")
+	assert.Equal(t, time.Minute, limiter.Wait("ip:10.0.0.1"))
+	assert.Equal(t, time.Minute, limiter.Wait("ip:10.0.0.2", "user:admin"), "换IP地址后同一用户仍然锁定")
+	assert.Zero(t, limiter.Wait("ip:10.0.0.2", "user:viewer"))
+
+	limiter.Fail("ip:10.0.0.1")
+	assert.Equal(t, 2*time.Minute, limiter.Wait("ip:10.0.0.1"), "每次失败锁定时长加倍")
+	for i := 0; i < 5; i++ {
+		limiter.Fail("ip:10.0.0.1")
+	}
+	assert.Equal(t, 5*time.Minute, limiter.Wait("ip:10.0.0.1"), "锁定时长不超过上限")
+
+	now = now.Add(5 * time.Minute)
+	assert.Zero(t, limiter.Wait("ip:10.0.0.1"), "锁定结束后可以再次尝试")
+	limiter.Fail("ip:10.0.0.1")
+	assert.Equal(t, 5*time.Minute, limiter.Wait("ip:10.0.0.1"), "锁定结束后失败次数不清零")
+
+	limiter.Reset("user:admin")
+	assert.Zero(t, limiter.Wait("user:admin"), "登录成功后清除")
+
+	now = now.Add(2 * time.Hour)
+	assert.Zero(t, limiter.Wait("ip:10.0.0.1"))
+	assert.Empty(t, limiter.failures, "长时间没有失败的记录被清除")
+}
@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultLoginFreeAttempts 连续登录失败多少次之后开始锁定
+	DefaultLoginFreeAttempts = 5
+	// DefaultLoginLockout 第一次锁定的时长，之后每次失败加倍
+	DefaultLoginLockout = 30 * time.Second
+	// DefaultLoginMaxLockout 锁定时长的上限
+	DefaultLoginMaxLockout = 15 * time.Minute
+	// loginForgetAfter 超过这个时间没有失败的记录被清除
+	loginForgetAfter = time.Hour
+)
+
+// loginFailures 同一个IP地址或用户的连续登录失败
+type loginFailures struct {
+	count       int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+// LoginLimiter 限制登录尝试：同一个键（IP地址或用户名）连续失败超过 free 次后锁定，
+// 锁定时长从 lockout 开始每次失败加倍，最长 maxLockout。记录保存在内存中，重启后清除
+type LoginLimiter struct {
+	mu         sync.Mutex
+	free       int
+	lockout    time.Duration
+	maxLockout time.Duration
+	failures   map[string]*loginFailures
+	now        func() time.Time
+}
+
+// NewLoginLimiter 创建登录尝试限制，连续失败 free 次之后锁定 lockout，之后每次失败加倍，最长 maxLockout
+func NewLoginLimiter(free int, lockout, maxLockout time.Duration) *LoginLimiter {
+	return &LoginLimiter{
+		free:       free,
+		lockout:    lockout,
+		maxLockout: maxLockout,
+		failures:   make(map[string]*loginFailures),
+		now:        time.Now,
+	}
+}
+
+// Wait 返回需要等待多久才能再次尝试登录，取各个键中最长的锁定时间，没有锁定时返回0
+func (l *LoginLimiter) Wait(keys ...string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.prune()
+
+	now := l.now()
+	var wait time.Duration
+	for _, key := range keys {
+		if f, ok := l.failures[key]; ok && f.lockedUntil.After(now) {
+			if d := f.lockedUntil.Sub(now); d > wait {
+				wait = d
+			}
+		}
+	}
+	return wait
+}
+
+// Fail 记录一次登录失败，连续失败超过允许的次数时锁定
+func (l *LoginLimiter) Fail(keys ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.prune()
+
+	now := l.now()
+	for _, key := range keys {
+		f, ok := l.failures[key]
+		if !ok {
+			f = &loginFailures{}
+			l.failures[key] = f
+		}
+		f.count++
+		f.lastFailure = now
+		if over := f.count - l.free; over > 0 {
+			lockout := l.lockout
+			for i := 1; i < over && lockout < l.maxLockout; i++ {
+				lockout *= 2
+			}
+			if lockout > l.maxLockout {
+				lockout = l.maxLockout
+			}
+			f.lockedUntil = now.Add(lockout)
+		}
+	}
+}
+
+// Reset 清除登录失败记录，用于登录成功之后
+func (l *LoginLimiter) Reset(keys ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, key := range keys {
+		delete(l.failures, key)
+	}
+}
+
+// prune 清除长时间没有失败且不在锁定中的记录，调用时需要持有锁
+func (l *LoginLimiter) prune() {
+	now := l.now()
+	for key, f := range l.failures {
+		if now.Sub(f.lastFailure) > loginForgetAfter && !f.lockedUntil.After(now) {
+			delete(l.failures, key)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultSessionTTL 登录会话的有效期
+const DefaultSessionTTL = 7 * 24 * time.Hour
+
+// SessionCookie 保存会话ID的Cookie名称
+const SessionCookie = "tvsubscribe_session"
+
+// Session Web界面的登录会话
+type Session struct {
+	ID        string
+	Username  string
+	Role      Role
+	ExpiresAt time.Time
+}
+
+// Sessions 保存在内存中的登录会话，重启后需要重新登录
+type Sessions struct {
+	mu       sync.Mutex
+	ttl      time.Duration
+	sessions map[string]Session
+	now      func() time.Time
+}
+
+// NewSessions 创建会话存储，ttl 为登录会话的有效期
+func NewSessions(ttl time.Duration) *Sessions {
+	return &Sessions{ttl: ttl, sessions: make(map[string]Session), now: time.Now}
+}
+
+// Create 为登录的用户创建会话
+func (s *Sessions) Create(user User) (Session, error) {
+	id, err := randomString(32)
+	if err != nil {
+		return Session{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune()
+	session := Session{ID: id, Username: user.Username, Role: user.Role, ExpiresAt: s.now().Add(s.ttl)}
+	s.sessions[id] = session
+	return session, nil
+}
+
+// Get 返回有效的会话，会话不存在或已过期时返回 false
+func (s *Sessions) Get(id string) (Session, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[id]
+	if !ok {
+		return Session{}, false
+	}
+	if !s.now().Before(session.ExpiresAt) {
+		delete(s.sessions, id)
+		return Session{}, false
+	}
+	return session, true
+}
+
+// TTL 返回登录会话的有效期，用于设置Cookie的有效期
+func (s *Sessions) TTL() time.Duration {
+	return s.ttl
+}
+
+// Delete 删除会话，用于退出登录
+func (s *Sessions) Delete(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, id)
+}
+
+// DeleteUser 删除用户的全部会话，用于删除用户和修改密码之后
+func (s *Sessions) DeleteUser(username string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, session := range s.sessions {
+		if session.Username == username {
+			delete(s.sessions, id)
+		}
+	}
+}
+
+// prune 删除已过期的会话，调用方需持有锁
+func (s *Sessions) prune() {
+	now := s.now()
+	for id, session := range s.sessions {
+		if !now.Before(session.ExpiresAt) {
+			delete(s.sessions, id)
+		}
+	}
+}
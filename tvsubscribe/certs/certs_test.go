@@ -0,0 +1,109 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestEnsureSelfSigned 测试自签名证书只在不存在或临近到期时生成，并且可以作为CA证书验证自身
+func TestEnsureSelfSigned(t *testing.T) {
+	dir := t.TempDir()
+	certPath := filepath.Join(dir, "tls", "cert.pem")
+	keyPath := filepath.Join(dir, "tls", "key.pem")
+
+	generated, err := EnsureSelfSigned(certPath, keyPath, []string{"localhost", "127.0.0.1"})
+	require.NoError(t, err)
+	assert.True(t, generated)
+
+	info, err := os.Stat(keyPath)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// 已存在且未到期时不重新生成
+	generated, err = EnsureSelfSigned(certPath, keyPath, []string{"localhost"})
+	require.NoError(t, err)
+	assert.False(t, generated)
+
+	// 客户端把证书文件作为CA证书时可以验证主机名和IP
+	leaf := loadLeaf(t, certPath, keyPath)
+	pool := x509.NewCertPool()
+	pool.AddCert(leaf)
+	for _, host := range []string{"localhost", "127.0.0.1"} {
+		_, err := leaf.Verify(x509.VerifyOptions{DNSName: host, Roots: pool})
+		assert.NoError(t, err, host)
+	}
+	_, err = leaf.Verify(x509.VerifyOptions{DNSName: "example.com", Roots: pool})
+	assert.Error(t, err)
+
+	// 临近到期时重新生成
+	require.NoError(t, GenerateSelfSigned(certPath, keyPath, []string{"localhost"}, time.Now().Add(-350*24*time.Hour)))
+	generated, err = EnsureSelfSigned(certPath, keyPath, []string{"localhost"})
+	require.NoError(t, err)
+	assert.True(t, generated)
+	assert.True(t, loadLeaf(t, certPath, keyPath).NotAfter.After(time.Now().Add(300*24*time.Hour)))
+}
+
+// TestReloader 测试重新加载证书后新连接使用新证书，加载失败时继续使用之前的证书
+func TestReloader(t *testing.T) {
+	dir := t.TempDir()
+	certPath := filepath.Join(dir, "cert.pem")
+	keyPath := filepath.Join(dir, "key.pem")
+	require.NoError(t, GenerateSelfSigned(certPath, keyPath, []string{"127.0.0.1"}, time.Now()))
+
+	reloader, err := NewReloader(certPath, keyPath)
+	require.NoError(t, err)
+	first := reloader.Leaf()
+
+	// httptest 会使用自带的证书，这里直接监听
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", reloader.TLSConfig())
+	require.NoError(t, err)
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})}
+	go server.Serve(listener)
+	defer server.Close()
+	serverURL := "https://" + listener.Addr().String()
+
+	// served 返回服务器在新连接上使用的证书
+	served := func() *x509.Certificate {
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+		defer client.CloseIdleConnections()
+		resp, err := client.Get(serverURL)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.TLS.PeerCertificates[0]
+	}
+	assert.Equal(t, Fingerprint(first), Fingerprint(served()))
+
+	require.NoError(t, GenerateSelfSigned(certPath, keyPath, []string{"127.0.0.1"}, time.Now()))
+	require.NoError(t, reloader.Reload())
+	second := reloader.Leaf()
+	assert.NotEqual(t, Fingerprint(first), Fingerprint(second))
+	assert.Equal(t, Fingerprint(second), Fingerprint(served()))
+
+	require.NoError(t, os.WriteFile(certPath, []byte("not a certificate"), 0644))
+	assert.Error(t, reloader.Reload())
+	assert.Equal(t, Fingerprint(second), Fingerprint(served()))
+}
+
+// TestNewReloaderMissing 测试证书文件不存在时返回错误
+func TestNewReloaderMissing(t *testing.T) {
+	dir := t.TempDir()
+	_, err := NewReloader(filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem"))
+	assert.Error(t, err)
+}
+
+func loadLeaf(t *testing.T, certPath, keyPath string) *x509.Certificate {
+	t.Helper()
+	cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+	require.NoError(t, err)
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	return leaf
+}
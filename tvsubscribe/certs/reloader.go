@@ -0,0 +1,87 @@
+package certs
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Reloader 保存HTTPS使用的证书，证书文件更新后调用 Reload 替换，
+// 之后的新连接使用新证书，已建立的连接不受影响
+type Reloader struct {
+	certPath string
+	keyPath  string
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+}
+
+// NewReloader 加载证书和私钥文件
+func NewReloader(certPath, keyPath string) (*Reloader, error) {
+	r := &Reloader{certPath: certPath, keyPath: keyPath}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload 重新加载证书和私钥文件，失败时继续使用之前的证书
+func (r *Reloader) Reload() error {
+	cert, err := tls.LoadX509KeyPair(r.certPath, r.keyPath)
+	if err != nil {
+		return fmt.Errorf("加载证书 %s 失败: %v", r.certPath, err)
+	}
+	if cert.Leaf == nil {
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			return fmt.Errorf("解析证书 %s 失败: %v", r.certPath, err)
+		}
+		cert.Leaf = leaf
+	}
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.mu.Unlock()
+	return nil
+}
+
+// Paths 返回证书和私钥文件的路径，用于监听文件变化
+func (r *Reloader) Paths() []string {
+	return []string{r.certPath, r.keyPath}
+}
+
+// Leaf 返回当前使用的证书
+func (r *Reloader) Leaf() *x509.Certificate {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert.Leaf
+}
+
+// GetCertificate 返回当前使用的证书，用于 tls.Config
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// TLSConfig 返回使用当前证书的服务器TLS配置，最低版本为 TLS 1.2
+func (r *Reloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: r.GetCertificate,
+	}
+}
+
+// Fingerprint 返回证书的 SHA-256 指纹，格式为冒号分隔的大写十六进制，
+// 用于在客户端核对自签名证书
+func Fingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	parts := make([]string, len(sum))
+	for i, b := range sum {
+		parts[i] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(parts, ":")
+}
@@ -0,0 +1,120 @@
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"tvsubscribe/storage"
+)
+
+const (
+	selfSignedValidity = 365 * 24 * time.Hour // 自签名证书的有效期
+	renewBefore        = 30 * 24 * time.Hour  // 自签名证书在到期前多久重新生成
+)
+
+// DefaultHosts 返回自签名证书包含的主机名和IP：localhost、本机主机名和本机的全部IP地址
+func DefaultHosts() []string {
+	hosts := []string{"localhost"}
+	if hostname, err := os.Hostname(); err == nil && hostname != "" && hostname != "localhost" {
+		hosts = append(hosts, hostname)
+	}
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return append(hosts, "127.0.0.1", "::1")
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLinkLocalUnicast() {
+			hosts = append(hosts, ipNet.IP.String())
+		}
+	}
+	return hosts
+}
+
+// EnsureSelfSigned 确保自签名证书和私钥文件存在且未临近到期，
+// 文件不存在、只有其中一个或证书在30天内到期时重新生成，返回是否生成了新证书
+func EnsureSelfSigned(certPath, keyPath string, hosts []string) (bool, error) {
+	_, certErr := os.Stat(certPath)
+	_, keyErr := os.Stat(keyPath)
+	if certErr == nil && keyErr == nil {
+		cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+		if err != nil {
+			return false, fmt.Errorf("加载自签名证书失败，删除 %s 和 %s 后重新生成: %v", certPath, keyPath, err)
+		}
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			return false, fmt.Errorf("解析自签名证书失败: %v", err)
+		}
+		if time.Now().Add(renewBefore).Before(leaf.NotAfter) {
+			return false, nil
+		}
+	}
+
+	if err := GenerateSelfSigned(certPath, keyPath, hosts, time.Now()); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// GenerateSelfSigned 生成 ECDSA P-256 自签名证书，hosts 中的IP地址和主机名写入证书的备用名称，
+// 私钥文件的权限为 0600
+func GenerateSelfSigned(certPath, keyPath string, hosts []string, now time.Time) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return fmt.Errorf("生成私钥失败: %v", err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return fmt.Errorf("生成证书序列号失败: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "tvsubscribe", Organization: []string{"tvsubscribe self-signed"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(selfSignedValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else if host != "" {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return fmt.Errorf("生成证书失败: %v", err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return fmt.Errorf("序列化私钥失败: %v", err)
+	}
+
+	for _, path := range []string{certPath, keyPath} {
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return fmt.Errorf("创建证书目录失败: %v", err)
+		}
+	}
+	// 先写私钥，证书文件的变化会触发重新加载，这时私钥已经是新的
+	if err := storage.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		return fmt.Errorf("保存私钥失败: %v", err)
+	}
+	if err := storage.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		return fmt.Errorf("保存证书失败: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,274 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"tvsubscribe/auth"
+)
+
+// Login 使用用户名和密码登录，之后的请求使用登录会话
+func (c *Client) Login(username, password string) (*auth.Identity, error) {
+	url := fmt.Sprintf("%s/api/auth/login", c.baseURL)
+
+	jsonData, err := json.Marshal(map[string]string{"username": username, "password": password})
+	if err != nil {
+		return nil, fmt.Errorf("序列化请求失败: %v", err)
+	}
+
+	resp, err := c.httpClient.Post(url, "application/json", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应失败: %v", err)
+	}
+
+	var response struct {
+		Success bool          `json:"success"`
+		Message string        `json:"message"`
+		Data    auth.Identity `json:"data"`
+	}
+
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, statusError(resp.StatusCode)
+	}
+
+	if !response.Success {
+		return nil, fmt.Errorf("登录失败: %s", response.Message)
+	}
+
+	return &response.Data, nil
+}
+
+// WhoAmI 获取当前使用的身份和角色
+func (c *Client) WhoAmI() (*auth.Identity, error) {
+	url := fmt.Sprintf("%s/api/auth/me", c.baseURL)
+	resp, err := c.httpClient.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应失败: %v", err)
+	}
+
+	var response struct {
+		Success bool          `json:"success"`
+		Message string        `json:"message"`
+		Data    auth.Identity `json:"data"`
+	}
+
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("解析响应失败: %v", err)
+	}
+
+	if !response.Success {
+		return nil, fmt.Errorf("操作失败: %s", response.Message)
+	}
+
+	return &response.Data, nil
+}
+
+// ListTokens 获取全部API令牌，不包含令牌本身
+func (c *Client) ListTokens() ([]auth.Token, error) {
+	url := fmt.Sprintf("%s/api/auth/tokens", c.baseURL)
+	resp, err := c.httpClient.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应失败: %v", err)
+	}
+
+	var response struct {
+		Success bool         `json:"success"`
+		Message string       `json:"message"`
+		Data    []auth.Token `json:"data"`
+	}
+
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("解析响应失败: %v", err)
+	}
+
+	if !response.Success {
+		return nil, fmt.Errorf("操作失败: %s", response.Message)
+	}
+
+	return response.Data, nil
+}
+
+// CreateToken 创建API令牌，返回只显示这一次的令牌和令牌信息
+func (c *Client) CreateToken(name string, role auth.Role) (string, *auth.Token, error) {
+	url := fmt.Sprintf("%s/api/auth/tokens", c.baseURL)
+
+	jsonData, err := json.Marshal(map[string]interface{}{"name": name, "role": role})
+	if err != nil {
+		return "", nil, fmt.Errorf("序列化请求失败: %v", err)
+	}
+
+	resp, err := c.httpClient.Post(url, "application/json", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "", nil, fmt.Errorf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", nil, fmt.Errorf("读取响应失败: %v", err)
+	}
+
+	var response struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+		Data    struct {
+			Token string     `json:"token"`
+			Info  auth.Token `json:"info"`
+		} `json:"data"`
+	}
+
+	if err := json.Unmarshal(body, &response); err != nil {
+		return "", nil, statusError(resp.StatusCode)
+	}
+
+	if !response.Success {
+		return "", nil, fmt.Errorf("操作失败: %s", response.Message)
+	}
+
+	return response.Data.Token, &response.Data.Info, nil
+}
+
+// RevokeToken 删除API令牌
+func (c *Client) RevokeToken(id string) error {
+	return c.deleteResource("/api/auth/tokens/" + url.PathEscape(id))
+}
+
+// ListUsers 获取全部用户
+func (c *Client) ListUsers() ([]auth.User, error) {
+	url := fmt.Sprintf("%s/api/auth/users", c.baseURL)
+	resp, err := c.httpClient.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应失败: %v", err)
+	}
+
+	var response struct {
+		Success bool        `json:"success"`
+		Message string      `json:"message"`
+		Data    []auth.User `json:"data"`
+	}
+
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("解析响应失败: %v", err)
+	}
+
+	if !response.Success {
+		return nil, fmt.Errorf("操作失败: %s", response.Message)
+	}
+
+	return response.Data, nil
+}
+
+// AddUser 添加用户
+func (c *Client) AddUser(username, password string, role auth.Role) error {
+	url := fmt.Sprintf("%s/api/auth/users", c.baseURL)
+
+	jsonData, err := json.Marshal(map[string]interface{}{"username": username, "password": password, "role": role})
+	if err != nil {
+		return fmt.Errorf("序列化请求失败: %v", err)
+	}
+
+	resp, err := c.httpClient.Post(url, "application/json", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("读取响应失败: %v", err)
+	}
+
+	var response struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+	}
+
+	if err := json.Unmarshal(body, &response); err != nil {
+		return statusError(resp.StatusCode)
+	}
+
+	if !response.Success {
+		return fmt.Errorf("操作失败: %s", response.Message)
+	}
+
+	return nil
+}
+
+// RemoveUser 删除用户
+func (c *Client) RemoveUser(username string) error {
+	return c.deleteResource("/api/auth/users/" + url.PathEscape(username))
+}
+
+// deleteResource 发送 DELETE 请求
+func (c *Client) deleteResource(path string) error {
+	req, err := http.NewRequest(http.MethodDelete, c.baseURL+path, nil)
+	if err != nil {
+		return fmt.Errorf("创建请求失败: %v", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("读取响应失败: %v", err)
+	}
+
+	var response struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+	}
+
+	if err := json.Unmarshal(body, &response); err != nil {
+		return statusError(resp.StatusCode)
+	}
+
+	if !response.Success {
+		return fmt.Errorf("操作失败: %s", response.Message)
+	}
+
+	return nil
+}
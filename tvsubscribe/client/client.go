@@ -2,33 +2,96 @@ package client
 
 import (
 	"bytes"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/cookiejar"
+	"os"
+	"strings"
 	"time"
 
 	"tvsubscribe"
+	"tvsubscribe/config"
+	"tvsubscribe/history"
+	"tvsubscribe/subscribe"
+	"tvsubscribe/wishlist"
 )
 
 // Client HTTP客户端
 type Client struct {
 	baseURL    string
 	httpClient *http.Client
+	transport  *http.Transport
 }
 
-// NewClient 创建新的HTTP客户端
+// NewClient 创建新的HTTP客户端，登录后的会话Cookie保存在客户端中。
+// serverURL 可以以 http:// 或 https:// 开头，没有协议时使用 http://
 func NewClient(serverURL string) *Client {
+	if !strings.Contains(serverURL, "://") {
+		serverURL = "http://" + serverURL
+	}
+	jar, _ := cookiejar.New(nil)
+	transport := http.DefaultTransport.(*http.Transport).Clone()
 	return &Client{
-		baseURL: serverURL,
+		baseURL: strings.TrimSuffix(serverURL, "/"),
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout:   30 * time.Second,
+			Jar:       jar,
+			Transport: transport,
 		},
+		transport: transport,
 	}
 }
 
+// SetToken 设置API令牌，之后的请求都带有 Authorization: Bearer 头
+func (c *Client) SetToken(token string) {
+	c.httpClient.Transport = &tokenTransport{token: token, base: c.transport}
+}
+
+// SetCACert 使用PEM格式的CA证书文件验证HTTPS服务器的证书，替代系统的CA证书。
+// 服务器使用自签名证书时，可以直接使用服务器的证书文件
+func (c *Client) SetCACert(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("读取CA证书失败: %v", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return fmt.Errorf("CA证书文件 %s 中没有有效的PEM证书", path)
+	}
+	c.transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	return nil
+}
+
+// tokenTransport 为每个请求添加API令牌
+type tokenTransport struct {
+	token string
+	base  http.RoundTripper
+}
+
+// RoundTrip 复制请求并添加 Authorization 头
+func (t *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+t.token)
+	return t.base.RoundTrip(req)
+}
+
+// statusError 返回服务器错误状态码对应的错误，未认证和权限不足时说明原因
+func statusError(code int) error {
+	switch code {
+	case http.StatusUnauthorized:
+		return fmt.Errorf("服务器返回错误状态码: %d，未登录或API令牌无效", code)
+	case http.StatusForbidden:
+		return fmt.Errorf("服务器返回错误状态码: %d，权限不足，需要管理员权限", code)
+	}
+	return fmt.Errorf("服务器返回错误状态码: %d", code)
+}
+
 // GetConfig 获取配置
-func (c *Client) GetConfig() (map[string]interface{}, error) {
+func (c *Client) GetConfig() (*config.Config, error) {
 	url := fmt.Sprintf("%s/getConfig", c.baseURL)
 	resp, err := c.httpClient.Get(url)
 	if err != nil {
@@ -37,7 +100,7 @@ func (c *Client) GetConfig() (map[string]interface{}, error) {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("服务器返回错误状态码: %d", resp.StatusCode)
+		return nil, statusError(resp.StatusCode)
 	}
 
 	body, err := io.ReadAll(resp.Body)
@@ -46,9 +109,9 @@ func (c *Client) GetConfig() (map[string]interface{}, error) {
 	}
 
 	var response struct {
-		Success bool                   `json:"success"`
-		Message string                 `json:"message"`
-		Data    map[string]interface{} `json:"data"`
+		Success bool          `json:"success"`
+		Message string        `json:"message"`
+		Data    config.Config `json:"data"`
 	}
 
 	if err := json.Unmarshal(body, &response); err != nil {
@@ -59,48 +122,118 @@ func (c *Client) GetConfig() (map[string]interface{}, error) {
 		return nil, fmt.Errorf("操作失败: %s", response.Message)
 	}
 
-	return response.Data, nil
+	return &response.Data, nil
 }
 
-// SetConfig 设置配置
-func (c *Client) SetConfig(config map[string]interface{}) error {
+// GetEffectiveConfig 获取生效的配置项和路径选项及其来源
+func (c *Client) GetEffectiveConfig() (*config.Effective, error) {
+	url := fmt.Sprintf("%s/api/config/effective", c.baseURL)
+	resp, err := c.httpClient.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应失败: %v", err)
+	}
+
+	var response struct {
+		Success bool             `json:"success"`
+		Message string           `json:"message"`
+		Data    config.Effective `json:"data"`
+	}
+
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("解析响应失败: %v", err)
+	}
+
+	if !response.Success {
+		return nil, fmt.Errorf("操作失败: %s", response.Message)
+	}
+
+	return &response.Data, nil
+}
+
+// SetConfig 修改配置，只修改 patch 中出现的配置项，返回修改后的配置
+func (c *Client) SetConfig(patch config.Patch) (*config.Config, error) {
 	url := fmt.Sprintf("%s/setConfig", c.baseURL)
 
-	jsonData, err := json.Marshal(config)
+	jsonData, err := json.Marshal(patch)
 	if err != nil {
-		return fmt.Errorf("序列化配置失败: %v", err)
+		return nil, fmt.Errorf("序列化配置失败: %v", err)
 	}
 
 	resp, err := c.httpClient.Post(url, "application/json", bytes.NewBuffer(jsonData))
 	if err != nil {
-		return fmt.Errorf("请求失败: %v", err)
+		return nil, fmt.Errorf("请求失败: %v", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("服务器返回错误状态码: %d", resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应失败: %v", err)
 	}
 
+	var response struct {
+		Success bool          `json:"success"`
+		Message string        `json:"message"`
+		Data    config.Config `json:"data"`
+	}
+
+	// 校验失败或配置文件冲突时服务器返回非200状态码，但响应中带有错误原因
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, statusError(resp.StatusCode)
+	}
+
+	if !response.Success {
+		return nil, fmt.Errorf("操作失败: %s", response.Message)
+	}
+
+	return &response.Data, nil
+}
+
+// ValidateConfig 校验配置修改但不保存，返回修改后的配置
+func (c *Client) ValidateConfig(patch config.Patch) (*config.Config, error) {
+	url := fmt.Sprintf("%s/validateConfig", c.baseURL)
+
+	jsonData, err := json.Marshal(patch)
+	if err != nil {
+		return nil, fmt.Errorf("序列化配置失败: %v", err)
+	}
+
+	resp, err := c.httpClient.Post(url, "application/json", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return fmt.Errorf("读取响应失败: %v", err)
+		return nil, fmt.Errorf("读取响应失败: %v", err)
 	}
 
 	var response struct {
-		Success bool   `json:"success"`
-		Message string `json:"message"`
-		Data    interface{} `json:"data"`
+		Success bool          `json:"success"`
+		Message string        `json:"message"`
+		Data    config.Config `json:"data"`
 	}
 
+	// 校验失败时服务器返回400，响应中带有每个配置项的错误原因
 	if err := json.Unmarshal(body, &response); err != nil {
-		return fmt.Errorf("解析响应失败: %v", err)
+		return nil, statusError(resp.StatusCode)
 	}
 
 	if !response.Success {
-		return fmt.Errorf("操作失败: %s", response.Message)
+		return nil, fmt.Errorf("校验失败: %s", response.Message)
 	}
 
-	return nil
+	return &response.Data, nil
 }
 
 // GetSubscribeList 获取订阅列表
@@ -113,7 +246,7 @@ func (c *Client) GetSubscribeList() ([]tvsubscribe.TVInfo, error) {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("服务器返回错误状态码: %d", resp.StatusCode)
+		return nil, statusError(resp.StatusCode)
 	}
 
 	body, err := io.ReadAll(resp.Body)
@@ -154,7 +287,7 @@ func (c *Client) AddSubscribe(tvInfo tvsubscribe.TVInfo) error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("服务器返回错误状态码: %d", resp.StatusCode)
+		return statusError(resp.StatusCode)
 	}
 
 	body, err := io.ReadAll(resp.Body)
@@ -179,6 +312,147 @@ func (c *Client) AddSubscribe(tvInfo tvsubscribe.TVInfo) error {
 	return nil
 }
 
+// UpdateSubscribe 按ID修改订阅，返回修改后的订阅
+func (c *Client) UpdateSubscribe(id string, patch tvsubscribe.TVInfoPatch) (*tvsubscribe.TVInfo, error) {
+	url := fmt.Sprintf("%s/api/subscribes/%s", c.baseURL, id)
+
+	jsonData, err := json.Marshal(patch)
+	if err != nil {
+		return nil, fmt.Errorf("序列化订阅信息失败: %v", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPatch, url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应失败: %v", err)
+	}
+
+	var response struct {
+		Success bool               `json:"success"`
+		Message string             `json:"message"`
+		Data    tvsubscribe.TVInfo `json:"data"`
+	}
+
+	// 校验失败、订阅不存在或重复时服务器返回非200状态码，但响应中带有错误原因
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, statusError(resp.StatusCode)
+	}
+
+	if !response.Success {
+		return nil, fmt.Errorf("操作失败: %s", response.Message)
+	}
+
+	return &response.Data, nil
+}
+
+// ExportSubscribes 导出全部订阅，format 为 json 或 csv，返回导出的文件内容
+func (c *Client) ExportSubscribes(format string) ([]byte, error) {
+	url := fmt.Sprintf("%s/api/subscribes/export?format=%s", c.baseURL, format)
+	resp, err := c.httpClient.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应失败: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var response struct {
+			Success bool   `json:"success"`
+			Message string `json:"message"`
+		}
+		if err := json.Unmarshal(body, &response); err != nil {
+			return nil, statusError(resp.StatusCode)
+		}
+		return nil, fmt.Errorf("操作失败: %s", response.Message)
+	}
+
+	return body, nil
+}
+
+// ImportSubscribes 导入订阅，mode 为 merge 或 replace，dryRun 为 true 时只预览不保存
+func (c *Client) ImportSubscribes(format, mode string, dryRun bool, data []byte) (*subscribe.ImportResult, error) {
+	url := fmt.Sprintf("%s/api/subscribes/import?format=%s&mode=%s&dry_run=%t", c.baseURL, format, mode, dryRun)
+
+	contentType := "application/json"
+	if format == subscribe.FormatCSV {
+		contentType = "text/csv"
+	}
+	resp, err := c.httpClient.Post(url, contentType, bytes.NewBuffer(data))
+	if err != nil {
+		return nil, fmt.Errorf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应失败: %v", err)
+	}
+
+	var response struct {
+		Success bool                   `json:"success"`
+		Message string                 `json:"message"`
+		Data    subscribe.ImportResult `json:"data"`
+	}
+
+	// 文件格式错误或导入模式无效时服务器返回非200状态码，但响应中带有错误原因
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, statusError(resp.StatusCode)
+	}
+
+	if !response.Success {
+		return nil, fmt.Errorf("操作失败: %s", response.Message)
+	}
+
+	return &response.Data, nil
+}
+
+// SyncWishlist 立即同步豆瓣想看列表
+func (c *Client) SyncWishlist() (*wishlist.Result, error) {
+	url := fmt.Sprintf("%s/api/wishlist/sync", c.baseURL)
+	resp, err := c.httpClient.Post(url, "application/json", nil)
+	if err != nil {
+		return nil, fmt.Errorf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应失败: %v", err)
+	}
+
+	var response struct {
+		Success bool            `json:"success"`
+		Message string          `json:"message"`
+		Data    wishlist.Result `json:"data"`
+	}
+
+	// 未配置豆瓣用户或获取列表失败时服务器返回非200状态码，但响应中带有错误原因
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, statusError(resp.StatusCode)
+	}
+
+	if !response.Success {
+		return nil, fmt.Errorf("操作失败: %s", response.Message)
+	}
+
+	return &response.Data, nil
+}
+
 // DelSubscribe 删除订阅
 func (c *Client) DelSubscribe(tvInfo tvsubscribe.TVInfo) error {
 	url := fmt.Sprintf("%s/delSubscribe", c.baseURL)
@@ -195,7 +469,7 @@ func (c *Client) DelSubscribe(tvInfo tvsubscribe.TVInfo) error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("服务器返回错误状态码: %d", resp.StatusCode)
+		return statusError(resp.StatusCode)
 	}
 
 	body, err := io.ReadAll(resp.Body)
@@ -218,4 +492,215 @@ func (c *Client) DelSubscribe(tvInfo tvsubscribe.TVInfo) error {
 	}
 
 	return nil
+}
+
+// postAction 发送不带请求体的POST请求
+func (c *Client) postAction(path string) error {
+	url := fmt.Sprintf("%s%s", c.baseURL, path)
+
+	resp, err := c.httpClient.Post(url, "application/json", nil)
+	if err != nil {
+		return fmt.Errorf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return statusError(resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("读取响应失败: %v", err)
+	}
+
+	var response struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+	}
+
+	if err := json.Unmarshal(body, &response); err != nil {
+		return fmt.Errorf("解析响应失败: %v", err)
+	}
+
+	if !response.Success {
+		return fmt.Errorf("操作失败: %s", response.Message)
+	}
+
+	return nil
+}
+
+// postIDs 发送带订阅ID数组的POST请求
+func (c *Client) postIDs(path string, ids []string) error {
+	url := fmt.Sprintf("%s%s", c.baseURL, path)
+
+	jsonData, err := json.Marshal(map[string][]string{"ids": ids})
+	if err != nil {
+		return fmt.Errorf("序列化请求失败: %v", err)
+	}
+
+	resp, err := c.httpClient.Post(url, "application/json", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("读取响应失败: %v", err)
+	}
+
+	var response struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+	}
+
+	if err := json.Unmarshal(body, &response); err != nil {
+		return statusError(resp.StatusCode)
+	}
+
+	if !response.Success {
+		return fmt.Errorf("操作失败: %s", response.Message)
+	}
+
+	return nil
+}
+
+// PauseSubscribes 暂停订阅
+func (c *Client) PauseSubscribes(ids []string) error {
+	return c.postIDs("/pauseSubscribe", ids)
+}
+
+// ResumeSubscribes 恢复已暂停的订阅
+func (c *Client) ResumeSubscribes(ids []string) error {
+	return c.postIDs("/resumeSubscribe", ids)
+}
+
+// ReactivateSubscribes 重新激活已完结归档的订阅
+func (c *Client) ReactivateSubscribes(ids []string) error {
+	return c.postIDs("/reactivateSubscribe", ids)
+}
+
+// GetSchedulerStatus 获取调度器是否已暂停
+func (c *Client) GetSchedulerStatus() (bool, error) {
+	url := fmt.Sprintf("%s/getSchedulerStatus", c.baseURL)
+	resp, err := c.httpClient.Get(url)
+	if err != nil {
+		return false, fmt.Errorf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, statusError(resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return false, fmt.Errorf("读取响应失败: %v", err)
+	}
+
+	var response struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+		Data    struct {
+			Paused bool `json:"paused"`
+		} `json:"data"`
+	}
+
+	if err := json.Unmarshal(body, &response); err != nil {
+		return false, fmt.Errorf("解析响应失败: %v", err)
+	}
+
+	if !response.Success {
+		return false, fmt.Errorf("操作失败: %s", response.Message)
+	}
+
+	return response.Data.Paused, nil
+}
+
+// PauseScheduler 暂停定时处理
+func (c *Client) PauseScheduler() error {
+	return c.postAction("/pauseScheduler")
+}
+
+// ResumeScheduler 恢复定时处理
+func (c *Client) ResumeScheduler() error {
+	return c.postAction("/resumeScheduler")
+}
+
+// RunNow 立即处理所有订阅
+func (c *Client) RunNow() error {
+	return c.postAction("/runNow")
+}
+
+// ListRuns 获取最近的处理记录，limit 为0时返回全部
+func (c *Client) ListRuns(limit int) ([]history.Run, error) {
+	url := fmt.Sprintf("%s/api/runs?limit=%d", c.baseURL, limit)
+	resp, err := c.httpClient.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应失败: %v", err)
+	}
+
+	var response struct {
+		Success bool          `json:"success"`
+		Message string        `json:"message"`
+		Data    []history.Run `json:"data"`
+	}
+
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("解析响应失败: %v", err)
+	}
+
+	if !response.Success {
+		return nil, fmt.Errorf("操作失败: %s", response.Message)
+	}
+
+	return response.Data, nil
+}
+
+// GetRun 获取单条处理记录的详情
+func (c *Client) GetRun(id string) (*history.Run, error) {
+	url := fmt.Sprintf("%s/api/runs/%s", c.baseURL, id)
+	resp, err := c.httpClient.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("处理记录不存在: %s", id)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应失败: %v", err)
+	}
+
+	var response struct {
+		Success bool        `json:"success"`
+		Message string      `json:"message"`
+		Data    history.Run `json:"data"`
+	}
+
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("解析响应失败: %v", err)
+	}
+
+	if !response.Success {
+		return nil, fmt.Errorf("操作失败: %s", response.Message)
+	}
+
+	return &response.Data, nil
 }
\ No newline at end of file
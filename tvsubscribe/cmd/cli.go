@@ -1,18 +1,69 @@
 package main
 
 import (
+	"bufio"
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 
 	"tvsubscribe"
+	"tvsubscribe/auth"
 	"tvsubscribe/client"
+	"tvsubscribe/config"
+	"tvsubscribe/subscribe"
 )
 
+const (
+	tokenEnv  = "TVSUBSCRIBE_TOKEN"   // 命令行默认使用的API令牌环境变量
+	caCertEnv = "TVSUBSCRIBE_CA_CERT" // 命令行默认使用的CA证书文件环境变量
+)
+
+// connect 创建连接服务器的客户端，serverURL 没有协议时使用 http://，
+// caCert 为空时使用环境变量 TVSUBSCRIBE_CA_CERT，都为空时使用系统的CA证书
+func connect(serverURL, caCert string) *client.Client {
+	c := client.NewClient(serverURL)
+	if caCert == "" {
+		caCert = os.Getenv(caCertEnv)
+	}
+	if caCert != "" {
+		if err := c.SetCACert(caCert); err != nil {
+			log.Fatal(err)
+		}
+	}
+	return c
+}
+
+// newClient 创建使用API令牌的客户端，token 为空时使用环境变量 TVSUBSCRIBE_TOKEN
+func newClient(serverURL, token, caCert string) *client.Client {
+	c := connect(serverURL, caCert)
+	if token == "" {
+		token = os.Getenv(tokenEnv)
+	}
+	if token != "" {
+		c.SetToken(token)
+	}
+	return c
+}
+
+// loginClient 使用用户名和密码登录，返回使用登录会话的客户端，密码从标准输入读取
+func loginClient(serverURL, caCert, username string) *client.Client {
+	password, err := readSecret(username + " 的密码")
+	if err != nil {
+		log.Fatalf("读取密码失败: %v", err)
+	}
+	c := connect(serverURL, caCert)
+	if _, err := c.Login(username, password); err != nil {
+		log.Fatal(err)
+	}
+	return c
+}
+
 // parseKeyValuePairs 解析key=value格式的参数
 func parseKeyValuePairs(args []string) map[string]string {
 	result := make(map[string]string)
@@ -25,37 +76,66 @@ func parseKeyValuePairs(args []string) map[string]string {
 	return result
 }
 
+// splitTags 解析逗号分隔的标签列表，忽略空标签
+func splitTags(value string) []string {
+	var tags []string
+	for _, tag := range strings.Split(value, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
 // handleConfigCommand 处理config命令
 func handleConfigCommand(args []string) {
 	var serverURL string
+	var token string
+	var caCert string
 	var listFlag bool
+	var effectiveFlag bool
 	var setFlag bool
+	var unset string
+	var validateFlag bool
+	var secretKey string
 
 	configCmd := flag.NewFlagSet("config", flag.ExitOnError)
-	configCmd.StringVar(&serverURL, "url", "127.0.0.1:8443", "服务器地址")
+	configCmd.StringVar(&serverURL, "url", "127.0.0.1:8443", "服务器地址，HTTPS服务器使用 https:// 开头的地址")
+	configCmd.StringVar(&token, "token", "", "API令牌，默认使用环境变量 TVSUBSCRIBE_TOKEN")
+	configCmd.StringVar(&caCert, "ca-cert", "", "验证HTTPS服务器证书的CA证书文件，默认使用环境变量 TVSUBSCRIBE_CA_CERT")
 	configCmd.BoolVar(&listFlag, "list", false, "获取配置")
+	configCmd.BoolVar(&effectiveFlag, "effective", false, "查看生效的配置及每一项的来源")
 	configCmd.BoolVar(&setFlag, "set", false, "设置配置")
+	configCmd.StringVar(&unset, "unset", "", "清空配置项或恢复默认值，多个配置项用逗号分隔")
+	configCmd.BoolVar(&validateFlag, "validate", false, "只校验修改后的配置，不保存")
+	configCmd.StringVar(&secretKey, "secret", "", "从标准输入读取敏感配置项的值，避免出现在命令历史中")
 
 	configCmd.Parse(args)
 
-	if !listFlag && !setFlag {
+	if !listFlag && !effectiveFlag && !setFlag && unset == "" && secretKey == "" {
 		fmt.Println("使用方法: tvsubscribe config [选项]")
 		fmt.Println("选项:")
 		fmt.Println("  --list              获取配置")
+		fmt.Println("  --effective         查看生效的配置及每一项的来源")
 		fmt.Println("  --set key=value...  设置配置")
-		fmt.Println("  --url string        服务器地址 (默认 \"127.0.0.1:8443\")")
+		fmt.Println("  --unset key,...     清空配置项或恢复默认值")
+		fmt.Println("  --validate          只校验修改后的配置，不保存")
+		fmt.Println("  --secret key        从标准输入读取敏感配置项的值")
+		fmt.Println("  --url string        服务器地址，HTTPS服务器使用 https:// 开头 (默认 \"127.0.0.1:8443\")")
+		fmt.Println("  --token string      API令牌，默认使用环境变量 TVSUBSCRIBE_TOKEN")
+		fmt.Println("  --ca-cert file      验证HTTPS服务器证书的CA证书文件，默认使用环境变量 TVSUBSCRIBE_CA_CERT")
 		os.Exit(1)
 	}
 
-	client := client.NewClient("http://" + serverURL)
+	client := newClient(serverURL, token, caCert)
 
 	if listFlag {
-		configMap, err := client.GetConfig()
+		cfg, err := client.GetConfig()
 		if err != nil {
 			log.Fatalf("获取配置失败: %v", err)
 		}
 
-		jsonData, err := json.MarshalIndent(configMap, "", "  ")
+		jsonData, err := json.MarshalIndent(cfg, "", "  ")
 		if err != nil {
 			log.Fatalf("序列化配置失败: %v", err)
 		}
@@ -64,6 +144,22 @@ func handleConfigCommand(args []string) {
 		return
 	}
 
+	if effectiveFlag {
+		effective, err := client.GetEffectiveConfig()
+		if err != nil {
+			log.Fatalf("获取配置失败: %v", err)
+		}
+		fmt.Println("路径:")
+		printSettings(effective.Paths)
+		fmt.Println("配置项:")
+		printSettings(effective.Settings)
+		return
+	}
+
+	// 构建更新配置
+	updateConfig := make(map[string]interface{})
+	updated := false
+
 	if setFlag {
 		setArgs := configCmd.Args()
 		if len(setArgs) == 0 {
@@ -75,10 +171,6 @@ func handleConfigCommand(args []string) {
 			log.Fatal("无效的 key=value 参数格式")
 		}
 
-		// 构建更新配置
-		updateConfig := make(map[string]interface{})
-		updated := false
-
 		for key, value := range kvPairs {
 			switch key {
 			case "endpoint":
@@ -94,12 +186,109 @@ func handleConfigCommand(args []string) {
 				} else {
 					log.Printf("警告: 无效的 interval_minutes 值: %s", value)
 				}
+			case "cron", "active_hours":
+				updateConfig[key] = value
+				updated = true
+			case "jitter_seconds":
+				if jitter, err := strconv.Atoi(value); err == nil && jitter >= 0 {
+					updateConfig["jitter_seconds"] = jitter
+					updated = true
+				} else {
+					log.Printf("警告: 无效的 jitter_seconds 值: %s", value)
+				}
 			case "wechat_server":
 				updateConfig["wechat_server"] = value
 				updated = true
 			case "wechat_token":
 				updateConfig["wechat_token"] = value
 				updated = true
+			case "wechat_notify_mode":
+				updateConfig["wechat_notify_mode"] = value
+				updated = true
+			case "wechat_daily_digest_at":
+				updateConfig["wechat_daily_digest_at"] = value
+				updated = true
+			case "workers", "site_concurrency":
+				if n, err := strconv.Atoi(value); err == nil && n > 0 {
+					updateConfig[key] = n
+					updated = true
+				} else {
+					log.Printf("警告: 无效的 %s 值: %s", key, value)
+				}
+			case "wechat_rate_limit", "notify_dedup_minutes":
+				if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+					updateConfig[key] = n
+					updated = true
+				} else {
+					log.Printf("警告: 无效的 %s 值: %s", key, value)
+				}
+			case "quiet_hours":
+				updateConfig["quiet_hours"] = value
+				updated = true
+			case "air_schedule":
+				if enabled, err := strconv.ParseBool(value); err == nil {
+					updateConfig["air_schedule"] = enabled
+					updated = true
+				} else {
+					log.Printf("警告: 无效的 air_schedule 值: %s", value)
+				}
+			case "douban_sync_movies", "douban_sync_archive":
+				if enabled, err := strconv.ParseBool(value); err == nil {
+					updateConfig[key] = enabled
+					updated = true
+				} else {
+					log.Printf("警告: 无效的 %s 值: %s", key, value)
+				}
+			case "douban_user", "douban_cookie":
+				updateConfig[key] = value
+				updated = true
+			case "douban_sync_lists", "douban_sync_tags":
+				updateConfig[key] = splitList(value)
+				updated = true
+			case "douban_sync_minutes", "douban_sync_resolution":
+				if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+					updateConfig[key] = n
+					updated = true
+				} else {
+					log.Printf("警告: 无效的 %s 值: %s", key, value)
+				}
+			case "team_mode":
+				updateConfig[key] = value
+				updated = true
+			case "team_list":
+				updateConfig[key] = splitList(value)
+				updated = true
+			case "team_fallback_hours":
+				if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+					updateConfig[key] = n
+					updated = true
+				} else {
+					log.Printf("警告: 无效的 %s 值: %s", key, value)
+				}
+			case "site_teams":
+				if siteTeams, err := parseSiteTeams(value); err == nil {
+					updateConfig[key] = siteTeams
+					updated = true
+				} else {
+					log.Printf("警告: %v", err)
+				}
+			case "filter_include", "filter_exclude", "filter_allow_groups", "filter_deny_groups":
+				updateConfig[key] = splitList(value)
+				updated = true
+			case "filter_include_regex", "filter_exclude_regex":
+				// 正则中可能包含逗号，每项只设置一个正则，为空时清空
+				updateConfig[key] = singleList(value)
+				updated = true
+			case "air_time", "douban_base_url", "tmdb_base_url", "tmdb_api_key", "movie_min_quality":
+				updateConfig[key] = value
+				updated = true
+			case "air_window_hours", "air_poll_minutes", "off_air_poll_minutes", "run_history_days", "run_history_limit":
+				if n, err := strconv.Atoi(value); err == nil && n > 0 {
+					updateConfig[key] = n
+					updated = true
+				} else {
+					log.Printf("警告: 无效的 %s 值: %s", key, value)
+				}
 			case "port":
 				if port, err := strconv.Atoi(value); err == nil && port > 0 {
 					updateConfig["port"] = port
@@ -107,53 +296,195 @@ func handleConfigCommand(args []string) {
 				} else {
 					log.Printf("警告: 无效的 port 值: %s", value)
 				}
+			case "listen", "tls_cert", "tls_key":
+				updateConfig[key] = value
+				updated = true
+			case "tls_self_signed":
+				if enabled, err := strconv.ParseBool(value); err == nil {
+					updateConfig[key] = enabled
+					updated = true
+				} else {
+					log.Printf("警告: 无效的 %s 值: %s", key, value)
+				}
+			case "http_redirect_port":
+				if port, err := strconv.Atoi(value); err == nil && port >= 0 {
+					updateConfig[key] = port
+					updated = true
+				} else {
+					log.Printf("警告: 无效的 %s 值: %s", key, value)
+				}
 			default:
 				log.Printf("警告: 未知的配置项: %s", key)
 			}
 		}
 
-		if !updated {
-			log.Fatal("没有有效的配置项被更新")
+	}
+
+	patch := config.Patch{}
+	for key, value := range updateConfig {
+		if err := patch.Set(key, value); err != nil {
+			log.Fatalf("序列化配置失败: %v", err)
+		}
+	}
+	for _, key := range splitList(unset) {
+		if !config.ValidKey(key) {
+			log.Printf("警告: 未知的配置项: %s", key)
+			continue
+		}
+		patch.Clear(key)
+		updated = true
+	}
+	if secretKey != "" {
+		if !config.IsSecret(secretKey) {
+			log.Fatalf("%s 不是敏感配置项，可选: %s", secretKey, strings.Join(config.SecretKeys(), ", "))
+		}
+		value, err := readSecret(secretKey)
+		if err != nil {
+			log.Fatalf("读取 %s 失败: %v", secretKey, err)
+		}
+		if err := patch.Set(secretKey, value); err != nil {
+			log.Fatalf("序列化配置失败: %v", err)
 		}
+		updated = true
+	}
 
-		if err := client.SetConfig(updateConfig); err != nil {
-			log.Fatalf("设置配置失败: %v", err)
+	if !updated {
+		log.Fatal("没有有效的配置项被更新")
+	}
+
+	if validateFlag {
+		if _, err := client.ValidateConfig(patch); err != nil {
+			log.Fatalf("%v", err)
 		}
+		fmt.Println("配置校验通过")
+		return
+	}
+
+	if _, err := client.SetConfig(patch); err != nil {
+		log.Fatalf("设置配置失败: %v", err)
+	}
+
+	fmt.Println("配置更新成功")
+}
+
+// stdin 读取敏感信息的标准输入，多次读取时共用缓冲
+var stdin = bufio.NewReader(os.Stdin)
 
-		fmt.Println("配置更新成功")
+// readSecret 从标准输入读取一行作为敏感配置项或密码的值，标准输入是终端时先输出提示
+func readSecret(key string) (string, error) {
+	if info, err := os.Stdin.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+		fmt.Fprintf(os.Stderr, "请输入 %s: ", key)
+	}
+	line, err := stdin.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// printSettings 输出配置项的值和来源，被环境变量或命令行参数覆盖的配置项同时输出覆盖它的名称
+func printSettings(settings []config.Setting) {
+	for _, setting := range settings {
+		value, err := json.Marshal(setting.Value)
+		if err != nil {
+			value = []byte(fmt.Sprint(setting.Value))
+		}
+		line := fmt.Sprintf("  %-24s %-8s %s", setting.Key, setting.Source, value)
+		switch setting.Source {
+		case config.SourceEnv:
+			line += "  (" + setting.Env + ")"
+		case config.SourceFlag:
+			line += "  (" + setting.Flag + ")"
+		}
+		fmt.Println(line)
 	}
 }
 
 // handleSubscribeCommand 处理subscribe命令
 func handleSubscribeCommand(args []string) {
 	var serverURL string
+	var token string
+	var caCert string
 	var listFlag bool
 	var addFlag bool
 	var delFlag bool
+	var pauseFlag bool
+	var resumeFlag bool
+	var updateFlag bool
+	var reactivateFlag bool
+	var archivedFlag bool
+	var exportFile string
+	var importFile string
+	var formatFlag string
+	var modeFlag string
+	var dryRunFlag bool
+	var syncDoubanFlag bool
 
 	subscribeCmd := flag.NewFlagSet("subscribe", flag.ExitOnError)
-	subscribeCmd.StringVar(&serverURL, "url", "127.0.0.1:8443", "服务器地址")
+	subscribeCmd.StringVar(&serverURL, "url", "127.0.0.1:8443", "服务器地址，HTTPS服务器使用 https:// 开头的地址")
+	subscribeCmd.StringVar(&token, "token", "", "API令牌，默认使用环境变量 TVSUBSCRIBE_TOKEN")
+	subscribeCmd.StringVar(&caCert, "ca-cert", "", "验证HTTPS服务器证书的CA证书文件，默认使用环境变量 TVSUBSCRIBE_CA_CERT")
 	subscribeCmd.BoolVar(&listFlag, "list", false, "获取订阅列表")
 	subscribeCmd.BoolVar(&addFlag, "add", false, "添加订阅")
 	subscribeCmd.BoolVar(&delFlag, "del", false, "删除订阅")
+	subscribeCmd.BoolVar(&pauseFlag, "pause", false, "暂停订阅")
+	subscribeCmd.BoolVar(&resumeFlag, "resume", false, "恢复订阅")
+	subscribeCmd.BoolVar(&updateFlag, "update", false, "修改订阅")
+	subscribeCmd.BoolVar(&reactivateFlag, "reactivate", false, "重新激活已完结的订阅")
+	subscribeCmd.BoolVar(&archivedFlag, "archived", false, "与 --list 一起使用，只列出已完结的订阅")
+	subscribeCmd.StringVar(&exportFile, "export", "", "导出订阅到文件，- 表示输出到标准输出")
+	subscribeCmd.StringVar(&importFile, "import", "", "从文件导入订阅")
+	subscribeCmd.StringVar(&formatFlag, "format", "", "导入导出的文件格式 json 或 csv，默认根据文件扩展名判断")
+	subscribeCmd.StringVar(&modeFlag, "mode", "merge", "导入模式 merge 或 replace")
+	subscribeCmd.BoolVar(&dryRunFlag, "dry-run", false, "与 --import 一起使用，只预览不保存")
+	subscribeCmd.BoolVar(&syncDoubanFlag, "sync-douban", false, "立即同步豆瓣想看/在看列表")
 
 	subscribeCmd.Parse(args)
 
-	if !listFlag && !addFlag && !delFlag {
+	if !listFlag && !addFlag && !delFlag && !pauseFlag && !resumeFlag && !updateFlag && !reactivateFlag && exportFile == "" && importFile == "" && !syncDoubanFlag {
 		fmt.Println("使用方法: tvsubscribe subscribe [选项]")
 		fmt.Println("选项:")
 		fmt.Println("  --list                          获取订阅列表")
+		fmt.Println("  --list --archived               只列出已完结归档的订阅")
 		fmt.Println("  --add douban_id=xxx...          添加订阅")
 		fmt.Println("  --del douban_id=xxx...          删除订阅")
-		fmt.Println("  --url string                    服务器地址 (默认 \"127.0.0.1:8443\")")
+		fmt.Println("  --pause id...                   暂停订阅，暂停后不再定时处理")
+		fmt.Println("  --resume id...                  恢复已暂停的订阅")
+		fmt.Println("  --update id=xxx key=value...    修改订阅，订阅ID保持不变")
+		fmt.Println("  --reactivate id...              重新激活已完结的订阅")
+		fmt.Println("  --export file [--format csv]    导出订阅到文件，file 为 - 时输出到标准输出")
+		fmt.Println("  --import file [--mode replace]  导入订阅，默认 merge 模式跳过已存在的订阅")
+		fmt.Println("  --import file --dry-run         预览导入结果，不保存")
+		fmt.Println("  --format json|csv               导入导出的文件格式，默认根据文件扩展名判断")
+		fmt.Println("  --sync-douban                   立即同步豆瓣想看/在看列表，需先配置 douban_user")
+		fmt.Println("  --url string                    服务器地址，HTTPS服务器使用 https:// 开头 (默认 \"127.0.0.1:8443\")")
+		fmt.Println("  --token string                  API令牌，默认使用环境变量 TVSUBSCRIBE_TOKEN")
+		fmt.Println("  --ca-cert file                  验证HTTPS服务器证书的CA证书文件，默认使用环境变量 TVSUBSCRIBE_CA_CERT")
 		fmt.Println()
 		fmt.Println("添加/删除订阅的参数格式:")
 		fmt.Println("  douban_id=豆瓣ID (必填)")
 		fmt.Println("  resolution=分辨率 (可选，默认为1)")
+		fmt.Println("  schedule=cron表达式 (可选，仅添加时有效)")
+		fmt.Println("  active_hours=HH:MM-HH:MM (可选，仅添加时有效)")
+		fmt.Println("  notes=备注 (可选，仅添加时有效)")
+		fmt.Println("  tags=标签1,标签2 (可选，仅添加时有效)")
+		fmt.Println("  kind=series|movie (可选，仅添加时有效，默认根据豆瓣条目判断)")
+		fmt.Println("  min_quality=cam|hdtv|webrip|web-dl|bluray (可选，仅电影有效，默认使用 movie_min_quality)")
+		fmt.Println("  include=中字 exclude=国语,HDR (可选，关键字过滤，逗号分隔)")
+		fmt.Println("  include_regex=正则 exclude_regex=正则 (可选，每项一个正则)")
+		fmt.Println("  allow_groups=制作组 deny_groups=制作组 (可选，制作组白名单/黑名单，逗号分隔)")
+		fmt.Println("  team_mode=list|any|prefer teams=制作组,... fallback_hours=24 (可选，搜索的制作组，默认使用全局设置)")
+		fmt.Println()
+		fmt.Println("修改订阅可用的 key:")
+		fmt.Println("  douban_id, name (为空时重新从豆瓣获取), resolution, schedule, active_hours,")
+		fmt.Println("  enabled (true/false), notes, tags (逗号分隔，为空时清空), kind (series/movie),")
+		fmt.Println("  min_quality (为空时使用全局默认值), include, exclude, include_regex, exclude_regex,")
+		fmt.Println("  allow_groups, deny_groups (只替换提供的过滤规则，为空时清空该项),")
+		fmt.Println("  team_mode, teams, fallback_hours (整体替换制作组设置，team_mode 为空时使用全局设置)")
 		os.Exit(1)
 	}
 
-	client := client.NewClient("http://" + serverURL)
+	client := newClient(serverURL, token, caCert)
 
 	if listFlag {
 		subscribes, err := client.GetSubscribeList()
@@ -161,6 +492,16 @@ func handleSubscribeCommand(args []string) {
 			log.Fatalf("获取订阅列表失败: %v", err)
 		}
 
+		if archivedFlag {
+			var archived []tvsubscribe.TVInfo
+			for _, subscribe := range subscribes {
+				if subscribe.Completed {
+					archived = append(archived, subscribe)
+				}
+			}
+			subscribes = archived
+		}
+
 		if len(subscribes) == 0 {
 			fmt.Println("暂无订阅")
 			return
@@ -175,6 +516,116 @@ func handleSubscribeCommand(args []string) {
 		return
 	}
 
+	if syncDoubanFlag {
+		result, err := client.SyncWishlist()
+		if err != nil {
+			log.Fatalf("同步豆瓣列表失败: %v", err)
+		}
+		fmt.Printf("豆瓣列表共 %d 个条目\n", result.Listed)
+		for _, tv := range result.Added {
+			fmt.Printf("  新增订阅: %s (豆瓣ID=%s, ID=%s)\n", tv.Name, tv.DouBanID, tv.ID)
+		}
+		for _, tv := range result.Archived {
+			fmt.Printf("  归档订阅: %s (豆瓣ID=%s, ID=%s)\n", tv.Name, tv.DouBanID, tv.ID)
+		}
+		for _, name := range result.Skipped {
+			fmt.Printf("  跳过电影: %s\n", name)
+		}
+		for _, message := range result.Errors {
+			fmt.Printf("  失败: %s\n", message)
+		}
+		fmt.Printf("新增 %d 个订阅，归档 %d 个订阅\n", len(result.Added), len(result.Archived))
+		return
+	}
+
+	if exportFile != "" {
+		format := transferFormat(formatFlag, exportFile)
+		data, err := client.ExportSubscribes(format)
+		if err != nil {
+			log.Fatalf("导出订阅失败: %v", err)
+		}
+		if exportFile == "-" {
+			os.Stdout.Write(data)
+			return
+		}
+		if err := os.WriteFile(exportFile, data, 0644); err != nil {
+			log.Fatalf("写入文件失败: %v", err)
+		}
+		fmt.Printf("订阅已导出到 %s\n", exportFile)
+		return
+	}
+
+	if importFile != "" {
+		data, err := os.ReadFile(importFile)
+		if err != nil {
+			log.Fatalf("读取文件失败: %v", err)
+		}
+		result, err := client.ImportSubscribes(transferFormat(formatFlag, importFile), modeFlag, dryRunFlag, data)
+		if err != nil {
+			log.Fatalf("导入订阅失败: %v", err)
+		}
+		printImportResult(result)
+		return
+	}
+
+	if reactivateFlag {
+		ids := subscribeCmd.Args()
+		if len(ids) == 0 {
+			log.Fatal("需要提供订阅ID")
+		}
+		if err := client.ReactivateSubscribes(ids); err != nil {
+			log.Fatalf("重新激活订阅失败: %v", err)
+		}
+		fmt.Printf("已重新激活 %d 个订阅\n", len(ids))
+		return
+	}
+
+	if pauseFlag || resumeFlag {
+		ids := subscribeCmd.Args()
+		if len(ids) == 0 {
+			log.Fatal("需要提供订阅ID")
+		}
+		if pauseFlag {
+			if err := client.PauseSubscribes(ids); err != nil {
+				log.Fatalf("暂停订阅失败: %v", err)
+			}
+			fmt.Printf("已暂停 %d 个订阅\n", len(ids))
+		} else {
+			if err := client.ResumeSubscribes(ids); err != nil {
+				log.Fatalf("恢复订阅失败: %v", err)
+			}
+			fmt.Printf("已恢复 %d 个订阅\n", len(ids))
+		}
+		return
+	}
+
+	if updateFlag {
+		kvPairs := parseKeyValuePairs(subscribeCmd.Args())
+		id := kvPairs["id"]
+		if id == "" {
+			log.Fatal("必须提供 id 参数")
+		}
+		delete(kvPairs, "id")
+
+		patch, err := buildSubscribePatch(kvPairs)
+		if err != nil {
+			log.Fatalf("无效的参数: %v", err)
+		}
+
+		updated, err := client.UpdateSubscribe(id, patch)
+		if err != nil {
+			log.Fatalf("修改订阅失败: %v", err)
+		}
+
+		jsonData, err := json.MarshalIndent(updated, "", "  ")
+		if err != nil {
+			log.Fatalf("序列化订阅失败: %v", err)
+		}
+		fmt.Println("订阅修改成功:")
+		fmt.Println(string(jsonData))
+		return
+	}
+
 	if addFlag || delFlag {
 		cmdArgs := subscribeCmd.Args()
 		if len(cmdArgs) == 0 {
@@ -204,6 +655,24 @@ func handleSubscribeCommand(args []string) {
 		} else {
 			tvInfo.Resolution = 1 // 默认分辨率
 		}
+		tvInfo.Schedule = kvPairs["schedule"]
+		tvInfo.ActiveHours = kvPairs["active_hours"]
+		tvInfo.Notes = kvPairs["notes"]
+		if tags, ok := kvPairs["tags"]; ok {
+			tvInfo.Tags = splitTags(tags)
+		}
+		tvInfo.Kind = kvPairs["kind"]
+		tvInfo.MinQuality = kvPairs["min_quality"]
+		if filters, err := buildFilterRules(kvPairs); err != nil {
+			log.Fatal(err)
+		} else if !filters.Empty() {
+			tvInfo.Filters = &filters
+		}
+		if policy, err := buildTeamPolicy(kvPairs); err != nil {
+			log.Fatal(err)
+		} else if policy.Mode != "" {
+			tvInfo.TeamPolicy = &policy
+		}
 
 		if addFlag {
 			if err := client.AddSubscribe(tvInfo); err != nil {
@@ -219,6 +688,436 @@ func handleSubscribeCommand(args []string) {
 	}
 }
 
+// transferFormat 返回导入导出的文件格式，未指定时根据文件扩展名判断，默认为 json
+func transferFormat(format, filename string) string {
+	if format != "" {
+		return format
+	}
+	if strings.EqualFold(filepath.Ext(filename), ".csv") {
+		return subscribe.FormatCSV
+	}
+	return subscribe.FormatJSON
+}
+
+// printImportResult 打印导入结果，列出被跳过和无效的条目
+func printImportResult(result *subscribe.ImportResult) {
+	if result.DryRun {
+		fmt.Println("导入预览（未保存）:")
+	} else {
+		fmt.Println("导入完成:")
+	}
+	for _, entry := range result.Entries {
+		line := fmt.Sprintf("  #%d %-7s 豆瓣ID=%s, 分辨率=%d", entry.Index, entry.Action, entry.DouBanID, entry.Resolution)
+		if entry.Name != "" {
+			line += " " + entry.Name
+		}
+		if entry.Message != "" {
+			line += " (" + entry.Message + ")"
+		}
+		fmt.Println(line)
+	}
+	fmt.Printf("添加 %d 个，跳过 %d 个，无效 %d 个", result.Added, result.Skipped, result.Invalid)
+	if result.Mode == subscribe.ImportReplace {
+		fmt.Printf("，替换现有订阅 %d 个", result.Removed)
+	}
+	fmt.Println()
+}
+
+// buildSubscribePatch 根据 key=value 参数构建订阅修改内容
+func buildSubscribePatch(kvPairs map[string]string) (tvsubscribe.TVInfoPatch, error) {
+	var patch tvsubscribe.TVInfoPatch
+	if len(kvPairs) == 0 {
+		return patch, fmt.Errorf("需要提供要修改的 key=value 参数")
+	}
+
+	for key, value := range kvPairs {
+		value := value
+		switch key {
+		case "douban_id":
+			patch.DouBanID = &value
+		case "name":
+			patch.Name = &value
+		case "resolution":
+			resolution, err := strconv.Atoi(value)
+			if err != nil {
+				return patch, fmt.Errorf("resolution 必须是整数: %s", value)
+			}
+			patch.Resolution = &resolution
+		case "schedule":
+			patch.Schedule = &value
+		case "active_hours":
+			patch.ActiveHours = &value
+		case "enabled":
+			enabled, err := strconv.ParseBool(value)
+			if err != nil {
+				return patch, fmt.Errorf("enabled 必须是 true 或 false: %s", value)
+			}
+			patch.Enabled = &enabled
+		case "notes":
+			patch.Notes = &value
+		case "tags":
+			tags := splitTags(value)
+			if tags == nil {
+				tags = []string{}
+			}
+			patch.Tags = &tags
+		case "kind":
+			patch.Kind = &value
+		case "min_quality":
+			patch.MinQuality = &value
+		case "include", "exclude", "include_regex", "exclude_regex", "allow_groups", "deny_groups",
+			"team_mode", "teams", "fallback_hours":
+			// 过滤规则和制作组设置在循环结束后统一处理
+		default:
+			return patch, fmt.Errorf("不支持修改的字段: %s", key)
+		}
+	}
+
+	for _, key := range filterKeys {
+		if _, ok := kvPairs[key]; ok {
+			filters, err := buildFilterRules(kvPairs)
+			if err != nil {
+				return patch, err
+			}
+			patch.Filters = &filters
+			break
+		}
+	}
+	if _, ok := kvPairs["team_mode"]; ok {
+		policy, err := buildTeamPolicy(kvPairs)
+		if err != nil {
+			return patch, err
+		}
+		patch.TeamPolicy = &policy
+	} else if kvPairs["teams"] != "" || kvPairs["fallback_hours"] != "" {
+		return patch, fmt.Errorf("修改制作组时需要同时提供 team_mode")
+	}
+	return patch, nil
+}
+
+// buildTeamPolicy 根据 team_mode、teams、fallback_hours 参数构建制作组设置，team_mode 为空表示使用全局设置
+func buildTeamPolicy(kvPairs map[string]string) (tvsubscribe.TeamPolicy, error) {
+	policy := tvsubscribe.TeamPolicy{
+		Mode:  strings.TrimSpace(kvPairs["team_mode"]),
+		Teams: splitTags(kvPairs["teams"]),
+	}
+	if policy.Mode == "" {
+		return policy, nil
+	}
+	if value := kvPairs["fallback_hours"]; value != "" {
+		hours, err := strconv.Atoi(value)
+		if err != nil {
+			return policy, fmt.Errorf("fallback_hours 必须是整数: %s", value)
+		}
+		policy.FallbackHours = hours
+	} else if policy.Mode == tvsubscribe.TeamModePrefer {
+		policy.FallbackHours = tvsubscribe.DefaultTeamFallbackHours
+	}
+	return policy, policy.Validate()
+}
+
+// parseSiteTeams 解析 名称:ID 形式的站点制作组映射，多个用逗号分隔
+func parseSiteTeams(value string) (map[string]int, error) {
+	siteTeams := make(map[string]int)
+	for _, item := range splitTags(value) {
+		parts := strings.SplitN(item, ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("无效的 site_teams 项: %s，格式为 名称:ID", item)
+		}
+		id, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("无效的制作组ID: %s", item)
+		}
+		siteTeams[strings.TrimSpace(parts[0])] = id
+	}
+	return siteTeams, nil
+}
+
+// filterKeys 订阅过滤规则对应的参数
+var filterKeys = []string{"include", "exclude", "include_regex", "exclude_regex", "allow_groups", "deny_groups"}
+
+// buildFilterRules 根据 key=value 参数构建过滤规则。
+// 关键字和制作组用逗号分隔；正则中可能包含逗号，每项只设置一个正则；值为空时清空该项。
+func buildFilterRules(kvPairs map[string]string) (tvsubscribe.FilterRules, error) {
+	var rules tvsubscribe.FilterRules
+	fields := map[string]*[]string{
+		"include":       &rules.Include,
+		"exclude":       &rules.Exclude,
+		"include_regex": &rules.IncludeRegex,
+		"exclude_regex": &rules.ExcludeRegex,
+		"allow_groups":  &rules.AllowGroups,
+		"deny_groups":   &rules.DenyGroups,
+	}
+	for key, field := range fields {
+		value, ok := kvPairs[key]
+		if !ok {
+			continue
+		}
+		if strings.HasSuffix(key, "_regex") {
+			*field = singleList(value)
+		} else {
+			*field = splitList(value)
+		}
+	}
+	return rules, rules.Validate()
+}
+
+// splitList 解析逗号分隔的列表，为空时返回空列表而不是 nil，用于清空配置
+func splitList(value string) []string {
+	if list := splitTags(value); list != nil {
+		return list
+	}
+	return []string{}
+}
+
+// singleList 将单个值转换为列表，为空时返回空列表
+func singleList(value string) []string {
+	if value = strings.TrimSpace(value); value != "" {
+		return []string{value}
+	}
+	return []string{}
+}
+
+// handleSchedulerCommand 处理scheduler命令
+func handleSchedulerCommand(args []string) {
+	var serverURL string
+	var token string
+	var caCert string
+	var statusFlag bool
+	var pauseFlag bool
+	var resumeFlag bool
+	var runNowFlag bool
+
+	schedulerCmd := flag.NewFlagSet("scheduler", flag.ExitOnError)
+	schedulerCmd.StringVar(&serverURL, "url", "127.0.0.1:8443", "服务器地址，HTTPS服务器使用 https:// 开头的地址")
+	schedulerCmd.StringVar(&token, "token", "", "API令牌，默认使用环境变量 TVSUBSCRIBE_TOKEN")
+	schedulerCmd.StringVar(&caCert, "ca-cert", "", "验证HTTPS服务器证书的CA证书文件，默认使用环境变量 TVSUBSCRIBE_CA_CERT")
+	schedulerCmd.BoolVar(&statusFlag, "status", false, "查看调度器状态")
+	schedulerCmd.BoolVar(&pauseFlag, "pause", false, "暂停定时处理")
+	schedulerCmd.BoolVar(&resumeFlag, "resume", false, "恢复定时处理")
+	schedulerCmd.BoolVar(&runNowFlag, "run-now", false, "立即处理所有订阅")
+
+	schedulerCmd.Parse(args)
+
+	if !statusFlag && !pauseFlag && !resumeFlag && !runNowFlag {
+		fmt.Println("使用方法: tvsubscribe scheduler [选项]")
+		fmt.Println("选项:")
+		fmt.Println("  --status            查看调度器状态")
+		fmt.Println("  --pause             暂停定时处理")
+		fmt.Println("  --resume            恢复定时处理")
+		fmt.Println("  --run-now           立即处理所有订阅")
+		fmt.Println("  --url string        服务器地址，HTTPS服务器使用 https:// 开头 (默认 \"127.0.0.1:8443\")")
+		fmt.Println("  --token string      API令牌，默认使用环境变量 TVSUBSCRIBE_TOKEN")
+		fmt.Println("  --ca-cert file      验证HTTPS服务器证书的CA证书文件，默认使用环境变量 TVSUBSCRIBE_CA_CERT")
+		os.Exit(1)
+	}
+
+	client := newClient(serverURL, token, caCert)
+
+	switch {
+	case pauseFlag:
+		if err := client.PauseScheduler(); err != nil {
+			log.Fatalf("暂停定时处理失败: %v", err)
+		}
+		fmt.Println("定时处理已暂停")
+	case resumeFlag:
+		if err := client.ResumeScheduler(); err != nil {
+			log.Fatalf("恢复定时处理失败: %v", err)
+		}
+		fmt.Println("定时处理已恢复")
+	case runNowFlag:
+		if err := client.RunNow(); err != nil {
+			log.Fatalf("立即处理失败: %v", err)
+		}
+		fmt.Println("已开始处理所有订阅")
+	default:
+		paused, err := client.GetSchedulerStatus()
+		if err != nil {
+			log.Fatalf("获取调度器状态失败: %v", err)
+		}
+		if paused {
+			fmt.Println("调度器状态: 已暂停")
+		} else {
+			fmt.Println("调度器状态: 运行中")
+		}
+	}
+}
+
+// handleRunsCommand 处理runs命令
+func handleRunsCommand(args []string) {
+	var serverURL string
+	var token string
+	var caCert string
+	var listFlag bool
+	var showID string
+	var limit int
+
+	runsCmd := flag.NewFlagSet("runs", flag.ExitOnError)
+	runsCmd.StringVar(&serverURL, "url", "127.0.0.1:8443", "服务器地址，HTTPS服务器使用 https:// 开头的地址")
+	runsCmd.StringVar(&token, "token", "", "API令牌，默认使用环境变量 TVSUBSCRIBE_TOKEN")
+	runsCmd.StringVar(&caCert, "ca-cert", "", "验证HTTPS服务器证书的CA证书文件，默认使用环境变量 TVSUBSCRIBE_CA_CERT")
+	runsCmd.BoolVar(&listFlag, "list", false, "查看最近的处理记录")
+	runsCmd.StringVar(&showID, "show", "", "查看指定处理记录的详情")
+	runsCmd.IntVar(&limit, "limit", 20, "列出的记录条数")
+
+	runsCmd.Parse(args)
+
+	if !listFlag && showID == "" {
+		fmt.Println("使用方法: tvsubscribe runs [选项]")
+		fmt.Println("选项:")
+		fmt.Println("  --list              查看最近的处理记录")
+		fmt.Println("  --limit int         列出的记录条数 (默认 20)")
+		fmt.Println("  --show id           查看指定处理记录的详情")
+		fmt.Println("  --url string        服务器地址，HTTPS服务器使用 https:// 开头 (默认 \"127.0.0.1:8443\")")
+		fmt.Println("  --token string      API令牌，默认使用环境变量 TVSUBSCRIBE_TOKEN")
+		fmt.Println("  --ca-cert file      验证HTTPS服务器证书的CA证书文件，默认使用环境变量 TVSUBSCRIBE_CA_CERT")
+		os.Exit(1)
+	}
+
+	client := newClient(serverURL, token, caCert)
+
+	if showID != "" {
+		run, err := client.GetRun(showID)
+		if err != nil {
+			log.Fatalf("获取处理记录失败: %v", err)
+		}
+
+		jsonData, err := json.MarshalIndent(run, "", "  ")
+		if err != nil {
+			log.Fatalf("序列化处理记录失败: %v", err)
+		}
+
+		fmt.Println(string(jsonData))
+		return
+	}
+
+	runs, err := client.ListRuns(limit)
+	if err != nil {
+		log.Fatalf("获取处理记录失败: %v", err)
+	}
+
+	if len(runs) == 0 {
+		fmt.Println("暂无处理记录")
+		return
+	}
+
+	for _, run := range runs {
+		fmt.Printf("%s  %s  %-9s 检查 %d  候选 %d  接受 %d  拒绝 %d  下载 %d  错误 %d  耗时 %dms\n",
+			run.ID, run.StartedAt.Format("2006-01-02 15:04:05"), run.Trigger,
+			run.Checked, run.Candidates, run.Accepted, run.Rejected, run.Downloaded, run.Errors, run.DurationMs)
+	}
+}
+
+// handleAuthCommand 处理auth命令：查看当前身份，管理API令牌和用户
+func handleAuthCommand(args []string) {
+	var serverURL string
+	var token string
+	var caCert string
+	var login string
+	var whoami bool
+	var listTokens bool
+	var createToken string
+	var revokeToken string
+	var listUsers bool
+	var addUser string
+	var removeUser string
+	var role string
+
+	authCmd := flag.NewFlagSet("auth", flag.ExitOnError)
+	authCmd.StringVar(&serverURL, "url", "127.0.0.1:8443", "服务器地址，HTTPS服务器使用 https:// 开头的地址")
+	authCmd.StringVar(&token, "token", "", "API令牌，默认使用环境变量 TVSUBSCRIBE_TOKEN")
+	authCmd.StringVar(&caCert, "ca-cert", "", "验证HTTPS服务器证书的CA证书文件，默认使用环境变量 TVSUBSCRIBE_CA_CERT")
+	authCmd.StringVar(&login, "login", "", "使用用户名和密码登录，密码从标准输入读取")
+	authCmd.BoolVar(&whoami, "whoami", false, "查看当前身份和角色")
+	authCmd.BoolVar(&listTokens, "tokens", false, "查看API令牌")
+	authCmd.StringVar(&createToken, "create-token", "", "创建API令牌，参数为令牌名称")
+	authCmd.StringVar(&revokeToken, "revoke-token", "", "删除API令牌，参数为令牌ID")
+	authCmd.BoolVar(&listUsers, "users", false, "查看用户")
+	authCmd.StringVar(&addUser, "add-user", "", "添加用户，密码从标准输入读取")
+	authCmd.StringVar(&removeUser, "remove-user", "", "删除用户")
+	authCmd.StringVar(&role, "role", string(auth.RoleReadOnly), "创建的令牌或用户的角色: readonly 或 admin")
+
+	authCmd.Parse(args)
+
+	if !whoami && !listTokens && createToken == "" && revokeToken == "" && !listUsers && addUser == "" && removeUser == "" {
+		fmt.Println("使用方法: tvsubscribe auth [选项]")
+		fmt.Println("选项:")
+		fmt.Println("  --whoami              查看当前身份和角色")
+		fmt.Println("  --tokens              查看API令牌")
+		fmt.Println("  --create-token name   创建API令牌，令牌只显示一次")
+		fmt.Println("  --revoke-token id     删除API令牌")
+		fmt.Println("  --users               查看用户")
+		fmt.Println("  --add-user name       添加用户，密码从标准输入读取")
+		fmt.Println("  --remove-user name    删除用户")
+		fmt.Println("  --role string         创建的令牌或用户的角色: readonly 或 admin (默认 \"readonly\")")
+		fmt.Println("  --login user          使用用户名和密码登录后执行，密码从标准输入读取")
+		fmt.Println("  --url string          服务器地址，HTTPS服务器使用 https:// 开头 (默认 \"127.0.0.1:8443\")")
+		fmt.Println("  --token string        API令牌，默认使用环境变量 TVSUBSCRIBE_TOKEN")
+		fmt.Println("  --ca-cert file        验证HTTPS服务器证书的CA证书文件，默认使用环境变量 TVSUBSCRIBE_CA_CERT")
+		os.Exit(1)
+	}
+
+	client := newClient(serverURL, token, caCert)
+	if login != "" {
+		client = loginClient(serverURL, caCert, login)
+	}
+
+	switch {
+	case whoami:
+		identity, err := client.WhoAmI()
+		if err != nil {
+			log.Fatalf("获取身份失败: %v", err)
+		}
+		fmt.Printf("%s (%s，通过%s认证)\n", identity.Name, identity.Role, identity.Method)
+	case listTokens:
+		tokens, err := client.ListTokens()
+		if err != nil {
+			log.Fatalf("获取令牌失败: %v", err)
+		}
+		for _, t := range tokens {
+			lastUsed := "未使用"
+			if t.LastUsedAt != nil {
+				lastUsed = t.LastUsedAt.Format("2006-01-02 15:04")
+			}
+			fmt.Printf("%s  %-16s %-8s 创建者: %s  创建于: %s  最近使用: %s\n", t.ID, t.Name, t.Role, t.CreatedBy, t.CreatedAt.Format("2006-01-02 15:04"), lastUsed)
+		}
+	case createToken != "":
+		plain, info, err := client.CreateToken(createToken, auth.Role(role))
+		if err != nil {
+			log.Fatalf("创建令牌失败: %v", err)
+		}
+		fmt.Fprintf(os.Stderr, "已创建令牌 %s (%s，ID: %s)，令牌只显示这一次:\n", info.Name, info.Role, info.ID)
+		fmt.Println(plain)
+	case revokeToken != "":
+		if err := client.RevokeToken(revokeToken); err != nil {
+			log.Fatalf("删除令牌失败: %v", err)
+		}
+		fmt.Println("令牌已删除")
+	case listUsers:
+		users, err := client.ListUsers()
+		if err != nil {
+			log.Fatalf("获取用户失败: %v", err)
+		}
+		for _, u := range users {
+			fmt.Printf("%-16s %-8s 创建于: %s\n", u.Username, u.Role, u.CreatedAt.Format("2006-01-02 15:04"))
+		}
+	case addUser != "":
+		password, err := readSecret(addUser + " 的密码")
+		if err != nil {
+			log.Fatalf("读取密码失败: %v", err)
+		}
+		if err := client.AddUser(addUser, password, auth.Role(role)); err != nil {
+			log.Fatalf("添加用户失败: %v", err)
+		}
+		fmt.Println("用户已添加")
+	case removeUser != "":
+		if err := client.RemoveUser(removeUser); err != nil {
+			log.Fatalf("删除用户失败: %v", err)
+		}
+		fmt.Println("用户已删除")
+	}
+}
+
 // RunCLI 运行命令行界面
 func RunCLI() {
 	if len(os.Args) < 2 {
@@ -226,6 +1125,9 @@ func RunCLI() {
 		fmt.Println("命令:")
 		fmt.Println("  config      配置管理")
 		fmt.Println("  subscribe   订阅管理")
+		fmt.Println("  scheduler   调度器控制")
+		fmt.Println("  runs        处理记录")
+		fmt.Println("  auth        账号和API令牌")
 		os.Exit(1)
 	}
 
@@ -237,6 +1139,12 @@ func RunCLI() {
 		handleConfigCommand(args)
 	case "subscribe":
 		handleSubscribeCommand(args)
+	case "scheduler":
+		handleSchedulerCommand(args)
+	case "runs":
+		handleRunsCommand(args)
+	case "auth":
+		handleAuthCommand(args)
 	default:
 		log.Fatalf("未知命令: %s", command)
 	}
@@ -1,329 +1,381 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
+	"net"
 	"os"
 	"os/signal"
-	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
 
 	"tvsubscribe"
+	"tvsubscribe/auth"
 	"tvsubscribe/config"
+	"tvsubscribe/history"
+	"tvsubscribe/metadata"
+	"tvsubscribe/notify"
 	"tvsubscribe/server"
+	"tvsubscribe/storage"
 	"tvsubscribe/subscribe"
 )
 
-// 辅助函数
-func getString(v interface{}) string {
-	if s, ok := v.(string); ok {
-		return s
+// teamDefaults 根据配置生成全局默认的制作组设置
+func teamDefaults(cfg config.Config) tvsubscribe.TeamPolicy {
+	return tvsubscribe.TeamPolicy{
+		Mode:          cfg.TeamMode,
+		Teams:         cfg.TeamList,
+		FallbackHours: cfg.TeamFallbackHours,
 	}
-	return ""
 }
 
-func getInt(v interface{}) int {
-	if i, ok := v.(int); ok {
-		return i
+// filterDefaults 根据配置生成全局默认过滤规则
+func filterDefaults(cfg config.Config) tvsubscribe.FilterRules {
+	return tvsubscribe.FilterRules{
+		Include:      cfg.FilterInclude,
+		Exclude:      cfg.FilterExclude,
+		IncludeRegex: cfg.FilterIncludeRegex,
+		ExcludeRegex: cfg.FilterExcludeRegex,
+		AllowGroups:  cfg.FilterAllowGroups,
+		DenyGroups:   cfg.FilterDenyGroups,
 	}
-	if f, ok := v.(float64); ok {
-		return int(f)
-	}
-	return 0
 }
 
+// shutdownTimeout 退出时等待HTTP请求和正在处理的订阅完成的最长时间
+const shutdownTimeout = 30 * time.Second
 
-// ConfigManager 配置管理器
+// ConfigManager 配置管理器。存储中的配置与环境变量、命令行参数分层保存，
+// 被覆盖的配置项不会写回存储
 type ConfigManager struct {
-	config      *config.Config
-	configPath  string
-	mu          sync.RWMutex
+	config    *config.Config // 生效的配置
+	file      *config.Config // 存储中的配置
+	overrides config.Overrides
+	paths     []config.Setting // 服务器路径选项，用于查看生效的配置
+	store     config.Store
+	mu        sync.RWMutex
 }
 
-// loadConfig 从配置文件加载配置
-func loadConfig(configPath string) (*config.Config, error) {
-	data, err := os.ReadFile(configPath)
+// loadConfig 从配置存储加载配置并填充默认值，应用环境变量和命令行参数后校验生效的配置
+func loadConfig(store config.Store, overrides config.Overrides) (file, effective *config.Config, err error) {
+	file, err = store.Load()
 	if err != nil {
-		return nil, fmt.Errorf("读取配置文件失败: %v", err)
-	}
-
-	var cfg config.Config
-	if err := json.Unmarshal(data, &cfg); err != nil {
-		return nil, fmt.Errorf("解析配置文件失败: %v", err)
-	}
-
-	// 验证必填字段
-	if cfg.Cookie == "" {
-		return nil, fmt.Errorf("配置文件中 cookie 不能为空")
+		return nil, nil, err
 	}
-	if cfg.IntervalMinutes <= 0 {
-		cfg.IntervalMinutes = 60 // 默认60分钟
+	file.ApplyDefaults()
+	cfg, err := overrides.Apply(*file)
+	if err != nil {
+		return nil, nil, err
 	}
-	if cfg.Port <= 0 {
-		cfg.Port = 8443 // 默认8443端口
+	if err := cfg.Validate(); err != nil {
+		return nil, nil, err
 	}
-
-	return &cfg, nil
+	return file, &cfg, nil
 }
 
-// NewConfigManager 创建新的配置管理器
-func NewConfigManager(configPath string) (*ConfigManager, error) {
-	config, err := loadConfig(configPath)
+// NewConfigManager 创建使用指定存储的配置管理器，overrides 中的配置项覆盖存储中的配置
+func NewConfigManager(store config.Store, overrides config.Overrides) (*ConfigManager, error) {
+	file, effective, err := loadConfig(store, overrides)
 	if err != nil {
 		return nil, err
 	}
 
-	// 获取配置文件的绝对路径
-	absPath, err := filepath.Abs(configPath)
-	if err != nil {
-		return nil, fmt.Errorf("获取配置文件绝对路径失败: %v", err)
-	}
-
 	manager := &ConfigManager{
-		config:     config,
-		configPath: absPath,
+		config:    effective,
+		file:      file,
+		overrides: overrides,
+		store:     store,
 	}
 
 	return manager, nil
 }
 
-// GetConfig 获取当前配置（线程安全）
-func (m *ConfigManager) GetConfig() map[string]interface{} {
+// GetConfig 获取当前生效配置的副本（线程安全），返回值中的切片和映射不能修改
+func (m *ConfigManager) GetConfig() config.Config {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
+	return *m.config
+}
 
-	// 将config.Config转换为map
-	result := map[string]interface{}{
-		"endpoint":         m.config.Endpoint,
-		"cookie":           m.config.Cookie,
-		"interval_minutes": m.config.IntervalMinutes,
-		"wechat_server":    m.config.WeChatServer,
-		"wechat_token":     m.config.WeChatToken,
-		"port":             m.config.Port,
+// EffectiveConfig 返回生效的配置项和服务器路径选项，以及它们的来源
+func (m *ConfigManager) EffectiveConfig() config.Effective {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return config.Effective{
+		Settings: m.overrides.Settings(*m.file, *m.config),
+		Paths:    m.paths,
 	}
-	return result
 }
 
-// UpdateConfig 更新配置
-func (m *ConfigManager) UpdateConfig(updates map[string]interface{}) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
-	updated := false
-	if endpoint, ok := updates["endpoint"].(string); ok && endpoint != "" {
-		m.config.Endpoint = endpoint
-		updated = true
+// prepare 在存储中的配置上应用修改，返回修改后存储中的配置和生效的配置，不修改当前配置。
+// 修改被环境变量或命令行参数覆盖的配置项时返回错误，值与生效的值相同时忽略（Web界面保存全部配置项）
+func (m *ConfigManager) prepare(patch config.Patch) (file, effective *config.Config, err error) {
+	if len(patch) == 0 {
+		return nil, nil, fmt.Errorf("没有需要修改的配置项")
 	}
-	if cookie, ok := updates["cookie"].(string); ok && cookie != "" {
-		m.config.Cookie = cookie
-		updated = true
+
+	filePatch := config.Patch{}
+	var overridden []config.FieldError
+	for key, value := range patch {
+		if config.IsMasked(key, value) {
+			// 敏感配置项的掩码表示不修改
+			continue
+		}
+		by := m.overrides.Overridden(key)
+		if by == "" {
+			filePatch[key] = value
+			continue
+		}
+		if current, err := json.Marshal(config.Value(*m.config, key)); err == nil && jsonEqual(current, value) {
+			continue
+		}
+		overridden = append(overridden, config.FieldError{Field: key, Message: "已由 " + by + " 设置，修改不会生效"})
 	}
-	if interval, ok := updates["interval_minutes"].(float64); ok && interval > 0 {
-		m.config.IntervalMinutes = int(interval)
-		updated = true
+	if len(overridden) > 0 {
+		sort.Slice(overridden, func(i, j int) bool { return overridden[i].Field < overridden[j].Field })
+		return nil, nil, &config.ValidationError{Errors: overridden}
 	}
-	if wechatServer, ok := updates["wechat_server"].(string); ok && wechatServer != "" {
-		m.config.WeChatServer = wechatServer
-		updated = true
+
+	candidate, err := m.file.Apply(filePatch)
+	if err != nil {
+		return nil, nil, err
 	}
-	if wechatToken, ok := updates["wechat_token"].(string); ok && wechatToken != "" {
-		m.config.WeChatToken = wechatToken
-		updated = true
+	cfg, err := m.overrides.Apply(candidate)
+	if err != nil {
+		return nil, nil, err
 	}
-	if port, ok := updates["port"].(float64); ok && port > 0 {
-		m.config.Port = int(port)
-		updated = true
+	if err := cfg.Validate(); err != nil {
+		return nil, nil, err
 	}
+	return &candidate, &cfg, nil
+}
 
-	if !updated {
-		return fmt.Errorf("没有有效的配置字段被更新")
+// jsonEqual 比较两个JSON值是否相同
+func jsonEqual(a, b []byte) bool {
+	var x, y interface{}
+	if json.Unmarshal(a, &x) != nil || json.Unmarshal(b, &y) != nil {
+		return false
 	}
-
-	// 保存配置到文件
-	return m.saveConfig()
+	return reflect.DeepEqual(x, y)
 }
 
-// saveConfig 保存配置到文件
-func (m *ConfigManager) saveConfig() error {
-	data, err := json.MarshalIndent(m.config, "", "  ")
-	if err != nil {
-		return fmt.Errorf("序列化配置失败: %v", err)
-	}
+// ValidateConfig 校验修改后的配置但不保存，返回修改后生效的配置
+func (m *ConfigManager) ValidateConfig(patch config.Patch) (config.Config, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 
-	if err := os.WriteFile(m.configPath, data, 0644); err != nil {
-		return fmt.Errorf("写入配置文件失败: %v", err)
+	_, effective, err := m.prepare(patch)
+	if err != nil {
+		return config.Config{}, err
 	}
-
-	return nil
+	return *effective, nil
 }
 
-// processSingleTV 处理单个电视剧订阅
-func processSingleTV(configMgr *ConfigManager, tvInfo tvsubscribe.TVInfo) {
-	// 获取实际的config对象
-	configMap := configMgr.GetConfig()
-
-	// 模拟config对象的行为
-	config := struct {
-		Endpoint        string
-		Cookie          string
-		IntervalMinutes int
-		WeChatServer    string
-		WeChatToken     string
-		Port            int
-	}{
-		Endpoint:        getString(configMap["endpoint"]),
-		Cookie:          getString(configMap["cookie"]),
-		IntervalMinutes: getInt(configMap["interval_minutes"]),
-		WeChatServer:    getString(configMap["wechat_server"]),
-		WeChatToken:     getString(configMap["wechat_token"]),
-		Port:            getInt(configMap["port"]),
-	}
-
-	log.Printf("处理豆瓣ID: %s, 分辨率: %d", tvInfo.DouBanID, tvInfo.Resolution)
-
-	// 查询种子列表
-	torrentInfos, err := tvsubscribe.QueryTorrentList(config.Cookie, &tvInfo)
-	if err != nil {
-		log.Printf("查询种子列表失败 (豆瓣ID: %s): %v", tvInfo.DouBanID, err)
-		return
-	}
+// UpdateConfig 应用配置修改并保存，只修改 patch 中出现的配置项，值为 null 时清空或恢复默认值。
+// 任一配置项无效时不做任何修改，返回 *config.ValidationError
+func (m *ConfigManager) UpdateConfig(patch config.Patch) (config.Config, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-	if len(torrentInfos) == 0 {
-		log.Printf("未找到可下载的种子 (豆瓣ID: %s)", tvInfo.DouBanID)
-		return
+	file, effective, err := m.prepare(patch)
+	if err != nil {
+		return config.Config{}, err
 	}
 
-	log.Printf("找到 %d 个种子 (豆瓣ID: %s)", len(torrentInfos), tvInfo.DouBanID)
-
-	// 下载种子
-	if err := tvsubscribe.DownloadTorrent(torrentInfos, config.Endpoint, config.WeChatServer, config.WeChatToken); err != nil {
-		log.Printf("下载种子失败 (豆瓣ID: %s): %v", tvInfo.DouBanID, err)
-	} else {
-		log.Printf("成功处理 %d 个种子 (豆瓣ID: %s)", len(torrentInfos), tvInfo.DouBanID)
+	// 保存配置到存储
+	if err := m.save(file, effective); err != nil {
+		return config.Config{}, err
 	}
+	return *effective, nil
 }
 
-// processTVSubscribes 处理所有订阅的电视剧
-func processTVSubscribes(configMgr *ConfigManager, subscribes []tvsubscribe.TVInfo) {
-	// 获取实际的config对象
-	configMap := configMgr.GetConfig()
-
-	// 模拟config对象的行为
-	config := struct {
-		Endpoint        string
-		Cookie          string
-		IntervalMinutes int
-		WeChatServer    string
-		WeChatToken     string
-		Port            int
-	}{
-		Endpoint:        getString(configMap["endpoint"]),
-		Cookie:          getString(configMap["cookie"]),
-		IntervalMinutes: getInt(configMap["interval_minutes"]),
-		WeChatServer:    getString(configMap["wechat_server"]),
-		WeChatToken:     getString(configMap["wechat_token"]),
-		Port:            getInt(configMap["port"]),
-	}
-	log.Println("开始处理电视剧订阅...")
-
-	if len(subscribes) == 0 {
-		log.Println("没有订阅的电视剧")
-		return
-	}
-
-	for _, tv := range subscribes {
-		log.Printf("处理豆瓣ID: %s, 分辨率: %d", tv.DouBanID, tv.Resolution)
-
-		// 查询种子列表
-		torrentInfos, err := tvsubscribe.QueryTorrentList(config.Cookie, &tv)
-		if err != nil {
-			log.Printf("查询种子列表失败 (豆瓣ID: %s): %v", tv.DouBanID, err)
-			continue
-		}
-
-		if len(torrentInfos) == 0 {
-			log.Printf("未找到可下载的种子 (豆瓣ID: %s)", tv.DouBanID)
-			continue
-		}
-
-		log.Printf("找到 %d 个种子 (豆瓣ID: %s)", len(torrentInfos), tv.DouBanID)
-
-		// 下载种子
-		if err := tvsubscribe.DownloadTorrent(torrentInfos, config.Endpoint, config.WeChatServer, config.WeChatToken); err != nil {
-			log.Printf("下载种子失败 (豆瓣ID: %s): %v", tv.DouBanID, err)
-		} else {
-			log.Printf("成功处理 %d 个种子 (豆瓣ID: %s)", len(torrentInfos), tv.DouBanID)
+// save 保存存储中的配置，成功后替换当前配置，调用方需持有写锁。
+// 配置文件已被外部修改时重新加载外部的修改并返回冲突错误，本次修改不生效
+func (m *ConfigManager) save(file, effective *config.Config) error {
+	if err := m.store.Save(file); err != nil {
+		if errors.Is(err, storage.ErrConflict) {
+			if _, reloadErr := m.reload(); reloadErr != nil {
+				log.Printf("重新加载配置失败: %v", reloadErr)
+			}
 		}
+		return err
 	}
-
-	log.Println("电视剧订阅处理完成")
+	m.file = file
+	m.config = effective
+	return nil
 }
 
-// startScheduler 启动定时任务
-func startScheduler(configManager *ConfigManager, subscribeManager *subscribe.SubscribeManager) {
-	// 立即执行一次
-	processTVSubscribes(configManager, subscribeManager.GetSubscribes())
-
-	// 定时执行
-	go func() {
-		for {
-			configMap := configManager.GetConfig()
-			interval := time.Duration(getInt(configMap["interval_minutes"])) * time.Minute
+// Reload 从存储重新加载配置，用于配置文件被外部修改之后。
+// 新的配置校验失败时保留当前配置，返回值表示生效的配置是否发生了变化
+func (m *ConfigManager) Reload() (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.reload()
+}
 
-			log.Printf("定时任务等待 %v 后执行", interval)
-			time.Sleep(interval)
+// reload 重新加载配置，调用方需持有写锁
+func (m *ConfigManager) reload() (bool, error) {
+	file, cfg, err := loadConfig(m.store, m.overrides)
+	if err != nil {
+		return false, err
+	}
 
-			processTVSubscribes(configManager, subscribeManager.GetSubscribes())
-		}
-	}()
+	before, _ := json.Marshal(m.config)
+	after, _ := json.Marshal(cfg)
+	m.file = file
+	m.config = cfg
+	return string(before) != string(after), nil
 }
 
 func main() {
-	// 检查是否为CLI模式
-	if len(os.Args) > 1 {
+	// 检查是否为CLI模式，以 - 开头的参数为服务器选项
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
 		RunCLI()
 		return
 	}
 
-	// 服务器模式
+	// 服务器模式，路径和配置项可以用命令行参数和 TVSUBSCRIBE_ 开头的环境变量设置
+	opts, err := parseServerOptions(os.Args[1:], os.LookupEnv)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatalf("启动参数无效: %v", err)
+	}
+	if err := opts.ensureDataDir(); err != nil {
+		log.Fatal(err)
+	}
+	tvsubscribe.TorrentDir = opts.torrentDir
+
+	// 打开数据存储
+	stores, err := openStores(opts)
+	if err != nil {
+		log.Fatalf("数据存储打开失败: %v", err)
+	}
+	defer stores.close()
+	log.Printf("数据存储: %s (%s)", stores.backend, stores.subscribes)
+
 	// 创建配置管理器
-	configManager, err := NewConfigManager("./config.json")
+	configManager, err := NewConfigManager(stores.config, opts.overrides)
 	if err != nil {
 		log.Fatalf("配置管理器创建失败: %v", err)
 	}
+	configManager.paths = opts.settings()
 
 	// 创建订阅管理器
-	subscribeManager, err := subscribe.NewSubscribeManager("./subscribes.json")
+	subscribeManager, err := subscribe.NewSubscribeManagerWithStore(stores.subscribes)
 	if err != nil {
 		log.Fatalf("订阅管理器创建失败: %v", err)
 	}
 
 	// 获取初始配置
-	configMap := configManager.GetConfig()
-	log.Printf("配置加载成功，监听端口: %d, 检查间隔: %d 分钟", getInt(configMap["port"]), getInt(configMap["interval_minutes"]))
+	cfg := configManager.GetConfig()
+	log.Printf("配置加载成功，监听地址: %s, 检查间隔: %d 分钟", net.JoinHostPort(cfg.Listen, strconv.Itoa(cfg.Port)), cfg.IntervalMinutes)
+	if ip := net.ParseIP(cfg.Listen); ip != nil && ip.IsLoopback() {
+		log.Printf("只允许本机访问，其他设备访问需要把 listen 设置为 0.0.0.0 或本机的局域网地址")
+	}
 
 	// 设置信号监听，优雅退出
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
-	// 创建处理函数
-	processTVFunc := func() {
-		subscribes := subscribeManager.GetSubscribes()
-		processTVSubscribes(configManager, subscribes)
+	// 创建通知分发器，去重记录和排队中的消息保存在状态文件中，重启后继续生效
+	dispatcher, err := notify.NewDispatcher(opts.dataPath("notify_state.json"))
+	if err != nil {
+		log.Fatalf("通知分发器创建失败: %v", err)
 	}
 
-	processSingleFunc := func(tvInfo tvsubscribe.TVInfo) {
-		processSingleTV(configManager, tvInfo)
+	// 创建通知中心，并启动每日汇总和排队消息检查
+	hub := notify.NewHub()
+	hub.SetDispatcher(dispatcher)
+	applyNotifyConfig(configManager, hub)
+	hubStop := make(chan struct{})
+	go hub.Run(hubStop)
+
+	// 创建播出时间表存储
+	airStore, err := metadata.NewStore(opts.dataPath("air_schedules.json"))
+	if err != nil {
+		log.Fatalf("播出时间表加载失败: %v", err)
 	}
 
-	// 创建HTTP服务器
-	httpServer := server.NewServer(configManager, subscribeManager, processTVFunc, processSingleFunc)
+	// 创建处理记录存储
+	runHistory, err := history.NewStore(opts.dataPath("runs.json"))
+	if err != nil {
+		log.Fatalf("处理记录加载失败: %v", err)
+	}
+
+	// 加载非首选制作组种子的首次发现时间，重启后继续之前的等待
+	seenStore, err := history.NewSeenStore(opts.dataPath("torrent_seen.json"), history.DefaultSeenRetention)
+	if err != nil {
+		log.Fatalf("种子发现时间加载失败: %v", err)
+	}
+
+	// 加载用户和API令牌，首次启动时创建管理员账号
+	authStore, err := auth.NewStore(opts.dataPath(authFile))
+	if err != nil {
+		log.Fatalf("用户加载失败: %v", err)
+	}
+	if err := ensureAdmin(authStore, opts); err != nil {
+		log.Fatalf("创建管理员账号失败: %v", err)
+	}
+
+	// 创建订阅处理流程，所有触发来源都经由任务引擎处理，避免同一订阅被并发处理
+	proc := newProcessor(configManager, subscribeManager, hub, airStore, runHistory, seenStore)
 
 	// 启动定时任务
-	startScheduler(configManager, subscribeManager)
+	sched := startScheduler(configManager, subscribeManager, proc)
+
+	// 启动豆瓣想看列表同步
+	syncer := startWishlistSync(configManager, subscribeManager, sched, proc)
+	syncStop := make(chan struct{})
+	syncDone := make(chan struct{})
+	go func() {
+		syncer.Run(syncStop)
+		close(syncDone)
+	}()
+
+	// 数据文件被外部修改或收到 SIGHUP 时重新加载，校验失败时继续使用当前数据
+	reload := &reloader{stores: stores, configMgr: configManager, subscribes: subscribeManager, sched: sched, hub: hub}
+	if err := stores.watch(reload.fileChanged); err != nil {
+		log.Printf("监听数据文件失败，只在收到 SIGHUP 时重新加载: %v", err)
+	}
+
+	// 创建HTTP服务器
+	httpServer := server.NewServer(configManager, subscribeManager, sched, runHistory, authStore, proc.processSubscribes, syncer.Sync, opts.webDir)
+
+	// 使用HTTPS时加载证书，证书文件更新后自动重新加载
+	scheme := "http"
+	if cfg.TLSEnabled() {
+		certificates, err := loadCertificates(cfg, opts)
+		if err != nil {
+			log.Fatalf("HTTPS证书加载失败: %v", err)
+		}
+		httpServer.EnableTLS(certificates, cfg.HTTPRedirectPort)
+		reload.certificates = certificates
+		if watcher, err := storage.Watch(certificates.Paths(), storage.DefaultWatchDelay, func(string) { reload.reloadCertificates() }); err != nil {
+			log.Printf("监听证书文件失败，只在收到 SIGHUP 时重新加载: %v", err)
+		} else {
+			defer watcher.Close()
+		}
+		scheme = "https"
+	}
+
+	hupChan := make(chan os.Signal, 1)
+	signal.Notify(hupChan, syscall.SIGHUP)
+	go func() {
+		for range hupChan {
+			log.Println("接收到 SIGHUP，重新加载配置、订阅和证书")
+			reload.reloadAll()
+		}
+	}()
 
 	// 在单独的goroutine中启动HTTP服务器
 	go func() {
@@ -335,9 +387,53 @@ func main() {
 	}()
 
 	log.Println("程序已启动，按 Ctrl+C 退出")
-	log.Printf("HTTP API服务器已启动，访问地址: http://127.0.0.1:%d", getInt(configMap["port"]))
+	log.Printf("HTTP API服务器已启动，访问地址: %s://%s", scheme, accessHost(cfg))
 
 	// 等待退出信号
 	sig := <-sigChan
 	log.Printf("接收到信号: %v，正在退出...", sig)
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	// 停止接收新的请求，等待进行中的请求完成
+	if err := httpServer.Shutdown(ctx); err != nil {
+		log.Printf("HTTP服务器关闭失败: %v", err)
+	}
+
+	// 先停止定时任务和豆瓣列表同步并等待它们退出，之后不再提交新的订阅；
+	// 它们在等待进行中的处理，超时后中止进行中的查询和下载
+	triggersStopped := make(chan struct{})
+	go func() {
+		close(syncStop)
+		sched.Stop(ctx)
+		<-syncDone
+		close(triggersStopped)
+	}()
+	select {
+	case <-triggersStopped:
+	case <-ctx.Done():
+		log.Println("等待定时任务和豆瓣列表同步停止超时，中止进行中的任务")
+		proc.cancel()
+		<-triggersStopped
+	}
+
+	// 等待其余正在处理的订阅完成，超时后中止进行中的查询和下载
+	if err := proc.shutdown(ctx); err != nil {
+		log.Printf("等待订阅处理完成超时，已中止进行中的任务: %v", err)
+	}
+
+	// 停止通知中心
+	close(hubStop)
+
+	log.Println("程序已退出")
+}
+
+// accessHost 返回本机访问服务器使用的地址，监听所有网络接口时使用 127.0.0.1
+func accessHost(cfg config.Config) string {
+	host := cfg.Listen
+	if ip := net.ParseIP(host); ip != nil && ip.IsUnspecified() {
+		host = "127.0.0.1"
+	}
+	return net.JoinHostPort(host, strconv.Itoa(cfg.Port))
 }
\ No newline at end of file
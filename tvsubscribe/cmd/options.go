@@ -0,0 +1,211 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"tvsubscribe/auth"
+	"tvsubscribe/config"
+)
+
+const (
+	authFile            = "auth.json"              // 数据目录下的用户和API令牌文件
+	initialPasswordFile = "initial_admin_password" // 首次启动时生成的管理员密码
+	adminPasswordEnv    = "TVSUBSCRIBE_ADMIN_PASSWORD"
+)
+
+// serverOptions 服务器启动选项。路径的优先级为 默认值 < 环境变量 < 命令行参数，
+// 配置项的优先级为 默认值 < 配置文件 < 环境变量 < 命令行参数
+type serverOptions struct {
+	dataDir    string // 数据目录，配置、订阅、数据库和状态文件默认保存在这里
+	configPath string // 配置文件路径，默认为数据目录下的 config.json
+	webDir     string // Web界面的构建目录
+	torrentDir string // 种子文件的保存目录，默认为数据目录下的 torrents
+	storage    string // 数据存储类型
+	secretKey  string // 加密敏感配置项的密钥文件，为空时不加密
+	sources    map[string]config.Source
+	overrides  config.Overrides
+
+	// adminPassword 首次启动时管理员 admin 的密码，来自环境变量 TVSUBSCRIBE_ADMIN_PASSWORD，为空时随机生成
+	adminPassword string
+}
+
+// pathOption 服务器路径选项，name 同时是命令行参数名称，环境变量为 TVSUBSCRIBE_ 加上大写的名称
+type pathOption struct {
+	name   string
+	target *string
+	usage  string
+}
+
+// pathEnvName 返回路径选项对应的环境变量名称，如 data-dir 对应 TVSUBSCRIBE_DATA_DIR
+func pathEnvName(name string) string {
+	return config.EnvName(strings.ReplaceAll(name, "-", "_"))
+}
+
+// parseServerOptions 解析服务器的命令行参数和环境变量
+func parseServerOptions(args []string, lookupEnv func(string) (string, bool)) (*serverOptions, error) {
+	opts := &serverOptions{sources: make(map[string]config.Source)}
+	options := []pathOption{
+		{"data-dir", &opts.dataDir, "数据目录，默认为当前目录"},
+		{"config", &opts.configPath, "配置文件路径，默认为数据目录下的 config.json；使用 sqlite 时只用于首次导入"},
+		{"web-dir", &opts.webDir, "Web界面的构建目录，默认为 ./web/dist"},
+		{"torrent-dir", &opts.torrentDir, "种子文件的保存目录，默认为数据目录下的 torrents"},
+		{"storage", &opts.storage, "数据存储: auto、json、sqlite，auto 表示数据库文件存在时使用 sqlite，默认 auto"},
+		{"secret-key-file", &opts.secretKey, "加密保存敏感配置项的密钥文件，不存在时自动生成，为空时不加密"},
+	}
+
+	serverCmd := flag.NewFlagSet("tvsubscribe", flag.ContinueOnError)
+	for _, option := range options {
+		serverCmd.StringVar(option.target, option.name, "", option.usage)
+	}
+
+	// 每个配置项都可以用命令行参数覆盖
+	opts.overrides.Flags = config.Patch{}
+	for _, key := range config.Keys() {
+		serverCmd.Func(config.FlagName(key), fmt.Sprintf("覆盖配置项 %s，也可以使用环境变量 %s", key, config.EnvName(key)), func(value string) error {
+			if _, ok := opts.overrides.FlagNames[key]; ok {
+				return fmt.Errorf("不能与 --%s 同时使用", config.SecretFileFlagName(key))
+			}
+			raw, err := config.ParseValue(key, value)
+			if err != nil {
+				return err
+			}
+			opts.overrides.Flags[key] = raw
+			return nil
+		})
+	}
+
+	// 敏感配置项还可以从文件读取，避免出现在进程参数中
+	opts.overrides.FlagNames = make(map[string]string)
+	for _, key := range config.SecretKeys() {
+		name := config.SecretFileFlagName(key)
+		serverCmd.Func(name, fmt.Sprintf("从文件读取配置项 %s，也可以使用环境变量 %s", key, config.SecretFileEnvName(key)), func(path string) error {
+			if _, ok := opts.overrides.Flags[key]; ok {
+				return fmt.Errorf("不能与 --%s 同时使用", config.FlagName(key))
+			}
+			value, err := config.ReadSecretFile(path)
+			if err != nil {
+				return err
+			}
+			opts.overrides.FlagNames[key] = "--" + name
+			return opts.overrides.Flags.Set(key, value)
+		})
+	}
+	if err := serverCmd.Parse(args); err != nil {
+		return nil, err
+	}
+
+	// 没有命令行参数时使用环境变量
+	set := make(map[string]bool)
+	serverCmd.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	for _, option := range options {
+		if set[option.name] {
+			opts.sources[option.name] = config.SourceFlag
+		} else if value, ok := lookupEnv(pathEnvName(option.name)); ok && value != "" {
+			*option.target = value
+			opts.sources[option.name] = config.SourceEnv
+		} else {
+			opts.sources[option.name] = config.SourceDefault
+		}
+	}
+
+	env, err := config.EnvOverrides(lookupEnv)
+	if err != nil {
+		return nil, err
+	}
+	files, names, err := config.SecretFileOverrides(lookupEnv)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range files {
+		env[key] = value
+	}
+	opts.overrides.Env = env
+	opts.overrides.EnvNames = names
+	opts.adminPassword, _ = lookupEnv(adminPasswordEnv)
+
+	// 默认值，相对于数据目录的路径在数据目录确定之后计算
+	if opts.dataDir == "" {
+		opts.dataDir = "."
+	}
+	if opts.configPath == "" {
+		opts.configPath = filepath.Join(opts.dataDir, configFile)
+	}
+	if opts.webDir == "" {
+		opts.webDir = "./web/dist"
+	}
+	if opts.torrentDir == "" {
+		opts.torrentDir = filepath.Join(opts.dataDir, "torrents")
+	}
+	if opts.storage == "" {
+		opts.storage = storageAuto
+	}
+	return opts, nil
+}
+
+// dataPath 返回数据目录下的文件路径
+func (o *serverOptions) dataPath(name string) string {
+	return filepath.Join(o.dataDir, name)
+}
+
+// settings 返回路径选项及其来源，用于查看生效的配置
+func (o *serverOptions) settings() []config.Setting {
+	values := []struct {
+		name  string
+		value string
+	}{
+		{"data-dir", o.dataDir},
+		{"config", o.configPath},
+		{"web-dir", o.webDir},
+		{"torrent-dir", o.torrentDir},
+		{"storage", o.storage},
+		{"secret-key-file", o.secretKey},
+	}
+	settings := make([]config.Setting, len(values))
+	for i, v := range values {
+		value := v.value
+		if v.name != "storage" && v.value != "" {
+			if abs, err := filepath.Abs(v.value); err == nil {
+				value = abs
+			}
+		}
+		settings[i] = config.Setting{
+			Key:    v.name,
+			Value:  value,
+			Source: o.sources[v.name],
+			Env:    pathEnvName(v.name),
+			Flag:   "--" + v.name,
+		}
+	}
+	return settings
+}
+
+// ensureAdmin 没有任何用户时创建管理员 admin。没有指定密码时生成随机密码，
+// 保存到数据目录下的 initial_admin_password 文件中，只有所有者可读
+func ensureAdmin(store *auth.Store, opts *serverOptions) error {
+	password, err := store.EnsureAdmin(opts.adminPassword)
+	if err != nil {
+		return err
+	}
+	if password == "" {
+		return nil
+	}
+	path := opts.dataPath(initialPasswordFile)
+	if err := os.WriteFile(path, []byte(password+"\n"), 0600); err != nil {
+		return fmt.Errorf("保存初始密码失败: %v", err)
+	}
+	log.Printf("已创建管理员账号 admin，初始密码保存在 %s，登录后请修改密码并删除该文件", path)
+	return nil
+}
+
+// ensureDataDir 创建数据目录
+func (o *serverOptions) ensureDataDir() error {
+	if err := os.MkdirAll(o.dataDir, 0755); err != nil {
+		return fmt.Errorf("创建数据目录失败: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,766 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"tvsubscribe"
+	"tvsubscribe/config"
+	"tvsubscribe/engine"
+	"tvsubscribe/history"
+	"tvsubscribe/metadata"
+	"tvsubscribe/notify"
+	"tvsubscribe/scheduler"
+	"tvsubscribe/subscribe"
+	"tvsubscribe/wishlist"
+)
+
+// cookieRetryInterval Cookie失效后，使用同一Cookie重新尝试查询的间隔
+const cookieRetryInterval = time.Hour
+
+// airScheduleTTL 播出时间表的刷新间隔
+const airScheduleTTL = 24 * time.Hour
+
+// 种子被拒绝时记录的原因
+const (
+	reasonDownloaded  = "已下载过"
+	reasonLowQuality  = "低于最低片源质量"
+	reasonNotSelected = "已选择更好的版本"
+)
+
+// processor 订阅处理流程，调度器、配置更新、添加订阅和立即触发都经由它提交任务
+type processor struct {
+	configMgr  *ConfigManager
+	subscribes *subscribe.SubscribeManager
+	hub        *notify.Hub
+	engine     *engine.Engine
+	airStore   *metadata.Store
+	history    *history.Store
+	seen       *history.SeenStore // 非首选制作组种子首次被发现的时间
+	ctx        context.Context    // 退出超时后被取消，中止进行中的请求
+	cancel     context.CancelFunc
+
+	mu            sync.Mutex
+	expiredCookie string                                // 最近一次被判定为失效的Cookie
+	expiredAt     time.Time                             // 判定失效的时间
+	results       map[string]history.SubscriptionResult // 订阅ID -> 最近一次处理结果
+	batches       map[string][]*notify.Batch            // 订阅ID -> 等待它处理完成的各轮处理的通知批次，先开始的在前
+}
+
+// newProcessor 创建订阅处理流程并启动任务引擎
+func newProcessor(configMgr *ConfigManager, subscribeMgr *subscribe.SubscribeManager, hub *notify.Hub, airStore *metadata.Store, runHistory *history.Store, seen *history.SeenStore) *processor {
+	cfg := configMgr.GetConfig()
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &processor{
+		configMgr:  configMgr,
+		subscribes: subscribeMgr,
+		hub:        hub,
+		airStore:   airStore,
+		history:    runHistory,
+		seen:       seen,
+		results:    make(map[string]history.SubscriptionResult),
+		batches:    make(map[string][]*notify.Batch),
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+	p.engine = engine.New(engine.Options{
+		Workers: cfg.Workers,
+		PerSite: cfg.SiteConcurrency,
+	}, func(tvInfo tvsubscribe.TVInfo) string {
+		return tvsubscribe.SiteHost(&tvInfo)
+	}, p.processTV)
+	p.engine.Start()
+	return p
+}
+
+// shutdown 停止任务引擎并等待正在处理的订阅完成，超过 ctx 期限后取消进行中的请求
+func (p *processor) shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		p.engine.Stop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		p.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// applyNotifyConfig 根据当前配置更新通知分发选项和通知渠道
+func applyNotifyConfig(configMgr *ConfigManager, hub *notify.Hub) {
+	cfg := configMgr.GetConfig()
+	if dispatcher := hub.Dispatcher(); dispatcher != nil {
+		dispatcher.SetOptions(notify.DispatchOptions{
+			DedupWindow: time.Duration(cfg.NotifyDedupMinutes) * time.Minute,
+			QuietHours:  cfg.QuietHours,
+		})
+	}
+	wechat := notify.NewWeChat(cfg.WeChatServer, cfg.WeChatToken)
+	hub.SetChannel(wechat, notify.Options{
+		Mode:             notify.ParseMode(cfg.WeChatNotifyMode),
+		DailyAt:          cfg.WeChatDailyDigestAt,
+		RateLimitPerHour: cfg.WeChatRateLimit,
+	})
+}
+
+// cookieExpired 判断Cookie是否刚被判定为失效，避免同一轮中所有订阅重复请求登录页
+func (p *processor) cookieExpired(cookie string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return cookie == p.expiredCookie && time.Since(p.expiredAt) < cookieRetryInterval
+}
+
+// markCookieExpired 记录失效的Cookie
+func (p *processor) markCookieExpired(cookie string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.expiredCookie = cookie
+	p.expiredAt = time.Now()
+}
+
+// processTV 查询并下载单个电视剧的种子，由任务引擎调用，处理结果保存供本轮记录使用
+func (p *processor) processTV(tvInfo tvsubscribe.TVInfo) {
+	result := history.SubscriptionResult{
+		ID:        tvInfo.ID,
+		DouBanID:  tvInfo.DouBanID,
+		Name:      tvInfo.Name,
+		StartedAt: time.Now(),
+	}
+	p.checkTV(tvInfo, &result)
+	result.Finish()
+
+	p.mu.Lock()
+	p.results[tvInfo.ID] = result
+	p.mu.Unlock()
+
+	if tvInfo.IsMovie() {
+		p.trackMovie(tvInfo, result)
+	} else {
+		p.trackEpisodes(tvInfo, result)
+	}
+}
+
+// trackMovie 电影下载过任一版本后将订阅标记为完结并发送通知
+func (p *processor) trackMovie(tvInfo tvsubscribe.TVInfo, result history.SubscriptionResult) {
+	grabbed := len(result.Downloaded) > 0
+	for _, item := range result.Rejected {
+		if item.Reason == reasonDownloaded {
+			grabbed = true
+		}
+	}
+	if !grabbed || p.ctx.Err() != nil {
+		return
+	}
+
+	completed, err := p.subscribes.CompleteSubscribe(tvInfo.ID)
+	if err != nil {
+		log.Printf("更新完结状态失败 (豆瓣ID: %s): %v", tvInfo.DouBanID, err)
+		return
+	}
+	if !completed {
+		return
+	}
+
+	show := tvInfo.Name
+	if show == "" {
+		show = fmt.Sprintf("豆瓣ID: %s", tvInfo.DouBanID)
+	}
+	release := "此前已下载"
+	if len(result.Downloaded) > 0 {
+		release = result.Downloaded[0].Info
+	}
+	log.Printf("电影已下载，订阅已归档 (豆瓣ID: %s)", tvInfo.DouBanID)
+	p.publisher(tvInfo.ID).Publish(notify.Event{
+		Level:   notify.LevelInfo,
+		Show:    show,
+		Title:   "电影已下载",
+		Content: "已下载最佳版本，订阅已归档",
+		Detail:  fmt.Sprintf("电影: %s\n豆瓣ID: %s\n版本: %s\n不再定时检查，可在订阅管理的已完结列表中重新激活", show, tvInfo.DouBanID, release),
+		Summary: "已下载最佳版本，订阅已归档",
+	})
+}
+
+// trackEpisodes 将本次下载和此前已下载的种子包含的集数记入分集账本，
+// 账本有新增时与总集数比较，全部下载完成后将订阅标记为完结并发送通知
+func (p *processor) trackEpisodes(tvInfo tvsubscribe.TVInfo, result history.SubscriptionResult) {
+	var episodes []int
+	for _, item := range result.Downloaded {
+		episodes = append(episodes, tvsubscribe.ParseEpisodes(item.Info)...)
+	}
+	for _, item := range result.Rejected {
+		if item.Reason == reasonDownloaded {
+			episodes = append(episodes, tvsubscribe.ParseEpisodes(item.Info)...)
+		}
+	}
+	if len(episodes) == 0 || p.ctx.Err() != nil {
+		return
+	}
+
+	updated, added, err := p.subscribes.RecordEpisodes(tvInfo.ID, episodes)
+	if err != nil {
+		log.Printf("记录分集账本失败 (豆瓣ID: %s): %v", tvInfo.DouBanID, err)
+		return
+	}
+	if added == 0 || updated.Completed {
+		return
+	}
+
+	total := p.episodeCount(updated)
+	if total <= 0 {
+		return
+	}
+	completed, err := p.subscribes.UpdateCompletion(tvInfo.ID, total)
+	if err != nil {
+		log.Printf("更新完结状态失败 (豆瓣ID: %s): %v", tvInfo.DouBanID, err)
+		return
+	}
+	if !completed {
+		log.Printf("分集账本已更新 (豆瓣ID: %s, 已下载 %d/%d 集)", tvInfo.DouBanID, len(updated.Episodes), total)
+		return
+	}
+
+	show := updated.Name
+	if show == "" {
+		show = fmt.Sprintf("豆瓣ID: %s", updated.DouBanID)
+	}
+	log.Printf("电视剧已完结，订阅已归档 (豆瓣ID: %s, 共 %d 集)", tvInfo.DouBanID, total)
+	p.publisher(tvInfo.ID).Publish(notify.Event{
+		Level:   notify.LevelInfo,
+		Show:    show,
+		Title:   "电视剧已完结",
+		Content: fmt.Sprintf("已下载全部 %d 集，订阅已归档", total),
+		Detail:  fmt.Sprintf("电视剧: %s\n豆瓣ID: %s\n已下载全部 %d 集，不再定时检查\n可在订阅管理的已完结列表中重新激活", show, updated.DouBanID, total),
+		Summary: fmt.Sprintf("已下载全部 %d 集，订阅已归档", total),
+	})
+}
+
+// episodeCount 获取电视剧的总集数，优先使用播出时间表中的集数，否则通过豆瓣搜索获取
+func (p *processor) episodeCount(tvInfo tvsubscribe.TVInfo) int {
+	if schedule, ok := p.airStore.Get(tvInfo.DouBanID); ok && schedule.Episodes > 0 {
+		return schedule.Episodes
+	}
+	count, err := tvsubscribe.GetEpisodeCount(p.ctx, tvInfo.DouBanID, tvInfo.Name)
+	if err != nil {
+		log.Printf("获取总集数失败 (豆瓣ID: %s): %v", tvInfo.DouBanID, err)
+		return 0
+	}
+	return count
+}
+
+// recordChecks 将本轮的检查结果记录到订阅上，供订阅列表展示各订阅的状态，整轮只保存一次订阅
+func (p *processor) recordChecks(results []history.SubscriptionResult) {
+	var checks []subscribe.Check
+	for _, result := range results {
+		// 没有查询站点时保留上一次的检查结果
+		if result.Skipped != "" {
+			continue
+		}
+		lastError := ""
+		if len(result.Errors) > 0 {
+			lastError = result.Errors[len(result.Errors)-1].Message
+		}
+		checks = append(checks, subscribe.Check{
+			ID:        result.ID,
+			CheckedAt: result.FinishedAt,
+			Grabbed:   len(result.Downloaded) > 0,
+			LastError: lastError,
+		})
+	}
+	if len(checks) == 0 {
+		return
+	}
+	if _, err := p.subscribes.RecordChecks(checks); err != nil {
+		log.Printf("记录订阅检查结果失败: %v", err)
+	}
+}
+
+// checkTV 查询并下载单个电视剧的种子，过程记录到 result 中
+func (p *processor) checkTV(tvInfo tvsubscribe.TVInfo, result *history.SubscriptionResult) {
+	cfg := p.configMgr.GetConfig()
+	cookie := cfg.Cookie
+	endpoint := cfg.Endpoint
+
+	if p.ctx.Err() != nil {
+		log.Printf("程序正在退出，跳过豆瓣ID: %s", tvInfo.DouBanID)
+		result.Skipped = "程序正在退出"
+		return
+	}
+
+	if !tvInfo.IsMovie() {
+		p.refreshAirSchedule(tvInfo)
+	}
+
+	if p.cookieExpired(cookie) {
+		log.Printf("站点Cookie已失效，跳过豆瓣ID: %s", tvInfo.DouBanID)
+		result.Skipped = "站点Cookie已失效"
+		return
+	}
+
+	log.Printf("处理豆瓣ID: %s, 分辨率: %d", tvInfo.DouBanID, tvInfo.Resolution)
+
+	// 按制作组设置查询种子列表
+	torrentInfos, teamRejected, err := p.queryTorrents(tvInfo, cookie)
+	if err != nil {
+		log.Printf("查询种子列表失败 (豆瓣ID: %s): %v", tvInfo.DouBanID, err)
+		result.AddError(fmt.Sprintf("查询种子列表失败: %v", err))
+		if p.ctx.Err() != nil {
+			return
+		}
+		if errors.Is(err, tvsubscribe.ErrCookieExpired) {
+			p.markCookieExpired(cookie)
+			// Cookie失效属于严重错误，无论汇总模式如何都立即发送
+			p.publisher(tvInfo.ID).Publish(notify.Event{
+				Level:   notify.LevelCritical,
+				Title:   "站点Cookie已失效",
+				Content: "请登录站点后更新Cookie配置",
+				Detail:  fmt.Sprintf("查询种子列表时被重定向到登录页\n错误信息: %v", err),
+			})
+		}
+		return
+	}
+	result.Candidates = len(torrentInfos)
+
+	if len(torrentInfos) == 0 {
+		log.Printf("未找到可下载的种子 (豆瓣ID: %s)", tvInfo.DouBanID)
+		return
+	}
+
+	log.Printf("找到 %d 个种子 (豆瓣ID: %s)", len(torrentInfos), tvInfo.DouBanID)
+
+	// 全局默认规则与订阅规则合并，规则无效时不下载，避免下载到不想要的种子
+	filter, err := tvsubscribe.CompileFilter(tvsubscribe.MergeFilterRules(filterDefaults(cfg), tvInfo.Filters))
+	if err != nil {
+		log.Printf("过滤规则无效 (豆瓣ID: %s): %v", tvInfo.DouBanID, err)
+		result.AddError(fmt.Sprintf("过滤规则无效: %v", err))
+		return
+	}
+
+	// 筛选需要下载的种子
+	var accepted []tvsubscribe.TorrentInfo
+	for _, torrentInfo := range torrentInfos {
+		if tvsubscribe.TorrentDownloaded(torrentInfo) {
+			result.Rejected = append(result.Rejected, historyItem(torrentInfo, reasonDownloaded))
+			continue
+		}
+		if reason, ok := teamRejected[torrentInfo.ID]; ok {
+			result.Rejected = append(result.Rejected, historyItem(torrentInfo, reason))
+			continue
+		}
+		if reason := filter.Check(torrentInfo); reason != "" {
+			result.Rejected = append(result.Rejected, historyItem(torrentInfo, reason))
+			continue
+		}
+		accepted = append(accepted, torrentInfo)
+	}
+	if tvInfo.IsMovie() {
+		accepted = p.selectMovieRelease(tvInfo, accepted, result)
+	}
+	for _, torrentInfo := range accepted {
+		result.Accepted = append(result.Accepted, historyItem(torrentInfo, ""))
+	}
+	if len(accepted) == 0 {
+		log.Printf("没有新的种子需要下载 (豆瓣ID: %s)", tvInfo.DouBanID)
+		return
+	}
+
+	// 下载种子
+	show := tvInfo.Name
+	if show == "" {
+		show = fmt.Sprintf("豆瓣ID: %s", tvInfo.DouBanID)
+	}
+	downloads, err := tvsubscribe.DownloadTorrent(p.ctx, accepted, show, endpoint, p.publisher(tvInfo.ID))
+	for _, download := range downloads {
+		switch {
+		case download.Skipped:
+			continue
+		case download.Err != nil:
+			result.AddError(fmt.Sprintf("种子 %s: %v", download.Torrent.ID, download.Err))
+		default:
+			item := historyItem(download.Torrent, "")
+			item.Name = download.Name
+			result.Downloaded = append(result.Downloaded, item)
+		}
+	}
+	if err != nil {
+		log.Printf("下载种子失败 (豆瓣ID: %s): %v", tvInfo.DouBanID, err)
+	} else {
+		log.Printf("成功处理 %d 个种子 (豆瓣ID: %s)", len(accepted), tvInfo.DouBanID)
+	}
+}
+
+// teamPolicy 返回订阅的制作组设置，订阅未设置时使用全局设置
+func teamPolicy(tvInfo tvsubscribe.TVInfo, cfg config.Config) tvsubscribe.TeamPolicy {
+	if tvInfo.TeamPolicy != nil {
+		return *tvInfo.TeamPolicy
+	}
+	return teamDefaults(cfg)
+}
+
+// queryTorrents 按订阅的制作组设置查询种子列表。
+// prefer 模式下依次查询每个首选制作组，再不限制作组查询一次，
+// 返回所有种子以及因制作组优先级被拒绝的种子ID和原因。
+func (p *processor) queryTorrents(tvInfo tvsubscribe.TVInfo, cookie string) ([]tvsubscribe.TorrentInfo, map[string]string, error) {
+	cfg := p.configMgr.GetConfig()
+	policy := teamPolicy(tvInfo, cfg)
+	if policy.Mode == tvsubscribe.TeamModeAny {
+		torrentInfos, err := tvsubscribe.QueryTorrentListWithTeams(p.ctx, cookie, &tvInfo, nil)
+		return torrentInfos, nil, err
+	}
+
+	teamIDs, err := tvsubscribe.ResolveTeams(policy.Teams, cfg.SiteTeams)
+	if err != nil {
+		return nil, nil, err
+	}
+	if policy.Mode != tvsubscribe.TeamModePrefer {
+		torrentInfos, err := tvsubscribe.QueryTorrentListWithTeams(p.ctx, cookie, &tvInfo, teamIDs)
+		return torrentInfos, nil, err
+	}
+
+	var candidates []tvsubscribe.TeamCandidate
+	seen := make(map[string]bool)
+	addCandidates := func(torrentInfos []tvsubscribe.TorrentInfo, rank int, fallback bool) {
+		for _, torrentInfo := range torrentInfos {
+			if !seen[torrentInfo.ID] {
+				seen[torrentInfo.ID] = true
+				candidates = append(candidates, tvsubscribe.TeamCandidate{Torrent: torrentInfo, Rank: rank, Fallback: fallback})
+			}
+		}
+	}
+	for rank, teamID := range teamIDs {
+		torrentInfos, err := tvsubscribe.QueryTorrentListWithTeams(p.ctx, cookie, &tvInfo, []int{teamID})
+		if err != nil {
+			return nil, nil, err
+		}
+		addCandidates(torrentInfos, rank, false)
+	}
+	torrentInfos, err := tvsubscribe.QueryTorrentListWithTeams(p.ctx, cookie, &tvInfo, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	addCandidates(torrentInfos, len(teamIDs), true)
+
+	now := time.Now()
+	p.markSeen(candidates, now)
+	rejected := tvsubscribe.PreferTeams(candidates, policy.FallbackDelay(), now, p.seen.FirstSeen)
+
+	result := make([]tvsubscribe.TorrentInfo, 0, len(candidates))
+	for _, candidate := range candidates {
+		result = append(result, candidate.Torrent)
+	}
+	return result, rejected, nil
+}
+
+// markSeen 记录非首选制作组种子首次被发现的时间，保存失败只记录日志，本次仍按内存中的时间判断
+func (p *processor) markSeen(candidates []tvsubscribe.TeamCandidate, now time.Time) {
+	var ids []string
+	for _, candidate := range candidates {
+		if candidate.Fallback {
+			ids = append(ids, candidate.Torrent.ID)
+		}
+	}
+	if err := p.seen.Mark(ids, now); err != nil {
+		log.Printf("保存种子发现时间失败: %v", err)
+	}
+}
+
+// selectMovieRelease 电影只下载一个版本：已下载过任一版本时不再下载，
+// 否则从达到最低片源质量的种子中选出最佳版本，其余种子记录拒绝原因
+func (p *processor) selectMovieRelease(tvInfo tvsubscribe.TVInfo, candidates []tvsubscribe.TorrentInfo, result *history.SubscriptionResult) []tvsubscribe.TorrentInfo {
+	for _, item := range result.Rejected {
+		if item.Reason == reasonDownloaded {
+			for _, torrentInfo := range candidates {
+				result.Rejected = append(result.Rejected, historyItem(torrentInfo, reasonNotSelected))
+			}
+			return nil
+		}
+	}
+
+	minQuality := p.movieMinQuality(tvInfo)
+	best, ok := tvsubscribe.BestRelease(candidates, minQuality)
+	for _, torrentInfo := range candidates {
+		switch {
+		case ok && torrentInfo.ID == best.ID:
+			continue
+		case tvsubscribe.DetectQuality(torrentInfo) < minQuality:
+			result.Rejected = append(result.Rejected, historyItem(torrentInfo, reasonLowQuality))
+		default:
+			result.Rejected = append(result.Rejected, historyItem(torrentInfo, reasonNotSelected))
+		}
+	}
+	if !ok {
+		log.Printf("没有达到最低片源质量 %s 的版本 (豆瓣ID: %s)", minQuality, tvInfo.DouBanID)
+		return nil
+	}
+	log.Printf("选择最佳版本 %s (豆瓣ID: %s, 质量: %s, 大小: %s)", best.ID, tvInfo.DouBanID, tvsubscribe.DetectQuality(best), best.Volume)
+	return []tvsubscribe.TorrentInfo{best}
+}
+
+// movieMinQuality 返回电影订阅的最低片源质量，订阅未设置时使用全局配置
+func (p *processor) movieMinQuality(tvInfo tvsubscribe.TVInfo) tvsubscribe.Quality {
+	name := tvInfo.MinQuality
+	if name == "" {
+		name = p.configMgr.GetConfig().MovieMinQuality
+	}
+	quality, err := tvsubscribe.ParseQualityName(name)
+	if err != nil {
+		quality, _ = tvsubscribe.ParseQualityName(tvsubscribe.DefaultMovieMinQuality)
+	}
+	return quality
+}
+
+// historyItem 将种子信息转换为处理记录中的条目
+func historyItem(torrentInfo tvsubscribe.TorrentInfo, reason string) history.Item {
+	return history.Item{
+		TorrentID: torrentInfo.ID,
+		Title:     torrentInfo.Title,
+		Info:      torrentInfo.Info,
+		Volume:    torrentInfo.Volume,
+		Reason:    reason,
+	}
+}
+
+// refreshAirSchedule 启用按播出时间检查时，刷新过期的播出时间表
+func (p *processor) refreshAirSchedule(tvInfo tvsubscribe.TVInfo) {
+	cfg := p.configMgr.GetConfig()
+	if !cfg.AirSchedule {
+		return
+	}
+
+	previous, ok := p.airStore.Get(tvInfo.DouBanID)
+	if ok && time.Since(previous.FetchedAt) < airScheduleTTL {
+		return
+	}
+
+	fetcher := metadata.NewFetcher(
+		cfg.DoubanBaseURL,
+		cfg.TMDBBaseURL,
+		cfg.TMDBAPIKey,
+	)
+	var last *metadata.AirSchedule
+	if ok {
+		last = &previous
+	}
+	schedule, err := fetcher.Fetch(p.ctx, tvInfo.DouBanID, last)
+	if err != nil {
+		log.Printf("获取播出时间表失败 (豆瓣ID: %s): %v", tvInfo.DouBanID, err)
+		return
+	}
+	if err := p.airStore.Set(tvInfo.DouBanID, schedule); err != nil {
+		log.Printf("保存播出时间表失败 (豆瓣ID: %s): %v", tvInfo.DouBanID, err)
+		return
+	}
+	log.Printf("播出时间表已更新 (豆瓣ID: %s, 来源: %s, 已知 %d/%d 集)", tvInfo.DouBanID, schedule.Source, schedule.KnownDates(), schedule.Episodes)
+}
+
+// airPollOptions 根据当前配置生成按播出时间检查的选项
+func airPollOptions(configMgr *ConfigManager) metadata.PollOptions {
+	cfg := configMgr.GetConfig()
+	return metadata.PollOptions{
+		Enabled:        cfg.AirSchedule,
+		AirTime:        cfg.AirTime,
+		Window:         time.Duration(cfg.AirWindowHours) * time.Hour,
+		Interval:       time.Duration(cfg.AirPollMinutes) * time.Minute,
+		OffAirInterval: time.Duration(cfg.OffAirPollMinutes) * time.Minute,
+	}
+}
+
+// processSubscribes 提交一批订阅并等待处理完成，本批次的通知在结束后合并发送，处理结果保存为一条处理记录
+func (p *processor) processSubscribes(trigger string, subscribes []tvsubscribe.TVInfo) {
+	applyNotifyConfig(p.configMgr, p.hub)
+	// 一轮处理中的事件在结束后合并发送，同时进行的其他轮次的事件由它们各自发送
+	batch := p.hub.NewBatch()
+	p.addBatch(batch, subscribes)
+	defer batch.Flush()
+	defer p.removeBatch(batch, subscribes)
+
+	run := history.NewRun(trigger)
+	defer p.saveRun(run, subscribes)
+
+	log.Println("开始处理电视剧订阅...")
+
+	if len(subscribes) == 0 {
+		log.Println("没有订阅的电视剧")
+		return
+	}
+
+	<-p.engine.Submit(subscribes...)
+
+	log.Println("电视剧订阅处理完成")
+}
+
+// addBatch 登记本轮处理的通知批次，处理这些订阅时的事件发布到批次中
+func (p *processor) addBatch(batch *notify.Batch, subscribes []tvsubscribe.TVInfo) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, tv := range subscribes {
+		p.batches[tv.ID] = append(p.batches[tv.ID], batch)
+	}
+}
+
+// removeBatch 本轮处理结束后取消登记通知批次
+func (p *processor) removeBatch(batch *notify.Batch, subscribes []tvsubscribe.TVInfo) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, tv := range subscribes {
+		batches := p.batches[tv.ID]
+		for i, b := range batches {
+			if b == batch {
+				batches = append(batches[:i], batches[i+1:]...)
+				break
+			}
+		}
+		if len(batches) == 0 {
+			delete(p.batches, tv.ID)
+		} else {
+			p.batches[tv.ID] = batches
+		}
+	}
+}
+
+// publisher 返回处理订阅时发布事件的位置：重复触发被合并时只发布到最先开始的一轮，
+// 没有等待它的处理轮次时直接发布到通知中心
+func (p *processor) publisher(id string) notify.Publisher {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if batches := p.batches[id]; len(batches) > 0 {
+		return batches[0]
+	}
+	return p.hub
+}
+
+// saveRun 收集本轮各订阅的处理结果，记录到订阅上并保存处理记录。
+// 重复触发被合并时，多轮记录会共用同一次处理的结果。
+func (p *processor) saveRun(run *history.Run, subscribes []tvsubscribe.TVInfo) {
+	p.mu.Lock()
+	for _, tv := range subscribes {
+		result, ok := p.results[tv.ID]
+		if !ok || result.FinishedAt.Before(run.StartedAt) {
+			result = history.SubscriptionResult{
+				ID:       tv.ID,
+				DouBanID: tv.DouBanID,
+				Name:     tv.Name,
+				Skipped:  "没有处理结果",
+			}
+		}
+		run.Subscriptions = append(run.Subscriptions, result)
+	}
+	p.mu.Unlock()
+	run.Finish()
+	p.recordChecks(run.Subscriptions)
+
+	cfg := p.configMgr.GetConfig()
+	p.history.SetRetention(cfg.RunHistoryDays, cfg.RunHistoryLimit)
+	if err := p.history.Add(*run); err != nil {
+		log.Printf("保存处理记录失败: %v", err)
+	}
+}
+
+// scheduleDefaults 根据当前配置生成全局默认调度规则
+func scheduleDefaults(configMgr *ConfigManager) scheduler.Spec {
+	cfg := configMgr.GetConfig()
+	cronExpr := cfg.Cron
+	if cronExpr == "" {
+		cronExpr = fmt.Sprintf("@every %dm", cfg.IntervalMinutes)
+	}
+	return scheduler.Spec{
+		Cron:        cronExpr,
+		ActiveHours: cfg.ActiveHours,
+		Jitter:      time.Duration(cfg.JitterSeconds) * time.Second,
+	}
+}
+
+// startScheduler 启动定时任务
+func startScheduler(configManager *ConfigManager, subscribeManager *subscribe.SubscribeManager, proc *processor) *scheduler.Scheduler {
+	// 按订阅各自的调度规则定时执行
+	sched := scheduler.New(
+		func() scheduler.Spec { return scheduleDefaults(configManager) },
+		subscribeManager.GetSubscribes,
+		func(ctx context.Context, subscribes []tvsubscribe.TVInfo, manual bool) {
+			trigger := history.TriggerScheduled
+			if manual {
+				trigger = history.TriggerManual
+			}
+			done := make(chan struct{})
+			go func() {
+				proc.processSubscribes(trigger, subscribes)
+				close(done)
+			}()
+			// 停止调度器超时后中止进行中的查询和下载
+			select {
+			case <-done:
+			case <-ctx.Done():
+				proc.cancel()
+				<-done
+			}
+		},
+	)
+	// 启用按播出时间检查后，未设置自身调度规则的订阅按播出时间表规划检查时间
+	sched.SetPlanner(metadata.NewPlanner(proc.airStore, func() metadata.PollOptions {
+		return airPollOptions(configManager)
+	}))
+	sched.Start()
+
+	// 启动后立即执行一次
+	sched.RunNow()
+	return sched
+}
+
+// wishlistOptions 根据当前配置生成豆瓣列表同步设置
+func wishlistOptions(configMgr *ConfigManager) wishlist.Options {
+	cfg := configMgr.GetConfig()
+	return wishlist.Options{
+		BaseURL:    cfg.DoubanBaseURL,
+		User:       cfg.DoubanUser,
+		Cookie:     cfg.DoubanCookie,
+		Lists:      cfg.DoubanSyncLists,
+		Interval:   time.Duration(cfg.DoubanSyncMinutes) * time.Minute,
+		Resolution: cfg.DoubanSyncResolution,
+		Tags:       cfg.DoubanSyncTags,
+		Movies:     cfg.DoubanSyncMovies,
+		Archive:    cfg.DoubanSyncArchive,
+	}
+}
+
+// startWishlistSync 创建豆瓣列表同步器，同步新建订阅后立即处理新订阅并发送通知
+func startWishlistSync(configManager *ConfigManager, subscribeManager *subscribe.SubscribeManager, sched *scheduler.Scheduler, proc *processor) *wishlist.Syncer {
+	return wishlist.NewSyncer(subscribeManager, func() wishlist.Options {
+		return wishlistOptions(configManager)
+	}, func(result wishlist.Result) {
+		if !result.Changed() {
+			return
+		}
+		sched.Reschedule()
+		if len(result.Added) > 0 {
+			go proc.processSubscribes(history.TriggerWishlist, result.Added)
+		}
+
+		var detail []string
+		for _, tv := range result.Added {
+			detail = append(detail, "新增: "+tv.Name)
+		}
+		for _, tv := range result.Archived {
+			detail = append(detail, "归档: "+tv.Name)
+		}
+		summary := fmt.Sprintf("新增 %d 个订阅，归档 %d 个订阅", len(result.Added), len(result.Archived))
+		proc.hub.Publish(notify.Event{
+			Level:   notify.LevelInfo,
+			Show:    "豆瓣想看",
+			Title:   "豆瓣列表已同步",
+			Content: summary,
+			Detail:  strings.Join(detail, "\n"),
+			Summary: summary,
+		})
+		// 同步结果不属于任何一轮处理，汇总模式下单独发送
+		proc.hub.Flush()
+	})
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"log"
+	"os"
+
+	"tvsubscribe/certs"
+	"tvsubscribe/notify"
+	"tvsubscribe/scheduler"
+	"tvsubscribe/subscribe"
+)
+
+// reloader 数据文件被外部修改或收到 SIGHUP 时重新加载配置和订阅，
+// 证书文件被修改或收到 SIGHUP 时重新加载HTTPS证书
+type reloader struct {
+	stores       *dataStores
+	configMgr    *ConfigManager
+	subscribes   *subscribe.SubscribeManager
+	sched        *scheduler.Scheduler
+	hub          *notify.Hub
+	certificates *certs.Reloader // 未使用HTTPS时为 nil
+}
+
+// reloadAll 重新加载配置、订阅和证书
+func (r *reloader) reloadAll() {
+	r.reloadConfig()
+	r.reloadSubscribes()
+	r.reloadCertificates()
+}
+
+// fileChanged 处理数据文件的变化，path 为文件的绝对路径
+func (r *reloader) fileChanged(path string) {
+	switch path {
+	case r.stores.configPath:
+		r.reloadConfig()
+	case r.stores.subscribesPath:
+		r.reloadSubscribes()
+	}
+}
+
+// reloadConfig 重新加载配置，配置变化后更新通知设置并按新配置重新计算执行时间
+func (r *reloader) reloadConfig() {
+	if r.missing(r.stores.configPath) {
+		return
+	}
+	changed, err := r.configMgr.Reload()
+	if err != nil {
+		log.Printf("重新加载配置失败，继续使用当前配置: %v", err)
+		return
+	}
+	if !changed {
+		return
+	}
+	log.Println("配置已重新加载")
+	applyNotifyConfig(r.configMgr, r.hub)
+	r.sched.Reschedule()
+}
+
+// reloadSubscribes 重新加载订阅，订阅变化后重新计算执行时间
+func (r *reloader) reloadSubscribes() {
+	if r.missing(r.stores.subscribesPath) {
+		return
+	}
+	changed, err := r.subscribes.Reload()
+	if err != nil {
+		log.Printf("重新加载订阅失败，继续使用当前订阅: %v", err)
+		return
+	}
+	if !changed {
+		return
+	}
+	log.Printf("订阅已重新加载，共 %d 个订阅", len(r.subscribes.GetSubscribes()))
+	r.sched.Reschedule()
+}
+
+// reloadCertificates 重新加载HTTPS证书，之后的新连接使用新证书。
+// 证书和私钥先后更新时，只更新了其中一个的那次加载会失败，继续使用当前证书
+func (r *reloader) reloadCertificates() {
+	if r.certificates == nil {
+		return
+	}
+	if err := r.certificates.Reload(); err != nil {
+		log.Printf("重新加载证书失败，继续使用当前证书: %v", err)
+		return
+	}
+	log.Println("证书已重新加载")
+	logCertificate(r.certificates)
+}
+
+// missing 判断JSON数据文件是否不存在，文件被移走时不重新加载，避免清空内存中的数据
+func (r *reloader) missing(path string) bool {
+	if path == "" {
+		return false
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		log.Printf("%s 不存在，跳过重新加载", path)
+		return true
+	}
+	return false
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"tvsubscribe/config"
+	"tvsubscribe/storage"
+	"tvsubscribe/storage/sqlite"
+	"tvsubscribe/subscribe"
+)
+
+const (
+	storageAuto   = "auto"   // 数据库文件存在时使用 SQLite，否则使用JSON文件
+	storageJSON   = "json"   // 使用 config.json 和 subscribes.json
+	storageSQLite = "sqlite" // 使用 SQLite 数据库，首次使用时导入JSON文件
+
+	configFile     = "config.json"     // 数据目录下的配置文件
+	subscribesFile = "subscribes.json" // 数据目录下的订阅文件
+	databaseFile   = "tvsubscribe.db"  // 数据目录下的数据库文件
+)
+
+// dataStores 服务器使用的配置和订阅存储
+type dataStores struct {
+	backend        string
+	config         config.Store
+	subscribes     subscribe.Store
+	configPath     string // 使用JSON文件时配置文件的绝对路径
+	subscribesPath string // 使用JSON文件时订阅文件的绝对路径
+	closers        []func() error
+}
+
+// openStores 按存储类型打开数据目录中的配置和订阅存储，并锁定数据文件，防止两个进程同时运行时互相覆盖
+func openStores(opts *serverOptions) (*dataStores, error) {
+	configPath := opts.configPath
+	subscribesPath := opts.dataPath(subscribesFile)
+	databasePath := opts.dataPath(databaseFile)
+
+	backend := opts.storage
+	if backend == storageAuto {
+		backend = storageJSON
+		if _, err := os.Stat(databasePath); err == nil {
+			backend = storageSQLite
+		}
+	}
+
+	stores := &dataStores{backend: backend}
+	switch backend {
+	case storageJSON:
+		for _, path := range []string{configPath, subscribesPath} {
+			if err := stores.lock(path); err != nil {
+				stores.close()
+				return nil, err
+			}
+		}
+		var err error
+		if configPath, err = filepath.Abs(configPath); err != nil {
+			stores.close()
+			return nil, fmt.Errorf("获取配置文件绝对路径失败: %v", err)
+		}
+		if subscribesPath, err = filepath.Abs(subscribesPath); err != nil {
+			stores.close()
+			return nil, fmt.Errorf("获取订阅文件绝对路径失败: %v", err)
+		}
+		subscribeStore := subscribe.NewJSONStore(subscribesPath)
+		configStore := config.NewJSONStore(configPath)
+		// 版本1配置文件中的订阅在升级时保存到订阅文件
+		configStore.LegacySubscribes = subscribe.LegacyImporter(subscribeStore)
+		stores.config = configStore
+		stores.subscribes = subscribeStore
+		stores.configPath = configPath
+		stores.subscribesPath = subscribesPath
+	case storageSQLite:
+		if err := stores.lock(databasePath); err != nil {
+			return nil, err
+		}
+		db, err := sqlite.Open(databasePath)
+		if err != nil {
+			stores.close()
+			return nil, err
+		}
+		stores.closers = append(stores.closers, db.Close)
+
+		// 首次使用数据库时导入现有的JSON文件
+		result, err := db.ImportJSON(subscribesPath, configPath)
+		if err != nil {
+			stores.close()
+			return nil, fmt.Errorf("导入JSON数据失败: %v", err)
+		}
+		if result.Imported {
+			log.Printf("已将 %d 个订阅和配置导入数据库 %s，%s 和 %s 不再使用", result.Subscribes, databasePath, subscribesPath, configPath)
+		}
+		stores.config = db.Config()
+		stores.subscribes = db.Subscribes()
+	default:
+		return nil, fmt.Errorf("未知的存储类型: %s，可选 %s、%s、%s", backend, storageAuto, storageJSON, storageSQLite)
+	}
+
+	// 指定了密钥文件时加密保存敏感配置项
+	if opts.secretKey != "" {
+		cipher, err := config.LoadKeyFile(opts.secretKey)
+		if err != nil {
+			stores.close()
+			return nil, err
+		}
+		stores.config = config.NewEncryptedStore(stores.config, cipher)
+	}
+	return stores, nil
+}
+
+// watch 监听JSON数据文件，被外部修改后调用 onChange，停止存储时停止监听；使用数据库时不监听
+func (s *dataStores) watch(onChange func(path string)) error {
+	if s.backend != storageJSON {
+		return nil
+	}
+	watcher, err := storage.Watch([]string{s.configPath, s.subscribesPath}, storage.DefaultWatchDelay, onChange)
+	if err != nil {
+		return err
+	}
+	s.closers = append(s.closers, watcher.Close)
+	return nil
+}
+
+// lock 锁定数据文件，关闭存储时释放
+func (s *dataStores) lock(path string) error {
+	lock, err := storage.Lock(path)
+	if err != nil {
+		return fmt.Errorf("锁定数据文件失败: %v", err)
+	}
+	s.closers = append(s.closers, lock.Unlock)
+	return nil
+}
+
+// close 按打开的相反顺序关闭数据库并释放文件锁
+func (s *dataStores) close() {
+	for i := len(s.closers) - 1; i >= 0; i-- {
+		if err := s.closers[i](); err != nil {
+			log.Printf("关闭数据存储失败: %v", err)
+		}
+	}
+	s.closers = nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"log"
+	"path/filepath"
+
+	"tvsubscribe/certs"
+	"tvsubscribe/config"
+)
+
+// tlsDir 数据目录下保存自签名证书的目录
+const tlsDir = "tls"
+
+// loadCertificates 按配置加载HTTPS证书，使用自签名证书时在数据目录的 tls 目录下生成
+func loadCertificates(cfg config.Config, opts *serverOptions) (*certs.Reloader, error) {
+	certPath, keyPath := cfg.TLSCert, cfg.TLSKey
+	if cfg.TLSSelfSigned {
+		certPath = opts.dataPath(filepath.Join(tlsDir, "cert.pem"))
+		keyPath = opts.dataPath(filepath.Join(tlsDir, "key.pem"))
+		generated, err := certs.EnsureSelfSigned(certPath, keyPath, certs.DefaultHosts())
+		if err != nil {
+			return nil, err
+		}
+		if generated {
+			log.Printf("已生成自签名证书 %s，命令行可以使用 --ca-cert %s 验证服务器", certPath, certPath)
+		}
+	}
+
+	certificates, err := certs.NewReloader(certPath, keyPath)
+	if err != nil {
+		return nil, err
+	}
+	logCertificate(certificates)
+	return certificates, nil
+}
+
+// logCertificate 记录当前证书的有效期和指纹
+func logCertificate(certificates *certs.Reloader) {
+	leaf := certificates.Leaf()
+	log.Printf("HTTPS证书: %s，有效期至 %s，SHA-256 指纹 %s",
+		leaf.Subject.CommonName, leaf.NotAfter.Local().Format("2006-01-02 15:04"), certs.Fingerprint(leaf))
+}
@@ -1,11 +1,71 @@
 package config
 
+// DefaultListen 默认的监听地址，只允许本机访问
+const DefaultListen = "127.0.0.1"
+
 // Config 应用配置
 type Config struct {
-	Endpoint        string `json:"endpoint"`
-	Cookie          string `json:"cookie"`
-	IntervalMinutes int    `json:"interval_minutes"`
-	WeChatServer    string `json:"wechat_server"`
-	WeChatToken     string `json:"wechat_token"`
-	Port            int    `json:"port"`
+	Endpoint            string `json:"endpoint"`
+	Cookie              string `json:"cookie"`
+	IntervalMinutes     int    `json:"interval_minutes"`
+	Cron                string `json:"cron"`             // 全局cron表达式，设置后替代 interval_minutes
+	ActiveHours         string `json:"active_hours"`     // 全局活跃时段（HH:MM-HH:MM），为空表示全天
+	JitterSeconds       int    `json:"jitter_seconds"`   // 每次调度的随机延迟上限（秒）
+	Workers             int    `json:"workers"`          // 同时处理的订阅数量上限，修改后重启生效
+	SiteConcurrency     int    `json:"site_concurrency"` // 同一站点同时处理的订阅数量上限，修改后重启生效
+	WeChatServer        string `json:"wechat_server"`
+	WeChatToken         string `json:"wechat_token"`
+	WeChatNotifyMode    string `json:"wechat_notify_mode"`     // 微信通知模式：instant 逐条发送，digest 每轮汇总发送
+	WeChatDailyDigestAt string `json:"wechat_daily_digest_at"` // 每日汇总发送时间（HH:MM），为空表示不发送
+	WeChatRateLimit     int    `json:"wechat_rate_limit"`      // 微信每小时最多发送条数，0 表示不限制
+	NotifyDedupMinutes  int    `json:"notify_dedup_minutes"`   // 相同通知的去重窗口（分钟），默认360
+	QuietHours          string `json:"quiet_hours"`            // 免打扰时段（HH:MM-HH:MM），期间非紧急通知排队
+	AirSchedule         bool   `json:"air_schedule"`           // 是否按剧集播出时间规划检查
+	AirTime             string `json:"air_time"`               // 预计播出时刻（HH:MM），默认20:00
+	AirWindowHours      int    `json:"air_window_hours"`       // 预计播出时间之后频繁检查的时长（小时），默认6
+	AirPollMinutes      int    `json:"air_poll_minutes"`       // 播出窗口内的检查间隔（分钟），默认10
+	OffAirPollMinutes   int    `json:"off_air_poll_minutes"`   // 播出窗口外的检查间隔（分钟），默认720
+	DoubanBaseURL       string `json:"douban_base_url"`        // 豆瓣地址，默认 https://movie.douban.com
+	TMDBBaseURL         string `json:"tmdb_base_url"`          // TMDB接口地址，默认 https://api.themoviedb.org
+	TMDBAPIKey          string `json:"tmdb_api_key"`           // TMDB API Key，为空时不使用TMDB
+	RunHistoryDays      int    `json:"run_history_days"`       // 处理记录保留天数，默认30
+	RunHistoryLimit     int    `json:"run_history_limit"`      // 处理记录最多保留条数，默认500
+	MovieMinQuality     string `json:"movie_min_quality"`      // 电影订阅的默认最低片源质量，默认 web-dl
+	Port                int    `json:"port"`
+	Listen              string `json:"listen"` // 监听地址，默认 127.0.0.1 只允许本机访问，0.0.0.0 或 :: 监听所有网络接口，修改后重启生效
+
+	// HTTPS，修改后重启生效
+	TLSCert          string `json:"tls_cert"`           // 证书文件路径（PEM，可以包含中间证书），设置后使用HTTPS
+	TLSKey           string `json:"tls_key"`            // 私钥文件路径（PEM）
+	TLSSelfSigned    bool   `json:"tls_self_signed"`    // 未设置证书时使用自签名证书，首次启动时在数据目录的 tls 目录下生成
+	HTTPRedirectPort int    `json:"http_redirect_port"` // 使用HTTPS时在该端口把HTTP请求重定向到HTTPS，0 表示不监听
+
+	// 全局默认的种子过滤规则，与订阅自己的规则合并使用
+	FilterInclude      []string `json:"filter_include"`       // 必须包含的关键字
+	FilterExclude      []string `json:"filter_exclude"`       // 排除的关键字
+	FilterIncludeRegex []string `json:"filter_include_regex"` // 必须匹配的正则
+	FilterExcludeRegex []string `json:"filter_exclude_regex"` // 排除的正则
+	FilterAllowGroups  []string `json:"filter_allow_groups"`  // 制作组白名单
+	FilterDenyGroups   []string `json:"filter_deny_groups"`   // 制作组黑名单
+
+	// 全局默认的制作组设置，订阅设置了自己的制作组时替代全局设置
+	TeamMode          string         `json:"team_mode"`           // list 只搜索列出的制作组，any 不限制作组，prefer 优先列出的制作组，默认 list
+	TeamList          []string       `json:"team_list"`           // 制作组名称或站点制作组ID，默认 ["9"]
+	TeamFallbackHours int            `json:"team_fallback_hours"` // prefer 模式下等待首选制作组的时长（小时），默认24
+	SiteTeams         map[string]int `json:"site_teams"`          // 站点制作组名称到ID的映射，如 {"GroupA": 9}
+
+	// 豆瓣想看/在看列表同步，为列表中新的剧集自动创建订阅
+	DoubanUser           string   `json:"douban_user"`            // 豆瓣用户ID，为空时不同步
+	DoubanCookie         string   `json:"douban_cookie"`          // 豆瓣登录Cookie，列表仅自己可见时需要
+	DoubanSyncLists      []string `json:"douban_sync_lists"`      // 同步的列表，wish 想看、do 在看，默认两者都同步
+	DoubanSyncMinutes    int      `json:"douban_sync_minutes"`    // 同步间隔（分钟），默认360
+	DoubanSyncResolution int      `json:"douban_sync_resolution"` // 新建订阅的分辨率，默认1（1080P）
+	DoubanSyncTags       []string `json:"douban_sync_tags"`       // 新建订阅的标签
+	DoubanSyncMovies     bool     `json:"douban_sync_movies"`     // 是否同时为电影创建订阅
+	DoubanSyncArchive    bool     `json:"douban_sync_archive"`    // 是否归档已从列表中移除的同步订阅
+}
+
+// TLSEnabled 判断是否使用HTTPS
+func (c Config) TLSEnabled() bool {
+	return c.TLSCert != "" || c.TLSSelfSigned
 }
\ No newline at end of file
@@ -0,0 +1,118 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// validConfig 返回填充了默认值且能通过校验的配置
+func validConfig() Config {
+	cfg := Config{
+		Endpoint:  "http://127.0.0.1:9091/transmission/rpc",
+		Cookie:    "uid=1",
+		SiteTeams: map[string]int{"GroupA": 9},
+	}
+	cfg.ApplyDefaults()
+	return cfg
+}
+
+// fieldErrors 返回校验错误中的配置项名称
+func fieldErrors(t *testing.T, err error) []string {
+	var validationErr *ValidationError
+	require.True(t, errors.As(err, &validationErr), "需要 *ValidationError，实际为 %v", err)
+	fields := make([]string, len(validationErr.Errors))
+	for i, fieldErr := range validationErr.Errors {
+		fields[i] = fieldErr.Field
+	}
+	return fields
+}
+
+// TestApplyDefaults 测试只为未设置的配置项填充默认值
+func TestApplyDefaults(t *testing.T) {
+	cfg := Config{IntervalMinutes: 30, TeamMode: "any"}
+	cfg.ApplyDefaults()
+
+	assert.Equal(t, 30, cfg.IntervalMinutes)
+	assert.Equal(t, 8443, cfg.Port)
+	assert.Equal(t, "20:00", cfg.AirTime)
+	assert.Equal(t, "any", cfg.TeamMode)
+	assert.Empty(t, cfg.TeamList)
+	assert.Equal(t, []string{"wish", "do"}, cfg.DoubanSyncLists)
+}
+
+// TestApplyPatch 测试只修改出现的配置项，null 清空配置项或恢复默认值
+func TestApplyPatch(t *testing.T) {
+	base := validConfig()
+	base.Cron = "0 * * * *"
+	base.Port = 9000
+
+	patch := Patch{}
+	require.NoError(t, patch.Set("interval_minutes", 15))
+	require.NoError(t, patch.Set("site_teams", map[string]int{"GroupB": 10}))
+	patch.Clear("cron")
+	patch.Clear("port")
+
+	cfg, err := base.Apply(patch)
+	require.NoError(t, err)
+
+	assert.Equal(t, 15, cfg.IntervalMinutes)
+	assert.Equal(t, "", cfg.Cron)
+	assert.Equal(t, 8443, cfg.Port, "清空有默认值的配置项时恢复默认值")
+	assert.Equal(t, map[string]int{"GroupB": 10}, cfg.SiteTeams, "映射整体替换而不是合并")
+	assert.Equal(t, base.Cookie, cfg.Cookie, "未出现的配置项保持不变")
+
+	// 原配置不受影响
+	assert.Equal(t, "0 * * * *", base.Cron)
+	assert.Equal(t, map[string]int{"GroupA": 9}, base.SiteTeams)
+}
+
+// TestApplyPatchErrors 测试未知配置项和类型错误
+func TestApplyPatchErrors(t *testing.T) {
+	base := validConfig()
+
+	var patch Patch
+	require.NoError(t, json.Unmarshal([]byte(`{"port":"abc","unknown":1,"cookie":"new"}`), &patch))
+
+	_, err := base.Apply(patch)
+	require.Error(t, err)
+	assert.Equal(t, []string{"port", "unknown"}, fieldErrors(t, err))
+	assert.Contains(t, err.Error(), "类型错误，需要整数")
+	assert.Contains(t, err.Error(), "未知的配置项")
+	assert.Equal(t, "uid=1", base.Cookie)
+}
+
+// TestValidate 测试每个无效的配置项都有对应的错误
+func TestValidate(t *testing.T) {
+	cfg := validConfig()
+	require.NoError(t, cfg.Validate())
+
+	tests := []struct {
+		name   string
+		modify func(cfg *Config)
+		fields []string
+	}{
+		{"地址", func(cfg *Config) { cfg.Endpoint = "127.0.0.1:9091"; cfg.WeChatServer = "ftp://x" }, []string{"endpoint", "wechat_server"}},
+		{"端口", func(cfg *Config) { cfg.Port = 70000 }, []string{"port"}},
+		{"监听地址", func(cfg *Config) { cfg.Listen = "0.0.0.0:8443" }, []string{"listen"}},
+		{"证书", func(cfg *Config) { cfg.TLSCert = "cert.pem" }, []string{"tls_key"}},
+		{"自签名证书", func(cfg *Config) { cfg.TLSCert = "cert.pem"; cfg.TLSKey = "key.pem"; cfg.TLSSelfSigned = true }, []string{"tls_self_signed"}},
+		{"重定向端口", func(cfg *Config) { cfg.HTTPRedirectPort = 8080 }, []string{"http_redirect_port"}},
+		{"间隔", func(cfg *Config) { cfg.IntervalMinutes = -1; cfg.AirPollMinutes = -5 }, []string{"interval_minutes", "air_poll_minutes"}},
+		{"Cookie", func(cfg *Config) { cfg.Cookie = " " }, []string{"cookie"}},
+		{"Cron", func(cfg *Config) { cfg.Cron = "bad" }, []string{"cron"}},
+		{"正则", func(cfg *Config) { cfg.FilterExcludeRegex = []string{"("} }, []string{"filter_exclude_regex"}},
+		{"制作组", func(cfg *Config) { cfg.TeamList = []string{"Unknown"} }, []string{"team_list"}},
+		{"豆瓣列表", func(cfg *Config) { cfg.DoubanSyncLists = []string{"collect"} }, []string{"douban_sync_lists"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			tt.modify(&cfg)
+			assert.Equal(t, tt.fields, fieldErrors(t, cfg.Validate()))
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// EncryptedPrefix 加密保存的敏感配置项的前缀，之后是 base64 编码的随机数和密文
+const EncryptedPrefix = "enc:v1:"
+
+// keySize 密钥长度，使用 AES-256-GCM
+const keySize = 32
+
+// IsEncrypted 判断配置项的值是否为加密保存的密文
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, EncryptedPrefix)
+}
+
+// Cipher 使用 AES-GCM 加密敏感配置项
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// NewCipher 使用32字节的密钥创建加密器
+func NewCipher(key []byte) (*Cipher, error) {
+	if len(key) != keySize {
+		return nil, fmt.Errorf("密钥长度需要是 %d 字节，当前为 %d 字节", keySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("创建加密器失败: %v", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("创建加密器失败: %v", err)
+	}
+	return &Cipher{aead: aead}, nil
+}
+
+// LoadKeyFile 读取密钥文件并创建加密器。密钥文件内容为 base64 编码的32字节密钥（如 openssl rand -base64 32 的输出），
+// 也可以是32字节的原始密钥。文件不存在时生成新的随机密钥，以 0600 权限保存
+func LoadKeyFile(path string) (*Cipher, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return generateKeyFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取密钥文件失败: %v", err)
+	}
+
+	key := data
+	if len(data) != keySize {
+		key, err = base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil {
+			return nil, fmt.Errorf("密钥文件 %s 需要是 base64 编码的 %d 字节密钥: %v", path, keySize, err)
+		}
+	}
+	return NewCipher(key)
+}
+
+// generateKeyFile 生成随机密钥并保存到密钥文件
+func generateKeyFile(path string) (*Cipher, error) {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("生成密钥失败: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("创建密钥目录失败: %v", err)
+	}
+	// O_EXCL 避免覆盖同时创建的密钥文件
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("创建密钥文件失败: %v", err)
+	}
+	if _, err := f.WriteString(base64.StdEncoding.EncodeToString(key) + "\n"); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("写入密钥文件失败: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		return nil, fmt.Errorf("写入密钥文件失败: %v", err)
+	}
+	log.Printf("已生成新的密钥文件 %s，请妥善备份，丢失后无法解密已加密的配置项", path)
+	return NewCipher(key)
+}
+
+// Encrypt 加密配置项的值，空值和已加密的值保持不变
+func (c *Cipher) Encrypt(value string) (string, error) {
+	if value == "" || IsEncrypted(value) {
+		return value, nil
+	}
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("生成随机数失败: %v", err)
+	}
+	sealed := c.aead.Seal(nonce, nonce, []byte(value), nil)
+	return EncryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt 解密配置项的值，没有加密的值原样返回
+func (c *Cipher) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, EncryptedPrefix))
+	if err != nil || len(data) < c.aead.NonceSize() {
+		return "", fmt.Errorf("无效的密文")
+	}
+	nonce, sealed := data[:c.aead.NonceSize()], data[c.aead.NonceSize():]
+	plain, err := c.aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return "", fmt.Errorf("解密失败，密钥文件与加密时使用的不一致")
+	}
+	return string(plain), nil
+}
+
+// EncryptSecrets 返回敏感配置项加密后的配置副本
+func (c *Cipher) EncryptSecrets(cfg Config) (Config, error) {
+	value := reflect.ValueOf(&cfg).Elem()
+	for _, key := range secretKeys {
+		field := value.Field(fieldIndex[key])
+		encrypted, err := c.Encrypt(field.String())
+		if err != nil {
+			return Config{}, fmt.Errorf("加密配置项 %s 失败: %v", key, err)
+		}
+		field.SetString(encrypted)
+	}
+	return cfg, nil
+}
+
+// DecryptSecrets 返回敏感配置项解密后的配置副本，plaintext 为没有加密保存的敏感配置项数量
+func (c *Cipher) DecryptSecrets(cfg Config) (decrypted Config, plaintext int, err error) {
+	value := reflect.ValueOf(&cfg).Elem()
+	for _, key := range secretKeys {
+		field := value.Field(fieldIndex[key])
+		if field.String() != "" && !IsEncrypted(field.String()) {
+			plaintext++
+			continue
+		}
+		plain, err := c.Decrypt(field.String())
+		if err != nil {
+			return Config{}, 0, fmt.Errorf("配置项 %s %v", key, err)
+		}
+		field.SetString(plain)
+	}
+	return cfg, plaintext, nil
+}
+
+// EncryptedStore 加密保存敏感配置项的存储，读取时解密，保存时加密，其他配置项保持明文
+type EncryptedStore struct {
+	Store
+	cipher *Cipher
+}
+
+// NewEncryptedStore 创建加密保存敏感配置项的存储
+func NewEncryptedStore(store Store, cipher *Cipher) *EncryptedStore {
+	return &EncryptedStore{Store: store, cipher: cipher}
+}
+
+// Load 读取并解密配置，存储中还有明文保存的敏感配置项时立即加密写回
+func (s *EncryptedStore) Load() (*Config, error) {
+	stored, err := s.Store.Load()
+	if err != nil {
+		return nil, err
+	}
+	cfg, plaintext, err := s.cipher.DecryptSecrets(*stored)
+	if err != nil {
+		return nil, fmt.Errorf("解密 %s 失败: %v", s.Store, err)
+	}
+	if plaintext > 0 {
+		if err := s.Save(&cfg); err != nil {
+			return nil, fmt.Errorf("加密保存敏感配置项失败: %v", err)
+		}
+		log.Printf("已加密保存 %s 中的 %d 个敏感配置项", s.Store, plaintext)
+	}
+	return &cfg, nil
+}
+
+// Save 加密敏感配置项后保存
+func (s *EncryptedStore) Save(cfg *Config) error {
+	encrypted, err := s.cipher.EncryptSecrets(*cfg)
+	if err != nil {
+		return err
+	}
+	return s.Store.Save(&encrypted)
+}
@@ -0,0 +1,84 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCipher 测试加密解密以及密钥不一致时解密失败
+func TestCipher(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "secret.key")
+	cipher, err := LoadKeyFile(path)
+	require.NoError(t, err)
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm(), "生成的密钥文件只有所有者可读")
+
+	encrypted, err := cipher.Encrypt("uid=1")
+	require.NoError(t, err)
+	assert.True(t, IsEncrypted(encrypted))
+	again, err := cipher.Encrypt(encrypted)
+	require.NoError(t, err)
+	assert.Equal(t, encrypted, again, "已加密的值不重复加密")
+
+	// 再次读取同一个密钥文件可以解密
+	loaded, err := LoadKeyFile(path)
+	require.NoError(t, err)
+	plain, err := loaded.Decrypt(encrypted)
+	require.NoError(t, err)
+	assert.Equal(t, "uid=1", plain)
+
+	other, err := LoadKeyFile(filepath.Join(dir, "other.key"))
+	require.NoError(t, err)
+	_, err = other.Decrypt(encrypted)
+	assert.Error(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "short.key"), []byte("c2hvcnQ=\n"), 0600))
+	_, err = LoadKeyFile(filepath.Join(dir, "short.key"))
+	assert.Error(t, err)
+}
+
+// TestEncryptedStore 测试加密存储在读取时加密明文保存的敏感配置项，保存时只加密敏感配置项
+func TestEncryptedStore(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	cfg := validConfig()
+	cfg.WeChatToken = "token"
+	require.NoError(t, NewJSONStore(path).Save(&cfg))
+
+	cipher, err := LoadKeyFile(filepath.Join(dir, "secret.key"))
+	require.NoError(t, err)
+	store := NewEncryptedStore(NewJSONStore(path), cipher)
+	loaded, err := store.Load()
+	require.NoError(t, err)
+	assert.Equal(t, "uid=1", loaded.Cookie)
+	assert.Equal(t, "token", loaded.WeChatToken)
+
+	// 明文已被加密写回，其他配置项保持明文
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "uid=1")
+	var saved map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &saved))
+	assert.True(t, strings.HasPrefix(saved["cookie"].(string), EncryptedPrefix))
+	assert.Equal(t, "", saved["douban_cookie"])
+	assert.Equal(t, cfg.Endpoint, saved["endpoint"])
+
+	loaded.Cookie = "uid=2"
+	require.NoError(t, store.Save(loaded))
+	reloaded, err := store.Load()
+	require.NoError(t, err)
+	assert.Equal(t, "uid=2", reloaded.Cookie)
+
+	// 没有密钥时加密的配置项无法通过校验
+	plain, err := NewJSONStore(path).Load()
+	require.NoError(t, err)
+	assert.Contains(t, fieldErrors(t, plain.Validate()), "cookie")
+}
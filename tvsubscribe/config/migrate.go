@@ -0,0 +1,96 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"tvsubscribe/storage"
+)
+
+// Version 当前配置文件的格式版本，保存配置时写入 version 字段
+const Version = 3
+
+// newMigrations 返回配置文件的格式升级链，legacySubscribes 用于保存版本1配置文件中的订阅列表
+//
+//	版本1：最初的格式，订阅列表保存在配置文件的 subscribes 中，没有 port
+//	版本2：订阅列表移到 subscribes.json，增加 port
+//	版本3：增加 version 字段
+func newMigrations(legacySubscribes func(raw json.RawMessage) error) storage.Migrations {
+	return storage.Migrations{
+		Detect: detectVersion,
+		Steps: []storage.Migration{
+			{Version: 2, Description: "订阅列表移出配置文件，增加监听端口", Migrate: func(data []byte) ([]byte, error) {
+				return migrateV2(data, legacySubscribes)
+			}},
+			{Version: 3, Description: "增加格式版本号", Migrate: migrateV3},
+		},
+	}
+}
+
+// detectVersion 返回配置文件的格式版本，没有 version 字段时按内容推断
+func detectVersion(data []byte) (int, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return 0, err
+	}
+	if raw, ok := fields["version"]; ok {
+		var version int
+		if err := json.Unmarshal(raw, &version); err != nil || version <= 0 {
+			return 0, fmt.Errorf("无效的版本号: %s", raw)
+		}
+		return version, nil
+	}
+	_, hasSubscribes := fields["subscribes"]
+	_, hasPort := fields["port"]
+	if hasSubscribes || !hasPort {
+		return 1, nil
+	}
+	return 2, nil
+}
+
+// migrateV2 把配置文件中的订阅列表交给 legacySubscribes 保存后删除，没有端口时使用之前固定的8443端口。
+// 有订阅时保存失败或没有 legacySubscribes 都会使升级失败，配置文件保持原样，避免丢失订阅
+func migrateV2(data []byte, legacySubscribes func(raw json.RawMessage) error) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	if raw, ok := fields["subscribes"]; ok {
+		var subscribes []json.RawMessage
+		if err := json.Unmarshal(raw, &subscribes); err != nil {
+			return nil, fmt.Errorf("解析订阅列表失败: %v", err)
+		}
+		if len(subscribes) > 0 {
+			if legacySubscribes == nil {
+				return nil, fmt.Errorf("配置文件中有 %d 个订阅，没有可以保存订阅的位置", len(subscribes))
+			}
+			if err := legacySubscribes(raw); err != nil {
+				return nil, err
+			}
+		}
+		delete(fields, "subscribes")
+	}
+	if _, ok := fields["port"]; !ok {
+		fields["port"] = json.RawMessage("8443")
+	}
+	return json.MarshalIndent(fields, "", "  ")
+}
+
+// migrateV3 写入格式版本号
+func migrateV3(data []byte) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	fields["version"] = json.RawMessage("3")
+	return json.MarshalIndent(fields, "", "  ")
+}
+
+// versioned 保存到文件的配置，version 字段在最前。
+// 格式版本是文件的元数据而不是配置项，所以不放在 Config 中：Config 的每个字段都是配置项，
+// 会出现在接口、Web界面和 --effective 中，并且可以用环境变量和命令行参数覆盖；
+// 数据库存储也有自己的结构版本。读取时由 detectVersion 识别，保存时由这里写入
+type versioned struct {
+	Version int `json:"version"`
+	*Config
+}
@@ -0,0 +1,140 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"tvsubscribe/subscribe"
+)
+
+// readGolden 读取 testdata/migrations 中指定版本的配置文件
+func readGolden(t *testing.T, version int) []byte {
+	data, err := os.ReadFile(filepath.Join("testdata", "migrations", fmt.Sprintf("v%d.json", version)))
+	require.NoError(t, err)
+	return data
+}
+
+// TestMigrationSteps 测试每一步升级的结果与下一个版本的样例文件一致
+func TestMigrationSteps(t *testing.T) {
+	migrations := newMigrations(func(json.RawMessage) error { return nil })
+	assert.Equal(t, Version, migrations.Latest())
+	for _, step := range migrations.Steps {
+		t.Run(fmt.Sprintf("v%d", step.Version), func(t *testing.T) {
+			input := readGolden(t, step.Version-1)
+			version, err := detectVersion(input)
+			require.NoError(t, err)
+			assert.Equal(t, step.Version-1, version)
+
+			output, err := step.Migrate(input)
+			require.NoError(t, err)
+			assert.JSONEq(t, string(readGolden(t, step.Version)), string(output))
+
+			version, err = detectVersion(output)
+			require.NoError(t, err)
+			assert.Equal(t, step.Version, version)
+		})
+	}
+}
+
+// TestJSONStoreMigrates 测试读取旧版本的配置文件时逐步升级、每步升级前备份并写回文件
+func TestJSONStoreMigrates(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	require.NoError(t, os.WriteFile(path, readGolden(t, 1), 0644))
+
+	subscribesPath := filepath.Join(dir, "subscribes.json")
+	store := NewJSONStore(path)
+	store.LegacySubscribes = subscribe.LegacyImporter(subscribe.NewJSONStore(subscribesPath))
+	cfg, err := store.Load()
+	require.NoError(t, err)
+	assert.Equal(t, "c_secure_uid=1", cfg.Cookie)
+	assert.Equal(t, 30, cfg.IntervalMinutes)
+	assert.Equal(t, 8443, cfg.Port)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(readGolden(t, 3)), string(data))
+
+	// 配置文件中的订阅保存到了订阅文件，ID和时间是导入时生成的
+	data, err = os.ReadFile(subscribesPath)
+	require.NoError(t, err)
+	var subscribes map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &subscribes))
+	for _, item := range subscribes["subscribes"].([]interface{}) {
+		entry := item.(map[string]interface{})
+		assert.NotEmpty(t, entry["id"])
+		for _, key := range []string{"id", "created_at", "updated_at"} {
+			delete(entry, key)
+		}
+	}
+	expected, err := os.ReadFile(filepath.Join("testdata", "migrations", "v1-subscribes.json"))
+	require.NoError(t, err)
+	actual, err := json.Marshal(subscribes)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(expected), string(actual))
+
+	for _, version := range []int{1, 2} {
+		backups, err := filepath.Glob(filepath.Join(dir, "backups", fmt.Sprintf("config.json.v%d-*.bak", version)))
+		require.NoError(t, err)
+		require.Len(t, backups, 1, "版本 %d 的备份", version)
+		backup, err := os.ReadFile(backups[0])
+		require.NoError(t, err)
+		assert.JSONEq(t, string(readGolden(t, version)), string(backup))
+	}
+
+	// 升级后可以正常保存，保存的文件带有版本号
+	cfg.IntervalMinutes = 45
+	require.NoError(t, store.Save(cfg))
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	var saved map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &saved))
+	assert.Equal(t, float64(Version), saved["version"])
+	assert.Equal(t, float64(45), saved["interval_minutes"])
+}
+
+// TestJSONStoreNewerVersion 测试配置文件版本高于程序支持的版本时不做修改
+func TestJSONStoreNewerVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	content := `{"version": 99, "cookie": "c=1"}`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+
+	_, err := NewJSONStore(path).Load()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "高于程序支持的版本")
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, content, string(data))
+}
+
+// TestJSONStoreLegacySubscribesFailure 测试配置文件中的订阅无法保存时不升级，配置文件保持原样
+func TestJSONStoreLegacySubscribesFailure(t *testing.T) {
+	tests := []struct {
+		name   string
+		legacy func(raw json.RawMessage) error
+	}{
+		{"没有保存位置", nil},
+		{"保存失败", func(json.RawMessage) error { return fmt.Errorf("磁盘已满") }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.json")
+			require.NoError(t, os.WriteFile(path, readGolden(t, 1), 0644))
+
+			store := NewJSONStore(path)
+			store.LegacySubscribes = tt.legacy
+			_, err := store.Load()
+			require.Error(t, err)
+
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, string(readGolden(t, 1)), string(data))
+		})
+	}
+}
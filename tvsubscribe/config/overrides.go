@@ -0,0 +1,234 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// EnvPrefix 覆盖配置项的环境变量前缀，如 TVSUBSCRIBE_PORT 覆盖 port
+const EnvPrefix = "TVSUBSCRIBE_"
+
+// Source 配置项的来源，优先级从低到高为默认值、配置文件、环境变量、命令行参数
+type Source string
+
+const (
+	SourceDefault Source = "default" // 默认值
+	SourceFile    Source = "file"    // 配置文件或数据库
+	SourceEnv     Source = "env"     // 环境变量
+	SourceFlag    Source = "flag"    // 命令行参数
+)
+
+// Setting 生效的配置项及其来源
+type Setting struct {
+	Key    string      `json:"key"`
+	Value  interface{} `json:"value"`
+	Source Source      `json:"source"`
+	Env    string      `json:"env,omitempty"`  // 覆盖该项的环境变量
+	Flag   string      `json:"flag,omitempty"` // 覆盖该项的命令行参数
+}
+
+// Effective 生效的配置项和服务器路径选项
+type Effective struct {
+	Settings []Setting `json:"settings"`
+	Paths    []Setting `json:"paths"`
+}
+
+// Value 返回配置项的值，未知的配置项返回 nil
+func Value(c Config, key string) interface{} {
+	i, ok := fieldIndex[key]
+	if !ok {
+		return nil
+	}
+	return reflect.ValueOf(c).Field(i).Interface()
+}
+
+// EnvName 返回覆盖配置项的环境变量名称
+func EnvName(key string) string {
+	return EnvPrefix + strings.ToUpper(key)
+}
+
+// FlagName 返回覆盖配置项的命令行参数名称（不含 --），下划线替换为连字符
+func FlagName(key string) string {
+	return strings.ReplaceAll(key, "_", "-")
+}
+
+// ParseValue 把环境变量或命令行参数的字符串解析为配置项的值。
+// 列表用逗号分隔，也可以写成JSON数组（正则中包含逗号时）；
+// 映射写成 名称:ID,名称:ID 或JSON对象；空字符串表示清空该配置项或恢复默认值
+func ParseValue(key, value string) (json.RawMessage, error) {
+	i, ok := fieldIndex[key]
+	if !ok {
+		return nil, fmt.Errorf("未知的配置项: %s", key)
+	}
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return json.RawMessage("null"), nil
+	}
+
+	var parsed interface{}
+	switch t := reflect.TypeOf(Config{}).Field(i).Type; t.Kind() {
+	case reflect.String:
+		parsed = value
+	case reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("无效的%s: %s", typeName(t), value)
+		}
+		parsed = n
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("无效的%s: %s，可选 true 或 false", typeName(t), value)
+		}
+		parsed = b
+	case reflect.Slice:
+		if strings.HasPrefix(value, "[") {
+			var list []string
+			if err := json.Unmarshal([]byte(value), &list); err != nil {
+				return nil, fmt.Errorf("无效的%s: %v", typeName(t), err)
+			}
+			parsed = list
+			break
+		}
+		list := []string{}
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		parsed = list
+	case reflect.Map:
+		if strings.HasPrefix(value, "{") {
+			var m map[string]int
+			if err := json.Unmarshal([]byte(value), &m); err != nil {
+				return nil, fmt.Errorf("无效的%s: %v", typeName(t), err)
+			}
+			parsed = m
+			break
+		}
+		m := make(map[string]int)
+		for _, item := range strings.Split(value, ",") {
+			name, id, ok := strings.Cut(item, ":")
+			n, err := strconv.Atoi(strings.TrimSpace(id))
+			if !ok || err != nil {
+				return nil, fmt.Errorf("无效的映射项: %s，格式为 名称:ID", item)
+			}
+			m[strings.TrimSpace(name)] = n
+		}
+		parsed = m
+	default:
+		return nil, fmt.Errorf("不支持的配置项类型: %s", t)
+	}
+	return json.Marshal(parsed)
+}
+
+// EnvOverrides 读取 TVSUBSCRIBE_ 开头的环境变量，lookup 通常为 os.LookupEnv。
+// 无法解析的环境变量以 *ValidationError 返回
+func EnvOverrides(lookup func(key string) (string, bool)) (Patch, error) {
+	patch := Patch{}
+	var v validator
+	for _, key := range fieldKeys {
+		name := EnvName(key)
+		value, ok := lookup(name)
+		if !ok {
+			continue
+		}
+		raw, err := ParseValue(key, value)
+		if err != nil {
+			v.check(name, err)
+			continue
+		}
+		patch[key] = raw
+	}
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+	return patch, nil
+}
+
+// Overrides 覆盖配置文件的环境变量和命令行参数，命令行参数优先。
+// 被覆盖的配置项不写回配置文件
+type Overrides struct {
+	Env   Patch
+	Flags Patch
+	// 从文件读取的敏感配置项，记录覆盖它的环境变量、凭据文件或命令行参数，如 TVSUBSCRIBE_COOKIE_FILE
+	EnvNames  map[string]string
+	FlagNames map[string]string
+}
+
+// Apply 在配置上依次应用环境变量和命令行参数，返回生效的配置
+func (o Overrides) Apply(c Config) (Config, error) {
+	c, err := c.Apply(o.Env)
+	if err != nil {
+		return Config{}, err
+	}
+	return c.Apply(o.Flags)
+}
+
+// Source 返回配置项的来源，没有被覆盖且与默认值相同的配置项视为默认值
+func (o Overrides) Source(key string, file Config) Source {
+	if _, ok := o.Flags[key]; ok {
+		return SourceFlag
+	}
+	if _, ok := o.Env[key]; ok {
+		return SourceEnv
+	}
+	var defaults Config
+	defaults.ApplyDefaults()
+	i := fieldIndex[key]
+	if sameValue(reflect.ValueOf(file).Field(i), reflect.ValueOf(defaults).Field(i)) {
+		return SourceDefault
+	}
+	return SourceFile
+}
+
+// Overridden 返回覆盖配置项的环境变量或命令行参数，没有被覆盖时返回空字符串
+func (o Overrides) Overridden(key string) string {
+	if _, ok := o.Flags[key]; ok {
+		if name, ok := o.FlagNames[key]; ok {
+			return name
+		}
+		return "--" + FlagName(key)
+	}
+	if _, ok := o.Env[key]; ok {
+		if name, ok := o.EnvNames[key]; ok {
+			return name
+		}
+		return EnvName(key)
+	}
+	return ""
+}
+
+// Settings 返回全部生效的配置项及其来源，file 为配置文件中的配置，effective 为生效的配置。
+// 敏感配置项的值以掩码返回
+func (o Overrides) Settings(file, effective Config) []Setting {
+	value := reflect.ValueOf(effective)
+	settings := make([]Setting, len(fieldKeys))
+	for i, key := range fieldKeys {
+		settings[i] = Setting{
+			Key:    key,
+			Value:  MaskValue(key, value.Field(fieldIndex[key]).Interface()),
+			Source: o.Source(key, file),
+			Env:    EnvName(key),
+			Flag:   "--" + FlagName(key),
+		}
+		if name, ok := o.EnvNames[key]; ok {
+			settings[i].Env = name
+		}
+		if name, ok := o.FlagNames[key]; ok {
+			settings[i].Flag = name
+		}
+	}
+	return settings
+}
+
+// sameValue 比较两个配置项的值，空列表和空映射视为相同
+func sameValue(a, b reflect.Value) bool {
+	if k := a.Kind(); (k == reflect.Slice || k == reflect.Map) && a.Len() == 0 && b.Len() == 0 {
+		return true
+	}
+	return reflect.DeepEqual(a.Interface(), b.Interface())
+}
@@ -0,0 +1,94 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseValue 测试按配置项类型解析环境变量和命令行参数
+func TestParseValue(t *testing.T) {
+	tests := []struct {
+		key   string
+		value string
+		want  string
+	}{
+		{"cookie", "a=1; b=2", `"a=1; b=2"`},
+		{"port", " 9000 ", `9000`},
+		{"air_schedule", "true", `true`},
+		{"team_list", "GroupA, GroupB,", `["GroupA","GroupB"]`},
+		{"filter_exclude_regex", `["a{1,2}"]`, `["a{1,2}"]`},
+		{"site_teams", "GroupA:9,GroupB:12", `{"GroupA":9,"GroupB":12}`},
+		{"site_teams", `{"GroupA": 9}`, `{"GroupA":9}`},
+		{"cron", "", `null`},
+	}
+	for _, tt := range tests {
+		raw, err := ParseValue(tt.key, tt.value)
+		require.NoError(t, err, tt.key)
+		assert.JSONEq(t, tt.want, string(raw), tt.key)
+	}
+
+	for key, value := range map[string]string{"port": "abc", "air_schedule": "yes", "site_teams": "GroupA", "unknown": "1"} {
+		_, err := ParseValue(key, value)
+		assert.Error(t, err, key)
+	}
+}
+
+// TestOverrides 测试分层的优先级：默认值 < 配置文件 < 环境变量 < 命令行参数
+func TestOverrides(t *testing.T) {
+	env := map[string]string{
+		"TVSUBSCRIBE_PORT":             "9000",
+		"TVSUBSCRIBE_INTERVAL_MINUTES": "20",
+		"TVSUBSCRIBE_OTHER":            "ignored",
+	}
+	envPatch, err := EnvOverrides(func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	})
+	require.NoError(t, err)
+	assert.Len(t, envPatch, 2)
+
+	flags := Patch{}
+	require.NoError(t, flags.Set("interval_minutes", 10))
+	overrides := Overrides{Env: envPatch, Flags: flags}
+
+	file := validConfig()
+	file.Cron = "0 * * * *"
+	file.Port = 8000
+	cfg, err := overrides.Apply(file)
+	require.NoError(t, err)
+	assert.Equal(t, 9000, cfg.Port)
+	assert.Equal(t, 10, cfg.IntervalMinutes)
+	assert.Equal(t, "0 * * * *", cfg.Cron)
+	assert.Equal(t, 8000, file.Port, "配置文件中的配置不受影响")
+
+	assert.Equal(t, SourceFlag, overrides.Source("interval_minutes", file))
+	assert.Equal(t, SourceEnv, overrides.Source("port", file))
+	assert.Equal(t, SourceFile, overrides.Source("cron", file))
+	assert.Equal(t, SourceDefault, overrides.Source("air_time", file))
+	assert.Equal(t, SourceDefault, overrides.Source("filter_include", file))
+	assert.Equal(t, "--interval-minutes", overrides.Overridden("interval_minutes"))
+	assert.Equal(t, "TVSUBSCRIBE_PORT", overrides.Overridden("port"))
+	assert.Equal(t, "", overrides.Overridden("cron"))
+
+	settings := overrides.Settings(file, cfg)
+	require.Len(t, settings, len(Keys()))
+	for _, setting := range settings {
+		if setting.Key == "port" {
+			assert.Equal(t, 9000, setting.Value)
+			assert.Equal(t, SourceEnv, setting.Source)
+			assert.Equal(t, "TVSUBSCRIBE_PORT", setting.Env)
+			assert.Equal(t, "--port", setting.Flag)
+		}
+	}
+
+	// 无法解析的环境变量
+	_, err = EnvOverrides(func(key string) (string, bool) {
+		if key == "TVSUBSCRIBE_WORKERS" {
+			return "many", true
+		}
+		return "", false
+	})
+	assert.Equal(t, []string{"TVSUBSCRIBE_WORKERS"}, fieldErrors(t, err))
+}
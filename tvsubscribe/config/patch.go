@@ -0,0 +1,118 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Patch 配置修改，键为配置项的JSON名称，值为JSON编码的新值。
+// 只修改出现的配置项；值为 null 时清空该配置项，有默认值的配置项恢复默认值
+type Patch map[string]json.RawMessage
+
+// Set 设置配置项的新值，value 为 nil 时清空该配置项
+func (p Patch) Set(key string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("序列化配置项 %s 失败: %v", key, err)
+	}
+	p[key] = data
+	return nil
+}
+
+// Clear 清空配置项，有默认值的配置项恢复默认值
+func (p Patch) Clear(key string) {
+	p[key] = json.RawMessage("null")
+}
+
+// fieldKeys 按结构体字段顺序排列的配置项JSON名称
+var fieldKeys []string
+
+// fieldIndex 配置项的JSON名称到结构体字段序号的映射
+var fieldIndex = func() map[string]int {
+	index := make(map[string]int)
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			index[name] = i
+			fieldKeys = append(fieldKeys, name)
+		}
+	}
+	return index
+}()
+
+// Keys 返回全部配置项的名称，按配置结构体的字段顺序排列
+func Keys() []string {
+	return append([]string(nil), fieldKeys...)
+}
+
+// ValidKey 判断是否为有效的配置项名称
+func ValidKey(key string) bool {
+	_, ok := fieldIndex[key]
+	return ok
+}
+
+// Apply 在配置的副本上应用修改并填充默认值。
+// 值为掩码的敏感配置项保持不变。
+// 未知的配置项和类型错误以 *ValidationError 返回，其他校验由 Validate 完成
+func (c Config) Apply(patch Patch) (Config, error) {
+	keys := make([]string, 0, len(patch))
+	for key := range patch {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var v validator
+	value := reflect.ValueOf(&c).Elem()
+	for _, key := range keys {
+		i, ok := fieldIndex[key]
+		if !ok {
+			v.add(key, "未知的配置项")
+			continue
+		}
+		if IsMasked(key, patch[key]) {
+			// 读取到的敏感配置项是掩码，原样提交时保持原值
+			continue
+		}
+		field := value.Field(i)
+		raw := bytes.TrimSpace(patch[key])
+		if len(raw) == 0 || string(raw) == "null" {
+			field.Set(reflect.Zero(field.Type()))
+			continue
+		}
+		// 解码到新值再替换，避免映射与原值合并
+		decoded := reflect.New(field.Type())
+		if err := json.Unmarshal(raw, decoded.Interface()); err != nil {
+			v.add(key, "类型错误，需要"+typeName(field.Type()))
+			continue
+		}
+		field.Set(decoded.Elem())
+	}
+	if err := v.err(); err != nil {
+		return Config{}, err
+	}
+
+	c.ApplyDefaults()
+	return c, nil
+}
+
+// typeName 返回配置项类型的中文名称，用于错误信息
+func typeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "字符串"
+	case reflect.Int:
+		return "整数"
+	case reflect.Bool:
+		return "布尔值"
+	case reflect.Slice:
+		return "字符串列表"
+	case reflect.Map:
+		return "名称到整数的映射"
+	}
+	return t.String()
+}
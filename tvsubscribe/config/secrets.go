@@ -0,0 +1,126 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// Mask 敏感配置项在接口和命令行输出中的掩码。
+// 修改配置时值为掩码的敏感配置项保持不变，因此可以直接提交读取到的配置
+const Mask = "******"
+
+// CredentialsDirEnv systemd LoadCredential= 设置的凭据目录环境变量，
+// 目录中与敏感配置项同名的文件作为该配置项的值
+const CredentialsDirEnv = "CREDENTIALS_DIRECTORY"
+
+// secretKeys 敏感配置项，按配置结构体的字段顺序排列
+var secretKeys = []string{"cookie", "wechat_token", "tmdb_api_key", "douban_cookie"}
+
+// SecretKeys 返回全部敏感配置项的名称
+func SecretKeys() []string {
+	return append([]string(nil), secretKeys...)
+}
+
+// IsSecret 判断配置项是否为敏感信息
+func IsSecret(key string) bool {
+	for _, secret := range secretKeys {
+		if key == secret {
+			return true
+		}
+	}
+	return false
+}
+
+// IsMasked 判断修改中的值是否为敏感配置项的掩码
+func IsMasked(key string, raw json.RawMessage) bool {
+	if !IsSecret(key) {
+		return false
+	}
+	var value string
+	return json.Unmarshal(bytes.TrimSpace(raw), &value) == nil && value == Mask
+}
+
+// MaskValue 隐藏敏感配置项的值，空值保持为空以便区分是否已设置
+func MaskValue(key string, value interface{}) interface{} {
+	if s, ok := value.(string); ok && s != "" && IsSecret(key) {
+		return Mask
+	}
+	return value
+}
+
+// Masked 返回隐藏了敏感配置项的配置副本，用于接口和命令行输出
+func (c Config) Masked() Config {
+	value := reflect.ValueOf(&c).Elem()
+	for _, key := range secretKeys {
+		if field := value.Field(fieldIndex[key]); field.String() != "" {
+			field.SetString(Mask)
+		}
+	}
+	return c
+}
+
+// SecretFileEnvName 返回从文件读取敏感配置项的环境变量名称，如 TVSUBSCRIBE_COOKIE_FILE
+func SecretFileEnvName(key string) string {
+	return EnvName(key) + "_FILE"
+}
+
+// SecretFileFlagName 返回从文件读取敏感配置项的命令行参数名称（不含 --），如 cookie-file
+func SecretFileFlagName(key string) string {
+	return FlagName(key) + "-file"
+}
+
+// ReadSecretFile 读取保存敏感配置项的文件，去掉首尾的空白和换行
+func ReadSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("读取密钥文件失败: %v", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// SecretFileOverrides 读取 TVSUBSCRIBE_<配置项>_FILE 指定的文件，以及 systemd 凭据目录中与敏感配置项同名的文件，
+// 适用于 Docker secrets 和 systemd credentials。已用环境变量直接设置的配置项不读取凭据目录。
+// 返回配置修改和每个配置项的来源名称，无法读取的文件以 *ValidationError 返回
+func SecretFileOverrides(lookup func(key string) (string, bool)) (Patch, map[string]string, error) {
+	patch := Patch{}
+	names := make(map[string]string)
+	credentials, _ := lookup(CredentialsDirEnv)
+	var v validator
+	for _, key := range secretKeys {
+		name := SecretFileEnvName(key)
+		path, ok := lookup(name)
+		if !ok || path == "" {
+			if _, set := lookup(EnvName(key)); set || credentials == "" {
+				continue
+			}
+			path = filepath.Join(credentials, key)
+			if _, err := os.Stat(path); err != nil {
+				continue
+			}
+			name = path
+		} else if _, set := lookup(EnvName(key)); set {
+			v.add(name, "不能与 %s 同时设置", EnvName(key))
+			continue
+		}
+
+		value, err := ReadSecretFile(path)
+		if err != nil {
+			v.check(name, err)
+			continue
+		}
+		if err := patch.Set(key, value); err != nil {
+			v.check(name, err)
+			continue
+		}
+		names[key] = name
+	}
+	if err := v.err(); err != nil {
+		return nil, nil, err
+	}
+	return patch, names, nil
+}
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMasked 测试敏感配置项以掩码输出，提交掩码时保持原值
+func TestMasked(t *testing.T) {
+	cfg := validConfig()
+	cfg.WeChatToken = "token"
+	masked := cfg.Masked()
+	assert.Equal(t, Mask, masked.Cookie)
+	assert.Equal(t, Mask, masked.WeChatToken)
+	assert.Empty(t, masked.DoubanCookie, "未设置的敏感配置项保持为空")
+	assert.Equal(t, cfg.Endpoint, masked.Endpoint)
+	assert.Equal(t, "uid=1", cfg.Cookie, "不修改原配置")
+
+	patch := Patch{}
+	require.NoError(t, patch.Set("cookie", Mask))
+	require.NoError(t, patch.Set("wechat_token", "new"))
+	require.NoError(t, patch.Set("endpoint", Mask))
+	assert.True(t, IsMasked("cookie", patch["cookie"]))
+	assert.False(t, IsMasked("endpoint", patch["endpoint"]), "只有敏感配置项使用掩码")
+
+	updated, err := cfg.Apply(patch)
+	require.NoError(t, err)
+	assert.Equal(t, "uid=1", updated.Cookie)
+	assert.Equal(t, "new", updated.WeChatToken)
+	assert.Equal(t, Mask, updated.Endpoint)
+
+	settings := Overrides{}.Settings(cfg, cfg)
+	for _, setting := range settings {
+		if setting.Key == "cookie" {
+			assert.Equal(t, Mask, setting.Value)
+		}
+	}
+}
+
+// TestSecretFileOverrides 测试从 _FILE 环境变量和 systemd 凭据目录读取敏感配置项
+func TestSecretFileOverrides(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "cookie.txt"), []byte("uid=2\n"), 0600))
+	credentials := filepath.Join(dir, "credentials")
+	require.NoError(t, os.Mkdir(credentials, 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(credentials, "wechat_token"), []byte("token"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(credentials, "tmdb_api_key"), []byte("ignored"), 0600))
+
+	env := map[string]string{
+		"TVSUBSCRIBE_COOKIE_FILE":  filepath.Join(dir, "cookie.txt"),
+		"TVSUBSCRIBE_TMDB_API_KEY": "key",
+		CredentialsDirEnv:          credentials,
+	}
+	lookup := func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+	patch, names, err := SecretFileOverrides(lookup)
+	require.NoError(t, err)
+	assert.JSONEq(t, `"uid=2"`, string(patch["cookie"]))
+	assert.JSONEq(t, `"token"`, string(patch["wechat_token"]))
+	assert.NotContains(t, patch, "tmdb_api_key", "已用环境变量设置的配置项不读取凭据目录")
+	assert.Equal(t, "TVSUBSCRIBE_COOKIE_FILE", names["cookie"])
+	assert.Equal(t, filepath.Join(credentials, "wechat_token"), names["wechat_token"])
+
+	overrides := Overrides{Env: patch, EnvNames: names}
+	assert.Equal(t, "TVSUBSCRIBE_COOKIE_FILE", overrides.Overridden("cookie"))
+
+	// 同时设置环境变量和 _FILE、文件不存在时报错
+	env["TVSUBSCRIBE_COOKIE"] = "uid=3"
+	env["TVSUBSCRIBE_DOUBAN_COOKIE_FILE"] = filepath.Join(dir, "missing")
+	_, _, err = SecretFileOverrides(lookup)
+	assert.ElementsMatch(t, []string{"TVSUBSCRIBE_COOKIE_FILE", "TVSUBSCRIBE_DOUBAN_COOKIE_FILE"}, fieldErrors(t, err))
+}
@@ -0,0 +1,97 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"tvsubscribe/storage"
+)
+
+// Store 配置的持久化存储
+type Store interface {
+	// Load 读取配置，不填充默认值；配置不存在时返回 os.ErrNotExist
+	Load() (*Config, error)
+	// Save 保存配置，配置在上次读取之后被外部修改时返回 storage.ErrConflict，不覆盖外部的修改
+	Save(cfg *Config) error
+	// String 返回存储位置，用于日志
+	String() string
+}
+
+// JSONStore 使用JSON配置文件的存储，记录上次读取或写入的文件版本，保存前检查文件是否被外部修改
+type JSONStore struct {
+	// LegacySubscribes 升级版本1的配置文件时保存其中的订阅列表（JSON数组），
+	// 返回错误时升级失败，配置文件保持原样。为 nil 时有订阅的版本1配置文件无法升级
+	LegacySubscribes func(raw json.RawMessage) error
+
+	path    string
+	version string
+	loaded  bool
+	mu      sync.Mutex
+}
+
+// NewJSONStore 创建使用JSON配置文件的存储
+func NewJSONStore(path string) *JSONStore {
+	return &JSONStore{path: path}
+}
+
+// Load 读取配置文件，旧版本的配置文件升级到当前格式并写回。
+// 首次读取时文件损坏会从最新的有效备份恢复，之后重新加载时直接返回错误
+func (s *JSONStore) Load() (*Config, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file := storage.NewFile(s.path)
+	file.NoRecover = s.loaded
+	data, _, err := file.Read(func(data []byte) error {
+		if _, err := detectVersion(data); err != nil {
+			return err
+		}
+		var cfg Config
+		return json.Unmarshal(data, &cfg)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("读取配置文件失败: %w", err)
+	}
+	if data, _, err = file.Migrate(data, newMigrations(s.LegacySubscribes)); err != nil {
+		return nil, err
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("解析配置文件失败: %v", err)
+	}
+	s.version = storage.Version(data)
+	s.loaded = true
+	return &cfg, nil
+}
+
+// Save 原子写入配置文件并保存滚动备份，文件在上次读取之后被外部修改时返回 storage.ErrConflict
+func (s *JSONStore) Save(cfg *Config) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, err := storage.FileVersion(s.path)
+	if err != nil {
+		return fmt.Errorf("读取配置文件失败: %v", err)
+	}
+	if current != s.version {
+		return fmt.Errorf("%w: %s", storage.ErrConflict, s.path)
+	}
+
+	data, err := json.MarshalIndent(versioned{Version: Version, Config: cfg}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化配置失败: %v", err)
+	}
+
+	if err := storage.NewFile(s.path).Write(data); err != nil {
+		return fmt.Errorf("写入配置文件失败: %v", err)
+	}
+	s.version = storage.Version(data)
+	return nil
+}
+
+// String 返回配置文件路径
+func (s *JSONStore) String() string {
+	return s.path
+}
@@ -0,0 +1,270 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+
+	"tvsubscribe"
+	"tvsubscribe/history"
+	"tvsubscribe/metadata"
+	"tvsubscribe/notify"
+	"tvsubscribe/scheduler"
+	"tvsubscribe/wishlist"
+)
+
+// FieldError 单个配置项的错误
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError 配置校验失败，包含每个无效配置项的原因
+type ValidationError struct {
+	Errors []FieldError `json:"errors"`
+}
+
+// Error 返回全部配置项的错误原因
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, fieldErr := range e.Errors {
+		messages[i] = fieldErr.Field + ": " + fieldErr.Message
+	}
+	return "配置校验失败: " + strings.Join(messages, "；")
+}
+
+// validator 收集配置项的校验错误
+type validator struct {
+	errors []FieldError
+}
+
+// add 记录配置项的错误
+func (v *validator) add(field, format string, args ...interface{}) {
+	v.errors = append(v.errors, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// check err 不为空时记录配置项的错误
+func (v *validator) check(field string, err error) {
+	if err != nil {
+		v.add(field, "%v", err)
+	}
+}
+
+// positive 检查数值大于0
+func (v *validator) positive(field string, value int) {
+	if value <= 0 {
+		v.add(field, "必须大于0，当前为 %d", value)
+	}
+}
+
+// nonNegative 检查数值不小于0
+func (v *validator) nonNegative(field string, value int) {
+	if value < 0 {
+		v.add(field, "不能小于0，当前为 %d", value)
+	}
+}
+
+// url 检查非空的值是 http 或 https 地址
+func (v *validator) url(field, value string) {
+	if value == "" {
+		return
+	}
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		v.add(field, "无效的地址: %s，需要以 http:// 或 https:// 开头", value)
+	}
+}
+
+// err 没有错误时返回 nil
+func (v *validator) err() error {
+	if len(v.errors) == 0 {
+		return nil
+	}
+	return &ValidationError{Errors: v.errors}
+}
+
+// ApplyDefaults 为未设置（零值）的配置项填充默认值
+func (c *Config) ApplyDefaults() {
+	if c.IntervalMinutes == 0 {
+		c.IntervalMinutes = 60 // 默认60分钟
+	}
+	if c.Port == 0 {
+		c.Port = 8443 // 默认8443端口
+	}
+	if c.Listen == "" {
+		c.Listen = DefaultListen
+	}
+	if c.NotifyDedupMinutes == 0 {
+		c.NotifyDedupMinutes = 360 // 默认6小时内相同通知只发送一次
+	}
+	if c.Workers == 0 {
+		c.Workers = 3 // 默认同时处理3个订阅
+	}
+	if c.SiteConcurrency == 0 {
+		c.SiteConcurrency = 1 // 默认同一站点同时只处理1个订阅
+	}
+	if c.AirTime == "" {
+		c.AirTime = "20:00" // 默认晚上8点播出
+	}
+	if c.AirWindowHours == 0 {
+		c.AirWindowHours = 6
+	}
+	if c.AirPollMinutes == 0 {
+		c.AirPollMinutes = 10
+	}
+	if c.OffAirPollMinutes == 0 {
+		c.OffAirPollMinutes = 720
+	}
+	if c.RunHistoryDays == 0 {
+		c.RunHistoryDays = history.DefaultRetentionDays
+	}
+	if c.RunHistoryLimit == 0 {
+		c.RunHistoryLimit = history.DefaultRetentionCount
+	}
+	if c.MovieMinQuality == "" {
+		c.MovieMinQuality = tvsubscribe.DefaultMovieMinQuality
+	}
+	if c.TeamMode == "" {
+		// 默认只搜索之前固定使用的 team9
+		c.TeamMode = tvsubscribe.TeamModeList
+		c.TeamList = []string{"9"}
+		c.TeamFallbackHours = tvsubscribe.DefaultTeamFallbackHours
+	}
+	if len(c.DoubanSyncLists) == 0 {
+		// 未配置豆瓣同步时的默认值：同步想看和在看，新建1080P订阅
+		c.DoubanSyncLists = []string{tvsubscribe.DoubanListWish, tvsubscribe.DoubanListDo}
+		c.DoubanSyncResolution = tvsubscribe.RES_1080P
+	}
+	if c.DoubanSyncMinutes == 0 {
+		c.DoubanSyncMinutes = wishlist.DefaultIntervalMinutes
+	}
+	if c.DoubanBaseURL == "" {
+		c.DoubanBaseURL = metadata.DefaultDoubanBaseURL
+	}
+	if c.TMDBBaseURL == "" {
+		c.TMDBBaseURL = metadata.DefaultTMDBBaseURL
+	}
+}
+
+// Validate 校验配置，失败时返回 *ValidationError，包含所有无效的配置项
+func (c *Config) Validate() error {
+	var v validator
+
+	v.url("endpoint", c.Endpoint)
+	if strings.TrimSpace(c.Cookie) == "" {
+		v.add("cookie", "不能为空")
+	}
+	for _, key := range secretKeys {
+		if IsEncrypted(Value(*c, key).(string)) {
+			v.add(key, "已加密保存，需要使用 --secret-key-file 指定解密的密钥文件")
+		}
+	}
+	if c.Port < 1 || c.Port > 65535 {
+		v.add("port", "必须在 1-65535 之间，当前为 %d", c.Port)
+	}
+	if net.ParseIP(c.Listen) == nil && (c.Listen == "" || strings.ContainsAny(c.Listen, ":/ ")) {
+		v.add("listen", "需要是IP地址或主机名，如 127.0.0.1 或 0.0.0.0，端口使用 port 设置")
+	}
+
+	// HTTPS
+	if c.TLSCert != "" && c.TLSKey == "" {
+		v.add("tls_key", "设置 tls_cert 时不能为空")
+	}
+	if c.TLSKey != "" && c.TLSCert == "" {
+		v.add("tls_cert", "设置 tls_key 时不能为空")
+	}
+	if c.TLSSelfSigned && c.TLSCert != "" {
+		v.add("tls_self_signed", "不能与 tls_cert 同时设置")
+	}
+	if c.HTTPRedirectPort != 0 {
+		switch {
+		case c.HTTPRedirectPort < 0 || c.HTTPRedirectPort > 65535:
+			v.add("http_redirect_port", "必须在 1-65535 之间，当前为 %d", c.HTTPRedirectPort)
+		case c.HTTPRedirectPort == c.Port:
+			v.add("http_redirect_port", "不能与 port 相同")
+		case !c.TLSEnabled():
+			v.add("http_redirect_port", "需要设置 tls_cert 或 tls_self_signed")
+		}
+	}
+
+	// 调度
+	v.positive("interval_minutes", c.IntervalMinutes)
+	if c.Cron != "" {
+		v.check("cron", scheduler.ValidateCron(c.Cron))
+	}
+	if c.ActiveHours != "" {
+		_, _, err := scheduler.ParseActiveHours(c.ActiveHours)
+		v.check("active_hours", err)
+	}
+	v.nonNegative("jitter_seconds", c.JitterSeconds)
+	v.positive("workers", c.Workers)
+	v.positive("site_concurrency", c.SiteConcurrency)
+
+	// 通知
+	v.url("wechat_server", c.WeChatServer)
+	if mode := c.WeChatNotifyMode; mode != "" && mode != string(notify.ModeInstant) && mode != string(notify.ModeDigest) {
+		v.add("wechat_notify_mode", "无效的通知模式: %s，可选值为 instant 或 digest", mode)
+	}
+	if c.WeChatDailyDigestAt != "" {
+		_, _, err := notify.ParseDailyAt(c.WeChatDailyDigestAt)
+		v.check("wechat_daily_digest_at", err)
+	}
+	v.nonNegative("wechat_rate_limit", c.WeChatRateLimit)
+	v.positive("notify_dedup_minutes", c.NotifyDedupMinutes)
+	if c.QuietHours != "" {
+		_, _, err := notify.ParseQuietHours(c.QuietHours)
+		v.check("quiet_hours", err)
+	}
+
+	// 播出时间表
+	_, _, err := metadata.ParseAirTime(c.AirTime)
+	v.check("air_time", err)
+	v.positive("air_window_hours", c.AirWindowHours)
+	v.positive("air_poll_minutes", c.AirPollMinutes)
+	v.positive("off_air_poll_minutes", c.OffAirPollMinutes)
+	v.url("douban_base_url", c.DoubanBaseURL)
+	v.url("tmdb_base_url", c.TMDBBaseURL)
+
+	// 处理记录和电影
+	v.positive("run_history_days", c.RunHistoryDays)
+	v.positive("run_history_limit", c.RunHistoryLimit)
+	_, err = tvsubscribe.ParseQualityName(c.MovieMinQuality)
+	v.check("movie_min_quality", err)
+
+	// 过滤规则，两组正则分别校验以便定位到具体的配置项
+	v.check("filter_include_regex", tvsubscribe.FilterRules{IncludeRegex: c.FilterIncludeRegex}.Validate())
+	v.check("filter_exclude_regex", tvsubscribe.FilterRules{ExcludeRegex: c.FilterExcludeRegex}.Validate())
+
+	// 制作组
+	policy := tvsubscribe.TeamPolicy{Mode: c.TeamMode, Teams: c.TeamList, FallbackHours: c.TeamFallbackHours}
+	if err := policy.Validate(); err != nil {
+		v.check("team_mode", err)
+	} else if _, err := tvsubscribe.ResolveTeams(policy.Teams, c.SiteTeams); err != nil {
+		v.check("team_list", err)
+	}
+	names := make([]string, 0, len(c.SiteTeams))
+	for name := range c.SiteTeams {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if id := c.SiteTeams[name]; strings.TrimSpace(name) == "" || id <= 0 {
+			v.add("site_teams", "无效的制作组映射: %q: %d", name, id)
+		}
+	}
+
+	// 豆瓣同步
+	for _, list := range c.DoubanSyncLists {
+		if !tvsubscribe.ValidDoubanList(list) {
+			v.add("douban_sync_lists", "无效的豆瓣列表: %s，可选值为 wish 或 do", list)
+		}
+	}
+	v.positive("douban_sync_minutes", c.DoubanSyncMinutes)
+	if !tvsubscribe.ValidResolution(c.DoubanSyncResolution) {
+		v.add("douban_sync_resolution", "无效的分辨率: %d", c.DoubanSyncResolution)
+	}
+
+	return v.err()
+}
@@ -1,80 +1,31 @@
 package tvsubscribe
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/hekmon/transmissionrpc/v3"
+	"tvsubscribe/notify"
 )
 
 // 种子下载链接示例：
 // https://springsunday.net/download.php?id=577692&passkey=xxxxxx&https=1
 
-// WeChatMessageRequest 微信消息发送请求
-type WeChatMessageRequest struct {
-	Token   string `json:"token"`
-	Title   string `json:"title"`
-	Content string `json:"content"`
-	Detail  string `json:"detail,omitempty"`
-}
-
-// WeChatMessageResponse 微信消息发送响应
-type WeChatMessageResponse struct {
-	Success bool   `json:"success"`
-	Message string `json:"message"`
-	Error   string `json:"error,omitempty"`
-}
-
-// sendWeChatMessage 发送微信消息
-func sendWeChatMessage(serverURL, token, title, content, detail string) error {
-	if serverURL == "" || token == "" {
-		return fmt.Errorf("微信服务器配置不完整，跳过消息发送")
-	}
-
-	request := WeChatMessageRequest{
-		Token:   token,
-		Title:   title,
-		Content: content,
-		Detail:  detail,
-	}
-
-	jsonData, err := json.Marshal(request)
-	if err != nil {
-		return fmt.Errorf("序列化微信消息失败: %v", err)
-	}
-
-	resp, err := http.Post(serverURL+"/send-message", "application/json", bytes.NewBuffer(jsonData))
+// downloadFile 下载文件到指定路径，先写入临时文件，完整下载后再重命名，避免留下不完整的种子文件
+func downloadFile(ctx context.Context, url string, path string) error {
+	// 创建HTTP请求
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return fmt.Errorf("发送微信消息失败: %v", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("微信消息发送失败，状态码: %d", resp.StatusCode)
-	}
-
-	var response WeChatMessageResponse
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return fmt.Errorf("解析微信消息响应失败: %v", err)
-	}
-
-	if !response.Success {
-		return fmt.Errorf("微信消息发送失败: %s", response.Error)
+		return err
 	}
-
-	return nil
-}
-
-func downloadFile(url string, path string) error {
-	// 创建HTTP请求
-	resp, err := http.Get(url)
+	client := &http.Client{Timeout: 60 * time.Second}
+	resp, err := client.Do(req)
 	if err != nil {
 		return err
 	}
@@ -91,20 +42,28 @@ func downloadFile(url string, path string) error {
 		return err
 	}
 
-	// 创建文件
-	out, err := os.Create(path)
+	// 创建临时文件
+	tmpPath := path + ".part"
+	out, err := os.Create(tmpPath)
 	if err != nil {
 		return err
 	}
-	defer out.Close()
 
 	// 复制数据
-	_, err = io.Copy(out, resp.Body)
-	return err
+	if _, err := io.Copy(out, resp.Body); err != nil {
+		out.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return os.Rename(tmpPath, path)
 }
 
 // addTorrentToTransmission 通过 transmissionrpc 库添加种子到 Transmission
-func addTorrentToTransmission(torrentPath, endpoint string) (*transmissionrpc.Torrent, error) {
+func addTorrentToTransmission(ctx context.Context, torrentPath, endpoint string) (*transmissionrpc.Torrent, error) {
 	// Transmission RPC 配置
 	endpointURL, err := url.Parse(endpoint)
 	if err != nil {
@@ -116,7 +75,7 @@ func addTorrentToTransmission(torrentPath, endpoint string) (*transmissionrpc.To
 	if err != nil {
 		return nil, fmt.Errorf("创建 Transmission 客户端失败: %v", err)
 	}
-	torrent, err := client.TorrentAddFile(context.TODO(), torrentPath)
+	torrent, err := client.TorrentAddFile(ctx, torrentPath)
 	if err != nil {
 		return nil, fmt.Errorf("添加种子文件失败: %v", err)
 	}
@@ -124,92 +83,129 @@ func addTorrentToTransmission(torrentPath, endpoint string) (*transmissionrpc.To
 }
 
 
-// downloadATorrentFromInfo 从 TorrentInfo 下载单个种子文件并添加到 Transmission
-func downloadATorrentFromInfo(torrentInfo *TorrentInfo, path, endpoint, wechatServer, wechatToken string) error {
+// torrentDetail 生成种子通知的详情文本
+func torrentDetail(torrentInfo *TorrentInfo) string {
+	var detailMsg string
+	if torrentInfo.Info != "" {
+		detailMsg = fmt.Sprintf("种子ID: %s\n种子信息: %s", torrentInfo.ID, torrentInfo.Info)
+	} else {
+		detailMsg = fmt.Sprintf("种子ID: %s", torrentInfo.ID)
+	}
+	if torrentInfo.Volume != "" {
+		detailMsg += fmt.Sprintf("\n种子大小: %s", torrentInfo.Volume)
+	}
+	return detailMsg
+}
+
+// DownloadResult 单个种子的下载结果
+type DownloadResult struct {
+	Torrent TorrentInfo
+	Name    string // 添加到 Transmission 后的种子名称
+	Skipped bool   // 种子文件已存在，此前已经下载过
+	Err     error
+}
+
+// downloadATorrentFromInfo 从 TorrentInfo 下载单个种子文件并添加到 Transmission，返回种子名称
+func downloadATorrentFromInfo(ctx context.Context, torrentInfo *TorrentInfo, path, show, endpoint string, publisher notify.Publisher) (string, error) {
 	// 直接使用 TorrentInfo 中的下载链接
 	downloadURL := torrentInfo.DownloadLink
 	if downloadURL == "" {
-		return fmt.Errorf("种子下载链接为空，种子ID: %s", torrentInfo.ID)
+		return "", fmt.Errorf("种子下载链接为空，种子ID: %s", torrentInfo.ID)
 	}
 
 	// 下载种子文件
-	err := downloadFile(downloadURL, path)
+	err := downloadFile(ctx, downloadURL, path)
 	if err != nil {
 		// 删除可能已创建的不完整文件
 		os.Remove(path)
-		// 发送下载失败通知
-		var detailMsg string
-		if torrentInfo.Info != "" {
-			detailMsg = fmt.Sprintf("种子ID: %s\n种子信息: %s", torrentInfo.ID, torrentInfo.Info)
-		} else {
-			detailMsg = fmt.Sprintf("种子ID: %s", torrentInfo.ID)
-		}
-		if torrentInfo.Volume != "" {
-			detailMsg += fmt.Sprintf("\n种子大小: %s", torrentInfo.Volume)
+		// 程序退出导致的中止不是下载失败，不发送通知
+		if ctx.Err() != nil {
+			return "", ctx.Err()
 		}
-		detailMsg += fmt.Sprintf("\n错误信息: %v", err)
-		if sendErr := sendWeChatMessage(wechatServer, wechatToken,
-			"种子下载失败", "下载失败，请检查详情", detailMsg); sendErr != nil {
-			fmt.Printf("发送下载失败通知失败: %v\n", sendErr)
-		}
-		return fmt.Errorf("下载种子文件失败: %v", err)
+		// 发送下载失败通知
+		publisher.Publish(notify.Event{
+			Level:   notify.LevelError,
+			Show:    show,
+			Title:   "种子下载失败",
+			Content: "下载失败，请检查详情",
+			Detail:  torrentDetail(torrentInfo) + fmt.Sprintf("\n错误信息: %v", err),
+			Summary: fmt.Sprintf("下载失败 种子ID: %s，%v", torrentInfo.ID, err),
+		})
+		return "", fmt.Errorf("下载种子文件失败: %v", err)
 	}
 
 	// 添加到 Transmission
-	torrent, err := addTorrentToTransmission(path, endpoint)
+	torrent, err := addTorrentToTransmission(ctx, path, endpoint)
 	if err != nil {
 		// 删除种子文件
 		os.Remove(path)
-		// 发送添加失败通知
-		var detailMsg string
-		if torrentInfo.Info != "" {
-			detailMsg = fmt.Sprintf("种子ID: %s\n种子信息: %s", torrentInfo.ID, torrentInfo.Info)
-		} else {
-			detailMsg = fmt.Sprintf("种子ID: %s", torrentInfo.ID)
-		}
-		if torrentInfo.Volume != "" {
-			detailMsg += fmt.Sprintf("\n种子大小: %s", torrentInfo.Volume)
+		if ctx.Err() != nil {
+			return "", ctx.Err()
 		}
-		detailMsg += fmt.Sprintf("\n错误信息: %v", err)
-		if sendErr := sendWeChatMessage(wechatServer, wechatToken,
-			"添加种子失败", "添加失败，请检查详情", detailMsg); sendErr != nil {
-			fmt.Printf("发送添加失败通知失败: %v\n", sendErr)
-		}
-		return fmt.Errorf("添加种子到 Transmission 失败: %v", err)
+		// 发送添加失败通知
+		publisher.Publish(notify.Event{
+			Level:   notify.LevelError,
+			Show:    show,
+			Title:   "添加种子失败",
+			Content: "添加失败，请检查详情",
+			Detail:  torrentDetail(torrentInfo) + fmt.Sprintf("\n错误信息: %v", err),
+			Summary: fmt.Sprintf("添加失败 种子ID: %s，%v", torrentInfo.ID, err),
+		})
+		return "", fmt.Errorf("添加种子到 Transmission 失败: %v", err)
 	}
 
 	// 发送成功通知，包含更丰富的信息
-	var detailMsg string
-	if torrentInfo.Info != "" {
-		detailMsg = fmt.Sprintf("种子ID: %s\n种子信息: %s", torrentInfo.ID, torrentInfo.Info)
-	} else {
-		detailMsg = fmt.Sprintf("种子ID: %s", torrentInfo.ID)
-	}
+	summary := fmt.Sprintf("%s (种子ID: %s", *torrent.Name, torrentInfo.ID)
 	if torrentInfo.Volume != "" {
-		detailMsg += fmt.Sprintf("\n种子大小: %s", torrentInfo.Volume)
-	}
-	detailMsg += fmt.Sprintf("\n种子名称: %s\n已成功添加到 Transmission", *torrent.Name)
-	if err := sendWeChatMessage(wechatServer, wechatToken,
-		"种子下载成功", "下载成功并已添加", detailMsg); err != nil {
-		fmt.Printf("发送成功通知失败: %v\n", err)
-	}
+		summary += ", " + torrentInfo.Volume
+	}
+	summary += ")"
+	publisher.Publish(notify.Event{
+		Level:   notify.LevelInfo,
+		Show:    show,
+		Title:   "种子下载成功",
+		Content: "下载成功并已添加",
+		Detail:  torrentDetail(torrentInfo) + fmt.Sprintf("\n种子名称: %s\n已成功添加到 Transmission", *torrent.Name),
+		Summary: summary,
+	})
+
+	return *torrent.Name, nil
+}
 
-	return nil
+// TorrentDownloaded 判断种子此前是否已经下载过
+func TorrentDownloaded(torrentInfo TorrentInfo) bool {
+	_, err := os.Stat(torrentPath(torrentInfo))
+	return err == nil
 }
 
-// DownloadTorrent 批量下载种子并添加到 Transmission
-func DownloadTorrent(torrentInfos []TorrentInfo, endpoint, wechatServer, wechatToken string) error {
+// TorrentDir 种子文件的保存目录，已保存的种子文件用于判断是否下载过
+var TorrentDir = "torrents"
+
+// torrentPath 返回种子文件的保存路径
+func torrentPath(torrentInfo TorrentInfo) string {
+	return filepath.Join(TorrentDir, torrentInfo.ID+".torrent")
+}
+
+// DownloadTorrent 批量下载种子并添加到 Transmission，下载结果通过 publisher 上报，
+// 同时返回每个种子的处理结果。ctx 取消后不再处理剩余的种子。
+func DownloadTorrent(ctx context.Context, torrentInfos []TorrentInfo, show, endpoint string, publisher notify.Publisher) ([]DownloadResult, error) {
 	var lastError error
+	var results []DownloadResult
 
 	for i := range torrentInfos {
-		path := fmt.Sprintf("torrents/%s.torrent", torrentInfos[i].ID)
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+		path := torrentPath(torrentInfos[i])
 
 		// 检查文件是否已存在
 		if _, err := os.Stat(path); err == nil {
+			results = append(results, DownloadResult{Torrent: torrentInfos[i], Skipped: true})
 			continue // 文件已存在，跳过
 		}
 
-		err := downloadATorrentFromInfo(&torrentInfos[i], path, endpoint, wechatServer, wechatToken)
+		name, err := downloadATorrentFromInfo(ctx, &torrentInfos[i], path, show, endpoint, publisher)
+		results = append(results, DownloadResult{Torrent: torrentInfos[i], Name: name, Err: err})
 		if err != nil {
 			lastError = err
 			// 记录错误但继续处理其他种子
@@ -217,5 +213,5 @@ func DownloadTorrent(torrentInfos []TorrentInfo, endpoint, wechatServer, wechatT
 		}
 	}
 
-	return lastError
+	return results, lastError
 }
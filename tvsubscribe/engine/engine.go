@@ -0,0 +1,185 @@
+package engine
+
+import (
+	"log"
+	"sync"
+
+	"tvsubscribe"
+)
+
+// ProcessFunc 处理单个订阅的函数
+type ProcessFunc func(tvInfo tvsubscribe.TVInfo)
+
+// SiteFunc 返回订阅所属的站点，用于按站点限制并发
+type SiteFunc func(tvInfo tvsubscribe.TVInfo) string
+
+// Options 任务引擎选项
+type Options struct {
+	Workers int // 同时处理的订阅数量上限
+	PerSite int // 同一站点同时处理的订阅数量上限
+}
+
+// entry 一个订阅的待处理任务，合并了所有等待它完成的调用方
+type entry struct {
+	tvInfo  tvsubscribe.TVInfo
+	waiters []*sync.WaitGroup
+}
+
+// done 通知所有等待方任务已完成
+func (e *entry) done() {
+	for _, wg := range e.waiters {
+		wg.Done()
+	}
+}
+
+// Engine 订阅处理引擎：同一订阅串行处理并合并重复触发，不同订阅在有限的工作协程中并发处理
+type Engine struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	options Options
+	site    SiteFunc
+	process ProcessFunc
+
+	queue   []string          // 排队中的订阅ID，先进先出
+	queued  map[string]*entry // 排队中、尚未开始的任务
+	running map[string]bool   // 正在处理的订阅
+	rerun   map[string]*entry // 处理过程中再次被触发，完成后需要重新处理的任务
+	sites   map[string]chan struct{}
+	stopped bool
+	workers sync.WaitGroup
+}
+
+// New 创建任务引擎
+func New(options Options, site SiteFunc, process ProcessFunc) *Engine {
+	if options.Workers <= 0 {
+		options.Workers = 1
+	}
+	if options.PerSite <= 0 {
+		options.PerSite = 1
+	}
+	e := &Engine{
+		options: options,
+		site:    site,
+		process: process,
+		queued:  make(map[string]*entry),
+		running: make(map[string]bool),
+		rerun:   make(map[string]*entry),
+		sites:   make(map[string]chan struct{}),
+	}
+	e.cond = sync.NewCond(&e.mu)
+	return e
+}
+
+// Start 启动工作协程
+func (e *Engine) Start() {
+	for i := 0; i < e.options.Workers; i++ {
+		e.workers.Add(1)
+		go e.work()
+	}
+}
+
+// Stop 停止接收新任务，等待已排队和正在处理的任务完成
+func (e *Engine) Stop() {
+	e.mu.Lock()
+	e.stopped = true
+	e.cond.Broadcast()
+	e.mu.Unlock()
+	e.workers.Wait()
+}
+
+// Submit 提交订阅处理任务，返回的通道在这些订阅都处理完成后关闭。
+// 已在排队的订阅会被合并；正在处理的订阅会在当前处理结束后再处理一次。
+func (e *Engine) Submit(tvInfos ...tvsubscribe.TVInfo) <-chan struct{} {
+	wg := &sync.WaitGroup{}
+
+	e.mu.Lock()
+	for _, tv := range tvInfos {
+		if e.stopped {
+			log.Printf("任务引擎已停止，忽略订阅 ID=%s", tv.ID)
+			continue
+		}
+		wg.Add(1)
+		if queued, ok := e.queued[tv.ID]; ok {
+			// 尚未开始处理，合并到已有任务，使用最新的订阅信息
+			queued.tvInfo = tv
+			queued.waiters = append(queued.waiters, wg)
+			continue
+		}
+		if e.running[tv.ID] {
+			// 正在处理，合并为一次后续处理
+			if again, ok := e.rerun[tv.ID]; ok {
+				again.tvInfo = tv
+				again.waiters = append(again.waiters, wg)
+			} else {
+				e.rerun[tv.ID] = &entry{tvInfo: tv, waiters: []*sync.WaitGroup{wg}}
+			}
+			continue
+		}
+		e.queued[tv.ID] = &entry{tvInfo: tv, waiters: []*sync.WaitGroup{wg}}
+		e.queue = append(e.queue, tv.ID)
+	}
+	e.cond.Broadcast()
+	e.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	return done
+}
+
+// Pending 返回排队中和正在处理的订阅数量
+func (e *Engine) Pending() (queued, running int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return len(e.queued), len(e.running)
+}
+
+// siteSlot 返回站点的并发信号量，调用方需持有锁
+func (e *Engine) siteSlot(site string) chan struct{} {
+	slot, ok := e.sites[site]
+	if !ok {
+		slot = make(chan struct{}, e.options.PerSite)
+		e.sites[site] = slot
+	}
+	return slot
+}
+
+// work 工作协程：依次取出排队的任务并处理
+func (e *Engine) work() {
+	defer e.workers.Done()
+
+	for {
+		e.mu.Lock()
+		for len(e.queue) == 0 && !e.stopped {
+			e.cond.Wait()
+		}
+		if len(e.queue) == 0 && e.stopped {
+			e.mu.Unlock()
+			return
+		}
+		id := e.queue[0]
+		e.queue = e.queue[1:]
+		job := e.queued[id]
+		delete(e.queued, id)
+		e.running[id] = true
+		slot := e.siteSlot(e.site(job.tvInfo))
+		e.mu.Unlock()
+
+		slot <- struct{}{}
+		e.process(job.tvInfo)
+		<-slot
+
+		e.mu.Lock()
+		delete(e.running, id)
+		if again, ok := e.rerun[id]; ok {
+			delete(e.rerun, id)
+			e.queued[id] = again
+			e.queue = append(e.queue, id)
+			e.cond.Broadcast()
+		}
+		e.mu.Unlock()
+		job.done()
+	}
+}
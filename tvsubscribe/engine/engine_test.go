@@ -0,0 +1,116 @@
+package engine
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"tvsubscribe"
+)
+
+// waitDone 等待通道关闭，超时则测试失败
+func waitDone(t *testing.T, done <-chan struct{}) {
+	t.Helper()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("等待任务完成超时")
+	}
+}
+
+// TestEngine_CoalesceQueued 测试排队中的重复触发被合并
+func TestEngine_CoalesceQueued(t *testing.T) {
+	release := make(chan struct{})
+	var mu sync.Mutex
+	counts := make(map[string]int)
+
+	e := New(Options{Workers: 1, PerSite: 1}, func(tvsubscribe.TVInfo) string { return "site" }, func(tv tvsubscribe.TVInfo) {
+		<-release
+		mu.Lock()
+		counts[tv.ID]++
+		mu.Unlock()
+	})
+	e.Start()
+	defer e.Stop()
+
+	first := e.Submit(tvsubscribe.TVInfo{ID: "a"})
+	// a 正在处理时，b 被重复触发三次，只应处理一次
+	time.Sleep(10 * time.Millisecond)
+	second := e.Submit(tvsubscribe.TVInfo{ID: "b"})
+	third := e.Submit(tvsubscribe.TVInfo{ID: "b"}, tvsubscribe.TVInfo{ID: "b"})
+	close(release)
+
+	waitDone(t, first)
+	waitDone(t, second)
+	waitDone(t, third)
+	assert.Equal(t, 1, counts["a"])
+	assert.Equal(t, 1, counts["b"])
+}
+
+// TestEngine_RerunWhileRunning 测试处理中的订阅再次触发时，结束后只补处理一次且不并发
+func TestEngine_RerunWhileRunning(t *testing.T) {
+	release := make(chan struct{})
+	var running, maxRunning, total int32
+
+	e := New(Options{Workers: 4, PerSite: 4}, func(tvsubscribe.TVInfo) string { return "site" }, func(tv tvsubscribe.TVInfo) {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			m := atomic.LoadInt32(&maxRunning)
+			if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+				break
+			}
+		}
+		<-release
+		atomic.AddInt32(&running, -1)
+		atomic.AddInt32(&total, 1)
+	})
+	e.Start()
+	defer e.Stop()
+
+	first := e.Submit(tvsubscribe.TVInfo{ID: "a"})
+	time.Sleep(10 * time.Millisecond)
+	second := e.Submit(tvsubscribe.TVInfo{ID: "a"})
+	third := e.Submit(tvsubscribe.TVInfo{ID: "a"})
+	close(release)
+
+	waitDone(t, first)
+	waitDone(t, second)
+	waitDone(t, third)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&maxRunning), "同一订阅不能并发处理")
+	assert.Equal(t, int32(2), atomic.LoadInt32(&total))
+}
+
+// TestEngine_PerSiteLimit 测试同一站点的并发上限
+func TestEngine_PerSiteLimit(t *testing.T) {
+	var mu sync.Mutex
+	running := make(map[string]int)
+	maxRunning := make(map[string]int)
+
+	site := func(tv tvsubscribe.TVInfo) string { return tv.Name }
+	e := New(Options{Workers: 4, PerSite: 1}, site, func(tv tvsubscribe.TVInfo) {
+		mu.Lock()
+		running[tv.Name]++
+		if running[tv.Name] > maxRunning[tv.Name] {
+			maxRunning[tv.Name] = running[tv.Name]
+		}
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		running[tv.Name]--
+		mu.Unlock()
+	})
+	e.Start()
+	defer e.Stop()
+
+	done := e.Submit(
+		tvsubscribe.TVInfo{ID: "1", Name: "ssd"},
+		tvsubscribe.TVInfo{ID: "2", Name: "ssd"},
+		tvsubscribe.TVInfo{ID: "3", Name: "ssd"},
+		tvsubscribe.TVInfo{ID: "4", Name: "other"},
+	)
+	waitDone(t, done)
+	assert.Equal(t, 1, maxRunning["ssd"])
+	assert.Equal(t, 1, maxRunning["other"])
+}
@@ -0,0 +1,115 @@
+package tvsubscribe
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// episodeRangePattern 匹配种子信息中的集数，如 "第5集"、"第26-27集"、"第01集-第10集"
+var episodeRangePattern = regexp.MustCompile(`第\s*(\d+)\s*(?:集)?\s*(?:[-~～至到]\s*第?\s*(\d+))?\s*集`)
+
+// episodeFullPattern 匹配整季打包的种子，如 "全40集"
+var episodeFullPattern = regexp.MustCompile(`全\s*(\d+)\s*集`)
+
+// maxEpisodesPerTorrent 单个种子最多包含的集数，避免解析到异常数字时生成过大的账本
+const maxEpisodesPerTorrent = 500
+
+// ParseEpisodes 从种子信息中解析包含的集数，无法识别时返回 nil
+func ParseEpisodes(info string) []int {
+	var episodes []int
+	for _, match := range episodeFullPattern.FindAllStringSubmatch(info, -1) {
+		total, _ := strconv.Atoi(match[1])
+		episodes = append(episodes, episodeRange(1, total)...)
+	}
+	for _, match := range episodeRangePattern.FindAllStringSubmatch(info, -1) {
+		from, _ := strconv.Atoi(match[1])
+		to := from
+		if match[2] != "" {
+			to, _ = strconv.Atoi(match[2])
+		}
+		episodes = append(episodes, episodeRange(from, to)...)
+	}
+	merged, _ := MergeEpisodes(nil, episodes)
+	return merged
+}
+
+// episodeRange 返回 [from, to] 范围内的集数，范围无效时返回 nil
+func episodeRange(from, to int) []int {
+	if from <= 0 || to < from || to-from >= maxEpisodesPerTorrent {
+		return nil
+	}
+	episodes := make([]int, 0, to-from+1)
+	for episode := from; episode <= to; episode++ {
+		episodes = append(episodes, episode)
+	}
+	return episodes
+}
+
+// MergeEpisodes 将新的集数合并到分集账本中，返回排序去重后的账本和新增的集数数量
+func MergeEpisodes(ledger, episodes []int) ([]int, int) {
+	seen := make(map[int]bool, len(ledger)+len(episodes))
+	merged := make([]int, 0, len(ledger)+len(episodes))
+	for _, episode := range ledger {
+		if !seen[episode] {
+			seen[episode] = true
+			merged = append(merged, episode)
+		}
+	}
+	added := 0
+	for _, episode := range episodes {
+		if episode > 0 && !seen[episode] {
+			seen[episode] = true
+			merged = append(merged, episode)
+			added++
+		}
+	}
+	sort.Ints(merged)
+	if len(merged) == 0 {
+		return nil, 0
+	}
+	return merged, added
+}
+
+// EpisodesComplete 判断分集账本是否已包含第 1 集到第 total 集
+func EpisodesComplete(ledger []int, total int) bool {
+	if total <= 0 {
+		return false
+	}
+	have := make(map[int]bool, len(ledger))
+	for _, episode := range ledger {
+		have[episode] = true
+	}
+	for episode := 1; episode <= total; episode++ {
+		if !have[episode] {
+			return false
+		}
+	}
+	return true
+}
+
+// GetEpisodeCount 通过豆瓣搜索获取电视剧的总集数，name 为搜索使用的名称
+func GetEpisodeCount(ctx context.Context, douBanID, name string) (int, error) {
+	if strings.TrimSpace(name) == "" {
+		return 0, fmt.Errorf("电视剧名称不能为空")
+	}
+
+	results, err := SearchDouBan(ctx, name)
+	if err != nil {
+		return 0, err
+	}
+	for _, result := range results {
+		if result.ID != douBanID {
+			continue
+		}
+		count, err := strconv.Atoi(strings.TrimSpace(result.Episode))
+		if err != nil || count <= 0 {
+			return 0, fmt.Errorf("豆瓣没有提供总集数: %q", result.Episode)
+		}
+		return count, nil
+	}
+	return 0, fmt.Errorf("豆瓣搜索结果中没有找到豆瓣ID: %s", douBanID)
+}
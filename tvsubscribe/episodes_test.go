@@ -0,0 +1,69 @@
+package tvsubscribe
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseEpisodes(t *testing.T) {
+	tests := []struct {
+		name     string
+		info     string
+		expected []int
+	}{
+		{
+			name:     "多集",
+			info:     "天地剑心 / 狐妖小红娘·王权篇 | 第26-27集 | 成毅 / 李一桐 [国语] [简繁英字幕]",
+			expected: []int{26, 27},
+		},
+		{
+			name:     "单集",
+			info:     "庆余年 第二季 | 第5集 | 张若昀",
+			expected: []int{5},
+		},
+		{
+			name:     "带前导零和第字的范围",
+			info:     "琅琊榜 | 第01集-第03集",
+			expected: []int{1, 2, 3},
+		},
+		{
+			name:     "整季打包",
+			info:     "琅琊榜 | 全4集 | 胡歌",
+			expected: []int{1, 2, 3, 4},
+		},
+		{
+			name:     "无法识别",
+			info:     "琅琊榜 | 胡歌 / 刘涛",
+			expected: nil,
+		},
+		{
+			name:     "范围无效",
+			info:     "第27-26集",
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ParseEpisodes(tt.info))
+		})
+	}
+}
+
+func TestMergeEpisodes(t *testing.T) {
+	merged, added := MergeEpisodes([]int{1, 2, 5}, []int{5, 3, 4, 0})
+	assert.Equal(t, []int{1, 2, 3, 4, 5}, merged)
+	assert.Equal(t, 2, added)
+
+	merged, added = MergeEpisodes(nil, nil)
+	assert.Nil(t, merged)
+	assert.Equal(t, 0, added)
+}
+
+func TestEpisodesComplete(t *testing.T) {
+	assert.True(t, EpisodesComplete([]int{1, 2, 3}, 3))
+	assert.True(t, EpisodesComplete([]int{1, 2, 3, 4}, 3))
+	assert.False(t, EpisodesComplete([]int{1, 3}, 3))
+	assert.False(t, EpisodesComplete([]int{1, 2, 3}, 0))
+}
@@ -0,0 +1,162 @@
+package tvsubscribe
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// FilterRules 种子过滤规则，关键字和正则同时匹配种子标题和种子信息
+type FilterRules struct {
+	Include      []string `json:"include,omitempty"`       // 必须包含的关键字，全部包含才下载
+	Exclude      []string `json:"exclude,omitempty"`       // 包含任一关键字时不下载
+	IncludeRegex []string `json:"include_regex,omitempty"` // 必须匹配的正则，全部匹配才下载
+	ExcludeRegex []string `json:"exclude_regex,omitempty"` // 匹配任一正则时不下载
+	AllowGroups  []string `json:"allow_groups,omitempty"`  // 制作组白名单，设置后只下载这些制作组的种子
+	DenyGroups   []string `json:"deny_groups,omitempty"`   // 制作组黑名单
+}
+
+// Empty 判断是否没有设置任何规则
+func (r FilterRules) Empty() bool {
+	return len(r.Include) == 0 && len(r.Exclude) == 0 && len(r.IncludeRegex) == 0 &&
+		len(r.ExcludeRegex) == 0 && len(r.AllowGroups) == 0 && len(r.DenyGroups) == 0
+}
+
+// Patch 用 patch 中不为 nil 的列表替换对应的规则，空列表表示清空该项
+func (r FilterRules) Patch(patch FilterRules) FilterRules {
+	fields := []struct{ target, value *[]string }{
+		{&r.Include, &patch.Include},
+		{&r.Exclude, &patch.Exclude},
+		{&r.IncludeRegex, &patch.IncludeRegex},
+		{&r.ExcludeRegex, &patch.ExcludeRegex},
+		{&r.AllowGroups, &patch.AllowGroups},
+		{&r.DenyGroups, &patch.DenyGroups},
+	}
+	for _, field := range fields {
+		if *field.value != nil {
+			*field.target = *field.value
+		}
+	}
+	return r
+}
+
+// Validate 检查规则中的正则表达式是否有效
+func (r FilterRules) Validate() error {
+	_, err := CompileFilter(r)
+	return err
+}
+
+// MergeFilterRules 合并全局默认规则和订阅规则：关键字、正则和黑名单叠加，
+// 订阅设置了制作组白名单时替代全局白名单
+func MergeFilterRules(defaults FilterRules, rules *FilterRules) FilterRules {
+	if rules == nil {
+		return defaults
+	}
+	merged := FilterRules{
+		Include:      append(append([]string{}, defaults.Include...), rules.Include...),
+		Exclude:      append(append([]string{}, defaults.Exclude...), rules.Exclude...),
+		IncludeRegex: append(append([]string{}, defaults.IncludeRegex...), rules.IncludeRegex...),
+		ExcludeRegex: append(append([]string{}, defaults.ExcludeRegex...), rules.ExcludeRegex...),
+		AllowGroups:  defaults.AllowGroups,
+		DenyGroups:   append(append([]string{}, defaults.DenyGroups...), rules.DenyGroups...),
+	}
+	if len(rules.AllowGroups) > 0 {
+		merged.AllowGroups = rules.AllowGroups
+	}
+	return merged
+}
+
+// Filter 编译后的过滤规则
+type Filter struct {
+	rules        FilterRules
+	includeRegex []*regexp.Regexp
+	excludeRegex []*regexp.Regexp
+}
+
+// CompileFilter 编译过滤规则中的正则表达式
+func CompileFilter(rules FilterRules) (*Filter, error) {
+	filter := &Filter{rules: rules}
+	for _, pattern := range rules.IncludeRegex {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("无效的包含正则 %q: %v", pattern, err)
+		}
+		filter.includeRegex = append(filter.includeRegex, re)
+	}
+	for _, pattern := range rules.ExcludeRegex {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("无效的排除正则 %q: %v", pattern, err)
+		}
+		filter.excludeRegex = append(filter.excludeRegex, re)
+	}
+	return filter, nil
+}
+
+// Check 检查种子是否满足过滤规则，不满足时返回拒绝原因，满足时返回空字符串
+func (f *Filter) Check(torrentInfo TorrentInfo) string {
+	text := torrentInfo.Title + " " + torrentInfo.Info
+	lowerText := strings.ToLower(text)
+
+	for _, keyword := range f.rules.Exclude {
+		if keyword != "" && strings.Contains(lowerText, strings.ToLower(keyword)) {
+			return fmt.Sprintf("包含排除关键字: %s", keyword)
+		}
+	}
+	for _, keyword := range f.rules.Include {
+		if keyword != "" && !strings.Contains(lowerText, strings.ToLower(keyword)) {
+			return fmt.Sprintf("缺少必须关键字: %s", keyword)
+		}
+	}
+	for _, re := range f.excludeRegex {
+		if re.MatchString(text) {
+			return fmt.Sprintf("匹配排除正则: %s", re.String())
+		}
+	}
+	for _, re := range f.includeRegex {
+		if !re.MatchString(text) {
+			return fmt.Sprintf("不匹配必须正则: %s", re.String())
+		}
+	}
+
+	if len(f.rules.DenyGroups) == 0 && len(f.rules.AllowGroups) == 0 {
+		return ""
+	}
+	group := ReleaseGroup(torrentInfo.Title)
+	if group != "" && containsFold(f.rules.DenyGroups, group) {
+		return fmt.Sprintf("制作组在黑名单中: %s", group)
+	}
+	if len(f.rules.AllowGroups) > 0 && !containsFold(f.rules.AllowGroups, group) {
+		if group == "" {
+			return "无法识别制作组，不在白名单中"
+		}
+		return fmt.Sprintf("制作组不在白名单中: %s", group)
+	}
+	return ""
+}
+
+// releaseGroupPattern 匹配种子标题末尾的制作组，如 "-CHDWEB"、"@ADWeb"
+var releaseGroupPattern = regexp.MustCompile(`[-@]([A-Za-z0-9]+)$`)
+
+// ReleaseGroup 从种子标题中提取制作组，无法识别时返回空字符串
+func ReleaseGroup(title string) string {
+	match := releaseGroupPattern.FindStringSubmatch(strings.TrimSpace(title))
+	if match == nil {
+		return ""
+	}
+	// WEB-DL 等片源标记不是制作组
+	if strings.EqualFold(match[1], "DL") || strings.EqualFold(match[1], "Rip") {
+		return ""
+	}
+	return match[1]
+}
+
+// containsFold 判断列表中是否包含指定字符串，不区分大小写
+func containsFold(list []string, value string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
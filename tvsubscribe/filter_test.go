@@ -0,0 +1,72 @@
+package tvsubscribe
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReleaseGroup(t *testing.T) {
+	assert.Equal(t, "CHDWEB", ReleaseGroup("Sword.and.Beloved.S01E26-E27.2025.2160p.WEB-DL.H265.AAC-CHDWEB"))
+	assert.Equal(t, "ADWeb", ReleaseGroup("Movie.2023.1080p.WEB-DL.H264@ADWeb"))
+	assert.Equal(t, "", ReleaseGroup("Sword.and.Beloved.S01E26-E27.2025.2160p"))
+	assert.Equal(t, "", ReleaseGroup("Movie.2023.1080p.WEB-DL"))
+}
+
+func TestFilterCheck(t *testing.T) {
+	torrent := TorrentInfo{
+		Title: "Sword.and.Beloved.S01E26-E27.2025.2160p.WEB-DL.H265.HDR.AAC-CHDWEB",
+		Info:  "天地剑心 | 第26-27集 | 成毅 / 李一桐 [国语] [简繁英字幕]",
+	}
+
+	tests := []struct {
+		name     string
+		rules    FilterRules
+		expected string
+	}{
+		{"没有规则", FilterRules{}, ""},
+		{"排除关键字匹配种子信息", FilterRules{Exclude: []string{"国语"}}, "包含排除关键字: 国语"},
+		{"排除关键字不区分大小写", FilterRules{Exclude: []string{"hdr"}}, "包含排除关键字: hdr"},
+		{"缺少必须关键字", FilterRules{Include: []string{"中字"}}, "缺少必须关键字: 中字"},
+		{"包含必须关键字", FilterRules{Include: []string{"字幕", "2160p"}}, ""},
+		{"排除正则", FilterRules{ExcludeRegex: []string{`(?i)\.hdr\.`}}, `匹配排除正则: (?i)\.hdr\.`},
+		{"必须正则", FilterRules{IncludeRegex: []string{`S01E\d+`}}, ""},
+		{"不匹配必须正则", FilterRules{IncludeRegex: []string{`S02E\d+`}}, `不匹配必须正则: S02E\d+`},
+		{"制作组黑名单", FilterRules{DenyGroups: []string{"chdweb"}}, "制作组在黑名单中: CHDWEB"},
+		{"制作组白名单", FilterRules{AllowGroups: []string{"CHDWEB", "ADWeb"}}, ""},
+		{"不在制作组白名单", FilterRules{AllowGroups: []string{"ADWeb"}}, "制作组不在白名单中: CHDWEB"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := CompileFilter(tt.rules)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, filter.Check(torrent))
+		})
+	}
+}
+
+func TestMergeFilterRules(t *testing.T) {
+	defaults := FilterRules{Exclude: []string{"国语"}, AllowGroups: []string{"CHDWEB"}}
+
+	assert.Equal(t, defaults, MergeFilterRules(defaults, nil))
+
+	merged := MergeFilterRules(defaults, &FilterRules{Exclude: []string{"HDR"}, Include: []string{"中字"}, AllowGroups: []string{"ADWeb"}})
+	assert.Equal(t, []string{"国语", "HDR"}, merged.Exclude)
+	assert.Equal(t, []string{"中字"}, merged.Include)
+	assert.Equal(t, []string{"ADWeb"}, merged.AllowGroups)
+
+	assert.Error(t, FilterRules{IncludeRegex: []string{"("}}.Validate())
+}
+
+func TestFilterRulesPatch(t *testing.T) {
+	rules := FilterRules{Include: []string{"中字"}, Exclude: []string{"国语"}}
+
+	patched := rules.Patch(FilterRules{Exclude: []string{"HDR"}, DenyGroups: []string{}})
+	assert.Equal(t, []string{"中字"}, patched.Include)
+	assert.Equal(t, []string{"HDR"}, patched.Exclude)
+
+	// 空列表清空对应的规则
+	patched = patched.Patch(FilterRules{Include: []string{}, Exclude: []string{}})
+	assert.True(t, patched.Empty())
+}
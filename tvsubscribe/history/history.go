@@ -0,0 +1,118 @@
+// Package history 记录每一轮订阅处理的结果
+package history
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// 处理的触发来源
+const (
+	TriggerScheduled = "scheduled" // 调度器定时处理
+	TriggerManual    = "manual"    // 立即触发或立即处理全部
+	TriggerAdd       = "add"       // 添加订阅后立即处理
+	TriggerConfig    = "config"    // 修改配置后立即处理
+	TriggerWishlist  = "wishlist"  // 豆瓣想看同步创建订阅后立即处理
+)
+
+// Item 单个种子的处理结果
+type Item struct {
+	TorrentID string `json:"torrent_id"`
+	Title     string `json:"title,omitempty"`
+	Info      string `json:"info,omitempty"`
+	Volume    string `json:"volume,omitempty"`
+	Name      string `json:"name,omitempty"`   // 添加到 Transmission 后的种子名称
+	Reason    string `json:"reason,omitempty"` // 被拒绝的原因
+}
+
+// ErrorEntry 处理过程中发生的错误
+type ErrorEntry struct {
+	Time    time.Time `json:"time"`
+	Message string    `json:"message"`
+}
+
+// SubscriptionResult 单个订阅在一轮处理中的结果
+type SubscriptionResult struct {
+	ID         string       `json:"id"`
+	DouBanID   string       `json:"douban_id"`
+	Name       string       `json:"name"`
+	StartedAt  time.Time    `json:"started_at"`
+	FinishedAt time.Time    `json:"finished_at"`
+	DurationMs int64        `json:"duration_ms"`
+	Skipped    string       `json:"skipped,omitempty"` // 未查询站点的原因
+	Candidates int          `json:"candidates"`        // 站点返回的种子数量
+	Accepted   []Item       `json:"accepted,omitempty"`
+	Rejected   []Item       `json:"rejected,omitempty"`
+	Downloaded []Item       `json:"downloaded,omitempty"`
+	Errors     []ErrorEntry `json:"errors,omitempty"`
+}
+
+// AddError 记录一条错误
+func (r *SubscriptionResult) AddError(message string) {
+	r.Errors = append(r.Errors, ErrorEntry{Time: time.Now(), Message: message})
+}
+
+// Finish 记录结束时间和耗时
+func (r *SubscriptionResult) Finish() {
+	r.FinishedAt = time.Now()
+	r.DurationMs = r.FinishedAt.Sub(r.StartedAt).Milliseconds()
+}
+
+// Run 一轮订阅处理的记录
+type Run struct {
+	ID            string               `json:"id"`
+	Trigger       string               `json:"trigger"`
+	StartedAt     time.Time            `json:"started_at"`
+	FinishedAt    time.Time            `json:"finished_at"`
+	DurationMs    int64                `json:"duration_ms"`
+	Checked       int                  `json:"checked"`
+	Candidates    int                  `json:"candidates"`
+	Accepted      int                  `json:"accepted"`
+	Rejected      int                  `json:"rejected"`
+	Downloaded    int                  `json:"downloaded"`
+	Errors        int                  `json:"errors"`
+	Subscriptions []SubscriptionResult `json:"subscriptions,omitempty"`
+}
+
+// NewRun 开始一轮处理记录
+func NewRun(trigger string) *Run {
+	now := time.Now()
+	return &Run{
+		ID:        now.Format("20060102-150405") + "-" + randomSuffix(),
+		Trigger:   trigger,
+		StartedAt: now,
+	}
+}
+
+// Finish 记录结束时间，并根据各订阅的结果统计汇总数量
+func (r *Run) Finish() {
+	r.FinishedAt = time.Now()
+	r.DurationMs = r.FinishedAt.Sub(r.StartedAt).Milliseconds()
+	r.Checked, r.Candidates, r.Accepted, r.Rejected, r.Downloaded, r.Errors = 0, 0, 0, 0, 0, 0
+	for _, s := range r.Subscriptions {
+		if s.Skipped == "" {
+			r.Checked++
+		}
+		r.Candidates += s.Candidates
+		r.Accepted += len(s.Accepted)
+		r.Rejected += len(s.Rejected)
+		r.Downloaded += len(s.Downloaded)
+		r.Errors += len(s.Errors)
+	}
+}
+
+// Summary 返回不含各订阅明细的副本，用于列表展示
+func (r Run) Summary() Run {
+	r.Subscriptions = nil
+	return r
+}
+
+// randomSuffix 生成记录ID的随机后缀，避免同一秒内的记录冲突
+func randomSuffix() string {
+	bytes := make([]byte, 3)
+	if _, err := rand.Read(bytes); err != nil {
+		return "000000"
+	}
+	return hex.EncodeToString(bytes)
+}
@@ -0,0 +1,96 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"tvsubscribe/storage"
+)
+
+// DefaultSeenRetention 种子首次发现时间的默认保留时长
+const DefaultSeenRetention = 30 * 24 * time.Hour
+
+// SeenStore 保存种子首次被发现的时间，用于制作组优先时计算非首选制作组种子的等待时间，
+// 保存到文件后重启不会重新开始等待
+type SeenStore struct {
+	mu        sync.RWMutex
+	path      string
+	seen      map[string]time.Time // 种子ID -> 首次发现时间
+	retention time.Duration
+}
+
+// NewSeenStore 创建种子首次发现时间存储，超过 retention 的记录被清理，path 为空时只保存在内存中
+func NewSeenStore(path string, retention time.Duration) (*SeenStore, error) {
+	s := &SeenStore{
+		path:      path,
+		seen:      make(map[string]time.Time),
+		retention: retention,
+	}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) || (err == nil && len(data) == 0) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取种子发现时间文件失败: %v", err)
+	}
+	if err := json.Unmarshal(data, &s.seen); err != nil {
+		return nil, fmt.Errorf("解析种子发现时间文件失败: %v", err)
+	}
+	if s.seen == nil {
+		s.seen = make(map[string]time.Time)
+	}
+	return s, nil
+}
+
+// Mark 记录种子首次被发现的时间，已有记录的种子保持原来的时间，并清理过期的记录；有变化时保存到文件
+func (s *SeenStore) Mark(ids []string, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	changed := false
+	for id, seenAt := range s.seen {
+		if now.Sub(seenAt) > s.retention {
+			delete(s.seen, id)
+			changed = true
+		}
+	}
+	for _, id := range ids {
+		if _, ok := s.seen[id]; !ok {
+			s.seen[id] = now
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return s.save()
+}
+
+// FirstSeen 返回种子首次被发现的时间，没有记录时返回零值
+func (s *SeenStore) FirstSeen(id string) time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.seen[id]
+}
+
+// save 保存记录到文件，调用方需持有锁
+func (s *SeenStore) save() error {
+	if s.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(s.seen, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化种子发现时间失败: %v", err)
+	}
+	if err := storage.WriteFile(s.path, data, storage.FileMode); err != nil {
+		return fmt.Errorf("写入种子发现时间文件失败: %v", err)
+	}
+	return nil
+}
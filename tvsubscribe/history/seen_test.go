@@ -0,0 +1,34 @@
+package history
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSeenStore 测试首次发现时间不被覆盖、过期记录被清理，并且重新加载后保留
+func TestSeenStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "torrent_seen.json")
+	store, err := NewSeenStore(path, 24*time.Hour)
+	require.NoError(t, err)
+
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, store.Mark([]string{"1", "2"}, now))
+	require.NoError(t, store.Mark([]string{"2", "3"}, now.Add(time.Hour)))
+	assert.True(t, store.FirstSeen("2").Equal(now), "已有记录的种子保持首次发现的时间")
+	assert.True(t, store.FirstSeen("3").Equal(now.Add(time.Hour)))
+	assert.True(t, store.FirstSeen("4").IsZero())
+
+	// 重启后从文件加载
+	reloaded, err := NewSeenStore(path, 24*time.Hour)
+	require.NoError(t, err)
+	assert.True(t, reloaded.FirstSeen("1").Equal(now))
+	assert.True(t, reloaded.FirstSeen("3").Equal(now.Add(time.Hour)))
+
+	require.NoError(t, reloaded.Mark(nil, now.Add(24*time.Hour+30*time.Minute)))
+	assert.True(t, reloaded.FirstSeen("1").IsZero(), "超过保留时长的记录被清理")
+	assert.False(t, reloaded.FirstSeen("3").IsZero())
+}
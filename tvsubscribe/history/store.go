@@ -0,0 +1,132 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"tvsubscribe/storage"
+)
+
+// 默认的保留策略
+const (
+	DefaultRetentionDays  = 30
+	DefaultRetentionCount = 500
+)
+
+// Store 保存处理记录，按保留天数和条数清理旧记录
+type Store struct {
+	mu             sync.RWMutex
+	path           string
+	runs           []Run // 按开始时间从旧到新排列
+	retentionDays  int
+	retentionCount int
+}
+
+// NewStore 创建处理记录存储，path 为空时只保存在内存中
+func NewStore(path string) (*Store, error) {
+	s := &Store{
+		path:           path,
+		retentionDays:  DefaultRetentionDays,
+		retentionCount: DefaultRetentionCount,
+	}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) || (err == nil && len(data) == 0) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取处理记录文件失败: %v", err)
+	}
+	if err := json.Unmarshal(data, &s.runs); err != nil {
+		return nil, fmt.Errorf("解析处理记录文件失败: %v", err)
+	}
+	return s, nil
+}
+
+// SetRetention 设置保留天数和条数，小于等于0时使用默认值
+func (s *Store) SetRetention(days, count int) {
+	if days <= 0 {
+		days = DefaultRetentionDays
+	}
+	if count <= 0 {
+		count = DefaultRetentionCount
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.retentionDays = days
+	s.retentionCount = count
+}
+
+// Add 保存一条处理记录，并清理超出保留策略的旧记录
+func (s *Store) Add(run Run) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.runs = append(s.runs, run)
+	s.prune(time.Now())
+	return s.save()
+}
+
+// prune 清理超出保留策略的旧记录，调用方需持有锁
+func (s *Store) prune(now time.Time) {
+	cutoff := now.AddDate(0, 0, -s.retentionDays)
+	start := 0
+	for start < len(s.runs) && s.runs[start].StartedAt.Before(cutoff) {
+		start++
+	}
+	if len(s.runs)-start > s.retentionCount {
+		start = len(s.runs) - s.retentionCount
+	}
+	if start > 0 {
+		s.runs = append([]Run(nil), s.runs[start:]...)
+	}
+}
+
+// save 保存记录到文件，调用方需持有锁
+func (s *Store) save() error {
+	if s.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(s.runs, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化处理记录失败: %v", err)
+	}
+	if err := storage.WriteFile(s.path, data, storage.FileMode); err != nil {
+		return fmt.Errorf("写入处理记录文件失败: %v", err)
+	}
+	return nil
+}
+
+// ListRuns 返回最近的处理记录摘要，按时间从新到旧排列，limit 小于等于0时返回全部
+func (s *Store) ListRuns(limit int) []Run {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if limit <= 0 || limit > len(s.runs) {
+		limit = len(s.runs)
+	}
+	result := make([]Run, 0, limit)
+	for i := len(s.runs) - 1; i >= 0 && len(result) < limit; i-- {
+		result = append(result, s.runs[i].Summary())
+	}
+	return result
+}
+
+// GetRun 按ID获取完整的处理记录
+func (s *Store) GetRun(id string) (Run, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, run := range s.runs {
+		if run.ID == id {
+			return run, true
+		}
+	}
+	return Run{}, false
+}
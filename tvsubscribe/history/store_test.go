@@ -0,0 +1,86 @@
+package history
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRunFinish 测试根据订阅结果统计汇总数量
+func TestRunFinish(t *testing.T) {
+	run := NewRun(TriggerScheduled)
+	run.Subscriptions = []SubscriptionResult{
+		{
+			ID:         "a",
+			Candidates: 3,
+			Accepted:   []Item{{TorrentID: "1"}, {TorrentID: "2"}},
+			Rejected:   []Item{{TorrentID: "3", Reason: "已下载过"}},
+			Downloaded: []Item{{TorrentID: "1"}},
+			Errors:     []ErrorEntry{{Message: "下载失败"}},
+		},
+		{ID: "b", Skipped: "站点Cookie已失效"},
+	}
+	run.Finish()
+
+	assert.Equal(t, 1, run.Checked)
+	assert.Equal(t, 3, run.Candidates)
+	assert.Equal(t, 2, run.Accepted)
+	assert.Equal(t, 1, run.Rejected)
+	assert.Equal(t, 1, run.Downloaded)
+	assert.Equal(t, 1, run.Errors)
+	assert.False(t, run.FinishedAt.Before(run.StartedAt))
+}
+
+// TestStoreListAndGet 测试列表按时间倒序且不含明细，详情包含明细
+func TestStoreListAndGet(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "runs.json")
+	store, err := NewStore(path)
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		run := NewRun(TriggerManual)
+		run.ID = string(rune('a' + i))
+		run.Subscriptions = []SubscriptionResult{{ID: "tv"}}
+		run.Finish()
+		require.NoError(t, store.Add(*run))
+	}
+
+	reloaded, err := NewStore(path)
+	require.NoError(t, err)
+
+	runs := reloaded.ListRuns(2)
+	require.Len(t, runs, 2)
+	assert.Equal(t, "c", runs[0].ID)
+	assert.Equal(t, "b", runs[1].ID)
+	assert.Nil(t, runs[0].Subscriptions)
+	assert.Len(t, reloaded.ListRuns(0), 3)
+
+	run, ok := reloaded.GetRun("a")
+	require.True(t, ok)
+	assert.Len(t, run.Subscriptions, 1)
+
+	_, ok = reloaded.GetRun("missing")
+	assert.False(t, ok)
+}
+
+// TestStoreRetention 测试按条数和天数清理旧记录
+func TestStoreRetention(t *testing.T) {
+	store, err := NewStore("")
+	require.NoError(t, err)
+	store.SetRetention(7, 2)
+
+	old := Run{ID: "old", StartedAt: time.Now().AddDate(0, 0, -8)}
+	require.NoError(t, store.Add(old))
+	require.NoError(t, store.Add(Run{ID: "1", StartedAt: time.Now()}))
+	assert.Len(t, store.ListRuns(0), 1, "超过保留天数的记录应被清理")
+
+	require.NoError(t, store.Add(Run{ID: "2", StartedAt: time.Now()}))
+	require.NoError(t, store.Add(Run{ID: "3", StartedAt: time.Now()}))
+	runs := store.ListRuns(0)
+	require.Len(t, runs, 2)
+	assert.Equal(t, "3", runs[0].ID)
+	assert.Equal(t, "2", runs[1].ID)
+}
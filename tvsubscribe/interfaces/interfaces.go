@@ -1,18 +1,62 @@
 package interfaces
 
-import "tvsubscribe"
+import (
+	"context"
+	"time"
+
+	"tvsubscribe"
+	"tvsubscribe/auth"
+	"tvsubscribe/config"
+	"tvsubscribe/history"
+	"tvsubscribe/subscribe"
+)
 
 // ConfigManager 配置管理器接口
 type ConfigManager interface {
-	GetConfig() map[string]interface{}
-	UpdateConfig(updates map[string]interface{}) error
+	GetConfig() config.Config
+	UpdateConfig(patch config.Patch) (config.Config, error)
+	ValidateConfig(patch config.Patch) (config.Config, error)
+	EffectiveConfig() config.Effective
 }
 
 // SubscribeManager 订阅管理器接口
 type SubscribeManager interface {
 	GetSubscribes() []tvsubscribe.TVInfo
-	AddSubscribe(tvInfo tvsubscribe.TVInfo) error
+	AddSubscribe(ctx context.Context, tvInfo tvsubscribe.TVInfo) error
 	RemoveSubscribe(tvInfo tvsubscribe.TVInfo) error
 	RemoveSubscribesByID(ids []string) error
 	GetSubscribeByID(id string) (tvsubscribe.TVInfo, error)
+	SetSubscribesEnabled(ids []string, enabled bool) (int, error)
+	UpdateSubscribe(ctx context.Context, id string, patch tvsubscribe.TVInfoPatch) (tvsubscribe.TVInfo, error)
+	ReactivateSubscribes(ids []string) (int, error)
+	ImportSubscribes(ctx context.Context, records []subscribe.ImportRecord, mode string, dryRun bool) (subscribe.ImportResult, error)
+}
+
+// Scheduler 调度器接口
+type Scheduler interface {
+	NextRun(id string) (time.Time, bool)
+	Reschedule()
+	Pause()
+	Resume()
+	Paused() bool
+	RunNow()
+}
+
+// RunHistory 处理记录接口
+type RunHistory interface {
+	ListRuns(limit int) []history.Run
+	GetRun(id string) (history.Run, bool)
+}
+
+// Authenticator 用户和API令牌管理接口
+type Authenticator interface {
+	Authenticate(username, password string) (auth.User, error)
+	VerifyToken(token string) (auth.Token, bool)
+	Users() []auth.User
+	AddUser(username, password string, role auth.Role) error
+	RemoveUser(username string) error
+	SetPassword(username, password string) error
+	Tokens() []auth.Token
+	CreateToken(name string, role auth.Role, createdBy string) (string, auth.Token, error)
+	RevokeToken(id string) error
 }
\ No newline at end of file
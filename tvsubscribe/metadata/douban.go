@@ -0,0 +1,189 @@
+package metadata
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+var (
+	episodesPattern = regexp.MustCompile(`集数:\s*(\d+)`)
+	premierePattern = regexp.MustCompile(`(?:首播|上映日期):\s*(\d{4}-\d{2}-\d{2})`)
+	imdbPattern     = regexp.MustCompile(`IMDb:\s*(tt\d+)`)
+	datePattern     = regexp.MustCompile(`\d{4}-\d{2}-\d{2}`)
+)
+
+// subjectInfo 豆瓣条目页中与播出相关的信息
+type subjectInfo struct {
+	Episodes int
+	Premiere string
+	IMDbID   string
+}
+
+// Fetcher 从豆瓣获取剧集播出时间表，豆瓣没有分集日期时可选使用TMDB补充
+type Fetcher struct {
+	DoubanBaseURL string
+	TMDBBaseURL   string
+	TMDBAPIKey    string // 为空时不使用TMDB
+	client        *http.Client
+}
+
+// NewFetcher 创建播出时间表获取器，地址为空时使用默认地址
+func NewFetcher(doubanBaseURL, tmdbBaseURL, tmdbAPIKey string) *Fetcher {
+	if doubanBaseURL == "" {
+		doubanBaseURL = DefaultDoubanBaseURL
+	}
+	if tmdbBaseURL == "" {
+		tmdbBaseURL = DefaultTMDBBaseURL
+	}
+	return &Fetcher{
+		DoubanBaseURL: strings.TrimRight(doubanBaseURL, "/"),
+		TMDBBaseURL:   strings.TrimRight(tmdbBaseURL, "/"),
+		TMDBAPIKey:    tmdbAPIKey,
+		client:        &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// Fetch 获取豆瓣条目的播出时间表。previous 为上一次获取的结果，
+// 其中已经播出的分集日期会被复用，避免每次都请求所有分集页面。
+func (f *Fetcher) Fetch(ctx context.Context, doubanID string, previous *AirSchedule) (AirSchedule, error) {
+	if strings.TrimSpace(doubanID) == "" {
+		return AirSchedule{}, fmt.Errorf("豆瓣ID不能为空")
+	}
+
+	html, err := f.get(ctx, fmt.Sprintf("%s/subject/%s/", f.DoubanBaseURL, doubanID))
+	if err != nil {
+		return AirSchedule{}, fmt.Errorf("获取豆瓣条目页失败: %v", err)
+	}
+	subject, err := parseSubject(html)
+	if err != nil {
+		return AirSchedule{}, err
+	}
+
+	schedule := AirSchedule{
+		Source:    "douban",
+		Episodes:  subject.Episodes,
+		AirDates:  make([]string, subject.Episodes),
+		FetchedAt: time.Now(),
+	}
+
+	today := time.Now().Format(dateLayout)
+	for i := 0; i < subject.Episodes; i++ {
+		// 已经播出的分集日期不会再变化，直接复用
+		if previous != nil && i < len(previous.AirDates) && previous.AirDates[i] != "" && previous.AirDates[i] < today {
+			schedule.AirDates[i] = previous.AirDates[i]
+			continue
+		}
+		episodeHTML, err := f.get(ctx, fmt.Sprintf("%s/subject/%s/episode/%d/", f.DoubanBaseURL, doubanID, i+1))
+		if err != nil {
+			if ctx.Err() != nil {
+				return AirSchedule{}, ctx.Err()
+			}
+			log.Printf("获取豆瓣分集页失败 (豆瓣ID: %s, 第%d集): %v", doubanID, i+1, err)
+			continue
+		}
+		schedule.AirDates[i] = parseEpisodeAirDate(episodeHTML)
+	}
+
+	if schedule.KnownDates() > 0 {
+		return schedule, nil
+	}
+
+	// 豆瓣没有分集日期时尝试TMDB
+	if f.TMDBAPIKey != "" && subject.IMDbID != "" {
+		tmdbSchedule, err := f.fetchTMDB(ctx, subject.IMDbID)
+		if err == nil && tmdbSchedule.KnownDates() > 0 {
+			return tmdbSchedule, nil
+		}
+		if err != nil {
+			log.Printf("从TMDB获取播出时间表失败 (豆瓣ID: %s): %v", doubanID, err)
+		}
+	}
+
+	// 仍然没有分集日期时，至少使用首播日期作为第一集的播出日期
+	if subject.Premiere != "" {
+		if len(schedule.AirDates) == 0 {
+			schedule.AirDates = []string{""}
+		}
+		schedule.AirDates[0] = subject.Premiere
+	}
+	return schedule, nil
+}
+
+// get 请求页面并返回内容
+func (f *Fetcher) get(ctx context.Context, url string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return "", fmt.Errorf("创建请求失败: %v", err)
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+	req.Header.Set("Accept-Language", "zh-CN,zh;q=0.9")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("状态码: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("读取响应失败: %v", err)
+	}
+	return string(body), nil
+}
+
+// parseSubject 从豆瓣条目页的 #info 区域解析集数、首播日期和IMDb编号
+func parseSubject(html string) (subjectInfo, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return subjectInfo{}, fmt.Errorf("解析豆瓣条目页失败: %v", err)
+	}
+
+	info := doc.Find("#info")
+	if info.Length() == 0 {
+		return subjectInfo{}, fmt.Errorf("豆瓣条目页中没有找到条目信息")
+	}
+	text := info.Text()
+
+	var subject subjectInfo
+	if m := episodesPattern.FindStringSubmatch(text); m != nil {
+		subject.Episodes, _ = strconv.Atoi(m[1])
+	}
+	if m := premierePattern.FindStringSubmatch(text); m != nil {
+		subject.Premiere = m[1]
+	}
+	if m := imdbPattern.FindStringSubmatch(text); m != nil {
+		subject.IMDbID = m[1]
+	}
+	return subject, nil
+}
+
+// parseEpisodeAirDate 从豆瓣分集页解析播出日期，没有时返回空字符串
+func parseEpisodeAirDate(html string) string {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return ""
+	}
+
+	airDate := ""
+	doc.Find(".ep-info li").Each(func(i int, li *goquery.Selection) {
+		title := li.Find(".tit").Text()
+		if airDate == "" && (strings.Contains(title, "播出时间") || strings.Contains(title, "播放时间") || strings.Contains(title, "首播")) {
+			airDate = datePattern.FindString(li.Text())
+		}
+	})
+	return airDate
+}
@@ -0,0 +1,146 @@
+package metadata
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// subjectPage 构造豆瓣条目页
+func subjectPage(info string) string {
+	return `<html><body><div id="content"><h1><span>测试剧集</span></h1>
+<div id="info">` + info + `</div></div></body></html>`
+}
+
+// episodePage 构造豆瓣分集页
+func episodePage(airDate string) string {
+	return `<html><body><ul class="ep-info">
+<li><span class="tit">本集中文名:</span><span>第一集</span></li>
+<li><span class="tit">播出时间:</span><span>` + airDate + `</span></li>
+</ul></body></html>`
+}
+
+// newDoubanStub 创建豆瓣替身服务，episodeDates 为空字符串的分集返回404
+func newDoubanStub(t *testing.T, info string, episodeDates []string, requests *int32) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests != nil {
+			atomic.AddInt32(requests, 1)
+		}
+		if r.URL.Path == "/subject/100/" {
+			fmt.Fprint(w, subjectPage(info))
+			return
+		}
+		var episode int
+		if _, err := fmt.Sscanf(r.URL.Path, "/subject/100/episode/%d/", &episode); err == nil &&
+			episode >= 1 && episode <= len(episodeDates) && episodeDates[episode-1] != "" {
+			fmt.Fprint(w, episodePage(episodeDates[episode-1]))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// TestFetchFromDouban 测试从豆瓣条目页和分集页获取播出时间表
+func TestFetchFromDouban(t *testing.T) {
+	douban := newDoubanStub(t, `<span class="pl">首播:</span> <span property="v:initialReleaseDate" content="2025-06-01(中国大陆)">2025-06-01(中国大陆)</span><br/>
+<span class="pl">集数:</span> 3<br/>`, []string{"2025-06-01", "2025-06-02", ""}, nil)
+
+	fetcher := NewFetcher(douban.URL, "", "")
+	schedule, err := fetcher.Fetch(context.Background(), "100", nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, "douban", schedule.Source)
+	assert.Equal(t, 3, schedule.Episodes)
+	assert.Equal(t, []string{"2025-06-01", "2025-06-02", ""}, schedule.AirDates)
+	assert.Equal(t, 2, schedule.KnownDates())
+}
+
+// TestFetchReusesPastDates 测试已经播出的分集日期直接复用，不再请求分集页
+func TestFetchReusesPastDates(t *testing.T) {
+	var requests int32
+	douban := newDoubanStub(t, `<span class="pl">集数:</span> 2<br/>`, []string{"2000-01-01", "2099-01-02"}, &requests)
+
+	previous := &AirSchedule{AirDates: []string{"2000-01-01", ""}}
+	schedule, err := NewFetcher(douban.URL, "", "").Fetch(context.Background(), "100", previous)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"2000-01-01", "2099-01-02"}, schedule.AirDates)
+	// 条目页 + 第2集分集页
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+}
+
+// TestFetchFallsBackToTMDB 测试豆瓣没有分集日期时使用TMDB
+func TestFetchFallsBackToTMDB(t *testing.T) {
+	douban := newDoubanStub(t, `<span class="pl">集数:</span> 2<br/>
+<span class="pl">IMDb:</span> tt1234567<br/>`, nil, nil)
+
+	tmdb := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("api_key") != "key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/3/find/tt1234567":
+			assert.Equal(t, "imdb_id", r.URL.Query().Get("external_source"))
+			fmt.Fprint(w, `{"tv_results":[{"id":42}]}`)
+		case "/3/tv/42":
+			fmt.Fprint(w, `{"seasons":[{"season_number":0},{"season_number":1},{"season_number":2}]}`)
+		case "/3/tv/42/season/2":
+			fmt.Fprint(w, `{"episodes":[{"episode_number":1,"air_date":"2025-07-01"},{"episode_number":2,"air_date":"2025-07-08"}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer tmdb.Close()
+
+	schedule, err := NewFetcher(douban.URL, tmdb.URL, "key").Fetch(context.Background(), "100", nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, "tmdb", schedule.Source)
+	assert.Equal(t, []string{"2025-07-01", "2025-07-08"}, schedule.AirDates)
+}
+
+// TestFetchUsesPremiereWithoutEpisodeDates 测试没有分集日期且未配置TMDB时使用首播日期
+func TestFetchUsesPremiereWithoutEpisodeDates(t *testing.T) {
+	douban := newDoubanStub(t, `<span class="pl">首播:</span> 2025-06-01(中国大陆)<br/>
+<span class="pl">集数:</span> 2<br/>
+<span class="pl">IMDb:</span> tt1234567<br/>`, nil, nil)
+
+	schedule, err := NewFetcher(douban.URL, "", "").Fetch(context.Background(), "100", nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, "douban", schedule.Source)
+	assert.Equal(t, []string{"2025-06-01", ""}, schedule.AirDates)
+}
+
+// TestFetchErrors 测试条目页请求失败或缺少条目信息时返回错误
+func TestFetchErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/subject/100/") {
+			fmt.Fprint(w, `<html><body>登录</body></html>`)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	fetcher := NewFetcher(server.URL, "", "")
+
+	_, err := fetcher.Fetch(context.Background(), "", nil)
+	assert.Error(t, err)
+
+	_, err = fetcher.Fetch(context.Background(), "100", nil)
+	assert.ErrorContains(t, err, "没有找到条目信息")
+
+	_, err = fetcher.Fetch(context.Background(), "200", nil)
+	assert.ErrorContains(t, err, "获取豆瓣条目页失败")
+}
@@ -0,0 +1,54 @@
+// Package metadata 获取剧集的播出时间表，并据此规划订阅的检查时间
+package metadata
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 默认的元数据来源地址
+const (
+	DefaultDoubanBaseURL = "https://movie.douban.com"
+	DefaultTMDBBaseURL   = "https://api.themoviedb.org"
+)
+
+// dateLayout 播出日期格式
+const dateLayout = "2006-01-02"
+
+// AirSchedule 剧集的播出时间表
+type AirSchedule struct {
+	Source    string    `json:"source"`    // 数据来源：douban 或 tmdb
+	Episodes  int       `json:"episodes"`  // 总集数，未知时为0
+	AirDates  []string  `json:"air_dates"` // 按集数顺序排列的播出日期（YYYY-MM-DD），未知的为空字符串
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+// KnownDates 返回时间表中已知播出日期的数量
+func (s AirSchedule) KnownDates() int {
+	count := 0
+	for _, date := range s.AirDates {
+		if date != "" {
+			count++
+		}
+	}
+	return count
+}
+
+// ParseAirTime 解析预计播出时刻（HH:MM）
+func ParseAirTime(s string) (hour, minute int, err error) {
+	parts := strings.Split(strings.TrimSpace(s), ":")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("无效的播出时刻: %s，格式应为 HH:MM", s)
+	}
+	hour, err = strconv.Atoi(parts[0])
+	if err != nil || hour < 0 || hour > 23 {
+		return 0, 0, fmt.Errorf("无效的播出时刻: %s，格式应为 HH:MM", s)
+	}
+	minute, err = strconv.Atoi(parts[1])
+	if err != nil || minute < 0 || minute > 59 {
+		return 0, 0, fmt.Errorf("无效的播出时刻: %s，格式应为 HH:MM", s)
+	}
+	return hour, minute, nil
+}
@@ -0,0 +1,82 @@
+package metadata
+
+import (
+	"fmt"
+	"time"
+
+	"tvsubscribe"
+)
+
+// PollOptions 按播出时间规划检查的选项
+type PollOptions struct {
+	Enabled        bool
+	AirTime        string        // 预计播出时刻（HH:MM），播出日期加上该时刻即为预计播出时间
+	Window         time.Duration // 预计播出时间之后频繁检查的时长
+	Interval       time.Duration // 播出窗口内的检查间隔
+	OffAirInterval time.Duration // 播出窗口外的检查间隔
+}
+
+// Planner 根据播出时间表计算订阅的下一次检查时间：
+// 播出窗口内频繁检查，窗口外（包括已完结或停播的剧集）很少检查，但不会错过下一集的播出时间。
+type Planner struct {
+	store   *Store
+	options func() PollOptions
+}
+
+// NewPlanner 创建检查时间规划器，options 在每次计算时读取，修改配置后立即生效
+func NewPlanner(store *Store, options func() PollOptions) *Planner {
+	return &Planner{store: store, options: options}
+}
+
+// Next 返回订阅在 after 之后的下一次检查时间。
+// 未启用或没有已知的播出日期时返回 false，由调用方使用普通调度规则。
+func (p *Planner) Next(tvInfo tvsubscribe.TVInfo, after time.Time) (time.Time, bool) {
+	options := p.options()
+	if !options.Enabled || options.Interval <= 0 || options.OffAirInterval <= 0 {
+		return time.Time{}, false
+	}
+	schedule, ok := p.store.Get(tvInfo.DouBanID)
+	if !ok || schedule.KnownDates() == 0 {
+		return time.Time{}, false
+	}
+	hour, minute, err := ParseAirTime(options.AirTime)
+	if err != nil {
+		hour, minute = 0, 0
+	}
+
+	var upcoming time.Time
+	for _, date := range schedule.AirDates {
+		if date == "" {
+			continue
+		}
+		day, err := time.ParseInLocation(dateLayout, date, after.Location())
+		if err != nil {
+			continue
+		}
+		start := time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, after.Location())
+		end := start.Add(options.Window)
+		if !after.Before(start) && after.Before(end) {
+			// 处于播出窗口内
+			return after.Add(options.Interval), true
+		}
+		if start.After(after) && (upcoming.IsZero() || start.Before(upcoming)) {
+			upcoming = start
+		}
+	}
+
+	next := after.Add(options.OffAirInterval)
+	if !upcoming.IsZero() && upcoming.Before(next) {
+		next = upcoming
+	}
+	return next, true
+}
+
+// Key 返回影响订阅检查时间的状态摘要，选项或播出时间表变化时随之变化
+func (p *Planner) Key(tvInfo tvsubscribe.TVInfo) string {
+	options := p.options()
+	if !options.Enabled {
+		return ""
+	}
+	schedule, _ := p.store.Get(tvInfo.DouBanID)
+	return fmt.Sprintf("%+v|%d", options, schedule.FetchedAt.UnixNano())
+}
@@ -0,0 +1,108 @@
+package metadata
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tvsubscribe"
+)
+
+// testPollOptions 测试用的检查选项：20:00 播出，之后6小时内每10分钟检查，其余时间每12小时检查
+var testPollOptions = PollOptions{
+	Enabled:        true,
+	AirTime:        "20:00",
+	Window:         6 * time.Hour,
+	Interval:       10 * time.Minute,
+	OffAirInterval: 12 * time.Hour,
+}
+
+func newTestPlanner(t *testing.T, options PollOptions, airDates ...string) *Planner {
+	store, err := NewStore("")
+	require.NoError(t, err)
+	require.NoError(t, store.Set("100", AirSchedule{Source: "douban", Episodes: len(airDates), AirDates: airDates}))
+	return NewPlanner(store, func() PollOptions { return options })
+}
+
+// TestPlannerNext 测试播出窗口内外的检查时间
+func TestPlannerNext(t *testing.T) {
+	planner := newTestPlanner(t, testPollOptions, "2025-06-06", "2025-06-07", "", "2025-06-20")
+	tv := tvsubscribe.TVInfo{ID: "a", DouBanID: "100"}
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2025, 6, day, hour, minute, 0, 0, time.Local)
+	}
+
+	tests := []struct {
+		name     string
+		after    time.Time
+		expected time.Time
+	}{
+		{"播出前很少检查", at(6, 1, 0), at(6, 13, 0)},
+		{"临近播出时在播出时间检查", at(6, 15, 0), at(6, 20, 0)},
+		{"播出窗口内频繁检查", at(6, 20, 0), at(6, 20, 10)},
+		{"播出窗口末尾", at(7, 1, 55), at(7, 2, 5)},
+		{"窗口结束后等到下一集", at(7, 10, 0), at(7, 20, 0)},
+		{"停播期间很少检查", at(8, 10, 0), at(8, 22, 0)},
+		{"已完结后很少检查", at(25, 10, 0), at(25, 22, 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next, ok := planner.Next(tv, tt.after)
+			require.True(t, ok)
+			assert.Equal(t, tt.expected, next)
+		})
+	}
+}
+
+// TestPlannerFallback 测试未启用或没有播出日期时交给调度规则
+func TestPlannerFallback(t *testing.T) {
+	now := time.Date(2025, 6, 6, 10, 0, 0, 0, time.Local)
+
+	disabled := testPollOptions
+	disabled.Enabled = false
+	_, ok := newTestPlanner(t, disabled, "2025-06-06").Next(tvsubscribe.TVInfo{DouBanID: "100"}, now)
+	assert.False(t, ok)
+
+	_, ok = newTestPlanner(t, testPollOptions, "", "").Next(tvsubscribe.TVInfo{DouBanID: "100"}, now)
+	assert.False(t, ok)
+
+	_, ok = newTestPlanner(t, testPollOptions, "2025-06-06").Next(tvsubscribe.TVInfo{DouBanID: "200"}, now)
+	assert.False(t, ok)
+}
+
+// TestPlannerKey 测试选项或播出时间表变化时摘要随之变化
+func TestPlannerKey(t *testing.T) {
+	store, err := NewStore("")
+	require.NoError(t, err)
+	options := testPollOptions
+	planner := NewPlanner(store, func() PollOptions { return options })
+	tv := tvsubscribe.TVInfo{DouBanID: "100"}
+
+	key := planner.Key(tv)
+	require.NoError(t, store.Set("100", AirSchedule{AirDates: []string{"2025-06-06"}, FetchedAt: time.Now()}))
+	assert.NotEqual(t, key, planner.Key(tv))
+
+	key = planner.Key(tv)
+	options.Interval = 5 * time.Minute
+	assert.NotEqual(t, key, planner.Key(tv))
+}
+
+// TestStorePersistence 测试播出时间表保存到文件后可以重新加载
+func TestStorePersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "air_schedules.json")
+	store, err := NewStore(path)
+	require.NoError(t, err)
+
+	schedule := AirSchedule{Source: "douban", Episodes: 2, AirDates: []string{"2025-06-06", ""}, FetchedAt: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)}
+	require.NoError(t, store.Set("100", schedule))
+
+	reloaded, err := NewStore(path)
+	require.NoError(t, err)
+	got, ok := reloaded.Get("100")
+	require.True(t, ok)
+	assert.Equal(t, schedule, got)
+}
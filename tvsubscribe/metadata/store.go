@@ -0,0 +1,75 @@
+package metadata
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"tvsubscribe/storage"
+)
+
+// Store 按豆瓣ID保存播出时间表，同一部剧的不同分辨率订阅共用一份
+type Store struct {
+	mu        sync.RWMutex
+	path      string
+	schedules map[string]AirSchedule
+}
+
+// NewStore 创建播出时间表存储，path 为空时只保存在内存中
+func NewStore(path string) (*Store, error) {
+	s := &Store{
+		path:      path,
+		schedules: make(map[string]AirSchedule),
+	}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) || (err == nil && len(data) == 0) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取播出时间表文件失败: %v", err)
+	}
+	if err := json.Unmarshal(data, &s.schedules); err != nil {
+		return nil, fmt.Errorf("解析播出时间表文件失败: %v", err)
+	}
+	if s.schedules == nil {
+		s.schedules = make(map[string]AirSchedule)
+	}
+	return s, nil
+}
+
+// Get 获取豆瓣ID对应的播出时间表
+func (s *Store) Get(doubanID string) (AirSchedule, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	schedule, ok := s.schedules[doubanID]
+	if ok {
+		schedule.AirDates = append([]string(nil), schedule.AirDates...)
+	}
+	return schedule, ok
+}
+
+// Set 保存豆瓣ID对应的播出时间表
+func (s *Store) Set(doubanID string, schedule AirSchedule) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.schedules[doubanID] = schedule
+	if s.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(s.schedules, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化播出时间表失败: %v", err)
+	}
+	if err := storage.WriteFile(s.path, data, storage.FileMode); err != nil {
+		return fmt.Errorf("写入播出时间表文件失败: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// tmdbFindResponse TMDB按外部编号查找的响应
+type tmdbFindResponse struct {
+	TVResults []struct {
+		ID int `json:"id"`
+	} `json:"tv_results"`
+}
+
+// tmdbTVResponse TMDB剧集详情的响应
+type tmdbTVResponse struct {
+	Seasons []struct {
+		SeasonNumber int `json:"season_number"`
+	} `json:"seasons"`
+}
+
+// tmdbSeasonResponse TMDB单季详情的响应
+type tmdbSeasonResponse struct {
+	Episodes []struct {
+		EpisodeNumber int    `json:"episode_number"`
+		AirDate       string `json:"air_date"`
+	} `json:"episodes"`
+}
+
+// fetchTMDB 通过IMDb编号在TMDB上查找剧集，返回最新一季的播出时间表
+func (f *Fetcher) fetchTMDB(ctx context.Context, imdbID string) (AirSchedule, error) {
+	var found tmdbFindResponse
+	if err := f.getTMDB(ctx, "/3/find/"+url.PathEscape(imdbID), url.Values{"external_source": {"imdb_id"}}, &found); err != nil {
+		return AirSchedule{}, err
+	}
+	if len(found.TVResults) == 0 {
+		return AirSchedule{}, fmt.Errorf("TMDB中没有找到IMDb编号为 %s 的剧集", imdbID)
+	}
+	tvID := found.TVResults[0].ID
+
+	var tv tmdbTVResponse
+	if err := f.getTMDB(ctx, fmt.Sprintf("/3/tv/%d", tvID), nil, &tv); err != nil {
+		return AirSchedule{}, err
+	}
+	season := 0
+	for _, s := range tv.Seasons {
+		if s.SeasonNumber > season {
+			season = s.SeasonNumber
+		}
+	}
+	if season == 0 {
+		return AirSchedule{}, fmt.Errorf("TMDB剧集 %d 没有季信息", tvID)
+	}
+
+	var details tmdbSeasonResponse
+	if err := f.getTMDB(ctx, fmt.Sprintf("/3/tv/%d/season/%d", tvID, season), nil, &details); err != nil {
+		return AirSchedule{}, err
+	}
+
+	schedule := AirSchedule{
+		Source:    "tmdb",
+		Episodes:  len(details.Episodes),
+		AirDates:  make([]string, len(details.Episodes)),
+		FetchedAt: time.Now(),
+	}
+	for _, episode := range details.Episodes {
+		if episode.EpisodeNumber >= 1 && episode.EpisodeNumber <= len(schedule.AirDates) {
+			schedule.AirDates[episode.EpisodeNumber-1] = episode.AirDate
+		}
+	}
+	return schedule, nil
+}
+
+// getTMDB 请求TMDB接口并解析JSON响应
+func (f *Fetcher) getTMDB(ctx context.Context, path string, params url.Values, out interface{}) error {
+	if params == nil {
+		params = url.Values{}
+	}
+	params.Set("api_key", f.TMDBAPIKey)
+
+	body, err := f.get(ctx, f.TMDBBaseURL+path+"?"+params.Encode())
+	if err != nil {
+		return fmt.Errorf("请求TMDB失败: %v", err)
+	}
+	if err := json.Unmarshal([]byte(body), out); err != nil {
+		return fmt.Errorf("解析TMDB响应失败: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,322 @@
+package notify
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Mode 汇总模式
+type Mode string
+
+const (
+	ModeInstant Mode = "instant" // 每个事件单独发送
+	ModeDigest  Mode = "digest"  // 每轮处理结束后按电视剧分组合并为一条消息
+)
+
+// ParseMode 解析汇总模式，未知值按即时发送处理
+func ParseMode(s string) Mode {
+	if Mode(strings.TrimSpace(s)) == ModeDigest {
+		return ModeDigest
+	}
+	return ModeInstant
+}
+
+// Options 单个通知渠道的选项
+type Options struct {
+	Mode             Mode   // 汇总模式
+	DailyAt          string // 每日汇总发送时间（HH:MM），为空表示不发送每日汇总
+	RateLimitPerHour int    // 每小时最多发送条数，0 表示不限制
+}
+
+// ParseDailyAt 解析 HH:MM 格式的每日汇总时间
+func ParseDailyAt(s string) (hour, minute int, err error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, 0, fmt.Errorf("每日汇总时间格式错误，应为 HH:MM: %s", s)
+	}
+	return t.Hour(), t.Minute(), nil
+}
+
+// channel 通知渠道及其待汇总的事件
+type channel struct {
+	notifier  Notifier
+	options   Options
+	pending   []Event   // 本轮待汇总事件
+	daily     []Event   // 当日待汇总事件
+	lastDaily time.Time // 上次发送每日汇总的时间
+}
+
+// message 待发送的消息
+type message struct {
+	notifier Notifier
+	title    string
+	content  string
+	detail   string
+	urgent   bool
+	digest   bool // 汇总消息，不去重也不受限流限制
+}
+
+// Hub 通知中心，按渠道配置即时发送或汇总发送事件
+type Hub struct {
+	mu         sync.Mutex
+	channels   map[string]*channel
+	order      []string
+	dispatcher *Dispatcher // 为空时消息直接发送，不做去重、限流和免打扰处理
+}
+
+// NewHub 创建通知中心
+func NewHub() *Hub {
+	return &Hub{
+		channels: make(map[string]*channel),
+	}
+}
+
+// SetDispatcher 设置通知分发器
+func (h *Hub) SetDispatcher(dispatcher *Dispatcher) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.dispatcher = dispatcher
+}
+
+// Dispatcher 返回当前的通知分发器，未设置时返回 nil
+func (h *Hub) Dispatcher() *Dispatcher {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.dispatcher
+}
+
+// SetChannel 设置通知渠道，渠道按 Name() 区分，已缓存的事件会保留
+func (h *Hub) SetChannel(notifier Notifier, options Options) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.dispatcher != nil {
+		h.dispatcher.Register(notifier, options.RateLimitPerHour)
+	}
+
+	name := notifier.Name()
+	if ch, ok := h.channels[name]; ok {
+		ch.notifier = notifier
+		ch.options = options
+		return
+	}
+	h.channels[name] = &channel{notifier: notifier, options: options}
+	h.order = append(h.order, name)
+}
+
+// Publish 发布事件：严重错误和即时模式的渠道立即发送，其余缓存到通知中心，由 Flush 汇总发送。
+// 去重只用于立即发送的消息，事件总是记入汇总
+func (h *Hub) Publish(event Event) {
+	h.publish(event, nil)
+}
+
+// publish 发布事件，需要汇总的事件缓存到 batch 中，batch 为 nil 时缓存到通知中心
+func (h *Hub) publish(event Event, batch *Batch) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	var messages []message
+	h.mu.Lock()
+	dispatcher := h.dispatcher
+	for _, name := range h.order {
+		ch := h.channels[name]
+		if ch.options.DailyAt != "" {
+			ch.daily = append(ch.daily, event)
+		}
+		if event.Level == LevelCritical || ch.options.Mode != ModeDigest {
+			messages = append(messages, message{ch.notifier, event.Title, event.Content, event.Detail, event.Level == LevelCritical, false})
+			continue
+		}
+		if batch != nil {
+			batch.add(name, event)
+			continue
+		}
+		ch.pending = append(ch.pending, event)
+	}
+	h.mu.Unlock()
+
+	if len(messages) > 0 && dispatcher != nil && dispatcher.Duplicate(event) {
+		log.Printf("忽略重复的通知事件: %s %s", event.Show, event.Title)
+		return
+	}
+	h.send(messages)
+}
+
+// Flush 发送缓存在通知中心的汇总消息，即不属于任何批次的事件
+func (h *Hub) Flush() {
+	var messages []message
+	h.mu.Lock()
+	for _, name := range h.order {
+		ch := h.channels[name]
+		if len(ch.pending) == 0 {
+			continue
+		}
+		content, detail := formatDigest(ch.pending)
+		messages = append(messages, message{ch.notifier, "订阅处理汇总", content, detail, false, true})
+		ch.pending = nil
+	}
+	h.mu.Unlock()
+
+	h.send(messages)
+}
+
+// Batch 一轮处理的通知批次：即时发送的事件照常立即发送，需要汇总的事件缓存在批次中，
+// Flush 只发送本批次的汇总，同时进行的多轮处理互不影响
+type Batch struct {
+	hub     *Hub
+	mu      sync.Mutex
+	pending map[string][]Event // 渠道名称 -> 本批次待汇总的事件
+}
+
+// NewBatch 创建一轮处理的通知批次
+func (h *Hub) NewBatch() *Batch {
+	return &Batch{hub: h, pending: make(map[string][]Event)}
+}
+
+// Publish 发布事件，需要汇总的事件缓存到本批次
+func (b *Batch) Publish(event Event) {
+	b.hub.publish(event, b)
+}
+
+// add 缓存渠道的待汇总事件
+func (b *Batch) add(name string, event Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.pending[name] = append(b.pending[name], event)
+}
+
+// Flush 发送本批次的汇总消息，应在这一轮处理结束时调用
+func (b *Batch) Flush() {
+	b.mu.Lock()
+	pending := b.pending
+	b.pending = make(map[string][]Event)
+	b.mu.Unlock()
+
+	var messages []message
+	b.hub.mu.Lock()
+	for _, name := range b.hub.order {
+		if len(pending[name]) == 0 {
+			continue
+		}
+		content, detail := formatDigest(pending[name])
+		messages = append(messages, message{b.hub.channels[name].notifier, "订阅处理汇总", content, detail, false, true})
+	}
+	b.hub.mu.Unlock()
+
+	b.hub.send(messages)
+}
+
+// SendDailyDigests 发送已到时间的每日汇总
+func (h *Hub) SendDailyDigests(now time.Time) {
+	var messages []message
+	h.mu.Lock()
+	for _, name := range h.order {
+		ch := h.channels[name]
+		if ch.options.DailyAt == "" {
+			continue
+		}
+		hour, minute, err := ParseDailyAt(ch.options.DailyAt)
+		if err != nil {
+			continue
+		}
+		due := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, now.Location())
+		if now.Before(due) || !ch.lastDaily.Before(due) {
+			continue
+		}
+		ch.lastDaily = now
+		if len(ch.daily) == 0 {
+			continue
+		}
+		content, detail := formatDigest(ch.daily)
+		messages = append(messages, message{ch.notifier, "每日订阅汇总", content, detail, false, true})
+		ch.daily = nil
+	}
+	h.mu.Unlock()
+
+	h.send(messages)
+}
+
+// Run 每分钟检查一次每日汇总和排队中的消息，直到 stop 被关闭
+func (h *Hub) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			h.SendDailyDigests(now)
+			if dispatcher := h.Dispatcher(); dispatcher != nil {
+				dispatcher.FlushQueue()
+			}
+		}
+	}
+}
+
+// send 逐条发送消息，设置了分发器时交由分发器处理，发送失败只记录日志
+func (h *Hub) send(messages []message) {
+	dispatcher := h.Dispatcher()
+
+	for _, m := range messages {
+		if dispatcher != nil && m.digest {
+			dispatcher.DispatchDigest(m.notifier, m.title, m.content, m.detail)
+			continue
+		}
+		if dispatcher != nil {
+			dispatcher.Dispatch(m.notifier, m.title, m.content, m.detail, m.urgent)
+			continue
+		}
+		if err := m.notifier.Send(m.title, m.content, m.detail); err != nil {
+			log.Printf("发送%s通知失败: %v", m.notifier.Name(), err)
+		}
+	}
+}
+
+// formatDigest 将事件按电视剧分组生成汇总消息的内容和详情
+func formatDigest(events []Event) (string, string) {
+	var shows []string
+	grouped := make(map[string][]Event)
+	successCount, failCount := 0, 0
+	for _, event := range events {
+		show := event.Show
+		if show == "" {
+			show = "其他"
+		}
+		if _, ok := grouped[show]; !ok {
+			shows = append(shows, show)
+		}
+		grouped[show] = append(grouped[show], event)
+		if event.Level == LevelInfo {
+			successCount++
+		} else {
+			failCount++
+		}
+	}
+
+	var detail strings.Builder
+	for i, show := range shows {
+		if i > 0 {
+			detail.WriteString("\n")
+		}
+		fmt.Fprintf(&detail, "【%s】\n", show)
+		for _, event := range grouped[show] {
+			summary := event.Summary
+			if summary == "" {
+				summary = event.Title + ": " + event.Content
+			}
+			mark := "✓"
+			if event.Level != LevelInfo {
+				mark = "✗"
+			}
+			fmt.Fprintf(&detail, "%s %s\n", mark, summary)
+		}
+	}
+
+	content := fmt.Sprintf("共 %d 部电视剧，成功 %d 个，失败 %d 个", len(shows), successCount, failCount)
+	return content, strings.TrimRight(detail.String(), "\n")
+}
@@ -0,0 +1,125 @@
+package notify
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeNotifier 记录发送内容的测试通知渠道
+type fakeNotifier struct {
+	sent []string
+}
+
+func (f *fakeNotifier) Name() string { return "fake" }
+
+func (f *fakeNotifier) Send(title, content, detail string) error {
+	f.sent = append(f.sent, title+"|"+content+"|"+detail)
+	return nil
+}
+
+// TestHub_DigestMode 测试汇总模式下一轮事件合并为一条消息
+func TestHub_DigestMode(t *testing.T) {
+	fake := &fakeNotifier{}
+	hub := NewHub()
+	hub.SetChannel(fake, Options{Mode: ModeDigest})
+
+	hub.Publish(Event{Level: LevelInfo, Show: "庆余年", Title: "种子下载成功", Summary: "S02E01"})
+	hub.Publish(Event{Level: LevelInfo, Show: "琅琊榜", Title: "种子下载成功", Summary: "S01E01"})
+	hub.Publish(Event{Level: LevelError, Show: "庆余年", Title: "种子下载失败", Summary: "S02E02"})
+	assert.Empty(t, fake.sent, "汇总模式下不应立即发送")
+
+	hub.Flush()
+	if assert.Len(t, fake.sent, 1) {
+		msg := fake.sent[0]
+		assert.Contains(t, msg, "共 2 部电视剧，成功 2 个，失败 1 个")
+		// 同一部剧的事件归在一组
+		assert.Less(t, strings.Index(msg, "S02E02"), strings.Index(msg, "【琅琊榜】"))
+	}
+
+	// 再次 Flush 没有新事件，不应发送
+	hub.Flush()
+	assert.Len(t, fake.sent, 1)
+}
+
+// TestHub_CriticalSentImmediately 测试严重错误在汇总模式下也立即发送
+func TestHub_CriticalSentImmediately(t *testing.T) {
+	fake := &fakeNotifier{}
+	hub := NewHub()
+	hub.SetChannel(fake, Options{Mode: ModeDigest})
+
+	hub.Publish(Event{Level: LevelCritical, Title: "站点Cookie已失效"})
+	assert.Len(t, fake.sent, 1)
+
+	hub.Flush()
+	assert.Len(t, fake.sent, 1)
+}
+
+// TestHub_InstantMode 测试即时模式逐条发送
+func TestHub_InstantMode(t *testing.T) {
+	fake := &fakeNotifier{}
+	hub := NewHub()
+	hub.SetChannel(fake, Options{Mode: ModeInstant})
+
+	hub.Publish(Event{Level: LevelInfo, Title: "种子下载成功"})
+	hub.Publish(Event{Level: LevelInfo, Title: "种子下载成功"})
+	assert.Len(t, fake.sent, 2)
+}
+
+// TestHub_DailyDigest 测试每日汇总在指定时间后发送且每天只发送一次
+func TestHub_DailyDigest(t *testing.T) {
+	fake := &fakeNotifier{}
+	hub := NewHub()
+	hub.SetChannel(fake, Options{Mode: ModeInstant, DailyAt: "21:00"})
+
+	hub.Publish(Event{Level: LevelInfo, Show: "庆余年", Title: "种子下载成功"})
+	assert.Len(t, fake.sent, 1)
+
+	day := time.Date(2025, 6, 1, 0, 0, 0, 0, time.Local)
+	hub.SendDailyDigests(day.Add(20 * time.Hour))
+	assert.Len(t, fake.sent, 1, "未到发送时间")
+
+	hub.SendDailyDigests(day.Add(21 * time.Hour))
+	if assert.Len(t, fake.sent, 2) {
+		assert.True(t, strings.HasPrefix(fake.sent[1], "每日订阅汇总|"))
+	}
+
+	hub.Publish(Event{Level: LevelInfo, Show: "庆余年", Title: "种子下载成功"})
+	hub.SendDailyDigests(day.Add(22 * time.Hour))
+	assert.Len(t, fake.sent, 3, "当天已发送过每日汇总")
+
+	hub.SendDailyDigests(day.Add(45 * time.Hour))
+	assert.Len(t, fake.sent, 4, "第二天到点后发送")
+}
+
+// TestBatch_FlushOnlyOwnEvents 测试两轮处理同时进行时，每轮只汇总发送自己的事件
+func TestBatch_FlushOnlyOwnEvents(t *testing.T) {
+	fake := &fakeNotifier{}
+	hub := NewHub()
+	hub.SetChannel(fake, Options{Mode: ModeDigest})
+
+	first := hub.NewBatch()
+	second := hub.NewBatch()
+	first.Publish(Event{Level: LevelInfo, Show: "庆余年", Title: "种子下载成功", Summary: "S02E01"})
+	second.Publish(Event{Level: LevelInfo, Show: "琅琊榜", Title: "种子下载成功", Summary: "S01E01"})
+	second.Publish(Event{Level: LevelCritical, Title: "站点Cookie已失效"})
+	assert.Len(t, fake.sent, 1, "严重错误立即发送")
+
+	first.Flush()
+	if assert.Len(t, fake.sent, 2) {
+		assert.Contains(t, fake.sent[1], "S02E01")
+		assert.NotContains(t, fake.sent[1], "琅琊榜", "不发送另一轮的事件")
+	}
+
+	hub.Flush()
+	assert.Len(t, fake.sent, 2, "批次中的事件不缓存到通知中心")
+
+	second.Flush()
+	if assert.Len(t, fake.sent, 3) {
+		assert.Contains(t, fake.sent[2], "S01E01")
+	}
+	second.Flush()
+	assert.Len(t, fake.sent, 3)
+}
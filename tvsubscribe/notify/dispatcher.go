@@ -0,0 +1,317 @@
+package notify
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"tvsubscribe/storage"
+)
+
+// DefaultDedupWindow 默认的重复事件去重窗口
+const DefaultDedupWindow = 6 * time.Hour
+
+// DispatchOptions 通知分发选项
+type DispatchOptions struct {
+	DedupWindow time.Duration // 相同事件在窗口内只发送一次
+	QuietHours  string        // 免打扰时段（HH:MM-HH:MM，可跨零点），为空表示不启用
+}
+
+// ParseQuietHours 解析 HH:MM-HH:MM 格式的免打扰时段，返回起止分钟数
+func ParseQuietHours(s string) (start, end int, err error) {
+	parts := strings.SplitN(strings.TrimSpace(s), "-", 2)
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("免打扰时段格式错误，应为 HH:MM-HH:MM: %s", s)
+	}
+	startHour, startMinute, err := ParseDailyAt(parts[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("免打扰时段格式错误，应为 HH:MM-HH:MM: %s", s)
+	}
+	endHour, endMinute, err := ParseDailyAt(parts[1])
+	if err != nil {
+		return 0, 0, fmt.Errorf("免打扰时段格式错误，应为 HH:MM-HH:MM: %s", s)
+	}
+	return startHour*60 + startMinute, endHour*60 + endMinute, nil
+}
+
+// queuedMessage 排队等待发送的消息
+type queuedMessage struct {
+	Notifier string    `json:"notifier"`
+	Title    string    `json:"title"`
+	Content  string    `json:"content"`
+	Detail   string    `json:"detail"`
+	Digest   bool      `json:"digest,omitempty"` // 汇总消息，不受限流限制
+	QueuedAt time.Time `json:"queued_at"`
+}
+
+// dispatcherState 需要在重启后保留的分发状态
+type dispatcherState struct {
+	Seen  map[string]time.Time   `json:"seen"`  // 事件去重键 -> 最近一次出现时间
+	Sent  map[string][]time.Time `json:"sent"`  // 渠道名称 -> 最近一小时的发送时间
+	Queue []queuedMessage        `json:"queue"` // 等待发送的消息
+}
+
+// Dispatcher 通知分发器，负责事件去重、按渠道限流和免打扰时段排队
+type Dispatcher struct {
+	mu         sync.Mutex
+	options    DispatchOptions
+	statePath  string
+	state      dispatcherState
+	notifiers  map[string]Notifier
+	rateLimits map[string]int // 渠道名称 -> 每小时最多发送条数，0 表示不限制
+	now        func() time.Time
+}
+
+// NewDispatcher 创建通知分发器，statePath 为空时状态只保存在内存中
+func NewDispatcher(statePath string) (*Dispatcher, error) {
+	d := &Dispatcher{
+		options:    DispatchOptions{DedupWindow: DefaultDedupWindow},
+		statePath:  statePath,
+		notifiers:  make(map[string]Notifier),
+		rateLimits: make(map[string]int),
+		now:        time.Now,
+	}
+	if err := d.loadState(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// loadState 从状态文件加载分发状态
+func (d *Dispatcher) loadState() error {
+	d.state = dispatcherState{
+		Seen: make(map[string]time.Time),
+		Sent: make(map[string][]time.Time),
+	}
+	if d.statePath == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(d.statePath)
+	if os.IsNotExist(err) || (err == nil && len(data) == 0) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("读取通知状态文件失败: %v", err)
+	}
+
+	if err := json.Unmarshal(data, &d.state); err != nil {
+		return fmt.Errorf("解析通知状态文件失败: %v", err)
+	}
+	if d.state.Seen == nil {
+		d.state.Seen = make(map[string]time.Time)
+	}
+	if d.state.Sent == nil {
+		d.state.Sent = make(map[string][]time.Time)
+	}
+	return nil
+}
+
+// saveState 清理过期记录后保存分发状态，调用方需持有锁
+func (d *Dispatcher) saveState() {
+	now := d.now()
+	for key, seen := range d.state.Seen {
+		if now.Sub(seen) >= d.options.DedupWindow {
+			delete(d.state.Seen, key)
+		}
+	}
+	for name := range d.state.Sent {
+		d.pruneSent(name, now)
+	}
+
+	if d.statePath == "" {
+		return
+	}
+	data, err := json.MarshalIndent(d.state, "", "  ")
+	if err != nil {
+		log.Printf("序列化通知状态失败: %v", err)
+		return
+	}
+	if err := storage.WriteFile(d.statePath, data, storage.FileMode); err != nil {
+		log.Printf("写入通知状态文件失败: %v", err)
+	}
+}
+
+// pruneSent 删除一小时之前的发送记录，调用方需持有锁
+func (d *Dispatcher) pruneSent(name string, now time.Time) {
+	sent := d.state.Sent[name]
+	kept := sent[:0]
+	for _, t := range sent {
+		if now.Sub(t) < time.Hour {
+			kept = append(kept, t)
+		}
+	}
+	if len(kept) == 0 {
+		delete(d.state.Sent, name)
+		return
+	}
+	d.state.Sent[name] = kept
+}
+
+// SetOptions 更新分发选项
+func (d *Dispatcher) SetOptions(options DispatchOptions) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if options.DedupWindow <= 0 {
+		options.DedupWindow = DefaultDedupWindow
+	}
+	d.options = options
+}
+
+// Register 注册通知渠道及其每小时发送上限（0 表示不限制）
+func (d *Dispatcher) Register(notifier Notifier, rateLimitPerHour int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.notifiers[notifier.Name()] = notifier
+	d.rateLimits[notifier.Name()] = rateLimitPerHour
+}
+
+// eventKey 生成事件的去重键，级别、电视剧、标题和详情都相同视为同一事件
+func eventKey(event Event) string {
+	sum := sha1.Sum([]byte(fmt.Sprintf("%d|%s|%s|%s", event.Level, event.Show, event.Title, event.Detail)))
+	return hex.EncodeToString(sum[:])
+}
+
+// Duplicate 判断事件是否在去重窗口内已经出现过，未出现过则记录下来
+func (d *Dispatcher) Duplicate(event Event) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	key := eventKey(event)
+	now := d.now()
+	if seen, ok := d.state.Seen[key]; ok && now.Sub(seen) < d.options.DedupWindow {
+		return true
+	}
+	d.state.Seen[key] = now
+	d.saveState()
+	return false
+}
+
+// inQuietHours 判断当前是否处于免打扰时段，调用方需持有锁
+func (d *Dispatcher) inQuietHours(now time.Time) bool {
+	if d.options.QuietHours == "" {
+		return false
+	}
+	start, end, err := ParseQuietHours(d.options.QuietHours)
+	if err != nil || start == end {
+		return false
+	}
+	minute := now.Hour()*60 + now.Minute()
+	if start < end {
+		return minute >= start && minute < end
+	}
+	// 跨零点的时段，如 23:00-07:00
+	return minute >= start || minute < end
+}
+
+// allow 判断渠道当前是否还有发送额度，调用方需持有锁
+func (d *Dispatcher) allow(name string, now time.Time) bool {
+	limit := d.rateLimits[name]
+	if limit <= 0 {
+		return true
+	}
+	d.pruneSent(name, now)
+	return len(d.state.Sent[name]) < limit
+}
+
+// Dispatch 分发一条消息：紧急消息直接发送，其余消息在免打扰时段或超出限流时排队
+func (d *Dispatcher) Dispatch(notifier Notifier, title, content, detail string, urgent bool) {
+	d.dispatch(notifier, title, content, detail, urgent, false)
+}
+
+// DispatchDigest 分发一条汇总消息：只在免打扰时段排队，不受限流限制，也不占用渠道的发送额度
+func (d *Dispatcher) DispatchDigest(notifier Notifier, title, content, detail string) {
+	d.dispatch(notifier, title, content, detail, false, true)
+}
+
+// dispatch 分发消息，digest 表示汇总消息
+func (d *Dispatcher) dispatch(notifier Notifier, title, content, detail string, urgent, digest bool) {
+	d.mu.Lock()
+	name := notifier.Name()
+	if _, ok := d.notifiers[name]; !ok {
+		d.notifiers[name] = notifier
+	}
+	now := d.now()
+	limited := !digest && (!d.allow(name, now) || d.hasQueued(name))
+	if !urgent && (d.inQuietHours(now) || limited) {
+		d.state.Queue = append(d.state.Queue, queuedMessage{
+			Notifier: name,
+			Title:    title,
+			Content:  content,
+			Detail:   detail,
+			Digest:   digest,
+			QueuedAt: now,
+		})
+		d.saveState()
+		d.mu.Unlock()
+		return
+	}
+	if !digest {
+		d.state.Sent[name] = append(d.state.Sent[name], now)
+	}
+	d.saveState()
+	d.mu.Unlock()
+
+	if err := notifier.Send(title, content, detail); err != nil {
+		log.Printf("发送%s通知失败: %v", name, err)
+	}
+}
+
+// hasQueued 判断渠道是否有排队中的消息，有则新消息也需排队以保持顺序，调用方需持有锁
+func (d *Dispatcher) hasQueued(name string) bool {
+	for _, m := range d.state.Queue {
+		if m.Notifier == name {
+			return true
+		}
+	}
+	return false
+}
+
+// FlushQueue 在免打扰时段结束且限流允许时发送排队中的消息
+func (d *Dispatcher) FlushQueue() {
+	d.mu.Lock()
+	now := d.now()
+	if d.inQuietHours(now) || len(d.state.Queue) == 0 {
+		d.mu.Unlock()
+		return
+	}
+
+	var toSend []message
+	var remaining []queuedMessage
+	for _, m := range d.state.Queue {
+		notifier, ok := d.notifiers[m.Notifier]
+		if !ok || (!m.Digest && !d.allow(m.Notifier, now)) {
+			// 渠道尚未注册（如重启后）或已达到限流上限，继续排队
+			remaining = append(remaining, m)
+			continue
+		}
+		if !m.Digest {
+			d.state.Sent[m.Notifier] = append(d.state.Sent[m.Notifier], now)
+		}
+		toSend = append(toSend, message{notifier, m.Title, m.Content, m.Detail, false, m.Digest})
+	}
+	d.state.Queue = remaining
+	d.saveState()
+	d.mu.Unlock()
+
+	for _, m := range toSend {
+		if err := m.notifier.Send(m.title, m.content, m.detail); err != nil {
+			log.Printf("发送%s通知失败: %v", m.notifier.Name(), err)
+		}
+	}
+}
+
+// QueueLength 返回排队中的消息数量
+func (d *Dispatcher) QueueLength() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.state.Queue)
+}
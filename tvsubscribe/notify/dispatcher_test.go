@@ -0,0 +1,139 @@
+package notify
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestDispatcher 创建使用固定时钟的分发器
+func newTestDispatcher(t *testing.T, statePath string, now *time.Time) *Dispatcher {
+	d, err := NewDispatcher(statePath)
+	require.NoError(t, err)
+	d.now = func() time.Time { return *now }
+	return d
+}
+
+// TestDispatcher_Dedup 测试相同事件在窗口内只发送一次
+func TestDispatcher_Dedup(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.Local)
+	fake := &fakeNotifier{}
+	hub := NewHub()
+	hub.SetDispatcher(newTestDispatcher(t, "", &now))
+	hub.SetChannel(fake, Options{Mode: ModeInstant})
+
+	event := Event{Level: LevelError, Show: "庆余年", Title: "种子下载失败", Detail: "种子ID: 1\n错误信息: timeout"}
+	hub.Publish(event)
+	hub.Publish(event)
+	assert.Len(t, fake.sent, 1)
+
+	now = now.Add(DefaultDedupWindow)
+	hub.Publish(event)
+	assert.Len(t, fake.sent, 2, "超过去重窗口后应重新发送")
+}
+
+// TestDispatcher_QuietHours 测试免打扰时段内非紧急消息排队，结束后发送
+func TestDispatcher_QuietHours(t *testing.T) {
+	now := time.Date(2025, 6, 1, 23, 30, 0, 0, time.Local)
+	fake := &fakeNotifier{}
+	d := newTestDispatcher(t, "", &now)
+	d.SetOptions(DispatchOptions{QuietHours: "23:00-07:00"})
+
+	d.Dispatch(fake, "种子下载成功", "", "1", false)
+	d.Dispatch(fake, "站点Cookie已失效", "", "2", true)
+	assert.Len(t, fake.sent, 1, "紧急消息不受免打扰限制")
+	assert.Equal(t, 1, d.QueueLength())
+
+	now = now.Add(6 * time.Hour) // 05:30 仍在免打扰时段
+	d.FlushQueue()
+	assert.Len(t, fake.sent, 1)
+
+	now = now.Add(2 * time.Hour) // 07:30
+	d.FlushQueue()
+	assert.Len(t, fake.sent, 2)
+	assert.Equal(t, 0, d.QueueLength())
+}
+
+// TestDispatcher_RateLimit 测试每小时发送上限
+func TestDispatcher_RateLimit(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.Local)
+	fake := &fakeNotifier{}
+	d := newTestDispatcher(t, "", &now)
+	d.Register(fake, 2)
+
+	for i := 0; i < 3; i++ {
+		d.Dispatch(fake, "种子下载成功", "", "", false)
+	}
+	assert.Len(t, fake.sent, 2)
+	assert.Equal(t, 1, d.QueueLength())
+
+	now = now.Add(time.Hour)
+	d.FlushQueue()
+	assert.Len(t, fake.sent, 3)
+}
+
+// TestDispatcher_StatePersisted 测试去重记录和排队消息在重启后保留
+func TestDispatcher_StatePersisted(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "notify_state.json")
+	now := time.Date(2025, 6, 1, 23, 30, 0, 0, time.Local)
+	fake := &fakeNotifier{}
+
+	d := newTestDispatcher(t, statePath, &now)
+	d.SetOptions(DispatchOptions{QuietHours: "23:00-07:00"})
+	event := Event{Level: LevelError, Title: "种子下载失败", Detail: "种子ID: 1"}
+	assert.False(t, d.Duplicate(event))
+	d.Dispatch(fake, "种子下载失败", "", "种子ID: 1", false)
+
+	// 模拟重启
+	restarted := newTestDispatcher(t, statePath, &now)
+	restarted.SetOptions(DispatchOptions{QuietHours: "23:00-07:00"})
+	assert.True(t, restarted.Duplicate(event))
+	assert.Equal(t, 1, restarted.QueueLength())
+
+	now = now.Add(8 * time.Hour)
+	restarted.FlushQueue()
+	assert.Equal(t, 1, restarted.QueueLength(), "渠道注册前消息继续排队")
+	restarted.Register(fake, 0)
+	restarted.FlushQueue()
+	assert.Len(t, fake.sent, 1)
+}
+
+// namedNotifier 可以指定名称的测试通知渠道，用于注册多个渠道
+type namedNotifier struct {
+	fakeNotifier
+	name string
+}
+
+func (n *namedNotifier) Name() string { return n.name }
+
+// TestDispatcher_DigestNotDeduplicated 测试重复事件只在立即发送时去重，汇总中仍然记录，汇总消息不受限流限制
+func TestDispatcher_DigestNotDeduplicated(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.Local)
+	instant := &fakeNotifier{}
+	digest := &namedNotifier{name: "digest"}
+	hub := NewHub()
+	hub.SetDispatcher(newTestDispatcher(t, "", &now))
+	hub.SetChannel(instant, Options{Mode: ModeInstant, DailyAt: "21:00", RateLimitPerHour: 1})
+	hub.SetChannel(digest, Options{Mode: ModeDigest, RateLimitPerHour: 1})
+
+	event := Event{Level: LevelError, Show: "庆余年", Title: "种子下载失败", Summary: "S02E01", Detail: "种子ID: 1\n错误信息: timeout"}
+	hub.Publish(event)
+	hub.Publish(event)
+	assert.Len(t, instant.sent, 1, "立即发送的消息去重")
+
+	hub.Flush()
+	if assert.Len(t, digest.sent, 1) {
+		assert.Contains(t, digest.sent[0], "失败 2 个", "重复事件仍然记入汇总")
+	}
+	hub.Publish(Event{Level: LevelInfo, Show: "琅琊榜", Title: "种子下载成功", Summary: "S01E01"})
+	hub.Flush()
+	assert.Len(t, digest.sent, 2, "汇总消息不受限流限制")
+
+	hub.SendDailyDigests(time.Date(2025, 6, 1, 21, 0, 0, 0, time.Local))
+	if assert.Len(t, instant.sent, 2, "每日汇总不受限流限制") {
+		assert.Contains(t, instant.sent[1], "共 2 部电视剧，成功 1 个，失败 2 个")
+	}
+}
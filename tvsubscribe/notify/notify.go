@@ -0,0 +1,45 @@
+package notify
+
+import (
+	"time"
+)
+
+// Level 通知级别
+type Level int
+
+const (
+	LevelInfo     Level = iota // 普通通知，如种子下载成功
+	LevelError                 // 错误通知，如单个种子下载失败
+	LevelCritical              // 严重错误，如Cookie过期，始终立即发送
+)
+
+// Event 通知事件
+type Event struct {
+	Level   Level     // 通知级别
+	Show    string    // 所属电视剧名称，用于汇总时分组
+	Title   string    // 消息标题
+	Content string    // 消息内容
+	Detail  string    // 消息详情
+	Summary string    // 汇总消息中使用的单行摘要
+	Time    time.Time // 事件发生时间
+}
+
+// Notifier 通知渠道接口
+type Notifier interface {
+	// Name 渠道名称
+	Name() string
+	// Send 发送一条消息
+	Send(title, content, detail string) error
+}
+
+// Publisher 事件发布接口，处理流程通过它上报事件
+type Publisher interface {
+	Publish(event Event)
+}
+
+// Discard 丢弃所有事件的发布者
+var Discard Publisher = discard{}
+
+type discard struct{}
+
+func (discard) Publish(Event) {}
@@ -0,0 +1,79 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// WeChatMessageRequest 微信消息发送请求
+type WeChatMessageRequest struct {
+	Token   string `json:"token"`
+	Title   string `json:"title"`
+	Content string `json:"content"`
+	Detail  string `json:"detail,omitempty"`
+}
+
+// WeChatMessageResponse 微信消息发送响应
+type WeChatMessageResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+	Error   string `json:"error,omitempty"`
+}
+
+// WeChat 微信通知渠道
+type WeChat struct {
+	Server string
+	Token  string
+}
+
+// NewWeChat 创建微信通知渠道
+func NewWeChat(server, token string) *WeChat {
+	return &WeChat{Server: server, Token: token}
+}
+
+// Name 渠道名称
+func (w *WeChat) Name() string {
+	return "wechat"
+}
+
+// Send 发送微信消息
+func (w *WeChat) Send(title, content, detail string) error {
+	if w.Server == "" || w.Token == "" {
+		return fmt.Errorf("微信服务器配置不完整，跳过消息发送")
+	}
+
+	request := WeChatMessageRequest{
+		Token:   w.Token,
+		Title:   title,
+		Content: content,
+		Detail:  detail,
+	}
+
+	jsonData, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("序列化微信消息失败: %v", err)
+	}
+
+	resp, err := http.Post(w.Server+"/send-message", "application/json", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("发送微信消息失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("微信消息发送失败，状态码: %d", resp.StatusCode)
+	}
+
+	var response WeChatMessageResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return fmt.Errorf("解析微信消息响应失败: %v", err)
+	}
+
+	if !response.Success {
+		return fmt.Errorf("微信消息发送失败: %s", response.Error)
+	}
+
+	return nil
+}
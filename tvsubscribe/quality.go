@@ -0,0 +1,118 @@
+package tvsubscribe
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Quality 片源质量，数值越大质量越高
+type Quality int
+
+const (
+	QualityUnknown Quality = iota // 无法识别
+	QualityCam                    // 枪版、TS、TC
+	QualityHDTV                   // 电视录制
+	QualityWEBRip                 // 网络录制
+	QualityWEBDL                  // 网络原盘
+	QualityBluRay                 // 蓝光
+)
+
+// DefaultMovieMinQuality 电影默认的最低片源质量
+const DefaultMovieMinQuality = "web-dl"
+
+// qualityNames 配置和接口中使用的质量名称
+var qualityNames = map[Quality]string{
+	QualityUnknown: "unknown",
+	QualityCam:     "cam",
+	QualityHDTV:    "hdtv",
+	QualityWEBRip:  "webrip",
+	QualityWEBDL:   "web-dl",
+	QualityBluRay:  "bluray",
+}
+
+// qualityPatterns 按质量从高到低匹配种子标题和信息中的关键字
+var qualityPatterns = []struct {
+	quality Quality
+	pattern *regexp.Regexp
+}{
+	{QualityBluRay, regexp.MustCompile(`(?i)blu-?ray|bdrip|bdremux|\bremux\b|\buhd\b|蓝光|原盘`)},
+	{QualityWEBDL, regexp.MustCompile(`(?i)web-?dl`)},
+	{QualityWEBRip, regexp.MustCompile(`(?i)web-?rip|\bweb\b`)},
+	{QualityHDTV, regexp.MustCompile(`(?i)hdtv|tvrip`)},
+	{QualityCam, regexp.MustCompile(`(?i)\bcam\b|hdcam|\bts\b|telesync|\btc\b|hdtc|枪版`)},
+}
+
+// String 返回质量名称
+func (q Quality) String() string {
+	if name, ok := qualityNames[q]; ok {
+		return name
+	}
+	return qualityNames[QualityUnknown]
+}
+
+// ParseQualityName 解析配置中的质量名称，如 web-dl、bluray
+func ParseQualityName(name string) (Quality, error) {
+	normalized := strings.ToLower(strings.TrimSpace(name))
+	for quality, qualityName := range qualityNames {
+		if quality != QualityUnknown && (normalized == qualityName || normalized == strings.ReplaceAll(qualityName, "-", "")) {
+			return quality, nil
+		}
+	}
+	return QualityUnknown, fmt.Errorf("无效的片源质量: %s，可选值: cam, hdtv, webrip, web-dl, bluray", name)
+}
+
+// DetectQuality 根据种子标题和信息识别片源质量
+func DetectQuality(torrentInfo TorrentInfo) Quality {
+	text := torrentInfo.Title + " " + torrentInfo.Info
+	for _, candidate := range qualityPatterns {
+		if candidate.pattern.MatchString(text) {
+			return candidate.quality
+		}
+	}
+	return QualityUnknown
+}
+
+// BestRelease 选出不低于最低质量的最佳版本：质量优先，质量相同时选择体积更大的版本
+func BestRelease(torrentInfos []TorrentInfo, minQuality Quality) (TorrentInfo, bool) {
+	var best TorrentInfo
+	bestQuality := QualityUnknown
+	var bestSize float64
+	found := false
+	for _, torrentInfo := range torrentInfos {
+		quality := DetectQuality(torrentInfo)
+		if quality < minQuality || quality == QualityUnknown {
+			continue
+		}
+		size := parseVolume(torrentInfo.Volume)
+		if !found || quality > bestQuality || (quality == bestQuality && size > bestSize) {
+			best, bestQuality, bestSize, found = torrentInfo, quality, size, true
+		}
+	}
+	return best, found
+}
+
+// volumePattern 匹配种子大小，如 "1.5 GB"、"800MB"
+var volumePattern = regexp.MustCompile(`(?i)([\d.]+)\s*([KMGT]i?B)`)
+
+// parseVolume 将种子大小转换为MB，无法识别时返回0
+func parseVolume(volume string) float64 {
+	match := volumePattern.FindStringSubmatch(volume)
+	if match == nil {
+		return 0
+	}
+	size, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return 0
+	}
+	switch strings.ToUpper(match[2][:1]) {
+	case "K":
+		return size / 1024
+	case "G":
+		return size * 1024
+	case "T":
+		return size * 1024 * 1024
+	}
+	return size
+}
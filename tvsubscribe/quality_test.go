@@ -0,0 +1,61 @@
+package tvsubscribe
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectQuality(t *testing.T) {
+	tests := []struct {
+		title    string
+		expected Quality
+	}{
+		{"The.Wandering.Earth.II.2023.2160p.UHD.BluRay.x265.10bit.HDR", QualityBluRay},
+		{"The.Wandering.Earth.II.2023.1080p.WEB-DL.H264.AAC", QualityWEBDL},
+		{"The.Wandering.Earth.II.2023.1080p.WEBRip.x264", QualityWEBRip},
+		{"The.Wandering.Earth.II.2023.HDTV.1080i", QualityHDTV},
+		{"The.Wandering.Earth.II.2023.HDTC.1080p", QualityCam},
+		{"The.Wandering.Earth.II.2023.1080p", QualityUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			assert.Equal(t, tt.expected, DetectQuality(TorrentInfo{Title: tt.title}))
+		})
+	}
+}
+
+func TestParseQualityName(t *testing.T) {
+	quality, err := ParseQualityName("WEB-DL")
+	require.NoError(t, err)
+	assert.Equal(t, QualityWEBDL, quality)
+
+	quality, err = ParseQualityName("webdl")
+	require.NoError(t, err)
+	assert.Equal(t, QualityWEBDL, quality)
+
+	_, err = ParseQualityName("unknown")
+	assert.Error(t, err)
+}
+
+func TestBestRelease(t *testing.T) {
+	torrents := []TorrentInfo{
+		{ID: "1", Title: "Movie.2023.HDTC.1080p", Volume: "3 GB"},
+		{ID: "2", Title: "Movie.2023.1080p.WEB-DL", Volume: "4.2 GB"},
+		{ID: "3", Title: "Movie.2023.2160p.WEB-DL", Volume: "15.1 GB"},
+		{ID: "4", Title: "Movie.2023.1080p.WEBRip", Volume: "20 GB"},
+	}
+
+	best, ok := BestRelease(torrents, QualityWEBDL)
+	require.True(t, ok)
+	assert.Equal(t, "3", best.ID)
+
+	// 还没有达到最低质量的版本时继续等待
+	_, ok = BestRelease(torrents[:1], QualityWEBDL)
+	assert.False(t, ok)
+
+	best, ok = BestRelease(append(torrents, TorrentInfo{ID: "5", Title: "Movie.2023.1080p.BluRay", Volume: "800 MB"}), QualityWEBDL)
+	require.True(t, ok)
+	assert.Equal(t, "5", best.ID)
+}
@@ -0,0 +1,31 @@
+package scheduler
+
+import "time"
+
+// Clock 时钟接口，便于在测试中替换为可控时钟
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer 定时器接口
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+}
+
+// realClock 使用系统时间的时钟
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) NewTimer(d time.Duration) Timer { return realTimer{time.NewTimer(d)} }
+
+// realTimer 包装 time.Timer
+type realTimer struct {
+	t *time.Timer
+}
+
+func (r realTimer) C() <-chan time.Time { return r.t.C }
+
+func (r realTimer) Stop() bool { return r.t.Stop() }
@@ -0,0 +1,325 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"tvsubscribe"
+)
+
+// DefaultsFunc 返回全局默认调度规则
+type DefaultsFunc func() Spec
+
+// SubscribesFunc 返回当前订阅列表
+type SubscribesFunc func() []tvsubscribe.TVInfo
+
+// RunFunc 处理到期的订阅，manual 表示由立即执行触发；ctx 在停止调度器超时后被取消
+type RunFunc func(ctx context.Context, subscribes []tvsubscribe.TVInfo, manual bool)
+
+// SpecFor 合并全局默认规则和订阅自身的调度设置
+func SpecFor(tvInfo tvsubscribe.TVInfo, defaults Spec) Spec {
+	spec := defaults
+	if tvInfo.Schedule != "" {
+		spec.Cron = tvInfo.Schedule
+	}
+	if tvInfo.ActiveHours != "" {
+		spec.ActiveHours = tvInfo.ActiveHours
+	}
+	return spec
+}
+
+// Planner 根据订阅的元数据计算下一次执行时间，如按剧集播出时间规划
+type Planner interface {
+	// Next 返回订阅在 after 之后的下一次执行时间，返回 false 时使用调度规则
+	Next(tvInfo tvsubscribe.TVInfo, after time.Time) (time.Time, bool)
+	// Key 返回影响计算结果的状态摘要，变化时重新计算执行时间
+	Key(tvInfo tvsubscribe.TVInfo) string
+}
+
+// planKey 计算下一次执行时间时使用的规则
+type planKey struct {
+	spec Spec
+	plan string
+}
+
+// Scheduler 按订阅分别计算下一次执行时间的调度器。
+// 调度循环基于定时器等待，配置或订阅变化、暂停恢复和立即执行都会立刻唤醒循环；
+// 到期的订阅在后台处理，处理期间这些变化同样立即生效。
+type Scheduler struct {
+	mu         sync.Mutex
+	next       map[string]time.Time // 订阅ID -> 下一次执行时间
+	keys       map[string]planKey   // 订阅ID -> 计算下一次执行时间时使用的规则
+	running    map[string]int       // 订阅ID -> 包含它的进行中的处理数
+	held       bool                 // 有正在处理的订阅已到期，处理结束后需要唤醒循环
+	paused     bool
+	runAll     bool // 下一次唤醒时处理所有订阅
+	defaults   DefaultsFunc
+	subscribes SubscribesFunc
+	run        RunFunc
+	planner    Planner
+	clock      Clock
+	rnd        *rand.Rand
+
+	ctx    context.Context // 停止超时后被取消，中止进行中的处理
+	cancel context.CancelFunc
+	runs   sync.WaitGroup
+	wake   chan struct{}
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// New 创建调度器
+func New(defaults DefaultsFunc, subscribes SubscribesFunc, run RunFunc) *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Scheduler{
+		next:       make(map[string]time.Time),
+		keys:       make(map[string]planKey),
+		running:    make(map[string]int),
+		defaults:   defaults,
+		subscribes: subscribes,
+		run:        run,
+		clock:      realClock{},
+		rnd:        rand.New(rand.NewSource(time.Now().UnixNano())),
+		ctx:        ctx,
+		cancel:     cancel,
+		wake:       make(chan struct{}, 1),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+}
+
+// SetPlanner 设置执行时间规划器，没有设置自身调度规则的订阅优先使用规划器的结果
+func (s *Scheduler) SetPlanner(planner Planner) {
+	s.mu.Lock()
+	s.planner = planner
+	s.mu.Unlock()
+	s.signal()
+}
+
+// NextRun 返回订阅的下一次执行时间，调度器暂停时返回 false
+func (s *Scheduler) NextRun(id string) (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.paused {
+		return time.Time{}, false
+	}
+	next, ok := s.next[id]
+	return next, ok
+}
+
+// Reschedule 通知调度器配置或订阅已变化，立即重新计算执行时间
+func (s *Scheduler) Reschedule() {
+	s.signal()
+}
+
+// Pause 暂停定时处理
+func (s *Scheduler) Pause() {
+	s.mu.Lock()
+	s.paused = true
+	s.mu.Unlock()
+	s.signal()
+}
+
+// Resume 恢复定时处理，所有订阅从当前时间重新计算执行时间
+func (s *Scheduler) Resume() {
+	s.mu.Lock()
+	s.paused = false
+	s.next = make(map[string]time.Time)
+	s.keys = make(map[string]planKey)
+	s.mu.Unlock()
+	s.signal()
+}
+
+// Paused 返回调度器是否已暂停
+func (s *Scheduler) Paused() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.paused
+}
+
+// RunNow 立即处理所有启用且未完结的订阅，之后按调度规则继续
+func (s *Scheduler) RunNow() {
+	s.mu.Lock()
+	s.runAll = true
+	s.mu.Unlock()
+	s.signal()
+}
+
+// signal 非阻塞地唤醒调度循环
+func (s *Scheduler) signal() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// collectDue 返回已到期的订阅，并为规则变化或尚未计算的订阅计算下一次执行时间。
+// 正在处理的订阅到期后等处理结束再执行，立即执行不受影响。
+// 没有可等待的订阅时返回零值时间；manual 表示本次由立即执行触发。
+func (s *Scheduler) collectDue(now time.Time) (due []tvsubscribe.TVInfo, earliest time.Time, manual bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	defaults := s.defaults()
+	subscribes := s.subscribes()
+	runAll := s.runAll
+	s.runAll = false
+
+	alive := make(map[string]bool, len(subscribes))
+
+	for _, tv := range subscribes {
+		// 暂停和已完结的订阅不参与定时处理
+		if !tv.Active() {
+			continue
+		}
+		alive[tv.ID] = true
+		spec := SpecFor(tv, defaults)
+		usePlanner := s.planner != nil && tv.Schedule == ""
+		key := planKey{spec: spec}
+		if usePlanner {
+			key.plan = s.planner.Key(tv)
+		}
+		next, ok := s.next[tv.ID]
+		if ok && s.keys[tv.ID] != key {
+			// 调度规则已变化，按新规则从当前时间重新计算
+			ok = false
+		}
+		if runAll || (ok && !s.paused && s.running[tv.ID] == 0 && !next.After(now)) {
+			due = append(due, tv)
+			ok = false
+		}
+		if !ok {
+			planned := false
+			if usePlanner {
+				next, planned = s.planner.Next(tv, now)
+			}
+			if !planned {
+				var err error
+				next, err = spec.Next(now, s.rnd)
+				if err != nil {
+					log.Printf("计算订阅下一次执行时间失败 (ID: %s): %v", tv.ID, err)
+					delete(s.next, tv.ID)
+					delete(s.keys, tv.ID)
+					continue
+				}
+			}
+			s.next[tv.ID] = next
+			s.keys[tv.ID] = key
+		}
+		if s.running[tv.ID] > 0 && !next.After(now) {
+			s.held = true
+			continue
+		}
+		if earliest.IsZero() || next.Before(earliest) {
+			earliest = next
+		}
+	}
+
+	// 清理已删除的订阅
+	for id := range s.next {
+		if !alive[id] {
+			delete(s.next, id)
+			delete(s.keys, id)
+		}
+	}
+
+	if s.paused {
+		earliest = time.Time{}
+	}
+	return due, earliest, runAll
+}
+
+// Start 在后台启动调度循环
+func (s *Scheduler) Start() {
+	go s.loop()
+}
+
+// Stop 停止调度循环并等待进行中的处理结束，超过 ctx 期限后取消进行中的处理
+func (s *Scheduler) Stop(ctx context.Context) error {
+	close(s.stop)
+	<-s.done
+
+	finished := make(chan struct{})
+	go func() {
+		s.runs.Wait()
+		close(finished)
+	}()
+	defer s.cancel()
+
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		s.cancel()
+		<-finished
+		return ctx.Err()
+	}
+}
+
+// loop 调度循环：在后台处理到期订阅后等待最近的执行时间，或被唤醒后重新计算
+func (s *Scheduler) loop() {
+	defer close(s.done)
+
+	for {
+		now := s.clock.Now()
+		due, earliest, manual := s.collectDue(now)
+		if len(due) > 0 {
+			log.Printf("定时任务处理 %d 个到期订阅", len(due))
+			s.startRun(due, manual)
+			continue
+		}
+
+		var timer Timer
+		var timerC <-chan time.Time
+		if !earliest.IsZero() {
+			timer = s.clock.NewTimer(earliest.Sub(now))
+			timerC = timer.C()
+			log.Printf("定时任务将在 %s 执行", earliest.Format("2006-01-02 15:04:05"))
+		}
+
+		select {
+		case <-timerC:
+		case <-s.wake:
+		case <-s.stop:
+			if timer != nil {
+				timer.Stop()
+			}
+			return
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+// startRun 在后台处理到期的订阅，处理期间有订阅到期时结束后唤醒调度循环
+func (s *Scheduler) startRun(due []tvsubscribe.TVInfo, manual bool) {
+	s.mu.Lock()
+	for _, tv := range due {
+		s.running[tv.ID]++
+	}
+	s.mu.Unlock()
+
+	s.runs.Add(1)
+	go func() {
+		defer s.runs.Done()
+		s.run(s.ctx, due, manual)
+
+		s.mu.Lock()
+		for _, tv := range due {
+			if s.running[tv.ID]--; s.running[tv.ID] <= 0 {
+				delete(s.running, tv.ID)
+			}
+		}
+		held := s.held
+		s.held = false
+		s.mu.Unlock()
+		if held {
+			s.signal()
+		}
+	}()
+}
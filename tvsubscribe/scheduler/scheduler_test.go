@@ -0,0 +1,409 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tvsubscribe"
+)
+
+// fakeClock 可手动推进的时钟
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	timers  []*fakeTimer
+	created chan struct{}
+}
+
+// fakeTimer 由 fakeClock 触发的定时器
+type fakeTimer struct {
+	clock    *fakeClock
+	deadline time.Time
+	ch       chan time.Time
+	stopped  bool
+}
+
+func newFakeClock(now time.Time) *fakeClock {
+	return &fakeClock{now: now, created: make(chan struct{}, 100)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) NewTimer(d time.Duration) Timer {
+	c.mu.Lock()
+	t := &fakeTimer{clock: c, deadline: c.now.Add(d), ch: make(chan time.Time, 1)}
+	if d <= 0 {
+		t.stopped = true
+		t.ch <- c.now
+	} else {
+		c.timers = append(c.timers, t)
+	}
+	c.mu.Unlock()
+	c.created <- struct{}{}
+	return t
+}
+
+// Advance 推进时钟并触发到期的定时器
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	remaining := c.timers[:0]
+	for _, t := range c.timers {
+		if t.stopped {
+			continue
+		}
+		if !t.deadline.After(c.now) {
+			t.stopped = true
+			t.ch <- c.now
+			continue
+		}
+		remaining = append(remaining, t)
+	}
+	c.timers = remaining
+}
+
+// waitTimer 等待调度循环创建新的定时器，即进入等待状态
+func (c *fakeClock) waitTimer(t *testing.T) {
+	t.Helper()
+	select {
+	case <-c.created:
+	case <-time.After(2 * time.Second):
+		t.Fatal("调度器没有进入等待状态")
+	}
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.ch }
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	wasActive := !t.stopped
+	t.stopped = true
+	return wasActive
+}
+
+// testScheduler 测试用的调度器及其依赖
+type testScheduler struct {
+	*Scheduler
+	clock *fakeClock
+	runs  chan []tvsubscribe.TVInfo
+
+	mu         sync.Mutex
+	defaults   Spec
+	subscribes []tvsubscribe.TVInfo
+	block      chan struct{} // 不为空时处理阻塞到它被关闭
+}
+
+func newTestScheduler(t *testing.T, defaults Spec, subscribes []tvsubscribe.TVInfo) *testScheduler {
+	ts := &testScheduler{
+		clock:      newFakeClock(time.Date(2025, 6, 6, 10, 0, 0, 0, time.Local)),
+		runs:       make(chan []tvsubscribe.TVInfo, 10),
+		defaults:   defaults,
+		subscribes: subscribes,
+	}
+	ts.Scheduler = New(
+		func() Spec {
+			ts.mu.Lock()
+			defer ts.mu.Unlock()
+			return ts.defaults
+		},
+		func() []tvsubscribe.TVInfo {
+			ts.mu.Lock()
+			defer ts.mu.Unlock()
+			return ts.subscribes
+		},
+		func(ctx context.Context, due []tvsubscribe.TVInfo, manual bool) {
+			ts.runs <- due
+			ts.mu.Lock()
+			block := ts.block
+			ts.mu.Unlock()
+			if block != nil {
+				<-block
+			}
+		},
+	)
+	ts.Scheduler.clock = ts.clock
+	ts.Start()
+	t.Cleanup(func() { ts.Stop(context.Background()) })
+	return ts
+}
+
+func (ts *testScheduler) setDefaults(spec Spec) {
+	ts.mu.Lock()
+	ts.defaults = spec
+	ts.mu.Unlock()
+}
+
+func (ts *testScheduler) setBlock(block chan struct{}) {
+	ts.mu.Lock()
+	ts.block = block
+	ts.mu.Unlock()
+}
+
+func (ts *testScheduler) setSubscribes(subscribes []tvsubscribe.TVInfo) {
+	ts.mu.Lock()
+	ts.subscribes = subscribes
+	ts.mu.Unlock()
+}
+
+// expectRun 等待一次处理并返回到期的订阅ID
+func (ts *testScheduler) expectRun(t *testing.T) []string {
+	t.Helper()
+	select {
+	case due := <-ts.runs:
+		var ids []string
+		for _, tv := range due {
+			ids = append(ids, tv.ID)
+		}
+		return ids
+	case <-time.After(2 * time.Second):
+		t.Fatal("预期的处理没有发生")
+		return nil
+	}
+}
+
+// expectNoRun 确认没有发生处理
+func (ts *testScheduler) expectNoRun(t *testing.T) {
+	t.Helper()
+	select {
+	case due := <-ts.runs:
+		t.Fatalf("不应发生处理，实际处理了 %d 个订阅", len(due))
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// TestSchedulerRunsOnTimer 测试到期后由定时器触发处理
+func TestSchedulerRunsOnTimer(t *testing.T) {
+	ts := newTestScheduler(t, Spec{Cron: "@every 60m"}, []tvsubscribe.TVInfo{{ID: "a", Enabled: true}})
+	ts.clock.waitTimer(t)
+
+	next, ok := ts.NextRun("a")
+	require.True(t, ok)
+	assert.Equal(t, ts.clock.Now().Add(time.Hour), next)
+
+	ts.clock.Advance(59 * time.Minute)
+	ts.expectNoRun(t)
+
+	ts.clock.Advance(time.Minute)
+	assert.Equal(t, []string{"a"}, ts.expectRun(t))
+}
+
+// TestSchedulerIntervalChange 测试修改间隔后立即按新间隔重新计算
+func TestSchedulerIntervalChange(t *testing.T) {
+	ts := newTestScheduler(t, Spec{Cron: "@every 1440m"}, []tvsubscribe.TVInfo{{ID: "a", Enabled: true}})
+	ts.clock.waitTimer(t)
+
+	ts.setDefaults(Spec{Cron: "@every 10m"})
+	ts.Reschedule()
+	ts.clock.waitTimer(t)
+
+	next, ok := ts.NextRun("a")
+	require.True(t, ok)
+	assert.Equal(t, ts.clock.Now().Add(10*time.Minute), next)
+
+	ts.clock.Advance(10 * time.Minute)
+	assert.Equal(t, []string{"a"}, ts.expectRun(t))
+}
+
+// TestSchedulerSubscriptionScheduleOverride 测试订阅自身的调度规则优先于全局默认
+func TestSchedulerSubscriptionScheduleOverride(t *testing.T) {
+	ts := newTestScheduler(t, Spec{Cron: "@every 60m"}, []tvsubscribe.TVInfo{
+		{ID: "a", Enabled: true},
+		{ID: "b", Enabled: true, Schedule: "@every 10m"},
+	})
+	ts.clock.waitTimer(t)
+
+	ts.clock.Advance(10 * time.Minute)
+	assert.Equal(t, []string{"b"}, ts.expectRun(t))
+}
+
+// TestSchedulerPauseResume 测试暂停期间不处理，恢复后重新计算执行时间
+func TestSchedulerPauseResume(t *testing.T) {
+	ts := newTestScheduler(t, Spec{Cron: "@every 10m"}, []tvsubscribe.TVInfo{{ID: "a", Enabled: true}})
+	ts.clock.waitTimer(t)
+
+	ts.Pause()
+	assert.True(t, ts.Paused())
+	_, ok := ts.NextRun("a")
+	assert.False(t, ok)
+
+	ts.clock.Advance(30 * time.Minute)
+	ts.expectNoRun(t)
+
+	ts.Resume()
+	ts.clock.waitTimer(t)
+	assert.False(t, ts.Paused())
+	next, ok := ts.NextRun("a")
+	require.True(t, ok)
+	assert.Equal(t, ts.clock.Now().Add(10*time.Minute), next)
+
+	ts.clock.Advance(10 * time.Minute)
+	assert.Equal(t, []string{"a"}, ts.expectRun(t))
+}
+
+// TestSchedulerRunNow 测试立即执行处理所有订阅并从当前时间重新计算
+func TestSchedulerRunNow(t *testing.T) {
+	ts := newTestScheduler(t, Spec{Cron: "@every 60m"}, []tvsubscribe.TVInfo{{ID: "a", Enabled: true}, {ID: "b", Enabled: true}})
+	ts.clock.waitTimer(t)
+
+	ts.clock.Advance(20 * time.Minute)
+	ts.RunNow()
+	assert.ElementsMatch(t, []string{"a", "b"}, ts.expectRun(t))
+	ts.clock.waitTimer(t)
+
+	next, ok := ts.NextRun("a")
+	require.True(t, ok)
+	assert.Equal(t, ts.clock.Now().Add(time.Hour), next)
+}
+
+// TestSchedulerRunNowWhilePaused 测试暂停时仍可手动立即执行
+func TestSchedulerRunNowWhilePaused(t *testing.T) {
+	ts := newTestScheduler(t, Spec{Cron: "@every 60m"}, []tvsubscribe.TVInfo{{ID: "a", Enabled: true}})
+	ts.clock.waitTimer(t)
+
+	ts.Pause()
+	ts.RunNow()
+	assert.Equal(t, []string{"a"}, ts.expectRun(t))
+	assert.True(t, ts.Paused())
+}
+
+// TestSchedulerChangesDuringRun 测试处理进行中时修改间隔、暂停和立即执行都立即生效
+func TestSchedulerChangesDuringRun(t *testing.T) {
+	ts := newTestScheduler(t, Spec{Cron: "@every 60m"}, []tvsubscribe.TVInfo{{ID: "a", Enabled: true}, {ID: "b", Enabled: true}})
+	ts.clock.waitTimer(t)
+
+	release := make(chan struct{})
+	ts.setBlock(release)
+	ts.RunNow()
+	assert.ElementsMatch(t, []string{"a", "b"}, ts.expectRun(t))
+	ts.clock.waitTimer(t)
+
+	// 处理仍在进行中，修改间隔后立即重新计算
+	ts.setDefaults(Spec{Cron: "@every 10m"})
+	ts.Reschedule()
+	ts.clock.waitTimer(t)
+	next, ok := ts.NextRun("a")
+	require.True(t, ok)
+	assert.Equal(t, ts.clock.Now().Add(10*time.Minute), next)
+
+	// 正在处理的订阅到期后不重复处理
+	ts.clock.Advance(10 * time.Minute)
+	ts.expectNoRun(t)
+
+	ts.Pause()
+	assert.True(t, ts.Paused())
+	_, ok = ts.NextRun("a")
+	assert.False(t, ok)
+
+	// 暂停时立即执行仍然生效
+	ts.RunNow()
+	assert.ElementsMatch(t, []string{"a", "b"}, ts.expectRun(t))
+
+	close(release)
+	ts.clock.Advance(30 * time.Minute)
+	ts.expectNoRun(t)
+}
+
+// TestSchedulerSkipsDisabled 测试暂停和已完结的订阅不参与定时处理和立即执行，启用后重新计算执行时间
+func TestSchedulerSkipsDisabled(t *testing.T) {
+	ts := newTestScheduler(t, Spec{Cron: "@every 10m"}, []tvsubscribe.TVInfo{
+		{ID: "a", Enabled: true},
+		{ID: "b"},
+		{ID: "c", Enabled: true, Completed: true},
+	})
+	ts.clock.waitTimer(t)
+
+	_, ok := ts.NextRun("b")
+	assert.False(t, ok)
+	_, ok = ts.NextRun("c")
+	assert.False(t, ok)
+
+	ts.clock.Advance(10 * time.Minute)
+	assert.Equal(t, []string{"a"}, ts.expectRun(t))
+	ts.clock.waitTimer(t)
+
+	ts.RunNow()
+	assert.Equal(t, []string{"a"}, ts.expectRun(t))
+	ts.clock.waitTimer(t)
+
+	ts.setSubscribes([]tvsubscribe.TVInfo{
+		{ID: "a"},
+		{ID: "b", Enabled: true},
+	})
+	ts.Reschedule()
+	ts.clock.waitTimer(t)
+
+	_, ok = ts.NextRun("a")
+	assert.False(t, ok)
+	next, ok := ts.NextRun("b")
+	require.True(t, ok)
+	assert.Equal(t, ts.clock.Now().Add(10*time.Minute), next)
+
+	ts.clock.Advance(10 * time.Minute)
+	assert.Equal(t, []string{"b"}, ts.expectRun(t))
+}
+
+// fakePlanner 为指定订阅返回固定间隔的规划器
+type fakePlanner struct {
+	mu       sync.Mutex
+	interval time.Duration
+}
+
+func (p *fakePlanner) Next(tvInfo tvsubscribe.TVInfo, after time.Time) (time.Time, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if tvInfo.DouBanID != "planned" {
+		return time.Time{}, false
+	}
+	return after.Add(p.interval), true
+}
+
+func (p *fakePlanner) Key(tvInfo tvsubscribe.TVInfo) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.interval.String()
+}
+
+// TestSchedulerPlanner 测试规划器优先于全局规则，订阅自身的调度规则优先于规划器
+func TestSchedulerPlanner(t *testing.T) {
+	ts := newTestScheduler(t, Spec{Cron: "@every 60m"}, []tvsubscribe.TVInfo{
+		{ID: "a", Enabled: true, DouBanID: "planned"},
+		{ID: "b", Enabled: true, DouBanID: "planned", Schedule: "@every 30m"},
+		{ID: "c", Enabled: true, DouBanID: "other"},
+	})
+	ts.clock.waitTimer(t)
+
+	planner := &fakePlanner{interval: 10 * time.Minute}
+	ts.SetPlanner(planner)
+	ts.clock.waitTimer(t)
+
+	now := ts.clock.Now()
+	next, _ := ts.NextRun("a")
+	assert.Equal(t, now.Add(10*time.Minute), next)
+	next, _ = ts.NextRun("b")
+	assert.Equal(t, now.Add(30*time.Minute), next)
+	next, _ = ts.NextRun("c")
+	assert.Equal(t, now.Add(time.Hour), next)
+
+	// 规划结果变化后立即重新计算
+	planner.mu.Lock()
+	planner.interval = 5 * time.Minute
+	planner.mu.Unlock()
+	ts.Reschedule()
+	ts.clock.waitTimer(t)
+	next, _ = ts.NextRun("a")
+	assert.Equal(t, now.Add(5*time.Minute), next)
+
+	ts.clock.Advance(5 * time.Minute)
+	assert.Equal(t, []string{"a"}, ts.expectRun(t))
+}
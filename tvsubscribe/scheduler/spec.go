@@ -0,0 +1,96 @@
+package scheduler
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// cronParser 支持标准5段cron表达式和 @every、@daily 等描述符
+var cronParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// Spec 订阅的调度规则
+type Spec struct {
+	Cron        string        // cron表达式，如 "*/10 * * * 5,6"、"@every 60m"
+	ActiveHours string        // 活跃时段（HH:MM-HH:MM，可跨零点），为空表示全天
+	Jitter      time.Duration // 随机延迟上限，避免所有订阅同时请求站点
+}
+
+// ValidateCron 校验cron表达式
+func ValidateCron(expr string) error {
+	if _, err := cronParser.Parse(strings.TrimSpace(expr)); err != nil {
+		return fmt.Errorf("无效的cron表达式 %q: %v", expr, err)
+	}
+	return nil
+}
+
+// ParseActiveHours 解析 HH:MM-HH:MM 格式的活跃时段，返回起止分钟数
+func ParseActiveHours(s string) (start, end int, err error) {
+	parts := strings.SplitN(strings.TrimSpace(s), "-", 2)
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("活跃时段格式错误，应为 HH:MM-HH:MM: %s", s)
+	}
+	startTime, err1 := time.Parse("15:04", strings.TrimSpace(parts[0]))
+	endTime, err2 := time.Parse("15:04", strings.TrimSpace(parts[1]))
+	if err1 != nil || err2 != nil {
+		return 0, 0, fmt.Errorf("活跃时段格式错误，应为 HH:MM-HH:MM: %s", s)
+	}
+	return startTime.Hour()*60 + startTime.Minute(), endTime.Hour()*60 + endTime.Minute(), nil
+}
+
+// inWindow 判断时间是否在活跃时段内
+func inWindow(t time.Time, start, end int) bool {
+	if start == end {
+		return true
+	}
+	minute := t.Hour()*60 + t.Minute()
+	if start < end {
+		return minute >= start && minute < end
+	}
+	// 跨零点的时段，如 22:00-02:00
+	return minute >= start || minute < end
+}
+
+// nextWindowStart 返回 t 之后活跃时段的下一个开始时间
+func nextWindowStart(t time.Time, start int) time.Time {
+	ws := time.Date(t.Year(), t.Month(), t.Day(), start/60, start%60, 0, 0, t.Location())
+	if !ws.After(t) {
+		ws = ws.AddDate(0, 0, 1)
+	}
+	return ws
+}
+
+// Next 计算 after 之后的下一次执行时间，rnd 为空时不加随机延迟
+func (s Spec) Next(after time.Time, rnd *rand.Rand) (time.Time, error) {
+	schedule, err := cronParser.Parse(strings.TrimSpace(s.Cron))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("无效的cron表达式 %q: %v", s.Cron, err)
+	}
+
+	next := schedule.Next(after)
+	if next.IsZero() {
+		return time.Time{}, fmt.Errorf("cron表达式 %q 没有可执行的时间", s.Cron)
+	}
+	if s.ActiveHours != "" {
+		start, end, err := ParseActiveHours(s.ActiveHours)
+		if err != nil {
+			return time.Time{}, err
+		}
+		// 落在活跃时段外时，从下一个时段开始重新计算，最多向后查找一年
+		for i := 0; i < 366 && !next.IsZero() && !inWindow(next, start, end); i++ {
+			ws := nextWindowStart(next, start)
+			next = schedule.Next(ws.Add(-time.Second))
+		}
+		if next.IsZero() || !inWindow(next, start, end) {
+			return time.Time{}, fmt.Errorf("cron表达式 %q 在活跃时段 %s 内没有可执行的时间", s.Cron, s.ActiveHours)
+		}
+	}
+
+	if s.Jitter > 0 && rnd != nil {
+		next = next.Add(time.Duration(rnd.Int63n(int64(s.Jitter))))
+	}
+	return next, nil
+}
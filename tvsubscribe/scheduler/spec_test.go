@@ -0,0 +1,85 @@
+package scheduler
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSpecNext 测试cron表达式与活跃时段组合后的下一次执行时间
+func TestSpecNext(t *testing.T) {
+	// 2025-06-06 是周五
+	base := time.Date(2025, 6, 6, 10, 3, 0, 0, time.Local)
+
+	tests := []struct {
+		name     string
+		spec     Spec
+		after    time.Time
+		expected time.Time
+	}{
+		{
+			name:     "固定间隔",
+			spec:     Spec{Cron: "@every 60m"},
+			after:    base,
+			expected: base.Add(time.Hour),
+		},
+		{
+			name:     "播出日每10分钟",
+			spec:     Spec{Cron: "*/10 * * * 5,6"},
+			after:    base,
+			expected: time.Date(2025, 6, 6, 10, 10, 0, 0, time.Local),
+		},
+		{
+			name:     "非播出日顺延到下一个播出日",
+			spec:     Spec{Cron: "*/10 * * * 5,6"},
+			after:    time.Date(2025, 6, 8, 12, 0, 0, 0, time.Local),
+			expected: time.Date(2025, 6, 13, 0, 0, 0, 0, time.Local),
+		},
+		{
+			name:     "活跃时段外顺延到时段开始",
+			spec:     Spec{Cron: "*/10 * * * *", ActiveHours: "18:00-23:00"},
+			after:    base,
+			expected: time.Date(2025, 6, 6, 18, 0, 0, 0, time.Local),
+		},
+		{
+			name:     "跨零点的活跃时段",
+			spec:     Spec{Cron: "0 * * * *", ActiveHours: "22:00-02:00"},
+			after:    time.Date(2025, 6, 6, 1, 30, 0, 0, time.Local),
+			expected: time.Date(2025, 6, 6, 22, 0, 0, 0, time.Local),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next, err := tt.spec.Next(tt.after, nil)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, next)
+		})
+	}
+}
+
+// TestSpecNext_Jitter 测试随机延迟不超过上限
+func TestSpecNext_Jitter(t *testing.T) {
+	after := time.Date(2025, 6, 6, 10, 0, 0, 0, time.Local)
+	spec := Spec{Cron: "0 * * * *", Jitter: 5 * time.Minute}
+	rnd := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 20; i++ {
+		next, err := spec.Next(after, rnd)
+		require.NoError(t, err)
+		assert.False(t, next.Before(after.Add(time.Hour)))
+		assert.True(t, next.Before(after.Add(time.Hour+5*time.Minute)))
+	}
+}
+
+// TestValidateCron 测试cron表达式校验
+func TestValidateCron(t *testing.T) {
+	assert.NoError(t, ValidateCron("*/10 * * * 5,6"))
+	assert.NoError(t, ValidateCron("@daily"))
+	assert.Error(t, ValidateCron("every 10 minutes"))
+	_, _, err := ParseActiveHours("25:00-02:00")
+	assert.Error(t, err)
+}
@@ -0,0 +1,341 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"tvsubscribe/auth"
+)
+
+// identityKey 通过认证的请求身份在 gin.Context 中的键
+const identityKey = "identity"
+
+// requireRole 认证请求并检查角色：使用 Authorization: Bearer 令牌或登录会话Cookie，
+// 未认证返回401，权限不足返回403
+func (s *Server) requireRole(role auth.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		identity, ok := s.authenticate(c)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "未登录或API令牌无效",
+			})
+			return
+		}
+		if !identity.Role.Allows(role) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"success": false,
+				"message": "权限不足，需要管理员权限",
+			})
+			return
+		}
+		c.Set(identityKey, identity)
+		c.Next()
+	}
+}
+
+// authenticate 识别请求的身份，请求带有 Authorization 头时只使用API令牌
+func (s *Server) authenticate(c *gin.Context) (auth.Identity, bool) {
+	if header := c.GetHeader("Authorization"); header != "" {
+		plain, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok {
+			return auth.Identity{}, false
+		}
+		token, ok := s.auth.VerifyToken(strings.TrimSpace(plain))
+		if !ok {
+			return auth.Identity{}, false
+		}
+		return auth.Identity{Name: token.Name, Role: token.Role, Method: auth.MethodToken, TokenID: token.ID}, true
+	}
+
+	id, err := c.Cookie(auth.SessionCookie)
+	if err != nil {
+		return auth.Identity{}, false
+	}
+	session, ok := s.sessions.Get(id)
+	if !ok {
+		return auth.Identity{}, false
+	}
+	return auth.Identity{Name: session.Username, Role: session.Role, Method: auth.MethodSession}, true
+}
+
+// identity 返回 requireRole 认证的请求身份
+func identity(c *gin.Context) auth.Identity {
+	value, _ := c.Get(identityKey)
+	identity, _ := value.(auth.Identity)
+	return identity
+}
+
+// setSessionCookie 设置登录会话Cookie，HTTPS请求时只通过HTTPS发送
+func (s *Server) setSessionCookie(c *gin.Context, session auth.Session) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     auth.SessionCookie,
+		Value:    session.ID,
+		Path:     "/",
+		MaxAge:   int(s.sessions.TTL().Seconds()),
+		HttpOnly: true,
+		Secure:   c.Request.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// clearSessionCookie 删除登录会话Cookie
+func clearSessionCookie(c *gin.Context) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     auth.SessionCookie,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   c.Request.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// authError 返回用户和令牌操作失败的响应，不存在时返回404
+func authError(c *gin.Context, err error) {
+	status := http.StatusBadRequest
+	if errors.Is(err, auth.ErrNotFound) {
+		status = http.StatusNotFound
+	}
+	c.JSON(status, gin.H{
+		"success": false,
+		"message": err.Error(),
+	})
+}
+
+// loginKeys 返回登录尝试限制使用的键：客户端IP地址和用户名。
+// 使用连接的IP地址而不是 X-Forwarded-For，避免伪造请求头绕过限制
+func loginKeys(c *gin.Context, username string) []string {
+	return []string{"ip:" + c.RemoteIP(), "user:" + strings.ToLower(username)}
+}
+
+// login 使用用户名和密码登录，成功后设置会话Cookie；
+// 同一个IP地址或用户名连续失败多次后暂时锁定，返回429
+func (s *Server) login(c *gin.Context) {
+	var req struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的JSON格式: " + err.Error(),
+		})
+		return
+	}
+
+	keys := loginKeys(c, req.Username)
+	if wait := s.logins.Wait(keys...); wait > 0 {
+		seconds := int(math.Ceil(wait.Seconds()))
+		c.Header("Retry-After", strconv.Itoa(seconds))
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"success": false,
+			"message": fmt.Sprintf("登录失败次数过多，请 %d 秒后重试", seconds),
+		})
+		return
+	}
+
+	user, err := s.auth.Authenticate(req.Username, req.Password)
+	if err != nil {
+		s.logins.Fail(keys...)
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	// 只清除用户名的失败记录，IP地址的记录到期后清除，避免用一个已知账号的登录重置对其他账号的尝试次数
+	s.logins.Reset(keys[1])
+	session, err := s.sessions.Create(user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "创建会话失败: " + err.Error(),
+		})
+		return
+	}
+	s.setSessionCookie(c, session)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "登录成功",
+		"data":    auth.Identity{Name: user.Username, Role: user.Role, Method: auth.MethodSession},
+	})
+}
+
+// logout 退出登录，删除会话和Cookie
+func (s *Server) logout(c *gin.Context) {
+	if id, err := c.Cookie(auth.SessionCookie); err == nil {
+		s.sessions.Delete(id)
+	}
+	clearSessionCookie(c)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "已退出登录",
+	})
+}
+
+// me 返回当前请求的身份和角色
+func (s *Server) me(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    identity(c),
+	})
+}
+
+// changePassword 修改当前登录用户的密码，修改后该用户的其他会话失效
+func (s *Server) changePassword(c *gin.Context) {
+	current := identity(c)
+	if current.Method != auth.MethodSession {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "使用API令牌时不能修改密码",
+		})
+		return
+	}
+
+	var req struct {
+		OldPassword string `json:"old_password"`
+		NewPassword string `json:"new_password"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的JSON格式: " + err.Error(),
+		})
+		return
+	}
+	user, err := s.auth.Authenticate(current.Name, req.OldPassword)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "原密码错误",
+		})
+		return
+	}
+	if err := s.auth.SetPassword(user.Username, req.NewPassword); err != nil {
+		authError(c, err)
+		return
+	}
+
+	// 其他设备上的会话失效，当前会话换成新的会话
+	s.sessions.DeleteUser(user.Username)
+	if session, err := s.sessions.Create(user); err == nil {
+		s.setSessionCookie(c, session)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "密码已修改",
+	})
+}
+
+// listUsers 获取全部用户
+func (s *Server) listUsers(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    s.auth.Users(),
+	})
+}
+
+// addUser 添加用户
+func (s *Server) addUser(c *gin.Context) {
+	var req struct {
+		Username string    `json:"username"`
+		Password string    `json:"password"`
+		Role     auth.Role `json:"role"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的JSON格式: " + err.Error(),
+		})
+		return
+	}
+	if req.Role == "" {
+		req.Role = auth.RoleReadOnly
+	}
+	if err := s.auth.AddUser(req.Username, req.Password, req.Role); err != nil {
+		authError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "用户已添加",
+	})
+}
+
+// removeUser 删除用户，该用户的会话立即失效
+func (s *Server) removeUser(c *gin.Context) {
+	username := c.Param("username")
+	if err := s.auth.RemoveUser(username); err != nil {
+		authError(c, err)
+		return
+	}
+	s.sessions.DeleteUser(username)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "用户已删除",
+	})
+}
+
+// listTokens 获取全部API令牌，不返回令牌本身
+func (s *Server) listTokens(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    s.auth.Tokens(),
+	})
+}
+
+// createToken 创建API令牌，令牌只在这次响应中返回
+func (s *Server) createToken(c *gin.Context) {
+	var req struct {
+		Name string    `json:"name"`
+		Role auth.Role `json:"role"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的JSON格式: " + err.Error(),
+		})
+		return
+	}
+	if req.Role == "" {
+		req.Role = auth.RoleReadOnly
+	}
+	plain, token, err := s.auth.CreateToken(req.Name, req.Role, identity(c).Name)
+	if err != nil {
+		authError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "令牌已创建，请立即保存，之后无法再次查看",
+		"data": gin.H{
+			"token": plain,
+			"info":  token,
+		},
+	})
+}
+
+// revokeToken 删除API令牌，删除后立即失效
+func (s *Server) revokeToken(c *gin.Context) {
+	if err := s.auth.RevokeToken(c.Param("id")); err != nil {
+		authError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "令牌已删除",
+	})
+}
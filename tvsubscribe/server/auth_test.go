@@ -0,0 +1,186 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tvsubscribe/auth"
+	"tvsubscribe/config"
+	"tvsubscribe/interfaces"
+)
+
+// stubConfigManager 只返回固定配置的配置管理器，其他方法不会被认证测试调用
+type stubConfigManager struct {
+	interfaces.ConfigManager
+	cfg config.Config
+}
+
+func (m *stubConfigManager) GetConfig() config.Config {
+	return m.cfg
+}
+
+// newAuthTestServer 创建使用内存用户存储的服务器，包含管理员 admin 和只读用户 viewer，
+// 返回服务器、管理员令牌和只读令牌
+func newAuthTestServer(t *testing.T) (*Server, string, string) {
+	store, err := auth.NewStore("")
+	require.NoError(t, err)
+	_, err = store.EnsureAdmin("admin-password")
+	require.NoError(t, err)
+	require.NoError(t, store.AddUser("viewer", "viewer-password", auth.RoleReadOnly))
+	adminToken, _, err := store.CreateToken("脚本", auth.RoleAdmin, "admin")
+	require.NoError(t, err)
+	readToken, _, err := store.CreateToken("监控", auth.RoleReadOnly, "admin")
+	require.NoError(t, err)
+
+	server := NewServer(&stubConfigManager{}, nil, nil, nil, store, nil, nil, t.TempDir())
+	return server, adminToken, readToken
+}
+
+// serve 发送请求并返回响应，header 为额外的请求头
+func serve(s *Server, method, path, body string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for key, value := range header {
+		req.Header.Set(key, value)
+	}
+	w := httptest.NewRecorder()
+	s.engine.ServeHTTP(w, req)
+	return w
+}
+
+// login 登录并返回会话Cookie
+func login(t *testing.T, s *Server, username, password string) string {
+	w := serve(s, http.MethodPost, "/api/auth/login", `{"username":"`+username+`","password":"`+password+`"}`, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == auth.SessionCookie {
+			assert.True(t, cookie.HttpOnly)
+			return cookie.Name + "=" + cookie.Value
+		}
+	}
+	t.Fatal("登录响应没有设置会话Cookie")
+	return ""
+}
+
+// TestRequireRoleUnauthenticated 测试没有凭据或 Authorization 头无效时返回401
+func TestRequireRoleUnauthenticated(t *testing.T) {
+	server, adminToken, _ := newAuthTestServer(t)
+	cookie := login(t, server, "admin", "admin-password")
+
+	tests := []struct {
+		name   string
+		header map[string]string
+	}{
+		{"没有凭据", nil},
+		{"不是Bearer", map[string]string{"Authorization": "Basic " + adminToken}},
+		{"Bearer没有令牌", map[string]string{"Authorization": "Bearer"}},
+		{"小写bearer", map[string]string{"Authorization": "bearer " + adminToken}},
+		{"无效令牌", map[string]string{"Authorization": "Bearer tvs_invalid"}},
+		{"无效会话", map[string]string{"Cookie": auth.SessionCookie + "=invalid"}},
+		{"有Authorization头时不使用Cookie", map[string]string{"Authorization": "Bearer tvs_invalid", "Cookie": cookie}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(server, http.MethodGet, "/api/auth/me", "", tt.header)
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+			var resp struct {
+				Success bool   `json:"success"`
+				Message string `json:"message"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.False(t, resp.Success)
+			assert.NotEmpty(t, resp.Message)
+		})
+	}
+}
+
+// TestRequireRoleForbidden 测试只读令牌和只读用户的会话调用管理接口时返回403
+func TestRequireRoleForbidden(t *testing.T) {
+	server, _, readToken := newAuthTestServer(t)
+	cookie := login(t, server, "viewer", "viewer-password")
+
+	for name, header := range map[string]map[string]string{
+		"只读令牌": {"Authorization": "Bearer " + readToken},
+		"只读会话": {"Cookie": cookie},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, http.StatusForbidden, serve(server, http.MethodGet, "/api/auth/tokens", "", header).Code)
+			assert.Equal(t, http.StatusForbidden, serve(server, http.MethodPost, "/setConfig", `{"port":9000}`, header).Code)
+			assert.Equal(t, http.StatusOK, serve(server, http.MethodGet, "/api/auth/me", "", header).Code, "只读接口可以访问")
+		})
+	}
+}
+
+// TestRequireRoleAllowed 测试管理员令牌和会话可以访问管理接口，并返回请求的身份
+func TestRequireRoleAllowed(t *testing.T) {
+	server, adminToken, _ := newAuthTestServer(t)
+	cookie := login(t, server, "admin", "admin-password")
+
+	tests := []struct {
+		name     string
+		header   map[string]string
+		identity string // 令牌的身份为令牌名称，会话的身份为用户名
+		method   string
+	}{
+		{"令牌", map[string]string{"Authorization": "Bearer " + adminToken}, "脚本", auth.MethodToken},
+		{"会话Cookie", map[string]string{"Cookie": cookie}, "admin", auth.MethodSession},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(server, http.MethodGet, "/api/auth/me", "", tt.header)
+			require.Equal(t, http.StatusOK, w.Code)
+			var resp struct {
+				Data auth.Identity `json:"data"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, tt.identity, resp.Data.Name)
+			assert.Equal(t, auth.RoleAdmin, resp.Data.Role)
+			assert.Equal(t, tt.method, resp.Data.Method)
+
+			assert.Equal(t, http.StatusOK, serve(server, http.MethodGet, "/api/auth/tokens", "", tt.header).Code)
+		})
+	}
+
+	// 退出后会话失效
+	serve(server, http.MethodPost, "/api/auth/logout", "", map[string]string{"Cookie": cookie})
+	assert.Equal(t, http.StatusUnauthorized, serve(server, http.MethodGet, "/api/auth/me", "", map[string]string{"Cookie": cookie}).Code)
+}
+
+// TestPublicRoutes 测试健康检查和登录接口不需要认证
+func TestPublicRoutes(t *testing.T) {
+	server, _, _ := newAuthTestServer(t)
+
+	assert.Equal(t, http.StatusOK, serve(server, http.MethodGet, "/health", "", nil).Code)
+
+	w := serve(server, http.MethodPost, "/api/auth/login", `{"username":"admin","password":"wrong-password"}`, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code, "登录接口可以访问，密码错误返回401")
+	assert.Equal(t, http.StatusOK, serve(server, http.MethodPost, "/api/auth/logout", "", nil).Code)
+}
+
+// TestLoginLockout 测试连续登录失败后锁定，锁定期间正确的密码也返回429
+func TestLoginLockout(t *testing.T) {
+	server, _, _ := newAuthTestServer(t)
+
+	for i := 0; i < auth.DefaultLoginFreeAttempts; i++ {
+		w := serve(server, http.MethodPost, "/api/auth/login", `{"username":"admin","password":"wrong-password"}`, nil)
+		require.Equal(t, http.StatusUnauthorized, w.Code)
+	}
+	w := serve(server, http.MethodPost, "/api/auth/login", `{"username":"admin","password":"wrong-password"}`, nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code, "超过允许的次数后这次失败开始锁定")
+
+	w = serve(server, http.MethodPost, "/api/auth/login", `{"username":"admin","password":"admin-password"}`, nil)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+
+	w = serve(server, http.MethodPost, "/api/auth/login", `{"username":"ADMIN","password":"admin-password"}`, map[string]string{"X-Forwarded-For": "10.0.0.9"})
+	assert.Equal(t, http.StatusTooManyRequests, w.Code, "用户名不区分大小写，伪造 X-Forwarded-For 不能绕过锁定")
+}
@@ -1,45 +1,75 @@
 package server
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"net/http"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"tvsubscribe"
+	"tvsubscribe/auth"
+	"tvsubscribe/config"
+	"tvsubscribe/history"
 	"tvsubscribe/interfaces"
+	"tvsubscribe/scheduler"
+	"tvsubscribe/storage"
+	"tvsubscribe/subscribe"
+	"tvsubscribe/wishlist"
 )
 
-// ProcessTVSubscribesFunc 处理电视剧订阅的函数类型
-type ProcessTVSubscribesFunc func()
+// ProcessSubscribesFunc 处理一批订阅的函数类型，trigger 为触发来源，用于处理记录
+type ProcessSubscribesFunc func(trigger string, subscribes []tvsubscribe.TVInfo)
 
-// ProcessSingleTVFunc 处理单个电视剧的函数类型
-type ProcessSingleTVFunc func(tvInfo tvsubscribe.TVInfo)
+// SyncWishlistFunc 立即同步豆瓣想看列表的函数类型
+type SyncWishlistFunc func(ctx context.Context) (wishlist.Result, error)
 
 // Server HTTP服务器
 type Server struct {
-	configManager       interfaces.ConfigManager
-	subscribeManager    interfaces.SubscribeManager
-	engine              *gin.Engine
-	processTVSubscribes ProcessTVSubscribesFunc
-	processSingleTV     ProcessSingleTVFunc
+	configManager     interfaces.ConfigManager
+	subscribeManager  interfaces.SubscribeManager
+	scheduler         interfaces.Scheduler
+	runHistory        interfaces.RunHistory
+	auth              interfaces.Authenticator
+	sessions          *auth.Sessions
+	logins            *auth.LoginLimiter // 按IP地址和用户名限制登录失败次数
+	engine            *gin.Engine
+	httpServer        *http.Server
+	redirectServer    *http.Server // 使用HTTPS时把HTTP请求重定向到HTTPS，未启用时为 nil
+	processSubscribes ProcessSubscribesFunc
+	syncWishlist      SyncWishlistFunc
+	webDir            string // Web界面的构建目录
 }
 
-// NewServer 创建新的HTTP服务器
-func NewServer(configManager interfaces.ConfigManager, subscribeManager interfaces.SubscribeManager, processTVSubscribes ProcessTVSubscribesFunc, processSingleTV ProcessSingleTVFunc) *Server {
+// NewServer 创建新的HTTP服务器，webDir 为Web界面的构建目录，authenticator 用于认证API请求
+func NewServer(configManager interfaces.ConfigManager, subscribeManager interfaces.SubscribeManager, scheduler interfaces.Scheduler, runHistory interfaces.RunHistory, authenticator interfaces.Authenticator, processSubscribes ProcessSubscribesFunc, syncWishlist SyncWishlistFunc, webDir string) *Server {
 	gin.SetMode(gin.ReleaseMode)
 	engine := gin.New()
 	engine.Use(gin.Logger(), gin.Recovery())
 
 	server := &Server{
-		configManager:       configManager,
-		subscribeManager:    subscribeManager,
-		engine:              engine,
-		processTVSubscribes: processTVSubscribes,
-		processSingleTV:     processSingleTV,
+		configManager:     configManager,
+		subscribeManager:  subscribeManager,
+		scheduler:         scheduler,
+		runHistory:        runHistory,
+		auth:              authenticator,
+		sessions:          auth.NewSessions(auth.DefaultSessionTTL),
+		logins:            auth.NewLoginLimiter(auth.DefaultLoginFreeAttempts, auth.DefaultLoginLockout, auth.DefaultLoginMaxLockout),
+		engine:            engine,
+		processSubscribes: processSubscribes,
+		syncWishlist:      syncWishlist,
+		webDir:            webDir,
+	}
+	server.httpServer = &http.Server{
+		Addr:    server.listenAddr(server.port()),
+		Handler: engine,
 	}
 
 	server.setupRoutes()
@@ -49,19 +79,28 @@ func NewServer(configManager interfaces.ConfigManager, subscribeManager interfac
 // setupRoutes 设置路由
 func (s *Server) setupRoutes() {
 	// 静态文件服务 - 如果存在构建文件则提供Web界面
-	s.engine.Static("/assets", "./web/dist/assets")
-	s.engine.StaticFile("/", "./web/dist/index.html")
+	s.engine.Static("/assets", filepath.Join(s.webDir, "assets"))
+	s.engine.StaticFile("/", filepath.Join(s.webDir, "index.html"))
 
 	// 处理SPA路由 - 所有未匹配的路由都返回index.html
 	s.engine.NoRoute(func(c *gin.Context) {
 		// 如果是API请求，返回404
 		path := c.Request.URL.Path
-		if path == "/getConfig" ||
+		if strings.HasPrefix(path, "/api/") ||
+		   path == "/getConfig" ||
 		   path == "/setConfig" ||
+		   path == "/validateConfig" ||
 		   path == "/getSubscribeList" ||
 		   path == "/addSubscribe" ||
 		   path == "/delSubscribe" ||
 		   path == "/triggerNow" ||
+		   path == "/pauseSubscribe" ||
+		   path == "/resumeSubscribe" ||
+		   path == "/reactivateSubscribe" ||
+		   path == "/getSchedulerStatus" ||
+		   path == "/pauseScheduler" ||
+		   path == "/resumeScheduler" ||
+		   path == "/runNow" ||
 		   path == "/searchDouBan" ||
 		   path == "/health" ||
 		   path == "/proxy/image" {
@@ -72,79 +111,211 @@ func (s *Server) setupRoutes() {
 			return
 		}
 		// 对于其他路径，返回index.html以支持Vue Router
-		c.File("./web/dist/index.html")
+		c.File(filepath.Join(s.webDir, "index.html"))
 	})
 
+	// 登录和退出不需要认证，其他API按角色授权：查看需要只读权限，修改需要管理员权限
+	s.engine.POST("/api/auth/login", s.login)
+	s.engine.POST("/api/auth/logout", s.logout)
+	read := s.engine.Group("", s.requireRole(auth.RoleReadOnly))
+	admin := s.engine.Group("", s.requireRole(auth.RoleAdmin))
+
+	// 账号和API令牌
+	read.GET("/api/auth/me", s.me)
+	read.POST("/api/auth/password", s.changePassword)
+	admin.GET("/api/auth/users", s.listUsers)
+	admin.POST("/api/auth/users", s.addUser)
+	admin.DELETE("/api/auth/users/:username", s.removeUser)
+	admin.GET("/api/auth/tokens", s.listTokens)
+	admin.POST("/api/auth/tokens", s.createToken)
+	admin.DELETE("/api/auth/tokens/:id", s.revokeToken)
+
 	// 配置相关API
-	s.engine.GET("/getConfig", s.getConfig)
-	s.engine.POST("/setConfig", s.setConfig)
+	read.GET("/getConfig", s.getConfig)
+	admin.POST("/setConfig", s.setConfig)
+	admin.POST("/validateConfig", s.validateConfig)
+	read.GET("/api/config/effective", s.getEffectiveConfig)
 
 	// 订阅相关API
-	s.engine.GET("/getSubscribeList", s.getSubscribeList)
-	s.engine.POST("/addSubscribe", s.addSubscribe)
-	s.engine.POST("/delSubscribe", s.delSubscribe)
-	s.engine.POST("/triggerNow", s.triggerNow)
+	read.GET("/getSubscribeList", s.getSubscribeList)
+	admin.POST("/addSubscribe", s.addSubscribe)
+	admin.POST("/delSubscribe", s.delSubscribe)
+	admin.POST("/triggerNow", s.triggerNow)
+	admin.POST("/pauseSubscribe", s.pauseSubscribe)
+	admin.POST("/resumeSubscribe", s.resumeSubscribe)
+	admin.POST("/reactivateSubscribe", s.reactivateSubscribe)
+	admin.PATCH("/api/subscribes/:id", s.updateSubscribe)
+	read.GET("/api/subscribes/export", s.exportSubscribes)
+	admin.POST("/api/subscribes/import", s.importSubscribes)
+
+	// 调度器相关API
+	read.GET("/getSchedulerStatus", s.getSchedulerStatus)
+	admin.POST("/pauseScheduler", s.pauseScheduler)
+	admin.POST("/resumeScheduler", s.resumeScheduler)
+	admin.POST("/runNow", s.runNow)
+
+	// 处理记录API
+	read.GET("/api/runs", s.listRuns)
+	read.GET("/api/runs/:id", s.getRun)
 
 	// 豆瓣搜索
-	s.engine.GET("/searchDouBan", s.searchDouBan)
+	read.GET("/searchDouBan", s.searchDouBan)
+	admin.POST("/api/wishlist/sync", s.syncWishlistNow)
 
 	// 豆瓣图片代理
-	s.engine.GET("/proxy/image", s.proxyImage)
+	read.GET("/proxy/image", s.proxyImage)
 
 	// 健康检查
 	s.engine.GET("/health", s.health)
 }
 
-// getConfig 获取配置
+// getConfig 获取配置，敏感配置项以掩码返回
 func (s *Server) getConfig(c *gin.Context) {
 	configInterface := s.configManager.GetConfig()
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"data":    configInterface,
+		"data":    configInterface.Masked(),
 	})
 }
 
-// setConfig 设置配置
-func (s *Server) setConfig(c *gin.Context) {
-	var updateData map[string]interface{}
-	if err := c.ShouldBindJSON(&updateData); err != nil {
+// getEffectiveConfig 获取生效的配置项和路径选项，以及每一项来自默认值、配置文件、环境变量还是命令行参数
+func (s *Server) getEffectiveConfig(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    s.configManager.EffectiveConfig(),
+	})
+}
+
+// configError 返回配置修改失败的响应，校验失败时在 data.errors 中列出每个配置项的错误
+func configError(c *gin.Context, err error) {
+	var validationErr *config.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
 		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
-			"message": "无效的JSON格式: " + err.Error(),
+			"message": err.Error(),
+			"data":    validationErr,
+		})
+	case errors.Is(err, storage.ErrConflict):
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
 		})
-		return
 	}
+}
 
-	// 更新配置
-	if err := s.configManager.UpdateConfig(updateData); err != nil {
+// bindConfigPatch 解析配置修改请求，请求体是只包含需要修改的配置项的JSON对象
+func bindConfigPatch(c *gin.Context) (config.Patch, bool) {
+	var patch config.Patch
+	if err := c.ShouldBindJSON(&patch); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
-			"message": err.Error(),
+			"message": "无效的JSON格式: " + err.Error(),
 		})
+		return nil, false
+	}
+	return patch, true
+}
+
+// setConfig 设置配置，只修改请求中出现的配置项，值为 null 时清空该配置项或恢复默认值，值为掩码的敏感配置项保持不变
+func (s *Server) setConfig(c *gin.Context) {
+	patch, ok := bindConfigPatch(c)
+	if !ok {
+		return
+	}
+
+	// 更新配置
+	updatedConfig, err := s.configManager.UpdateConfig(patch)
+	if err != nil {
+		configError(c, err)
 		return
 	}
 
+	// 调度规则可能已变化，立即按新配置重新计算执行时间
+	s.scheduler.Reschedule()
+
 	// 配置更新成功后，立即执行一次电视剧订阅处理
 	go func() {
 		log.Println("配置已更新，立即执行电视剧订阅处理")
-		s.processTVSubscribes()
+		s.processSubscribes(history.TriggerConfig, s.activeSubscribes())
 	}()
 
 	// 返回更新后的配置
-	updatedConfig := s.configManager.GetConfig()
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "配置更新成功，正在立即处理订阅",
-		"data":    updatedConfig,
+		"data":    updatedConfig.Masked(),
+	})
+}
+
+// validateConfig 校验配置修改但不保存，返回修改后的完整配置
+func (s *Server) validateConfig(c *gin.Context) {
+	patch, ok := bindConfigPatch(c)
+	if !ok {
+		return
+	}
+
+	candidate, err := s.configManager.ValidateConfig(patch)
+	if err != nil {
+		configError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "配置校验通过",
+		"data":    candidate.Masked(),
 	})
 }
 
-// getSubscribeList 获取订阅列表
+// activeSubscribes 返回所有启用且未完结的订阅
+func (s *Server) activeSubscribes() []tvsubscribe.TVInfo {
+	var active []tvsubscribe.TVInfo
+	for _, subscribe := range s.subscribeManager.GetSubscribes() {
+		if subscribe.Active() {
+			active = append(active, subscribe)
+		}
+	}
+	return active
+}
+
+// subscribeView 订阅列表中返回的订阅信息，附带下一次执行时间
+type subscribeView struct {
+	tvsubscribe.TVInfo
+	NextRunAt *time.Time `json:"next_run_at,omitempty"`
+}
+
+// getSubscribeList 获取订阅列表，archived=true 只返回已完结的订阅，archived=false 只返回未完结的订阅
 func (s *Server) getSubscribeList(c *gin.Context) {
+	archived := c.Query("archived")
+	if archived != "" && archived != "true" && archived != "false" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "archived参数必须是true或false",
+		})
+		return
+	}
+
 	subscribes := s.subscribeManager.GetSubscribes()
+	views := make([]subscribeView, 0, len(subscribes))
+	for _, subscribe := range subscribes {
+		if archived != "" && subscribe.Completed != (archived == "true") {
+			continue
+		}
+		view := subscribeView{TVInfo: subscribe}
+		if next, ok := s.scheduler.NextRun(subscribe.ID); ok && subscribe.Active() {
+			view.NextRunAt = &next
+		}
+		views = append(views, view)
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"data":    subscribes,
+		"data":    views,
 	})
 }
 
@@ -170,9 +341,66 @@ func (s *Server) addSubscribe(c *gin.Context) {
 	if tvInfo.Resolution <= 0 {
 		tvInfo.Resolution = 1 // 默认分辨率
 	}
+	if tvInfo.Schedule != "" {
+		if err := scheduler.ValidateCron(tvInfo.Schedule); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	}
+	if tvInfo.ActiveHours != "" {
+		if _, _, err := scheduler.ParseActiveHours(tvInfo.ActiveHours); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	}
+	if !tvsubscribe.ValidKind(tvInfo.Kind) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的订阅类型: " + tvInfo.Kind + "，可选值为 series 或 movie",
+		})
+		return
+	}
+	if !tvsubscribe.ValidResolution(tvInfo.Resolution) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": fmt.Sprintf("无效的分辨率: %d", tvInfo.Resolution),
+		})
+		return
+	}
+	if tvInfo.MinQuality != "" {
+		if _, err := tvsubscribe.ParseQualityName(tvInfo.MinQuality); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	}
+	if tvInfo.Filters != nil {
+		if err := tvInfo.Filters.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	}
+	if err := s.validateTeamPolicy(tvInfo.TeamPolicy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
 
 	// 添加订阅
-	if err := s.subscribeManager.AddSubscribe(tvInfo); err != nil {
+	if err := s.subscribeManager.AddSubscribe(c.Request.Context(), tvInfo); err != nil {
 		c.JSON(http.StatusConflict, gin.H{
 			"success": false,
 			"message": err.Error(),
@@ -180,10 +408,17 @@ func (s *Server) addSubscribe(c *gin.Context) {
 		return
 	}
 
+	s.scheduler.Reschedule()
+
+	// 获取保存后的订阅，包含生成的ID和名称
+	if added, ok := s.findSubscribe(tvInfo.DouBanID, tvInfo.Resolution); ok {
+		tvInfo = added
+	}
+
 	// 订阅添加成功后，立即查询和下载该订阅的种子
 	go func() {
 		log.Printf("新订阅添加成功，立即处理豆瓣ID: %s, 分辨率: %d", tvInfo.DouBanID, tvInfo.Resolution)
-		s.processSingleTV(tvInfo)
+		s.processSubscribes(history.TriggerAdd, []tvsubscribe.TVInfo{tvInfo})
 	}()
 
 	c.JSON(http.StatusOK, gin.H{
@@ -193,6 +428,171 @@ func (s *Server) addSubscribe(c *gin.Context) {
 	})
 }
 
+// validateTeamPolicy 校验订阅的制作组设置，制作组名称必须已在 site_teams 中配置
+func (s *Server) validateTeamPolicy(policy *tvsubscribe.TeamPolicy) error {
+	if policy == nil || policy.Mode == "" {
+		return nil
+	}
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+	_, err := tvsubscribe.ResolveTeams(policy.Teams, s.configManager.GetConfig().SiteTeams)
+	return err
+}
+
+// updateSubscribe 按ID修改订阅，只修改请求中提供的字段
+func (s *Server) updateSubscribe(c *gin.Context) {
+	var patch tvsubscribe.TVInfoPatch
+	if err := c.ShouldBindJSON(&patch); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的JSON格式: " + err.Error(),
+		})
+		return
+	}
+
+	if patch.Schedule != nil && *patch.Schedule != "" {
+		if err := scheduler.ValidateCron(*patch.Schedule); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	}
+	if patch.ActiveHours != nil && *patch.ActiveHours != "" {
+		if _, _, err := scheduler.ParseActiveHours(*patch.ActiveHours); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	}
+
+	if err := s.validateTeamPolicy(patch.TeamPolicy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	updated, err := s.subscribeManager.UpdateSubscribe(c.Request.Context(), c.Param("id"), patch)
+	if err != nil {
+		status := http.StatusBadRequest
+		switch {
+		case errors.Is(err, tvsubscribe.ErrSubscribeNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, tvsubscribe.ErrSubscribeExists), errors.Is(err, storage.ErrConflict):
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// 调度规则或启用状态可能已变化
+	s.scheduler.Reschedule()
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "订阅修改成功",
+		"data":    updated,
+	})
+}
+
+// exportSubscribes 导出全部订阅，format 为 json（默认）或 csv
+func (s *Server) exportSubscribes(c *gin.Context) {
+	format := c.DefaultQuery("format", subscribe.FormatJSON)
+	data, err := subscribe.ExportSubscribes(format, s.subscribeManager.GetSubscribes())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	contentType := "application/json; charset=utf-8"
+	if format == subscribe.FormatCSV {
+		contentType = "text/csv; charset=utf-8"
+	}
+	filename := fmt.Sprintf("subscribes-%s.%s", time.Now().Format("20060102"), format)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(http.StatusOK, contentType, data)
+}
+
+// importSubscribes 导入订阅，请求体为导出的文件内容。
+// 查询参数：format 为 json（默认）或 csv，mode 为 merge（默认）或 replace，dry_run=true 时只预览不保存
+func (s *Server) importSubscribes(c *gin.Context) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "读取请求失败: " + err.Error(),
+		})
+		return
+	}
+
+	records, err := subscribe.ParseImport(c.DefaultQuery("format", subscribe.FormatJSON), body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// 制作组名称需要按当前的站点制作组映射解析
+	for i := range records {
+		if records[i].Err == nil {
+			if err := s.validateTeamPolicy(records[i].TV.TeamPolicy); err != nil {
+				records[i].Err = err
+			}
+		}
+	}
+
+	dryRun := c.Query("dry_run") == "true"
+	result, err := s.subscribeManager.ImportSubscribes(c.Request.Context(), records, c.Query("mode"), dryRun)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, tvsubscribe.ErrSubscribeExists) || errors.Is(err, storage.ErrConflict) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	message := fmt.Sprintf("导入预览：添加 %d 个，跳过 %d 个，无效 %d 个", result.Added, result.Skipped, result.Invalid)
+	if !dryRun {
+		// 新订阅可能带有自己的调度规则
+		s.scheduler.Reschedule()
+		message = fmt.Sprintf("导入完成：添加 %d 个，跳过 %d 个，无效 %d 个", result.Added, result.Skipped, result.Invalid)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": message,
+		"data":    result,
+	})
+}
+
+// findSubscribe 按豆瓣ID和分辨率查找订阅
+func (s *Server) findSubscribe(douBanID string, resolution int) (tvsubscribe.TVInfo, bool) {
+	for _, subscribe := range s.subscribeManager.GetSubscribes() {
+		if subscribe.DouBanID == douBanID && subscribe.Resolution == resolution {
+			return subscribe, true
+		}
+	}
+	return tvsubscribe.TVInfo{}, false
+}
+
 // delSubscribe 删除订阅（支持两种模式：旧版兼容和新版ID数组）
 func (s *Server) delSubscribe(c *gin.Context) {
 	var requestBody map[string]interface{}
@@ -242,6 +642,7 @@ func (s *Server) delSubscribe(c *gin.Context) {
 			})
 			return
 		}
+		s.scheduler.Reschedule()
 
 		c.JSON(http.StatusOK, gin.H{
 			"success": true,
@@ -279,6 +680,7 @@ func (s *Server) delSubscribe(c *gin.Context) {
 			})
 			return
 		}
+		s.scheduler.Reschedule()
 
 		c.JSON(http.StatusOK, gin.H{
 			"success": true,
@@ -362,8 +764,8 @@ func (s *Server) triggerNow(c *gin.Context) {
 		for _, subscribe := range subscribesToTrigger {
 			log.Printf("处理订阅 ID=%s, 豆瓣ID=%s, 分辨率=%d",
 				subscribe.ID, subscribe.DouBanID, subscribe.Resolution)
-			s.processSingleTV(subscribe)
 		}
+		s.processSubscribes(history.TriggerManual, subscribesToTrigger)
 		log.Printf("完成处理 %d 个立即触发的订阅", len(subscribesToTrigger))
 	}()
 
@@ -377,6 +779,199 @@ func (s *Server) triggerNow(c *gin.Context) {
 	})
 }
 
+// pauseSubscribe 暂停订阅，暂停的订阅不再定时处理，仍可手动触发
+func (s *Server) pauseSubscribe(c *gin.Context) {
+	s.setSubscribesEnabled(c, false)
+}
+
+// resumeSubscribe 恢复已暂停的订阅
+func (s *Server) resumeSubscribe(c *gin.Context) {
+	s.setSubscribesEnabled(c, true)
+}
+
+// reactivateSubscribe 将已完结归档的订阅重新激活，恢复定时处理
+func (s *Server) reactivateSubscribe(c *gin.Context) {
+	var request struct {
+		IDs []string `json:"ids"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的JSON格式: " + err.Error(),
+		})
+		return
+	}
+	if len(request.IDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "ID列表不能为空",
+		})
+		return
+	}
+
+	changed, err := s.subscribeManager.ReactivateSubscribes(request.IDs)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	s.scheduler.Reschedule()
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": fmt.Sprintf("已重新激活 %d 个订阅", changed),
+		"data": gin.H{
+			"changed_count": changed,
+		},
+	})
+}
+
+// setSubscribesEnabled 根据请求中的ID数组启用或暂停订阅
+func (s *Server) setSubscribesEnabled(c *gin.Context, enabled bool) {
+	var request struct {
+		IDs []string `json:"ids"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的JSON格式: " + err.Error(),
+		})
+		return
+	}
+	if len(request.IDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "ID列表不能为空",
+		})
+		return
+	}
+
+	changed, err := s.subscribeManager.SetSubscribesEnabled(request.IDs, enabled)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+	s.scheduler.Reschedule()
+
+	action := "暂停"
+	if enabled {
+		action = "恢复"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": fmt.Sprintf("已%s %d 个订阅", action, changed),
+		"data": gin.H{
+			"changed_count": changed,
+		},
+	})
+}
+
+// listRuns 获取最近的处理记录，可通过 limit 参数限制条数（默认50）
+func (s *Server) listRuns(c *gin.Context) {
+	limit := 50
+	if value := c.Query("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "limit参数必须是非负整数",
+			})
+			return
+		}
+		limit = n
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    s.runHistory.ListRuns(limit),
+	})
+}
+
+// getRun 获取单条处理记录的详情
+func (s *Server) getRun(c *gin.Context) {
+	run, ok := s.runHistory.GetRun(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "处理记录不存在",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    run,
+	})
+}
+
+// getSchedulerStatus 获取调度器状态
+func (s *Server) getSchedulerStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"paused": s.scheduler.Paused(),
+		},
+	})
+}
+
+// pauseScheduler 暂停定时处理
+func (s *Server) pauseScheduler(c *gin.Context) {
+	s.scheduler.Pause()
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "定时处理已暂停",
+	})
+}
+
+// resumeScheduler 恢复定时处理
+func (s *Server) resumeScheduler(c *gin.Context) {
+	s.scheduler.Resume()
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "定时处理已恢复",
+	})
+}
+
+// runNow 立即处理所有订阅，之后按调度规则继续
+func (s *Server) runNow(c *gin.Context) {
+	s.scheduler.RunNow()
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "已开始处理所有订阅",
+	})
+}
+
+// syncWishlistNow 立即同步豆瓣想看列表，返回新建和归档的订阅
+func (s *Server) syncWishlistNow(c *gin.Context) {
+	if s.configManager.GetConfig().DoubanUser == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "未配置豆瓣用户",
+		})
+		return
+	}
+
+	result, err := s.syncWishlist(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": fmt.Sprintf("同步完成：新增 %d 个订阅，归档 %d 个订阅", len(result.Added), len(result.Archived)),
+		"data":    result,
+	})
+}
+
 // searchDouBan 搜索豆瓣
 func (s *Server) searchDouBan(c *gin.Context) {
 	// 获取查询参数
@@ -390,7 +985,7 @@ func (s *Server) searchDouBan(c *gin.Context) {
 	}
 
 	// 调用豆瓣搜索功能
-	results, err := tvsubscribe.SearchDouBan(name)
+	results, err := tvsubscribe.SearchDouBan(c.Request.Context(), name)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -432,7 +1027,7 @@ func (s *Server) proxyImage(c *gin.Context) {
 	}
 
 	// 创建请求
-	req, err := http.NewRequest("GET", imageURL, nil)
+	req, err := http.NewRequestWithContext(c.Request.Context(), "GET", imageURL, nil)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -493,18 +1088,46 @@ func (s *Server) health(c *gin.Context) {
 	})
 }
 
-// Start 启动服务器
+// listenAddr 返回配置的监听地址上指定端口的地址，未设置时只监听本机
+func (s *Server) listenAddr(port int) string {
+	host := s.configManager.GetConfig().Listen
+	if host == "" {
+		host = config.DefaultListen
+	}
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
+
+// port 返回配置中的监听端口
+func (s *Server) port() int {
+	if port := s.configManager.GetConfig().Port; port > 0 {
+		return port
+	}
+	return 8443 // 默认端口
+}
+
+// Start 启动服务器，调用 EnableTLS 后使用HTTPS，调用 Shutdown 后正常返回 nil
 func (s *Server) Start() error {
-	configMap := s.configManager.GetConfig()
-	port := 8443 // 默认端口
-	if p, ok := configMap["port"].(int); ok {
-		port = p
-	} else if p, ok := configMap["port"].(float64); ok {
-		port = int(p)
-	}
-	addr := fmt.Sprintf(":%d", port)
-	log.Printf("HTTP服务器启动在端口 %d", port)
-	return s.engine.Run(addr)
+	var err error
+	if s.httpServer.TLSConfig != nil {
+		log.Printf("HTTPS服务器启动在 %s", s.httpServer.Addr)
+		s.startRedirect()
+		err = s.httpServer.ListenAndServeTLS("", "")
+	} else {
+		log.Printf("HTTP服务器启动在 %s", s.httpServer.Addr)
+		err = s.httpServer.ListenAndServe()
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Shutdown 停止接收新连接，并在 ctx 期限内等待进行中的请求完成
+func (s *Server) Shutdown(ctx context.Context) error {
+	if s.redirectServer != nil {
+		s.redirectServer.Shutdown(ctx)
+	}
+	return s.httpServer.Shutdown(ctx)
 }
 
 // GetEngine 获取gin引擎（用于测试）
@@ -0,0 +1,51 @@
+package server
+
+import (
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+
+	"tvsubscribe/certs"
+)
+
+// EnableTLS 使用HTTPS，证书由 certificates 提供，证书文件更新后调用其 Reload 即可生效。
+// redirectPort 大于0时在该端口监听HTTP，把请求重定向到HTTPS
+func (s *Server) EnableTLS(certificates *certs.Reloader, redirectPort int) {
+	s.httpServer.TLSConfig = certificates.TLSConfig()
+	if redirectPort > 0 {
+		s.redirectServer = &http.Server{
+			Addr:    s.listenAddr(redirectPort),
+			Handler: http.HandlerFunc(s.redirectToHTTPS),
+		}
+	}
+}
+
+// redirectToHTTPS 把HTTP请求重定向到HTTPS端口上相同的地址。
+// 使用307临时重定向：保留请求方法和请求体，关闭HTTPS后浏览器也不会继续使用缓存的重定向
+func (s *Server) redirectToHTTPS(w http.ResponseWriter, r *http.Request) {
+	host, _, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		host = r.Host
+	}
+	if port := s.port(); port != 443 {
+		host = net.JoinHostPort(host, strconv.Itoa(port))
+	} else if ip := net.ParseIP(host); ip != nil && ip.To4() == nil {
+		host = "[" + host + "]"
+	}
+	http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusTemporaryRedirect)
+}
+
+// startRedirect 启动HTTP重定向服务器，监听失败时只记录日志，不影响HTTPS服务
+func (s *Server) startRedirect() {
+	if s.redirectServer == nil {
+		return
+	}
+	go func() {
+		log.Printf("HTTP重定向服务器启动在 %s，请求重定向到HTTPS", s.redirectServer.Addr)
+		if err := s.redirectServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("HTTP重定向服务器启动失败: %v", err)
+		}
+	}()
+}
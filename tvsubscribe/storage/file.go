@@ -0,0 +1,213 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultBackupKeep 默认保留的备份数量
+	DefaultBackupKeep = 5
+	// DefaultBackupInterval 默认两次备份的最小间隔，频繁写入时不会每次都产生备份
+	DefaultBackupInterval = time.Hour
+
+	// FileMode 数据文件和备份的权限，只有运行程序的用户可以读写，其中可能包含站点Cookie等敏感信息
+	FileMode os.FileMode = 0600
+
+	backupDir        = "backups"
+	backupDirMode    = 0700
+	backupTimeLayout = "20060102-150405"
+)
+
+// ErrCorrupt 数据文件损坏且没有可用的备份
+var ErrCorrupt = errors.New("数据文件已损坏且没有可用的备份")
+
+// File 带滚动备份的数据文件，写入使用临时文件+重命名保证不会写出不完整的文件，
+// 读取时主文件损坏则自动从最新的有效备份恢复。
+// 备份保存在数据文件所在目录的 backups 子目录中，文件名为 <文件名>.<时间戳>.bak
+type File struct {
+	Path     string
+	Keep     int           // 保留的备份数量，0 表示不备份
+	Interval time.Duration // 两次备份的最小间隔，0 表示每次写入都备份
+	// NoRecover 文件损坏时不从备份恢复，直接返回 ErrCorrupt。
+	// 用于运行中重新加载，此时内存中的数据比备份更新
+	NoRecover bool
+}
+
+// NewFile 创建使用默认备份设置的数据文件
+func NewFile(path string) *File {
+	return &File{
+		Path:     path,
+		Keep:     DefaultBackupKeep,
+		Interval: DefaultBackupInterval,
+	}
+}
+
+// Write 原子写入数据文件，并按备份间隔保存一份备份。备份失败只记录日志，不影响写入结果
+func (f *File) Write(data []byte) error {
+	if err := WriteFile(f.Path, data, FileMode); err != nil {
+		return err
+	}
+	if f.Keep > 0 {
+		if err := f.backup(data, time.Now()); err != nil {
+			log.Printf("备份 %s 失败: %v", f.Path, err)
+		}
+	}
+	return nil
+}
+
+// Read 读取数据文件，validate 返回错误表示文件已损坏。
+// 主文件损坏时从最新的有效备份恢复：损坏的文件重命名为 <文件名>.corrupt-<时间戳> 保留，
+// 备份内容写回主文件，recovered 为使用的备份路径。
+// 文件不存在时返回 os.ErrNotExist；没有可用的备份时返回原始内容和 ErrCorrupt
+func (f *File) Read(validate func(data []byte) error) (data []byte, recovered string, err error) {
+	data, err = os.ReadFile(f.Path)
+	if err != nil {
+		return nil, "", err
+	}
+	validateErr := validate(data)
+	if validateErr == nil {
+		return data, "", nil
+	}
+	if f.NoRecover {
+		return data, "", fmt.Errorf("%w: %v", ErrCorrupt, validateErr)
+	}
+
+	backups, err := f.Backups()
+	if err != nil {
+		return data, "", fmt.Errorf("%w: %v，读取备份失败: %v", ErrCorrupt, validateErr, err)
+	}
+	for _, backup := range backups {
+		backupData, err := os.ReadFile(backup)
+		if err != nil || validate(backupData) != nil {
+			continue
+		}
+
+		corruptPath := fmt.Sprintf("%s.corrupt-%s", f.Path, time.Now().Format(backupTimeLayout))
+		if err := os.Rename(f.Path, corruptPath); err != nil {
+			return nil, "", fmt.Errorf("保留损坏的文件失败: %v", err)
+		}
+		if err := WriteFile(f.Path, backupData, FileMode); err != nil {
+			return nil, "", fmt.Errorf("从备份恢复失败: %v", err)
+		}
+		log.Printf("%s 已损坏 (%v)，已从备份 %s 恢复，损坏的文件保存为 %s", f.Path, validateErr, backup, corruptPath)
+		return backupData, backup, nil
+	}
+	return data, "", fmt.Errorf("%w: %v", ErrCorrupt, validateErr)
+}
+
+// Backups 返回数据文件的全部备份路径，最新的在前
+func (f *File) Backups() ([]string, error) {
+	dir := filepath.Join(filepath.Dir(f.Path), backupDir)
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := filepath.Base(f.Path) + "."
+	var backups []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ".bak") {
+			continue
+		}
+		// 时间戳格式固定，按文件名排序即按时间排序
+		if _, err := time.Parse(backupTimeLayout, strings.TrimSuffix(strings.TrimPrefix(name, prefix), ".bak")); err != nil {
+			continue
+		}
+		backups = append(backups, filepath.Join(dir, name))
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(backups)))
+	return backups, nil
+}
+
+// backup 距离最新的备份超过备份间隔时保存一份新备份，并删除超出保留数量的旧备份
+func (f *File) backup(data []byte, now time.Time) error {
+	backups, err := f.Backups()
+	if err != nil {
+		return err
+	}
+	if len(backups) > 0 && f.Interval > 0 {
+		latest := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(backups[0]), filepath.Base(f.Path)+"."), ".bak")
+		if t, err := time.ParseInLocation(backupTimeLayout, latest, now.Location()); err == nil && now.Sub(t) < f.Interval {
+			return nil
+		}
+	}
+
+	dir, err := f.backupDirectory()
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%s.%s.bak", filepath.Base(f.Path), now.Format(backupTimeLayout)))
+	if err := WriteFile(path, data, FileMode); err != nil {
+		return err
+	}
+
+	// 同一秒内的备份会覆盖，重新读取备份列表再清理
+	backups, err = f.Backups()
+	if err != nil {
+		return err
+	}
+	for _, old := range backups[min(f.Keep, len(backups)):] {
+		if err := os.Remove(old); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteFile 原子写入文件：先写入同目录下的临时文件并同步到磁盘，再重命名覆盖目标文件，
+// 写入过程中崩溃或磁盘已满时目标文件保持原样
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("创建临时文件失败: %v", err)
+	}
+	tmpPath := tmp.Name()
+	// 重命名成功后临时文件已不存在，删除失败可以忽略
+	defer os.Remove(tmpPath)
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("写入临时文件失败: %v", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("同步临时文件失败: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("关闭临时文件失败: %v", err)
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		return fmt.Errorf("设置文件权限失败: %v", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("重命名临时文件失败: %v", err)
+	}
+
+	// 同步目录，保证重命名本身已落盘
+	syncDir(dir)
+	return nil
+}
+
+// backupDirectory 创建备份目录并返回路径。之前的版本创建的目录所有用户可读，这里同时收紧权限
+func (f *File) backupDirectory() (string, error) {
+	dir := filepath.Join(filepath.Dir(f.Path), backupDir)
+	if err := os.MkdirAll(dir, backupDirMode); err != nil {
+		return "", err
+	}
+	if err := os.Chmod(dir, backupDirMode); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
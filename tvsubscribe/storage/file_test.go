@@ -0,0 +1,200 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// validJSON 校验文件内容是有效的JSON
+func validJSON(data []byte) error {
+	var v interface{}
+	return json.Unmarshal(data, &v)
+}
+
+// TestWriteFile 测试原子写入覆盖原文件且不留下临时文件
+func TestWriteFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"old": true}`), 0644))
+
+	require.NoError(t, WriteFile(path, []byte(`{"new": true}`), 0600))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, `{"new": true}`, string(data))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "data.json", entries[0].Name())
+
+	// 目录不存在时返回错误，不会写出任何文件
+	assert.Error(t, WriteFile(filepath.Join(dir, "missing", "data.json"), []byte(`{}`), 0644))
+}
+
+// TestBackupRotation 测试按间隔备份并只保留最新的几份
+func TestBackupRotation(t *testing.T) {
+	dir := t.TempDir()
+	file := &File{Path: filepath.Join(dir, "subscribes.json"), Keep: 3, Interval: time.Hour}
+
+	start := time.Date(2025, 6, 1, 20, 0, 0, 0, time.Local)
+	for i := 0; i < 5; i++ {
+		require.NoError(t, file.backup([]byte(`[]`), start.Add(time.Duration(i)*2*time.Hour)))
+	}
+	// 间隔内的写入不产生新备份
+	require.NoError(t, file.backup([]byte(`[]`), start.Add(8*time.Hour+time.Minute)))
+
+	backups, err := file.Backups()
+	require.NoError(t, err)
+	require.Len(t, backups, 3)
+	assert.Equal(t, filepath.Join(dir, "backups", "subscribes.json.20250602-040000.bak"), backups[0])
+	assert.Equal(t, filepath.Join(dir, "backups", "subscribes.json.20250602-020000.bak"), backups[1])
+	assert.Equal(t, filepath.Join(dir, "backups", "subscribes.json.20250602-000000.bak"), backups[2])
+
+	// 其他文件的备份互不影响
+	other := &File{Path: filepath.Join(dir, "config.json"), Keep: 3}
+	require.NoError(t, other.Write([]byte(`{}`)))
+	otherBackups, err := other.Backups()
+	require.NoError(t, err)
+	assert.Len(t, otherBackups, 1)
+	backups, err = file.Backups()
+	require.NoError(t, err)
+	assert.Len(t, backups, 3)
+
+	// 数据文件、备份和备份目录只有当前用户可以访问
+	for _, path := range []string{other.Path, otherBackups[0], backups[0]} {
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, FileMode, info.Mode().Perm(), path)
+	}
+	info, err := os.Stat(filepath.Join(dir, "backups"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0700), info.Mode().Perm())
+}
+
+// TestReadRecoversFromBackup 测试主文件损坏时从最新的有效备份恢复
+func TestReadRecoversFromBackup(t *testing.T) {
+	dir := t.TempDir()
+	file := &File{Path: filepath.Join(dir, "subscribes.json"), Keep: 5, Interval: time.Hour}
+
+	now := time.Now()
+	require.NoError(t, file.backup([]byte(`[{"id": "old"}]`), now.Add(-3*time.Hour)))
+	require.NoError(t, file.backup([]byte(`[{"id": "new"}]`), now.Add(-2*time.Hour)))
+	// 最新的备份本身也损坏了，应跳过
+	require.NoError(t, file.backup([]byte(`[{"id": `), now.Add(-time.Hour)))
+
+	// 主文件正常时直接返回
+	require.NoError(t, os.WriteFile(file.Path, []byte(`[]`), 0644))
+	data, recovered, err := file.Read(validJSON)
+	require.NoError(t, err)
+	assert.Equal(t, `[]`, string(data))
+	assert.Empty(t, recovered)
+
+	// 写入中途崩溃导致的截断
+	require.NoError(t, os.WriteFile(file.Path, []byte(`[{"id": "cur`), 0644))
+	data, recovered, err = file.Read(validJSON)
+	require.NoError(t, err)
+	assert.Equal(t, `[{"id": "new"}]`, string(data))
+	assert.Contains(t, recovered, "subscribes.json.")
+
+	// 恢复的内容已写回主文件，损坏的文件被保留
+	saved, err := os.ReadFile(file.Path)
+	require.NoError(t, err)
+	assert.Equal(t, `[{"id": "new"}]`, string(saved))
+	corrupt, err := filepath.Glob(file.Path + ".corrupt-*")
+	require.NoError(t, err)
+	require.Len(t, corrupt, 1)
+	corruptData, err := os.ReadFile(corrupt[0])
+	require.NoError(t, err)
+	assert.Equal(t, `[{"id": "cur`, string(corruptData))
+}
+
+// TestReadWithoutBackup 测试没有可用备份和文件不存在时的错误
+func TestReadWithoutBackup(t *testing.T) {
+	dir := t.TempDir()
+	file := NewFile(filepath.Join(dir, "config.json"))
+
+	_, _, err := file.Read(validJSON)
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+
+	require.NoError(t, os.WriteFile(file.Path, nil, 0644))
+	data, recovered, err := file.Read(validJSON)
+	assert.ErrorIs(t, err, ErrCorrupt)
+	assert.Empty(t, data)
+	assert.Empty(t, recovered)
+}
+
+// TestLock 测试同一文件不能被重复锁定，释放后可以再次锁定
+func TestLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "subscribes.json")
+
+	lock, err := Lock(path)
+	require.NoError(t, err)
+
+	_, err = Lock(path)
+	assert.ErrorIs(t, err, ErrLocked)
+
+	require.NoError(t, lock.Unlock())
+	lock, err = Lock(path)
+	require.NoError(t, err)
+	require.NoError(t, lock.Unlock())
+}
+
+// TestFileVersion 测试文件版本随内容变化，文件不存在时为空
+func TestFileVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	version, err := FileVersion(path)
+	require.NoError(t, err)
+	assert.Empty(t, version)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{}`), 0644))
+	version, err = FileVersion(path)
+	require.NoError(t, err)
+	assert.Equal(t, Version([]byte(`{}`)), version)
+	assert.NotEqual(t, Version([]byte(`{ }`)), version)
+}
+
+// TestWatch 测试原子替换和直接修改文件都会触发通知，连续修改合并为一次，其他文件的变化被忽略
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "subscribes.json")
+	require.NoError(t, os.WriteFile(path, []byte(`[]`), 0644))
+
+	changed := make(chan string, 10)
+	watcher, err := Watch([]string{path}, 50*time.Millisecond, func(path string) {
+		changed <- path
+	})
+	require.NoError(t, err)
+	defer watcher.Close()
+
+	// 等待通知，超时表示没有通知
+	wait := func() (string, bool) {
+		select {
+		case path := <-changed:
+			return path, true
+		case <-time.After(time.Second):
+			return "", false
+		}
+	}
+
+	require.NoError(t, WriteFile(path, []byte(`[{"id": "a"}]`), 0644))
+	got, ok := wait()
+	require.True(t, ok)
+	assert.Equal(t, path, got)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, os.WriteFile(path, []byte(`[{"id": "b"}]`), 0644))
+	}
+	_, ok = wait()
+	require.True(t, ok)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "config.json"), []byte(`{}`), 0644))
+	_, ok = wait()
+	assert.False(t, ok)
+}
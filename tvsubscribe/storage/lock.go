@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ErrLocked 文件已被其他进程锁定
+var ErrLocked = errors.New("文件已被其他进程锁定")
+
+// FileLock 建议性文件锁，防止两个进程同时使用同一份数据文件
+type FileLock struct {
+	file *os.File
+}
+
+// Lock 对 <path>.lock 加排他锁，锁已被其他进程持有时立即返回 ErrLocked。
+// 进程退出时操作系统自动释放锁，锁文件本身保留
+func Lock(path string) (*FileLock, error) {
+	lockPath := path + ".lock"
+	file, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, FileMode)
+	if err != nil {
+		return nil, fmt.Errorf("打开锁文件失败: %v", err)
+	}
+	if err := lockFile(file); err != nil {
+		file.Close()
+		if errors.Is(err, ErrLocked) {
+			return nil, fmt.Errorf("%w: %s，可能已有另一个 tvsubscribe 进程在运行", ErrLocked, path)
+		}
+		return nil, fmt.Errorf("锁定 %s 失败: %v", path, err)
+	}
+
+	// 记录持有锁的进程，便于排查
+	file.Truncate(0)
+	fmt.Fprintf(file, "%d\n", os.Getpid())
+	return &FileLock{file: file}, nil
+}
+
+// Unlock 释放文件锁
+func (l *FileLock) Unlock() error {
+	if err := unlockFile(l.file); err != nil {
+		l.file.Close()
+		return fmt.Errorf("释放文件锁失败: %v", err)
+	}
+	return l.file.Close()
+}
@@ -0,0 +1,18 @@
+//go:build !unix && !windows
+
+package storage
+
+import "os"
+
+// lockFile 当前平台不支持文件锁，不做限制
+func lockFile(file *os.File) error {
+	return nil
+}
+
+// unlockFile 当前平台不支持文件锁
+func unlockFile(file *os.File) error {
+	return nil
+}
+
+// syncDir 当前平台不同步目录
+func syncDir(dir string) {}
@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"time"
+)
+
+// Migration 数据文件格式的一步升级，把上一个版本的内容升级到 Version
+type Migration struct {
+	Version     int                               // 升级后的格式版本
+	Description string                            // 升级内容，用于日志
+	Migrate     func(data []byte) ([]byte, error) // 升级函数，输入为上一个版本的完整文件内容
+}
+
+// Migrations 数据文件格式的升级链，按版本从低到高排列
+type Migrations struct {
+	// Detect 返回文件内容的格式版本，没有版本号的旧文件按内容推断
+	Detect func(data []byte) (int, error)
+	Steps  []Migration
+}
+
+// Latest 返回当前程序使用的格式版本
+func (m Migrations) Latest() int {
+	if len(m.Steps) == 0 {
+		return 0
+	}
+	return m.Steps[len(m.Steps)-1].Version
+}
+
+// Migrate 把数据文件内容升级到最新的格式版本。
+// 每一步升级前把当前内容备份为 backups/<文件名>.v<版本>-<时间戳>.bak，迁移备份不参与滚动清理；
+// 全部升级完成后原子写回数据文件。返回升级后的内容，migrated 表示是否做了升级。
+// 文件版本高于程序支持的版本时返回错误，不做任何修改
+func (f *File) Migrate(data []byte, migrations Migrations) (result []byte, migrated bool, err error) {
+	version, err := migrations.Detect(data)
+	if err != nil {
+		return nil, false, fmt.Errorf("识别 %s 的格式版本失败: %v", f.Path, err)
+	}
+	latest := migrations.Latest()
+	if version > latest {
+		return nil, false, fmt.Errorf("%s 的格式版本 %d 高于程序支持的版本 %d，请升级程序", f.Path, version, latest)
+	}
+	if version == latest {
+		return data, false, nil
+	}
+
+	now := time.Now()
+	for _, step := range migrations.Steps {
+		if step.Version <= version {
+			continue
+		}
+		backup, err := f.migrationBackup(data, version, now)
+		if err != nil {
+			return nil, false, fmt.Errorf("升级 %s 前备份失败: %v", f.Path, err)
+		}
+		if data, err = step.Migrate(data); err != nil {
+			return nil, false, fmt.Errorf("%s 从版本 %d 升级到 %d 失败: %v", f.Path, version, step.Version, err)
+		}
+		log.Printf("%s 已从版本 %d 升级到 %d: %s，升级前的文件备份为 %s", f.Path, version, step.Version, step.Description, backup)
+		version = step.Version
+	}
+
+	if err := f.Write(data); err != nil {
+		return nil, false, err
+	}
+	return data, true, nil
+}
+
+// migrationBackup 保存升级前的文件内容，返回备份路径
+func (f *File) migrationBackup(data []byte, version int, now time.Time) (string, error) {
+	dir, err := f.backupDirectory()
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%s.v%d-%s.bak", filepath.Base(f.Path), version, now.Format(backupTimeLayout)))
+	if err := WriteFile(path, data, FileMode); err != nil {
+		return "", err
+	}
+	return path, nil
+}
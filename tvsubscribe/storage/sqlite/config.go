@@ -0,0 +1,119 @@
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"tvsubscribe/config"
+)
+
+// ConfigStore 保存在数据库中的配置，实现 config.Store。
+// 每个配置项一行，值为JSON编码，保存时只写入发生变化的配置项
+type ConfigStore struct {
+	db *DB
+}
+
+// Config 返回配置存储
+func (d *DB) Config() *ConfigStore {
+	return &ConfigStore{db: d}
+}
+
+// Load 读取配置，数据库中还没有配置时返回 os.ErrNotExist
+func (s *ConfigStore) Load() (*config.Config, error) {
+	values, err := loadConfigValues(s.db.db)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("数据库 %s 中没有配置: %w", s.db, os.ErrNotExist)
+	}
+
+	data, err := json.Marshal(values)
+	if err != nil {
+		return nil, fmt.Errorf("读取配置失败: %v", err)
+	}
+	var cfg config.Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("解析配置失败: %v", err)
+	}
+	return &cfg, nil
+}
+
+// Save 在一个事务中保存配置
+func (s *ConfigStore) Save(cfg *config.Config) error {
+	tx, err := s.db.db.Begin()
+	if err != nil {
+		return fmt.Errorf("开始数据库事务失败: %v", err)
+	}
+	if err := saveConfig(tx, cfg); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("保存配置失败: %v", err)
+	}
+	return nil
+}
+
+// String 返回数据库文件路径
+func (s *ConfigStore) String() string {
+	return s.db.String()
+}
+
+// loadConfigValues 读取全部配置项
+func loadConfigValues(q querier) (map[string]json.RawMessage, error) {
+	rows, err := q.Query(`SELECT key, value FROM config`)
+	if err != nil {
+		return nil, fmt.Errorf("读取配置失败: %v", err)
+	}
+	defer rows.Close()
+
+	values := make(map[string]json.RawMessage)
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, fmt.Errorf("读取配置失败: %v", err)
+		}
+		values[key] = json.RawMessage(value)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("读取配置失败: %v", err)
+	}
+	return values, nil
+}
+
+// saveConfig 在事务中保存配置，删除配置结构中已不存在的配置项
+func saveConfig(tx *sql.Tx, cfg *config.Config) error {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return fmt.Errorf("序列化配置失败: %v", err)
+	}
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(data, &values); err != nil {
+		return fmt.Errorf("序列化配置失败: %v", err)
+	}
+
+	existing, err := loadConfigValues(tx)
+	if err != nil {
+		return err
+	}
+	for key, value := range values {
+		if old, ok := existing[key]; ok && string(old) == string(value) {
+			continue
+		}
+		if _, err := tx.Exec(`INSERT INTO config (key, value) VALUES (?, ?) ON CONFLICT (key) DO UPDATE SET value = excluded.value`, key, string(value)); err != nil {
+			return fmt.Errorf("保存配置 %s 失败: %v", key, err)
+		}
+	}
+	for key := range existing {
+		if _, ok := values[key]; ok {
+			continue
+		}
+		if _, err := tx.Exec(`DELETE FROM config WHERE key = ?`, key); err != nil {
+			return fmt.Errorf("删除配置 %s 失败: %v", key, err)
+		}
+	}
+	return nil
+}
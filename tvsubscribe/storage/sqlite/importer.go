@@ -0,0 +1,91 @@
+package sqlite
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"tvsubscribe"
+	"tvsubscribe/config"
+	"tvsubscribe/subscribe"
+)
+
+// metaJSONImported 记录已从JSON文件导入的元数据键，值为导入时间
+const metaJSONImported = "json_imported_at"
+
+// ImportResult JSON文件导入的结果
+type ImportResult struct {
+	Imported   bool // 是否执行了导入，数据库已导入过或已有数据时为 false
+	Subscribes int  // 导入的订阅数量
+	Config     bool // 是否导入了配置
+}
+
+// ImportJSON 将 subscribes.json 和 config.json 一次性导入数据库。
+// 数据库已导入过或已有订阅、配置时不做任何修改；文件不存在时跳过对应的部分。
+// 导入在一个事务中完成，JSON文件除了升级旧的文件格式之外保持原样，之后不再读取
+func (d *DB) ImportJSON(subscribesPath, configPath string) (ImportResult, error) {
+	imported, err := getMeta(d.db, metaJSONImported)
+	if err != nil {
+		return ImportResult{}, err
+	}
+	if imported != "" {
+		return ImportResult{}, nil
+	}
+	var count int
+	if err := d.db.QueryRow(`SELECT (SELECT COUNT(*) FROM subscribes) + (SELECT COUNT(*) FROM config)`).Scan(&count); err != nil {
+		return ImportResult{}, fmt.Errorf("读取数据库失败: %v", err)
+	}
+	if count > 0 {
+		return ImportResult{}, nil
+	}
+
+	// 使用JSON存储读取，旧版本的文件同时完成字段补全，损坏的文件从备份恢复。
+	// 先读取配置：版本1配置文件中的订阅在升级时先保存到订阅文件，再随订阅文件一起导入
+	var subscribeStore *subscribe.JSONStore
+	if subscribesPath != "" {
+		subscribeStore = subscribe.NewJSONStore(subscribesPath)
+	}
+	var cfg *config.Config
+	if configPath != "" {
+		configStore := config.NewJSONStore(configPath)
+		if subscribeStore != nil {
+			configStore.LegacySubscribes = subscribe.LegacyImporter(subscribeStore)
+		}
+		cfg, err = configStore.Load()
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return ImportResult{}, err
+		}
+	}
+	var subscribes []tvsubscribe.TVInfo
+	if subscribeStore != nil {
+		subscribes, _, err = subscribeStore.Load()
+		if err != nil {
+			return ImportResult{}, err
+		}
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return ImportResult{}, fmt.Errorf("开始数据库事务失败: %v", err)
+	}
+	if err := saveSubscribes(tx, subscribes); err != nil {
+		tx.Rollback()
+		return ImportResult{}, err
+	}
+	if cfg != nil {
+		if err := saveConfig(tx, cfg); err != nil {
+			tx.Rollback()
+			return ImportResult{}, err
+		}
+	}
+	if err := setMeta(tx, metaJSONImported, time.Now().Format(time.RFC3339)); err != nil {
+		tx.Rollback()
+		return ImportResult{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return ImportResult{}, fmt.Errorf("导入数据失败: %v", err)
+	}
+
+	return ImportResult{Imported: true, Subscribes: len(subscribes), Config: cfg != nil}, nil
+}
@@ -0,0 +1,171 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"time"
+
+	"tvsubscribe/storage"
+
+	// 纯Go实现的SQLite驱动，不依赖cgo
+	_ "modernc.org/sqlite"
+)
+
+// DefaultPath 默认的数据库文件路径
+const DefaultPath = "./tvsubscribe.db"
+
+// migration 数据库结构的一次升级
+type migration struct {
+	version     int
+	description string
+	statements  []string
+}
+
+// migrations 按版本顺序排列的全部升级，已发布的升级不能修改，只能追加新的版本
+var migrations = []migration{
+	{
+		version:     1,
+		description: "创建订阅、配置和元数据表",
+		statements: []string{
+			`CREATE TABLE subscribes (
+				id         TEXT PRIMARY KEY,
+				position   INTEGER NOT NULL,
+				douban_id  TEXT NOT NULL,
+				resolution INTEGER NOT NULL,
+				data       TEXT NOT NULL
+			)`,
+			`CREATE INDEX idx_subscribes_douban ON subscribes (douban_id, resolution)`,
+			`CREATE TABLE config (
+				key   TEXT PRIMARY KEY,
+				value TEXT NOT NULL
+			)`,
+			`CREATE TABLE meta (
+				key   TEXT PRIMARY KEY,
+				value TEXT NOT NULL
+			)`,
+		},
+	},
+}
+
+// DB SQLite数据库，订阅和配置分别通过 Subscribes 和 Config 返回的存储读写
+type DB struct {
+	db   *sql.DB
+	path string
+}
+
+// Open 打开数据库，文件不存在时创建，并执行尚未执行的结构升级
+func Open(path string) (*DB, error) {
+	// 数据库中保存着配置中的敏感信息，只有运行程序的用户可以读写；WAL 文件沿用数据库文件的权限
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDONLY, storage.FileMode)
+	if err != nil {
+		return nil, fmt.Errorf("打开数据库失败: %v", err)
+	}
+	file.Close()
+	if err := os.Chmod(path, storage.FileMode); err != nil {
+		return nil, fmt.Errorf("设置数据库文件权限失败: %v", err)
+	}
+
+	// WAL 模式下读写互不阻塞，busy_timeout 避免偶发的锁冲突直接报错
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_pragma=synchronous(FULL)")
+	if err != nil {
+		return nil, fmt.Errorf("打开数据库失败: %v", err)
+	}
+	// SQLite 同一时间只允许一个写入者，使用单个连接串行化所有操作
+	db.SetMaxOpenConns(1)
+
+	d := &DB{db: db, path: path}
+	if err := d.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return d, nil
+}
+
+// Close 关闭数据库
+func (d *DB) Close() error {
+	return d.db.Close()
+}
+
+// String 返回数据库文件路径
+func (d *DB) String() string {
+	return d.path
+}
+
+// Version 返回数据库当前的结构版本
+func (d *DB) Version() (int, error) {
+	var version int
+	if err := d.db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version); err != nil {
+		return 0, fmt.Errorf("读取数据库版本失败: %v", err)
+	}
+	return version, nil
+}
+
+// migrate 依次执行尚未执行的结构升级，每个版本在单独的事务中完成
+func (d *DB) migrate() error {
+	if _, err := d.db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		applied_at TEXT NOT NULL
+	)`); err != nil {
+		return fmt.Errorf("创建版本表失败: %v", err)
+	}
+
+	current, err := d.Version()
+	if err != nil {
+		return err
+	}
+	if latest := migrations[len(migrations)-1].version; current > latest {
+		return fmt.Errorf("数据库版本 %d 高于程序支持的版本 %d，请升级程序", current, latest)
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		tx, err := d.db.Begin()
+		if err != nil {
+			return fmt.Errorf("开始数据库事务失败: %v", err)
+		}
+		for _, stmt := range m.statements {
+			if _, err := tx.Exec(stmt); err != nil {
+				tx.Rollback()
+				return fmt.Errorf("升级数据库到版本 %d (%s) 失败: %v", m.version, m.description, err)
+			}
+		}
+		if _, err := tx.Exec(`INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`, m.version, time.Now().Format(time.RFC3339)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("记录数据库版本失败: %v", err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("升级数据库到版本 %d 失败: %v", m.version, err)
+		}
+	}
+	return nil
+}
+
+// getMeta 读取元数据，不存在时返回空字符串
+func getMeta(q querier, key string) (string, error) {
+	var value string
+	err := q.QueryRow(`SELECT value FROM meta WHERE key = ?`, key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("读取元数据 %s 失败: %v", key, err)
+	}
+	return value, nil
+}
+
+// setMeta 写入元数据
+func setMeta(tx *sql.Tx, key, value string) error {
+	if _, err := tx.Exec(`INSERT INTO meta (key, value) VALUES (?, ?) ON CONFLICT (key) DO UPDATE SET value = excluded.value`, key, value); err != nil {
+		return fmt.Errorf("写入元数据 %s 失败: %v", key, err)
+	}
+	return nil
+}
+
+// querier 数据库和事务共有的查询方法
+type querier interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
@@ -0,0 +1,208 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tvsubscribe"
+	"tvsubscribe/config"
+	"tvsubscribe/interfaces"
+	"tvsubscribe/subscribe"
+)
+
+// openTestDB 打开临时目录中的数据库
+func openTestDB(t *testing.T, path string) *DB {
+	db, err := Open(path)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// TestMigrate 测试新建数据库执行全部升级，重复打开时不再执行，版本过高时拒绝打开
+func TestMigrate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tvsubscribe.db")
+	db := openTestDB(t, path)
+	version, err := db.Version()
+	require.NoError(t, err)
+	assert.Equal(t, migrations[len(migrations)-1].version, version)
+	require.NoError(t, db.Close())
+
+	db = openTestDB(t, path)
+	var count int
+	require.NoError(t, db.db.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&count))
+	assert.Equal(t, len(migrations), count)
+
+	// 更新版本的程序升级过的数据库
+	_, err = db.db.Exec(`INSERT INTO schema_migrations (version, applied_at) VALUES (999, '')`)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+	_, err = Open(path)
+	assert.ErrorContains(t, err, "999")
+}
+
+// TestSubscribeStore 测试订阅管理器使用数据库存储时的增删改和顺序
+func TestSubscribeStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tvsubscribe.db")
+	db := openTestDB(t, path)
+
+	manager, err := subscribe.NewSubscribeManagerWithStore(db.Subscribes())
+	require.NoError(t, err)
+	var _ interfaces.SubscribeManager = manager
+	assert.Empty(t, manager.GetSubscribes())
+
+	for _, tv := range []tvsubscribe.TVInfo{
+		{DouBanID: "1", Name: "a", Resolution: tvsubscribe.RES_1080P},
+		{DouBanID: "2", Name: "b", Resolution: tvsubscribe.RES_1080P, Tags: []string{"追更"}},
+		{DouBanID: "3", Name: "c", Resolution: tvsubscribe.RES_2160P},
+	} {
+		require.NoError(t, manager.AddSubscribe(context.Background(), tv))
+	}
+	assert.ErrorIs(t, manager.AddSubscribe(context.Background(), tvsubscribe.TVInfo{DouBanID: "1", Name: "a", Resolution: tvsubscribe.RES_1080P}), tvsubscribe.ErrSubscribeExists)
+
+	subscribes := manager.GetSubscribes()
+	name := "b2"
+	_, err = manager.UpdateSubscribe(context.Background(), subscribes[1].ID, tvsubscribe.TVInfoPatch{Name: &name})
+	require.NoError(t, err)
+	require.NoError(t, manager.RemoveSubscribesByID([]string{subscribes[0].ID}))
+	_, _, err = manager.RecordEpisodes(subscribes[2].ID, []int{1, 2})
+	require.NoError(t, err)
+
+	// 重新打开数据库后内容和顺序保持不变
+	require.NoError(t, db.Close())
+	db = openTestDB(t, path)
+	reloaded, err := subscribe.NewSubscribeManagerWithStore(db.Subscribes())
+	require.NoError(t, err)
+	got := reloaded.GetSubscribes()
+	require.Len(t, got, 2)
+	assert.Equal(t, subscribes[1].ID, got[0].ID)
+	assert.Equal(t, "b2", got[0].Name)
+	assert.Equal(t, []string{"追更"}, got[0].Tags)
+	assert.Equal(t, subscribes[2].ID, got[1].ID)
+	assert.Equal(t, []int{1, 2}, got[1].Episodes)
+}
+
+// TestConfigStore 测试配置的保存和读取
+func TestConfigStore(t *testing.T) {
+	db := openTestDB(t, filepath.Join(t.TempDir(), "tvsubscribe.db"))
+	store := db.Config()
+
+	_, err := store.Load()
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+
+	cfg := &config.Config{
+		Cookie:          "c=1",
+		IntervalMinutes: 30,
+		TeamList:        []string{"9", "GroupA"},
+		SiteTeams:       map[string]int{"GroupA": 9},
+		AirSchedule:     true,
+	}
+	require.NoError(t, store.Save(cfg))
+	loaded, err := store.Load()
+	require.NoError(t, err)
+	assert.Equal(t, cfg, loaded)
+
+	cfg.IntervalMinutes = 60
+	cfg.TeamList = nil
+	require.NoError(t, store.Save(cfg))
+	loaded, err = store.Load()
+	require.NoError(t, err)
+	assert.Equal(t, 60, loaded.IntervalMinutes)
+	assert.Nil(t, loaded.TeamList)
+	assert.Equal(t, "c=1", loaded.Cookie)
+}
+
+// TestImportJSON 测试从JSON文件一次性导入订阅和配置
+func TestImportJSON(t *testing.T) {
+	dir := t.TempDir()
+	subscribesPath := filepath.Join(dir, "subscribes.json")
+	configPath := filepath.Join(dir, "config.json")
+	// 旧版本的订阅文件缺少ID和启用状态，导入时补全
+	require.NoError(t, os.WriteFile(subscribesPath, []byte(`[
+  {"id": "a", "douban_id": "36391902", "name": "庆余年 第二季", "resolution": 1},
+  {"douban_id": "26798436", "name": "琅琊榜", "resolution": 0, "enabled": false}
+]`), 0644))
+	require.NoError(t, os.WriteFile(configPath, []byte(`{"cookie": "c=1", "interval_minutes": 45, "team_list": ["9"]}`), 0644))
+
+	db := openTestDB(t, filepath.Join(dir, "tvsubscribe.db"))
+	result, err := db.ImportJSON(subscribesPath, configPath)
+	require.NoError(t, err)
+	assert.Equal(t, ImportResult{Imported: true, Subscribes: 2, Config: true}, result)
+
+	subscribes, _, err := db.Subscribes().Load()
+	require.NoError(t, err)
+	require.Len(t, subscribes, 2)
+	assert.Equal(t, "a", subscribes[0].ID)
+	assert.True(t, subscribes[0].Enabled)
+	assert.NotEmpty(t, subscribes[1].ID)
+	assert.False(t, subscribes[1].Enabled)
+	assert.False(t, subscribes[1].CreatedAt.IsZero())
+
+	cfg, err := db.Config().Load()
+	require.NoError(t, err)
+	assert.Equal(t, "c=1", cfg.Cookie)
+	assert.Equal(t, 45, cfg.IntervalMinutes)
+	assert.Equal(t, []string{"9"}, cfg.TeamList)
+
+	// JSON文件保持原样
+	data, err := os.ReadFile(configPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"interval_minutes": 45`)
+
+	// 只导入一次，之后JSON文件的修改不再导入
+	require.NoError(t, os.WriteFile(subscribesPath, []byte(`[]`), 0644))
+	result, err = db.ImportJSON(subscribesPath, configPath)
+	require.NoError(t, err)
+	assert.False(t, result.Imported)
+	subscribes, _, err = db.Subscribes().Load()
+	require.NoError(t, err)
+	assert.Len(t, subscribes, 2)
+}
+
+// TestImportJSONLegacyConfig 测试版本1配置文件中的订阅与订阅文件中的订阅一起导入，重复的订阅只导入一次
+func TestImportJSONLegacyConfig(t *testing.T) {
+	dir := t.TempDir()
+	subscribesPath := filepath.Join(dir, "subscribes.json")
+	configPath := filepath.Join(dir, "config.json")
+	require.NoError(t, os.WriteFile(subscribesPath, []byte(`[{"id": "a", "douban_id": "36391902", "name": "庆余年 第二季", "resolution": 1}]`), 0644))
+	require.NoError(t, os.WriteFile(configPath, []byte(`{"cookie": "c=1", "subscribes": [
+  {"douban_id": "36391902", "name": "庆余年 第二季", "resolution": 1},
+  {"douban_id": "26798436", "name": "琅琊榜", "resolution": 0}
+]}`), 0644))
+
+	db := openTestDB(t, filepath.Join(dir, "tvsubscribe.db"))
+	result, err := db.ImportJSON(subscribesPath, configPath)
+	require.NoError(t, err)
+	assert.Equal(t, ImportResult{Imported: true, Subscribes: 2, Config: true}, result)
+
+	subscribes, _, err := db.Subscribes().Load()
+	require.NoError(t, err)
+	require.Len(t, subscribes, 2)
+	assert.Equal(t, "a", subscribes[0].ID)
+	assert.Equal(t, "26798436", subscribes[1].DouBanID)
+	assert.True(t, subscribes[1].Enabled)
+}
+
+// TestImportJSONMissingFiles 测试JSON文件不存在时只记录导入，数据库已有数据时不导入
+func TestImportJSONMissingFiles(t *testing.T) {
+	dir := t.TempDir()
+	db := openTestDB(t, filepath.Join(dir, "tvsubscribe.db"))
+	result, err := db.ImportJSON(filepath.Join(dir, "subscribes.json"), filepath.Join(dir, "config.json"))
+	require.NoError(t, err)
+	assert.Equal(t, ImportResult{Imported: true}, result)
+
+	other := openTestDB(t, filepath.Join(dir, "other.db"))
+	require.NoError(t, other.Config().Save(&config.Config{Cookie: "c=1"}))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "config.json"), []byte(`{"cookie": "c=2"}`), 0644))
+	result, err = other.ImportJSON("", filepath.Join(dir, "config.json"))
+	require.NoError(t, err)
+	assert.False(t, result.Imported)
+	cfg, err := other.Config().Load()
+	require.NoError(t, err)
+	assert.Equal(t, "c=1", cfg.Cookie)
+}
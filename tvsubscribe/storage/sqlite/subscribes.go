@@ -0,0 +1,120 @@
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"tvsubscribe"
+)
+
+// SubscribeStore 保存在数据库中的订阅列表，实现 subscribe.Store。
+// 每个订阅一行，保存时只写入发生变化的订阅
+type SubscribeStore struct {
+	db *DB
+}
+
+// Subscribes 返回订阅存储
+func (d *DB) Subscribes() *SubscribeStore {
+	return &SubscribeStore{db: d}
+}
+
+// Load 按订阅列表的顺序读取全部订阅
+func (s *SubscribeStore) Load() ([]tvsubscribe.TVInfo, bool, error) {
+	rows, err := s.db.db.Query(`SELECT data FROM subscribes ORDER BY position`)
+	if err != nil {
+		return nil, false, fmt.Errorf("读取订阅失败: %v", err)
+	}
+	defer rows.Close()
+
+	subscribes := []tvsubscribe.TVInfo{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, false, fmt.Errorf("读取订阅失败: %v", err)
+		}
+		var tv tvsubscribe.TVInfo
+		if err := json.Unmarshal([]byte(data), &tv); err != nil {
+			return nil, false, fmt.Errorf("解析订阅失败: %v", err)
+		}
+		subscribes = append(subscribes, tv)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, fmt.Errorf("读取订阅失败: %v", err)
+	}
+	return subscribes, false, nil
+}
+
+// Save 在一个事务中保存订阅列表：写入新增和变化的订阅，删除已不在列表中的订阅
+func (s *SubscribeStore) Save(subscribes []tvsubscribe.TVInfo) error {
+	tx, err := s.db.db.Begin()
+	if err != nil {
+		return fmt.Errorf("开始数据库事务失败: %v", err)
+	}
+	if err := saveSubscribes(tx, subscribes); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("保存订阅失败: %v", err)
+	}
+	return nil
+}
+
+// String 返回数据库文件路径
+func (s *SubscribeStore) String() string {
+	return s.db.String()
+}
+
+// storedSubscribe 数据库中已有的订阅行
+type storedSubscribe struct {
+	position int
+	data     string
+}
+
+// saveSubscribes 在事务中保存订阅列表
+func saveSubscribes(tx *sql.Tx, subscribes []tvsubscribe.TVInfo) error {
+	rows, err := tx.Query(`SELECT id, position, data FROM subscribes`)
+	if err != nil {
+		return fmt.Errorf("读取订阅失败: %v", err)
+	}
+	existing := make(map[string]storedSubscribe)
+	for rows.Next() {
+		var id string
+		var row storedSubscribe
+		if err := rows.Scan(&id, &row.position, &row.data); err != nil {
+			rows.Close()
+			return fmt.Errorf("读取订阅失败: %v", err)
+		}
+		existing[id] = row
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("读取订阅失败: %v", err)
+	}
+
+	for i, tv := range subscribes {
+		data, err := json.Marshal(tv)
+		if err != nil {
+			return fmt.Errorf("序列化订阅数据失败: %v", err)
+		}
+		row, ok := existing[tv.ID]
+		delete(existing, tv.ID)
+		if ok && row.position == i && row.data == string(data) {
+			continue
+		}
+		if _, err := tx.Exec(`INSERT INTO subscribes (id, position, douban_id, resolution, data) VALUES (?, ?, ?, ?, ?)
+			ON CONFLICT (id) DO UPDATE SET position = excluded.position, douban_id = excluded.douban_id,
+			resolution = excluded.resolution, data = excluded.data`,
+			tv.ID, i, tv.DouBanID, tv.Resolution, string(data)); err != nil {
+			return fmt.Errorf("保存订阅 %s 失败: %v", tv.Name, err)
+		}
+	}
+
+	for id := range existing {
+		if _, err := tx.Exec(`DELETE FROM subscribes WHERE id = ?`, id); err != nil {
+			return fmt.Errorf("删除订阅 %s 失败: %v", id, err)
+		}
+	}
+	return nil
+}
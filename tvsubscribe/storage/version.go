@@ -0,0 +1,29 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+)
+
+// ErrConflict 数据文件在上次读取之后被外部修改，保存会覆盖外部的修改
+var ErrConflict = errors.New("数据文件已被外部修改，已重新加载，请在最新数据的基础上重试")
+
+// Version 返回文件内容的版本，内容相同时版本相同
+func Version(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// FileVersion 返回文件当前内容的版本，文件不存在时返回空字符串
+func FileVersion(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return Version(data), nil
+}
@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// DefaultWatchDelay 文件变化后等待的时间，编辑器和部署工具的连续写入合并为一次通知
+const DefaultWatchDelay = 500 * time.Millisecond
+
+// Watcher 监听数据文件的变化。监听的是文件所在的目录，
+// 原子写入（临时文件+重命名）替换文件后仍然有效
+type Watcher struct {
+	watcher  *fsnotify.Watcher
+	files    map[string]bool
+	delay    time.Duration
+	onChange func(path string)
+	done     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// Watch 监听指定文件的创建、修改和替换，文件变化稳定 delay 之后调用 onChange，参数为文件的绝对路径
+func Watch(paths []string, delay time.Duration, onChange func(path string)) (*Watcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("创建文件监听失败: %v", err)
+	}
+
+	w := &Watcher{
+		watcher:  watcher,
+		files:    make(map[string]bool),
+		delay:    delay,
+		onChange: onChange,
+		done:     make(chan struct{}),
+	}
+	dirs := make(map[string]bool)
+	for _, path := range paths {
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			watcher.Close()
+			return nil, fmt.Errorf("获取文件绝对路径失败: %v", err)
+		}
+		w.files[absPath] = true
+		dir := filepath.Dir(absPath)
+		if dirs[dir] {
+			continue
+		}
+		if err := watcher.Add(dir); err != nil {
+			watcher.Close()
+			return nil, fmt.Errorf("监听目录 %s 失败: %v", dir, err)
+		}
+		dirs[dir] = true
+	}
+
+	w.wg.Add(1)
+	go w.run()
+	return w, nil
+}
+
+// Close 停止监听，等待进行中的回调完成
+func (w *Watcher) Close() error {
+	close(w.done)
+	err := w.watcher.Close()
+	w.wg.Wait()
+	return err
+}
+
+// run 处理文件事件，每个文件的连续事件在 delay 内合并
+func (w *Watcher) run() {
+	defer w.wg.Done()
+
+	timers := make(map[string]*time.Timer)
+	fired := make(chan string)
+	defer func() {
+		for _, timer := range timers {
+			timer.Stop()
+		}
+	}()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			if !w.files[event.Name] || !event.Has(fsnotify.Create|fsnotify.Write) {
+				continue
+			}
+			if timer, ok := timers[event.Name]; ok {
+				timer.Reset(w.delay)
+				continue
+			}
+			path := event.Name
+			timers[path] = time.AfterFunc(w.delay, func() {
+				select {
+				case fired <- path:
+				case <-w.done:
+				}
+			})
+		case path := <-fired:
+			delete(timers, path)
+			w.onChange(path)
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("监听数据文件出错: %v", err)
+		}
+	}
+}
@@ -1,16 +1,21 @@
 package subscribe
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
 	"tvsubscribe"
+	"tvsubscribe/storage"
 )
 
 // generateUniqueID 生成唯一的订阅ID
@@ -25,73 +30,145 @@ func generateUniqueID() string {
 
 // SubscribeManager 订阅管理器
 type SubscribeManager struct {
-	subscribes    []tvsubscribe.TVInfo
-	subscribePath string
-	mu            sync.RWMutex
+	subscribes []tvsubscribe.TVInfo
+	store      Store
+	mu         sync.RWMutex
 }
 
-// loadSubscribes 从订阅文件加载订阅列表
-func loadSubscribes(subscribePath string) ([]tvsubscribe.TVInfo, error) {
+// loadSubscribes 从订阅文件加载订阅列表，旧版本的订阅文件会补全新增字段，migrated 表示需要写回文件。
+// version 为读取到的文件内容的版本，文件不存在时为空。recover 表示文件损坏时从最新的有效备份恢复
+func loadSubscribes(subscribePath string, recover bool) (subscribes []tvsubscribe.TVInfo, version string, migrated bool, err error) {
+	file := storage.NewFile(subscribePath)
+	file.NoRecover = !recover
+	data, _, err := file.Read(func(data []byte) error {
+		version, err := detectVersion(data)
+		if err != nil {
+			return err
+		}
+		if version == 1 {
+			var v []tvsubscribe.TVInfo
+			return json.Unmarshal(data, &v)
+		}
+		var v subscribesFile
+		return json.Unmarshal(data, &v)
+	})
 	// 如果文件不存在，返回空列表
-	if _, err := os.Stat(subscribePath); os.IsNotExist(err) {
-		return []tvsubscribe.TVInfo{}, nil
+	if os.IsNotExist(err) {
+		return []tvsubscribe.TVInfo{}, "", false, nil
+	}
+	// 如果文件为空且没有可用的备份，返回空列表
+	if errors.Is(err, storage.ErrCorrupt) && len(data) == 0 {
+		return []tvsubscribe.TVInfo{}, storage.Version(data), false, nil
 	}
-
-	data, err := os.ReadFile(subscribePath)
 	if err != nil {
-		return nil, fmt.Errorf("读取订阅文件失败: %v", err)
+		return nil, "", false, fmt.Errorf("读取订阅文件失败: %v", err)
+	}
+	// 旧格式的订阅文件升级到当前格式并写回
+	if data, _, err = file.Migrate(data, migrations); err != nil {
+		return nil, "", false, err
 	}
+	version = storage.Version(data)
 
-	// 如果文件为空，返回空列表
-	if len(data) == 0 {
-		return []tvsubscribe.TVInfo{}, nil
+	var content subscribesFile
+	if err := json.Unmarshal(data, &content); err != nil {
+		return nil, "", false, fmt.Errorf("解析订阅文件失败: %v", err)
+	}
+	subscribes = content.Subscribes
+	if subscribes == nil {
+		subscribes = []tvsubscribe.TVInfo{}
 	}
 
-	var subscribes []tvsubscribe.TVInfo
-	if err := json.Unmarshal(data, &subscribes); err != nil {
-		return nil, fmt.Errorf("解析订阅文件失败: %v", err)
+	// 旧版本的订阅没有 enabled 字段，需要区分字段缺失和已暂停
+	var flags struct {
+		Subscribes []struct {
+			Enabled *bool `json:"enabled"`
+		} `json:"subscribes"`
 	}
+	if err := json.Unmarshal(data, &flags); err != nil {
+		return nil, "", false, fmt.Errorf("解析订阅文件失败: %v", err)
+	}
+
+	enabled := make([]*bool, len(flags.Subscribes))
+	for i, flag := range flags.Subscribes {
+		enabled[i] = flag.Enabled
+	}
+	migrated = fillMissing(subscribes, enabled)
 
-	// 为没有ID的订阅生成唯一ID
+	return subscribes, version, migrated, nil
+}
+
+// fillMissing 为旧版本的订阅补全ID、启用状态和时间，enabled 为每个订阅原始的 enabled 字段，
+// 返回是否补全了字段
+func fillMissing(subscribes []tvsubscribe.TVInfo, enabled []*bool) bool {
+	migrated := false
+	now := time.Now()
 	for i := range subscribes {
+		// 为没有ID的订阅生成唯一ID
 		if subscribes[i].ID == "" {
 			subscribes[i].ID = generateUniqueID()
+			migrated = true
+		}
+		if enabled[i] == nil {
+			subscribes[i].Enabled = true
+			migrated = true
+		}
+		if subscribes[i].CreatedAt.IsZero() {
+			subscribes[i].CreatedAt = now
+			migrated = true
+		}
+		if subscribes[i].UpdatedAt.IsZero() {
+			subscribes[i].UpdatedAt = subscribes[i].CreatedAt
+			migrated = true
 		}
 	}
-
-	return subscribes, nil
+	return migrated
 }
 
-// saveSubscribes 保存订阅列表到文件
-func saveSubscribes(subscribePath string, subscribes []tvsubscribe.TVInfo) error {
-	data, err := json.MarshalIndent(subscribes, "", "  ")
+// saveSubscribes 原子写入订阅列表并保存滚动备份，返回写入内容的版本
+func saveSubscribes(subscribePath string, subscribes []tvsubscribe.TVInfo) (string, error) {
+	if subscribes == nil {
+		subscribes = []tvsubscribe.TVInfo{}
+	}
+	data, err := json.MarshalIndent(subscribesFile{Version: Version, Subscribes: subscribes}, "", "  ")
 	if err != nil {
-		return fmt.Errorf("序列化订阅数据失败: %v", err)
+		return "", fmt.Errorf("序列化订阅数据失败: %v", err)
 	}
 
-	if err := os.WriteFile(subscribePath, data, 0644); err != nil {
-		return fmt.Errorf("写入订阅文件失败: %v", err)
+	if err := storage.NewFile(subscribePath).Write(data); err != nil {
+		return "", fmt.Errorf("写入订阅文件失败: %v", err)
 	}
 
-	return nil
+	return storage.Version(data), nil
 }
 
-// NewSubscribeManager 创建新的订阅管理器
+// NewSubscribeManager 创建使用JSON订阅文件的订阅管理器
 func NewSubscribeManager(subscribePath string) (*SubscribeManager, error) {
-	subscribes, err := loadSubscribes(subscribePath)
-	if err != nil {
-		return nil, err
-	}
-
 	// 获取订阅文件的绝对路径
 	absPath, err := filepath.Abs(subscribePath)
 	if err != nil {
 		return nil, fmt.Errorf("获取订阅文件绝对路径失败: %v", err)
 	}
+	return NewSubscribeManagerWithStore(NewJSONStore(absPath))
+}
+
+// NewSubscribeManagerWithStore 创建使用指定存储的订阅管理器
+func NewSubscribeManagerWithStore(store Store) (*SubscribeManager, error) {
+	subscribes, migrated, err := store.Load()
+	if err != nil {
+		return nil, err
+	}
 
 	manager := &SubscribeManager{
-		subscribes:    subscribes,
-		subscribePath: absPath,
+		subscribes: subscribes,
+		store:      store,
+	}
+
+	// 旧版本的订阅数据补全字段后立即写回
+	if migrated {
+		if err := store.Save(subscribes); err != nil {
+			return nil, err
+		}
+		log.Printf("订阅数据已升级到新格式: %s", store)
 	}
 
 	return manager, nil
@@ -108,43 +185,135 @@ func (m *SubscribeManager) GetSubscribes() []tvsubscribe.TVInfo {
 	return result
 }
 
+// save 保存订阅列表，成功后替换内存中的列表，调用方需持有写锁。
+// 存储中的数据已被外部修改时重新加载外部的修改并返回冲突错误，本次修改不生效
+func (m *SubscribeManager) save(subscribes []tvsubscribe.TVInfo) error {
+	if err := m.store.Save(subscribes); err != nil {
+		if errors.Is(err, storage.ErrConflict) {
+			if _, reloadErr := m.reload(); reloadErr != nil {
+				log.Printf("重新加载订阅数据失败: %v", reloadErr)
+			}
+		}
+		return err
+	}
+	m.subscribes = subscribes
+	return nil
+}
+
+// Reload 从存储重新加载订阅列表，用于数据文件被外部修改之后。
+// 新的数据校验失败时保留内存中的订阅，返回值表示订阅列表是否发生了变化
+func (m *SubscribeManager) Reload() (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.reload()
+}
+
+// reload 重新加载订阅列表，调用方需持有写锁
+func (m *SubscribeManager) reload() (bool, error) {
+	subscribes, migrated, err := m.store.Load()
+	if err != nil {
+		return false, err
+	}
+	if err := validateSubscribes(subscribes); err != nil {
+		return false, fmt.Errorf("订阅数据校验失败: %v", err)
+	}
+	// 外部添加的订阅缺少ID等字段时补全后写回
+	if migrated {
+		if err := m.store.Save(subscribes); err != nil {
+			return false, err
+		}
+	}
+
+	// 序列化后比较，忽略时间的时区等内部表示的差异
+	before, _ := json.Marshal(m.subscribes)
+	after, _ := json.Marshal(subscribes)
+	m.subscribes = subscribes
+	return string(before) != string(after), nil
+}
+
+// validateSubscribes 校验订阅列表中的每个订阅，并检查ID和豆瓣ID+分辨率是否重复
+func validateSubscribes(subscribes []tvsubscribe.TVInfo) error {
+	ids := make(map[string]bool)
+	keys := make(map[string]bool)
+	for i := range subscribes {
+		// 校验时会补全默认值，使用副本，保持加载的数据不变
+		copied := subscribes[i]
+		tv := &copied
+		if err := validateImport(tv); err != nil {
+			return fmt.Errorf("第 %d 个订阅 (%s): %v", i+1, tv.Name, err)
+		}
+		if ids[tv.ID] {
+			return fmt.Errorf("第 %d 个订阅 (%s): 订阅ID重复: %s", i+1, tv.Name, tv.ID)
+		}
+		ids[tv.ID] = true
+		key := fmt.Sprintf("%s/%d", tv.DouBanID, tv.Resolution)
+		if keys[key] {
+			return fmt.Errorf("第 %d 个订阅 (%s): %w: 豆瓣ID=%s, 分辨率=%d", i+1, tv.Name, tvsubscribe.ErrSubscribeExists, tv.DouBanID, tv.Resolution)
+		}
+		keys[key] = true
+	}
+	return nil
+}
+
 // AddSubscribe 添加订阅
-func (m *SubscribeManager) AddSubscribe(tvInfo tvsubscribe.TVInfo) error {
+func (m *SubscribeManager) AddSubscribe(ctx context.Context, tvInfo tvsubscribe.TVInfo) error {
+	if !tvsubscribe.ValidResolution(tvInfo.Resolution) {
+		return fmt.Errorf("无效的分辨率: %d", tvInfo.Resolution)
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	// 检查是否已存在相同的订阅
 	for _, existing := range m.subscribes {
 		if existing.DouBanID == tvInfo.DouBanID && existing.Resolution == tvInfo.Resolution {
-			return fmt.Errorf("订阅已存在: 豆瓣ID=%s, 分辨率=%d", tvInfo.DouBanID, tvInfo.Resolution)
+			return fmt.Errorf("%w: 豆瓣ID=%s, 分辨率=%d", tvsubscribe.ErrSubscribeExists, tvInfo.DouBanID, tvInfo.Resolution)
 		}
 	}
 
-	// 生成唯一ID
+	// 生成唯一ID，新订阅默认启用
 	tvInfo.ID = generateUniqueID()
-
-	// 如果名称为空，尝试从豆瓣获取
+	tvInfo.Enabled = true
+	tvInfo.CreatedAt = time.Now()
+	tvInfo.UpdatedAt = tvInfo.CreatedAt
+	tvInfo.LastCheckedAt = nil
+	tvInfo.LastGrabAt = nil
+	tvInfo.LastError = ""
+	tvInfo.Episodes = nil
+	tvInfo.TotalEpisodes = 0
+	tvInfo.Completed = false
+	tvInfo.CompletedAt = nil
+
+	// 如果名称为空，尝试从豆瓣获取名称和条目类型
 	if tvInfo.Name == "" {
-		name, err := tvsubscribe.GetTVNameByDouBanID(tvInfo.DouBanID)
+		subject, err := tvsubscribe.GetDoubanSubject(ctx, tvInfo.DouBanID)
 		if err != nil {
 			// 获取名称失败，但不阻止添加订阅，使用默认名称
 			tvInfo.Name = fmt.Sprintf("豆瓣ID: %s", tvInfo.DouBanID)
 		} else {
-			tvInfo.Name = name
+			tvInfo.Name = subject.Name
+			if tvInfo.Kind == "" {
+				tvInfo.Kind = subject.Kind
+			}
 		}
 	}
+	if tvInfo.Kind == "" {
+		tvInfo.Kind = tvsubscribe.KindSeries
+	}
+	if tvInfo.Filters != nil && tvInfo.Filters.Empty() {
+		tvInfo.Filters = nil
+	}
+	if tvInfo.TeamPolicy != nil && tvInfo.TeamPolicy.Mode == "" {
+		tvInfo.TeamPolicy = nil
+	}
 
 	// 添加新订阅
-	m.subscribes = append(m.subscribes, tvInfo)
+	newSubscribes := make([]tvsubscribe.TVInfo, len(m.subscribes), len(m.subscribes)+1)
+	copy(newSubscribes, m.subscribes)
+	newSubscribes = append(newSubscribes, tvInfo)
 
-	// 保存到文件
-	if err := saveSubscribes(m.subscribePath, m.subscribes); err != nil {
-		// 回滚内存中的修改
-		m.subscribes = m.subscribes[:len(m.subscribes)-1]
-		return err
-	}
-
-	return nil
+	// 保存到存储
+	return m.save(newSubscribes)
 }
 
 // RemoveSubscribe 删除订阅
@@ -164,15 +333,13 @@ func (m *SubscribeManager) RemoveSubscribe(tvInfo tvsubscribe.TVInfo) error {
 	}
 
 	if !found {
-		return fmt.Errorf("订阅不存在: 豆瓣ID=%s, 分辨率=%d", tvInfo.DouBanID, tvInfo.Resolution)
+		return fmt.Errorf("%w: 豆瓣ID=%s, 分辨率=%d", tvsubscribe.ErrSubscribeNotFound, tvInfo.DouBanID, tvInfo.Resolution)
 	}
 
-	// 保存到文件
-	if err := saveSubscribes(m.subscribePath, newSubscribes); err != nil {
+	// 保存到存储
+	if err := m.save(newSubscribes); err != nil {
 		return err
 	}
-
-	m.subscribes = newSubscribes
 	return nil
 }
 
@@ -209,12 +376,10 @@ func (m *SubscribeManager) RemoveSubscribesByID(ids []string) error {
 		return fmt.Errorf("未找到要删除的订阅")
 	}
 
-	// 保存到文件
-	if err := saveSubscribes(m.subscribePath, newSubscribes); err != nil {
+	// 保存到存储
+	if err := m.save(newSubscribes); err != nil {
 		return err
 	}
-
-	m.subscribes = newSubscribes
 	return nil
 }
 
@@ -229,5 +394,359 @@ func (m *SubscribeManager) GetSubscribeByID(id string) (tvsubscribe.TVInfo, erro
 		}
 	}
 
-	return tvsubscribe.TVInfo{}, fmt.Errorf("订阅不存在: ID=%s", id)
+	return tvsubscribe.TVInfo{}, fmt.Errorf("%w: ID=%s", tvsubscribe.ErrSubscribeNotFound, id)
+}
+
+// SetSubscribesEnabled 根据ID数组启用或暂停订阅，返回状态发生变化的订阅数量
+func (m *SubscribeManager) SetSubscribesEnabled(ids []string, enabled bool) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(ids) == 0 {
+		return 0, fmt.Errorf("ID列表不能为空")
+	}
+
+	idSet := make(map[string]bool)
+	for _, id := range ids {
+		if id != "" {
+			idSet[id] = true
+		}
+	}
+
+	newSubscribes := make([]tvsubscribe.TVInfo, len(m.subscribes))
+	copy(newSubscribes, m.subscribes)
+	found, changed := 0, 0
+	now := time.Now()
+	for i := range newSubscribes {
+		if !idSet[newSubscribes[i].ID] {
+			continue
+		}
+		found++
+		if newSubscribes[i].Enabled != enabled {
+			newSubscribes[i].Enabled = enabled
+			newSubscribes[i].UpdatedAt = now
+			changed++
+		}
+	}
+
+	if found == 0 {
+		return 0, fmt.Errorf("未找到要修改的订阅")
+	}
+	if changed == 0 {
+		return 0, nil
+	}
+
+	// 保存到存储
+	if err := m.save(newSubscribes); err != nil {
+		return 0, err
+	}
+	return changed, nil
+}
+
+// Check 订阅的一次检查结果
+type Check struct {
+	ID        string
+	CheckedAt time.Time
+	Grabbed   bool   // 下载到了新种子
+	LastError string // 为空表示检查成功
+}
+
+// RecordChecks 记录一轮处理中各订阅最近一次检查的结果，全部更新后只保存一次，返回更新的订阅数量。
+// 处理期间被删除的订阅会被忽略；检查结果不属于用户修改，不更新 updated_at。
+func (m *SubscribeManager) RecordChecks(checks []Check) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	newSubscribes := make([]tvsubscribe.TVInfo, len(m.subscribes))
+	copy(newSubscribes, m.subscribes)
+	updated := 0
+	for _, check := range checks {
+		for i := range newSubscribes {
+			if newSubscribes[i].ID != check.ID {
+				continue
+			}
+			checkedAt := check.CheckedAt
+			newSubscribes[i].LastCheckedAt = &checkedAt
+			if check.Grabbed {
+				newSubscribes[i].LastGrabAt = &checkedAt
+			}
+			newSubscribes[i].LastError = check.LastError
+			updated++
+			break
+		}
+	}
+	if updated == 0 {
+		return 0, nil
+	}
+	if err := m.save(newSubscribes); err != nil {
+		return 0, err
+	}
+	return updated, nil
+}
+
+// UpdateSubscribe 按ID修改订阅，只修改 patch 中提供的字段，订阅ID保持不变。
+// 修改豆瓣ID或将名称设置为空时重新从豆瓣获取名称。
+func (m *SubscribeManager) UpdateSubscribe(ctx context.Context, id string, patch tvsubscribe.TVInfoPatch) (tvsubscribe.TVInfo, error) {
+	if patch.Empty() {
+		return tvsubscribe.TVInfo{}, fmt.Errorf("没有需要修改的字段")
+	}
+	if patch.DouBanID != nil && strings.TrimSpace(*patch.DouBanID) == "" {
+		return tvsubscribe.TVInfo{}, fmt.Errorf("豆瓣ID不能为空")
+	}
+	if patch.Resolution != nil && !tvsubscribe.ValidResolution(*patch.Resolution) {
+		return tvsubscribe.TVInfo{}, fmt.Errorf("无效的分辨率: %d", *patch.Resolution)
+	}
+	if patch.Kind != nil && (*patch.Kind == "" || !tvsubscribe.ValidKind(*patch.Kind)) {
+		return tvsubscribe.TVInfo{}, fmt.Errorf("无效的订阅类型: %s", *patch.Kind)
+	}
+	if patch.MinQuality != nil && *patch.MinQuality != "" {
+		if _, err := tvsubscribe.ParseQualityName(*patch.MinQuality); err != nil {
+			return tvsubscribe.TVInfo{}, err
+		}
+	}
+	if patch.Filters != nil {
+		if err := patch.Filters.Validate(); err != nil {
+			return tvsubscribe.TVInfo{}, err
+		}
+	}
+	if patch.TeamPolicy != nil && patch.TeamPolicy.Mode != "" {
+		if err := patch.TeamPolicy.Validate(); err != nil {
+			return tvsubscribe.TVInfo{}, err
+		}
+	}
+
+	current, err := m.GetSubscribeByID(id)
+	if err != nil {
+		return tvsubscribe.TVInfo{}, err
+	}
+
+	// 获取名称需要请求豆瓣，在加锁之前完成
+	douBanID := current.DouBanID
+	if patch.DouBanID != nil {
+		douBanID = strings.TrimSpace(*patch.DouBanID)
+	}
+	name := ""
+	refetchName := (patch.Name != nil && strings.TrimSpace(*patch.Name) == "") ||
+		(patch.Name == nil && douBanID != current.DouBanID)
+	if refetchName {
+		fetched, err := tvsubscribe.GetTVNameByDouBanID(ctx, douBanID)
+		if err != nil {
+			name = fmt.Sprintf("豆瓣ID: %s", douBanID)
+		} else {
+			name = fetched
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	index := -1
+	for i := range m.subscribes {
+		if m.subscribes[i].ID == id {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return tvsubscribe.TVInfo{}, fmt.Errorf("%w: ID=%s", tvsubscribe.ErrSubscribeNotFound, id)
+	}
+
+	updated := m.subscribes[index]
+	updated.DouBanID = douBanID
+	switch {
+	case refetchName:
+		updated.Name = name
+	case patch.Name != nil:
+		updated.Name = strings.TrimSpace(*patch.Name)
+	}
+	if patch.Resolution != nil {
+		updated.Resolution = *patch.Resolution
+	}
+	if patch.Schedule != nil {
+		updated.Schedule = strings.TrimSpace(*patch.Schedule)
+	}
+	if patch.ActiveHours != nil {
+		updated.ActiveHours = strings.TrimSpace(*patch.ActiveHours)
+	}
+	if patch.Enabled != nil {
+		updated.Enabled = *patch.Enabled
+	}
+	if patch.Notes != nil {
+		updated.Notes = *patch.Notes
+	}
+	if patch.Tags != nil {
+		updated.Tags = *patch.Tags
+	}
+	if patch.Kind != nil {
+		updated.Kind = *patch.Kind
+	}
+	if patch.MinQuality != nil {
+		updated.MinQuality = strings.ToLower(strings.TrimSpace(*patch.MinQuality))
+	}
+	if patch.Filters != nil {
+		var filters tvsubscribe.FilterRules
+		if updated.Filters != nil {
+			filters = *updated.Filters
+		}
+		filters = filters.Patch(*patch.Filters)
+		updated.Filters = nil
+		if !filters.Empty() {
+			updated.Filters = &filters
+		}
+	}
+	if patch.TeamPolicy != nil {
+		updated.TeamPolicy = nil
+		if patch.TeamPolicy.Mode != "" {
+			policy := *patch.TeamPolicy
+			updated.TeamPolicy = &policy
+		}
+	}
+
+	// 更换剧集、分辨率或类型后，原来的分集账本不再适用
+	if updated.DouBanID != current.DouBanID || updated.Resolution != current.Resolution || updated.Kind != current.Kind {
+		updated.Episodes = nil
+		updated.TotalEpisodes = 0
+		updated.Completed = false
+		updated.CompletedAt = nil
+	}
+
+	// 检查修改后是否与其他订阅重复
+	for i, existing := range m.subscribes {
+		if i != index && existing.DouBanID == updated.DouBanID && existing.Resolution == updated.Resolution {
+			return tvsubscribe.TVInfo{}, fmt.Errorf("%w: 豆瓣ID=%s, 分辨率=%d", tvsubscribe.ErrSubscribeExists, updated.DouBanID, updated.Resolution)
+		}
+	}
+
+	updated.UpdatedAt = time.Now()
+
+	newSubscribes := make([]tvsubscribe.TVInfo, len(m.subscribes))
+	copy(newSubscribes, m.subscribes)
+	newSubscribes[index] = updated
+
+	// 保存到存储
+	if err := m.save(newSubscribes); err != nil {
+		return tvsubscribe.TVInfo{}, err
+	}
+	return updated, nil
+}
+
+// modifySubscribe 在锁内修改指定ID的订阅并保存，modify 返回 false 表示没有变化，不写入存储
+func (m *SubscribeManager) modifySubscribe(id string, modify func(tv *tvsubscribe.TVInfo) bool) (tvsubscribe.TVInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.subscribes {
+		if m.subscribes[i].ID != id {
+			continue
+		}
+		updated := m.subscribes[i]
+		if !modify(&updated) {
+			return updated, nil
+		}
+
+		newSubscribes := make([]tvsubscribe.TVInfo, len(m.subscribes))
+		copy(newSubscribes, m.subscribes)
+		newSubscribes[i] = updated
+		if err := m.save(newSubscribes); err != nil {
+			return tvsubscribe.TVInfo{}, err
+		}
+		return updated, nil
+	}
+
+	return tvsubscribe.TVInfo{}, fmt.Errorf("%w: ID=%s", tvsubscribe.ErrSubscribeNotFound, id)
+}
+
+// RecordEpisodes 将已下载的集数记入订阅的分集账本，返回更新后的订阅和新增的集数数量
+func (m *SubscribeManager) RecordEpisodes(id string, episodes []int) (tvsubscribe.TVInfo, int, error) {
+	added := 0
+	updated, err := m.modifySubscribe(id, func(tv *tvsubscribe.TVInfo) bool {
+		tv.Episodes, added = tvsubscribe.MergeEpisodes(tv.Episodes, episodes)
+		return added > 0
+	})
+	return updated, added, err
+}
+
+// UpdateCompletion 记录订阅的总集数，分集账本已包含全部剧集时将订阅标记为完结。
+// 返回值表示订阅是否在本次被标记为完结。
+func (m *SubscribeManager) UpdateCompletion(id string, totalEpisodes int) (bool, error) {
+	completed := false
+	_, err := m.modifySubscribe(id, func(tv *tvsubscribe.TVInfo) bool {
+		changed := tv.TotalEpisodes != totalEpisodes
+		tv.TotalEpisodes = totalEpisodes
+		if !tv.Completed && tvsubscribe.EpisodesComplete(tv.Episodes, totalEpisodes) {
+			now := time.Now()
+			tv.Completed = true
+			tv.CompletedAt = &now
+			completed = true
+			changed = true
+		}
+		return changed
+	})
+	return completed, err
+}
+
+// CompleteSubscribe 将订阅标记为完结，用于电影下载最佳版本之后。
+// 返回值表示订阅是否在本次被标记为完结。
+func (m *SubscribeManager) CompleteSubscribe(id string) (bool, error) {
+	completed := false
+	_, err := m.modifySubscribe(id, func(tv *tvsubscribe.TVInfo) bool {
+		if tv.Completed {
+			return false
+		}
+		now := time.Now()
+		tv.Completed = true
+		tv.CompletedAt = &now
+		completed = true
+		return true
+	})
+	return completed, err
+}
+
+// ReactivateSubscribes 根据ID数组将已完结的订阅重新激活，返回重新激活的订阅数量。
+// 重新激活的订阅同时被启用，分集账本保留，已下载过的种子不会重复下载。
+func (m *SubscribeManager) ReactivateSubscribes(ids []string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(ids) == 0 {
+		return 0, fmt.Errorf("ID列表不能为空")
+	}
+
+	idSet := make(map[string]bool)
+	for _, id := range ids {
+		if id != "" {
+			idSet[id] = true
+		}
+	}
+
+	newSubscribes := make([]tvsubscribe.TVInfo, len(m.subscribes))
+	copy(newSubscribes, m.subscribes)
+	found, changed := 0, 0
+	now := time.Now()
+	for i := range newSubscribes {
+		if !idSet[newSubscribes[i].ID] {
+			continue
+		}
+		found++
+		if newSubscribes[i].Completed || !newSubscribes[i].Enabled {
+			newSubscribes[i].Completed = false
+			newSubscribes[i].CompletedAt = nil
+			newSubscribes[i].Enabled = true
+			newSubscribes[i].UpdatedAt = now
+			changed++
+		}
+	}
+
+	if found == 0 {
+		return 0, fmt.Errorf("未找到要重新激活的订阅")
+	}
+	if changed == 0 {
+		return 0, nil
+	}
+
+	// 保存到存储
+	if err := m.save(newSubscribes); err != nil {
+		return 0, err
+	}
+	return changed, nil
 }
\ No newline at end of file
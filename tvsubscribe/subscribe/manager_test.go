@@ -0,0 +1,390 @@
+package subscribe
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tvsubscribe"
+	"tvsubscribe/storage"
+)
+
+// TestLoadSubscribesMigratesOldFormat 测试旧版本订阅文件补全启用状态和时间戳并写回文件
+func TestLoadSubscribesMigratesOldFormat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "subscribes.json")
+	old := `[
+  {"id": "a", "douban_id": "36391902", "name": "庆余年 第二季", "resolution": 1},
+  {"douban_id": "26798436", "name": "琅琊榜", "resolution": 0},
+  {"id": "c", "douban_id": "1", "name": "已暂停", "resolution": 1, "enabled": false}
+]`
+	require.NoError(t, os.WriteFile(path, []byte(old), 0644))
+
+	manager, err := NewSubscribeManager(path)
+	require.NoError(t, err)
+
+	subscribes := manager.GetSubscribes()
+	require.Len(t, subscribes, 3)
+	assert.True(t, subscribes[0].Enabled)
+	assert.True(t, subscribes[1].Enabled)
+	assert.NotEmpty(t, subscribes[1].ID)
+	assert.False(t, subscribes[2].Enabled)
+	for _, tv := range subscribes {
+		assert.False(t, tv.CreatedAt.IsZero())
+		assert.Equal(t, tv.CreatedAt, tv.UpdatedAt)
+		assert.Nil(t, tv.LastCheckedAt)
+	}
+
+	// 迁移结果已写回文件，再次加载不再变化
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var saved struct {
+		Version    int                      `json:"version"`
+		Subscribes []map[string]interface{} `json:"subscribes"`
+	}
+	require.NoError(t, json.Unmarshal(data, &saved))
+	assert.Equal(t, Version, saved.Version)
+	assert.Equal(t, true, saved.Subscribes[0]["enabled"])
+	assert.Equal(t, subscribes[1].ID, saved.Subscribes[1]["id"])
+
+	_, _, migrated, err := loadSubscribes(path, true)
+	require.NoError(t, err)
+	assert.False(t, migrated)
+}
+
+// TestSetSubscribesEnabledAndRecordChecks 测试暂停、恢复订阅以及记录检查结果
+func TestSetSubscribesEnabledAndRecordChecks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "subscribes.json")
+	require.NoError(t, os.WriteFile(path, []byte(`[{"id": "a", "douban_id": "1", "name": "a", "resolution": 1}]`), 0644))
+	manager, err := NewSubscribeManager(path)
+	require.NoError(t, err)
+	before, err := manager.GetSubscribeByID("a")
+	require.NoError(t, err)
+
+	changed, err := manager.SetSubscribesEnabled([]string{"a"}, false)
+	require.NoError(t, err)
+	assert.Equal(t, 1, changed)
+	changed, err = manager.SetSubscribesEnabled([]string{"a"}, false)
+	require.NoError(t, err)
+	assert.Equal(t, 0, changed)
+	_, err = manager.SetSubscribesEnabled([]string{"missing"}, true)
+	assert.Error(t, err)
+
+	paused, err := manager.GetSubscribeByID("a")
+	require.NoError(t, err)
+	assert.False(t, paused.Enabled)
+	assert.False(t, paused.UpdatedAt.Before(before.UpdatedAt))
+
+	checkedAt := time.Date(2025, 6, 6, 22, 0, 0, 0, time.Local)
+	updated, err := manager.RecordChecks([]Check{
+		{ID: "a", CheckedAt: checkedAt, LastError: "查询种子列表失败: timeout"},
+		{ID: "missing", CheckedAt: checkedAt},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, updated, "处理期间被删除的订阅被忽略")
+	a, err := manager.GetSubscribeByID("a")
+	require.NoError(t, err)
+	assert.Equal(t, "查询种子列表失败: timeout", a.LastError)
+	assert.Nil(t, a.LastGrabAt)
+
+	updated, err = manager.RecordChecks([]Check{{ID: "a", CheckedAt: checkedAt.Add(time.Hour), Grabbed: true}})
+	require.NoError(t, err)
+	assert.Equal(t, 1, updated)
+	updated, err = manager.RecordChecks([]Check{{ID: "missing", CheckedAt: checkedAt}})
+	require.NoError(t, err)
+	assert.Zero(t, updated)
+
+	// 重新加载确认检查结果已保存
+	reloaded, err := NewSubscribeManager(path)
+	require.NoError(t, err)
+	tv, err := reloaded.GetSubscribeByID("a")
+	require.NoError(t, err)
+	require.NotNil(t, tv.LastCheckedAt)
+	require.NotNil(t, tv.LastGrabAt)
+	assert.True(t, tv.LastCheckedAt.Equal(checkedAt.Add(time.Hour)))
+	assert.True(t, tv.LastGrabAt.Equal(checkedAt.Add(time.Hour)))
+	assert.Empty(t, tv.LastError)
+	assert.False(t, tv.Enabled)
+	assert.True(t, tv.UpdatedAt.Equal(paused.UpdatedAt))
+}
+
+// TestAddSubscribeInvalidResolution 测试添加订阅时校验分辨率
+func TestAddSubscribeInvalidResolution(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "subscribes.json")
+	manager, err := NewSubscribeManager(path)
+	require.NoError(t, err)
+
+	assert.Error(t, manager.AddSubscribe(context.Background(), tvsubscribe.TVInfo{DouBanID: "1", Name: "a", Resolution: 5}))
+	assert.Empty(t, manager.GetSubscribes())
+}
+
+// TestUpdateSubscribe 测试修改订阅保留ID，并校验豆瓣ID和分辨率的唯一性
+func TestUpdateSubscribe(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "subscribes.json")
+	data := `[
+  {"id": "a", "douban_id": "36391902", "name": "豆瓣ID: 36391902", "resolution": 1, "enabled": true},
+  {"id": "b", "douban_id": "26798436", "name": "琅琊榜", "resolution": 0, "enabled": true}
+]`
+	require.NoError(t, os.WriteFile(path, []byte(data), 0644))
+	manager, err := NewSubscribeManager(path)
+	require.NoError(t, err)
+
+	name := "庆余年 第二季"
+	resolution := tvsubscribe.RES_2160P
+	tags := []string{"追更"}
+	updated, err := manager.UpdateSubscribe(context.Background(), "a", tvsubscribe.TVInfoPatch{Name: &name, Resolution: &resolution, Tags: &tags})
+	require.NoError(t, err)
+	assert.Equal(t, "a", updated.ID)
+	assert.Equal(t, "36391902", updated.DouBanID)
+	assert.Equal(t, name, updated.Name)
+	assert.Equal(t, tvsubscribe.RES_2160P, updated.Resolution)
+	assert.Equal(t, tags, updated.Tags)
+	assert.True(t, updated.Enabled)
+
+	// 与其他订阅的豆瓣ID和分辨率重复
+	douBanID := "26798436"
+	_, err = manager.UpdateSubscribe(context.Background(), "a", tvsubscribe.TVInfoPatch{DouBanID: &douBanID, Name: &name})
+	assert.ErrorIs(t, err, tvsubscribe.ErrSubscribeExists)
+
+	_, err = manager.UpdateSubscribe(context.Background(), "missing", tvsubscribe.TVInfoPatch{Name: &name})
+	assert.ErrorIs(t, err, tvsubscribe.ErrSubscribeNotFound)
+
+	invalid := 5
+	_, err = manager.UpdateSubscribe(context.Background(), "a", tvsubscribe.TVInfoPatch{Resolution: &invalid})
+	assert.Error(t, err)
+
+	_, err = manager.UpdateSubscribe(context.Background(), "a", tvsubscribe.TVInfoPatch{})
+	assert.Error(t, err)
+
+	// 失败的修改不影响已保存的订阅
+	reloaded, err := NewSubscribeManager(path)
+	require.NoError(t, err)
+	tv, err := reloaded.GetSubscribeByID("a")
+	require.NoError(t, err)
+	assert.Equal(t, name, tv.Name)
+	assert.Equal(t, tvsubscribe.RES_2160P, tv.Resolution)
+	assert.Equal(t, tags, tv.Tags)
+}
+
+// TestEpisodeLedgerCompletion 测试分集账本下载完整后标记完结，重新激活后恢复定时处理
+func TestEpisodeLedgerCompletion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "subscribes.json")
+	require.NoError(t, os.WriteFile(path, []byte(`[{"id": "a", "douban_id": "1", "name": "a", "resolution": 1, "enabled": true}]`), 0644))
+	manager, err := NewSubscribeManager(path)
+	require.NoError(t, err)
+
+	tv, added, err := manager.RecordEpisodes("a", []int{2, 1})
+	require.NoError(t, err)
+	assert.Equal(t, 2, added)
+	assert.Equal(t, []int{1, 2}, tv.Episodes)
+
+	completed, err := manager.UpdateCompletion("a", 3)
+	require.NoError(t, err)
+	assert.False(t, completed)
+
+	_, added, err = manager.RecordEpisodes("a", []int{2, 3})
+	require.NoError(t, err)
+	assert.Equal(t, 1, added)
+
+	completed, err = manager.UpdateCompletion("a", 3)
+	require.NoError(t, err)
+	assert.True(t, completed)
+
+	// 已完结的订阅不会重复标记
+	completed, err = manager.UpdateCompletion("a", 3)
+	require.NoError(t, err)
+	assert.False(t, completed)
+
+	tv, err = manager.GetSubscribeByID("a")
+	require.NoError(t, err)
+	assert.True(t, tv.Completed)
+	assert.NotNil(t, tv.CompletedAt)
+	assert.Equal(t, 3, tv.TotalEpisodes)
+	assert.False(t, tv.Active())
+
+	changed, err := manager.ReactivateSubscribes([]string{"a"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, changed)
+
+	reloaded, err := NewSubscribeManager(path)
+	require.NoError(t, err)
+	tv, err = reloaded.GetSubscribeByID("a")
+	require.NoError(t, err)
+	assert.False(t, tv.Completed)
+	assert.Nil(t, tv.CompletedAt)
+	assert.True(t, tv.Active())
+	assert.Equal(t, []int{1, 2, 3}, tv.Episodes)
+}
+
+func TestMovieSubscribe(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "subscribes.json")
+	require.NoError(t, os.WriteFile(path, []byte(`[]`), 0644))
+	manager, err := NewSubscribeManager(path)
+	require.NoError(t, err)
+
+	// 提供名称时不请求豆瓣，未指定类型按剧集处理
+	require.NoError(t, manager.AddSubscribe(context.Background(), tvsubscribe.TVInfo{DouBanID: "1", Name: "剧集", Resolution: 1}))
+	require.NoError(t, manager.AddSubscribe(context.Background(), tvsubscribe.TVInfo{DouBanID: "2", Name: "电影", Resolution: 1, Kind: tvsubscribe.KindMovie, MinQuality: "bluray"}))
+	subscribes := manager.GetSubscribes()
+	require.Len(t, subscribes, 2)
+	assert.Equal(t, tvsubscribe.KindSeries, subscribes[0].Kind)
+	assert.False(t, subscribes[0].IsMovie())
+	assert.True(t, subscribes[1].IsMovie())
+
+	movie := subscribes[1]
+	invalid := "documentary"
+	_, err = manager.UpdateSubscribe(context.Background(), movie.ID, tvsubscribe.TVInfoPatch{Kind: &invalid})
+	assert.Error(t, err)
+	_, err = manager.UpdateSubscribe(context.Background(), movie.ID, tvsubscribe.TVInfoPatch{MinQuality: &invalid})
+	assert.Error(t, err)
+
+	// 清空最低质量后使用全局默认值
+	empty := ""
+	updated, err := manager.UpdateSubscribe(context.Background(), movie.ID, tvsubscribe.TVInfoPatch{MinQuality: &empty})
+	require.NoError(t, err)
+	assert.Empty(t, updated.MinQuality)
+
+	completed, err := manager.CompleteSubscribe(movie.ID)
+	require.NoError(t, err)
+	assert.True(t, completed)
+	completed, err = manager.CompleteSubscribe(movie.ID)
+	require.NoError(t, err)
+	assert.False(t, completed)
+
+	movie, err = manager.GetSubscribeByID(movie.ID)
+	require.NoError(t, err)
+	assert.False(t, movie.Active())
+	assert.NotNil(t, movie.CompletedAt)
+}
+
+func TestUpdateSubscribeFilters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "subscribes.json")
+	require.NoError(t, os.WriteFile(path, []byte(`[{"id": "a", "douban_id": "1", "name": "a", "resolution": 1, "enabled": true}]`), 0644))
+	manager, err := NewSubscribeManager(path)
+	require.NoError(t, err)
+
+	updated, err := manager.UpdateSubscribe(context.Background(), "a", tvsubscribe.TVInfoPatch{Filters: &tvsubscribe.FilterRules{Exclude: []string{"国语"}, Include: []string{"中字"}}})
+	require.NoError(t, err)
+	require.NotNil(t, updated.Filters)
+	assert.Equal(t, []string{"国语"}, updated.Filters.Exclude)
+
+	// 只替换提供的规则列表
+	updated, err = manager.UpdateSubscribe(context.Background(), "a", tvsubscribe.TVInfoPatch{Filters: &tvsubscribe.FilterRules{Exclude: []string{"HDR"}}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"HDR"}, updated.Filters.Exclude)
+	assert.Equal(t, []string{"中字"}, updated.Filters.Include)
+
+	// 无效的正则不保存
+	_, err = manager.UpdateSubscribe(context.Background(), "a", tvsubscribe.TVInfoPatch{Filters: &tvsubscribe.FilterRules{ExcludeRegex: []string{"("}}})
+	assert.Error(t, err)
+
+	// 规则全部清空后不再保存过滤规则
+	updated, err = manager.UpdateSubscribe(context.Background(), "a", tvsubscribe.TVInfoPatch{Filters: &tvsubscribe.FilterRules{Exclude: []string{}, Include: []string{}}})
+	require.NoError(t, err)
+	assert.Nil(t, updated.Filters)
+}
+
+// TestLoadSubscribesRecoversFromBackup 测试订阅文件被截断时从备份恢复
+func TestLoadSubscribesRecoversFromBackup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "subscribes.json")
+	manager, err := NewSubscribeManager(path)
+	require.NoError(t, err)
+	require.NoError(t, manager.AddSubscribe(context.Background(), tvsubscribe.TVInfo{DouBanID: "1", Name: "a", Resolution: 1}))
+
+	// 模拟写入中途崩溃
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, data[:len(data)/2], 0644))
+
+	recovered, err := NewSubscribeManager(path)
+	require.NoError(t, err)
+	subscribes := recovered.GetSubscribes()
+	require.Len(t, subscribes, 1)
+	assert.Equal(t, manager.GetSubscribes()[0].ID, subscribes[0].ID)
+	assert.Equal(t, "a", subscribes[0].Name)
+}
+
+// TestSaveConflict 测试订阅文件被外部修改后，内存中的修改不会覆盖外部的修改
+func TestSaveConflict(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "subscribes.json")
+	require.NoError(t, os.WriteFile(path, []byte(`[{"id": "a", "douban_id": "1", "name": "a", "resolution": 1, "enabled": true}]`), 0644))
+	manager, err := NewSubscribeManager(path)
+	require.NoError(t, err)
+
+	// 外部编辑添加了一个订阅
+	external := `[
+  {"id": "a", "douban_id": "1", "name": "a", "resolution": 1, "enabled": true},
+  {"id": "b", "douban_id": "2", "name": "b", "resolution": 1, "enabled": true}
+]`
+	require.NoError(t, os.WriteFile(path, []byte(external), 0644))
+
+	err = manager.AddSubscribe(context.Background(), tvsubscribe.TVInfo{DouBanID: "3", Name: "c", Resolution: 1})
+	assert.ErrorIs(t, err, storage.ErrConflict)
+	var saved subscribesFile
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &saved))
+	require.Len(t, saved.Subscribes, 2)
+	assert.Equal(t, "b", saved.Subscribes[1].ID)
+
+	// 冲突后已加载外部的修改，重试成功且保留外部添加的订阅
+	assert.Len(t, manager.GetSubscribes(), 2)
+	require.NoError(t, manager.AddSubscribe(context.Background(), tvsubscribe.TVInfo{DouBanID: "3", Name: "c", Resolution: 1}))
+	reloaded, err := NewSubscribeManager(path)
+	require.NoError(t, err)
+	assert.Len(t, reloaded.GetSubscribes(), 3)
+}
+
+// TestReload 测试重新加载外部修改的订阅文件，校验失败时保留当前订阅
+func TestReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "subscribes.json")
+	require.NoError(t, os.WriteFile(path, []byte(`[{"id": "a", "douban_id": "1", "name": "a", "resolution": 1, "enabled": true}]`), 0644))
+	manager, err := NewSubscribeManager(path)
+	require.NoError(t, err)
+
+	// 内容没有变化
+	changed, err := manager.Reload()
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	// 手动添加的订阅没有ID，重新加载时补全并写回
+	require.NoError(t, os.WriteFile(path, []byte(`[
+  {"id": "a", "douban_id": "1", "name": "a2", "resolution": 1, "enabled": true},
+  {"douban_id": "2", "name": "b", "resolution": 1}
+]`), 0644))
+	changed, err = manager.Reload()
+	require.NoError(t, err)
+	assert.True(t, changed)
+	subscribes := manager.GetSubscribes()
+	require.Len(t, subscribes, 2)
+	assert.Equal(t, "a2", subscribes[0].Name)
+	assert.NotEmpty(t, subscribes[1].ID)
+	assert.True(t, subscribes[1].Enabled)
+	var saved subscribesFile
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &saved))
+	assert.Equal(t, subscribes[1].ID, saved.Subscribes[1].ID)
+
+	// 写回之后仍然可以正常保存
+	_, err = manager.SetSubscribesEnabled([]string{"a"}, false)
+	require.NoError(t, err)
+
+	// 无效的修改不生效
+	for _, content := range []string{
+		`[{"id": "a", "douban_id": "1", "name": "a", "resolution": 9}]`,
+		`[{"id": "a", "douban_id": "1", "resolution": 1}, {"id": "a", "douban_id": "2", "resolution": 1}]`,
+		`[{"id": "a", "douban_id": "1", "resolution": 1}, {"id": "b", "douban_id": "1", "resolution": 1}]`,
+		`[{"id": "a", "douban_id": `,
+	} {
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		_, err = manager.Reload()
+		assert.Error(t, err, content)
+		assert.Len(t, manager.GetSubscribes(), 2, content)
+	}
+}
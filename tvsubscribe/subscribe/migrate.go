@@ -0,0 +1,114 @@
+package subscribe
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"tvsubscribe"
+	"tvsubscribe/storage"
+)
+
+// Version 当前订阅文件的格式版本
+const Version = 2
+
+// migrations 订阅文件的格式升级链
+//
+//	版本1：订阅数组
+//	版本2：{"version": 2, "subscribes": [...]}，可以记录格式版本
+var migrations = storage.Migrations{
+	Detect: detectVersion,
+	Steps: []storage.Migration{
+		{Version: 2, Description: "订阅数组改为带版本号的对象", Migrate: migrateV2},
+	},
+}
+
+// subscribesFile 订阅文件的内容
+type subscribesFile struct {
+	Version    int                  `json:"version"`
+	Subscribes []tvsubscribe.TVInfo `json:"subscribes"`
+}
+
+// detectVersion 返回订阅文件的格式版本，数组为版本1
+func detectVersion(data []byte) (int, error) {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return 0, err
+	}
+	switch v := value.(type) {
+	case []interface{}:
+		return 1, nil
+	case map[string]interface{}:
+		version, ok := v["version"].(float64)
+		if !ok || version <= 0 || version != float64(int(version)) {
+			return 0, fmt.Errorf("无效的版本号: %v", v["version"])
+		}
+		return int(version), nil
+	}
+	return 0, fmt.Errorf("订阅文件需要是数组或对象")
+}
+
+// migrateV2 把订阅数组放入带版本号的对象，订阅内容保持原样
+func migrateV2(data []byte) ([]byte, error) {
+	var subscribes []json.RawMessage
+	if err := json.Unmarshal(data, &subscribes); err != nil {
+		return nil, err
+	}
+	if subscribes == nil {
+		subscribes = []json.RawMessage{}
+	}
+	return json.MarshalIndent(struct {
+		Version    int               `json:"version"`
+		Subscribes []json.RawMessage `json:"subscribes"`
+	}{2, subscribes}, "", "  ")
+}
+
+// LegacyImporter 返回把版本1配置文件中的订阅列表导入 store 的函数，用于 config.JSONStore.LegacySubscribes。
+// 订阅按豆瓣ID和分辨率合并，store 中已有的订阅保持不变，因此升级中断后重新导入不会重复；
+// 保存失败时返回错误，配置文件不会升级
+func LegacyImporter(store Store) func(raw json.RawMessage) error {
+	return func(raw json.RawMessage) error {
+		var legacy []tvsubscribe.TVInfo
+		if err := json.Unmarshal(raw, &legacy); err != nil {
+			return fmt.Errorf("解析配置文件中的订阅失败: %v", err)
+		}
+		var flags []struct {
+			Enabled *bool `json:"enabled"`
+		}
+		if err := json.Unmarshal(raw, &flags); err != nil {
+			return fmt.Errorf("解析配置文件中的订阅失败: %v", err)
+		}
+		enabled := make([]*bool, len(flags))
+		for i, flag := range flags {
+			enabled[i] = flag.Enabled
+		}
+		fillMissing(legacy, enabled)
+
+		subscribes, _, err := store.Load()
+		if err != nil {
+			return err
+		}
+		added := 0
+		for _, tvInfo := range legacy {
+			exists := false
+			for _, existing := range subscribes {
+				if existing.DouBanID == tvInfo.DouBanID && existing.Resolution == tvInfo.Resolution {
+					exists = true
+					break
+				}
+			}
+			if !exists {
+				subscribes = append(subscribes, tvInfo)
+				added++
+			}
+		}
+		if added == 0 {
+			return nil
+		}
+		if err := store.Save(subscribes); err != nil {
+			return fmt.Errorf("保存配置文件中的订阅失败: %v", err)
+		}
+		log.Printf("已将配置文件中的 %d 个订阅导入 %s", added, store)
+		return nil
+	}
+}
@@ -0,0 +1,72 @@
+package subscribe
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readGolden 读取 testdata/migrations 中指定版本的订阅文件
+func readGolden(t *testing.T, version int) []byte {
+	data, err := os.ReadFile(filepath.Join("testdata", "migrations", fmt.Sprintf("v%d.json", version)))
+	require.NoError(t, err)
+	return data
+}
+
+// TestMigrationSteps 测试每一步升级的结果与下一个版本的样例文件一致
+func TestMigrationSteps(t *testing.T) {
+	assert.Equal(t, Version, migrations.Latest())
+	for _, step := range migrations.Steps {
+		t.Run(fmt.Sprintf("v%d", step.Version), func(t *testing.T) {
+			input := readGolden(t, step.Version-1)
+			version, err := detectVersion(input)
+			require.NoError(t, err)
+			assert.Equal(t, step.Version-1, version)
+
+			output, err := step.Migrate(input)
+			require.NoError(t, err)
+			assert.JSONEq(t, string(readGolden(t, step.Version)), string(output))
+
+			version, err = detectVersion(output)
+			require.NoError(t, err)
+			assert.Equal(t, step.Version, version)
+		})
+	}
+}
+
+// TestLoadSubscribesUpgradesVersion 测试读取订阅数组时升级为带版本号的格式并保留升级前的备份
+func TestLoadSubscribesUpgradesVersion(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "subscribes.json")
+	require.NoError(t, os.WriteFile(path, readGolden(t, 1), 0644))
+
+	subscribes, _, _, err := loadSubscribes(path, true)
+	require.NoError(t, err)
+	require.Len(t, subscribes, 2)
+	assert.Equal(t, "a", subscribes[0].ID)
+
+	version, err := detectVersion(mustReadFile(t, path))
+	require.NoError(t, err)
+	assert.Equal(t, Version, version)
+
+	backups, err := filepath.Glob(filepath.Join(dir, "backups", "subscribes.json.v1-*.bak"))
+	require.NoError(t, err)
+	require.Len(t, backups, 1)
+	assert.JSONEq(t, string(readGolden(t, 1)), string(mustReadFile(t, backups[0])))
+
+	// 版本高于程序支持的版本时返回错误
+	require.NoError(t, os.WriteFile(path, []byte(`{"version": 99, "subscribes": []}`), 0644))
+	_, _, _, err = loadSubscribes(path, true)
+	assert.ErrorContains(t, err, "高于程序支持的版本")
+}
+
+// mustReadFile 读取文件内容
+func mustReadFile(t *testing.T, path string) []byte {
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return data
+}
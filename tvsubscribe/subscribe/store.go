@@ -0,0 +1,75 @@
+package subscribe
+
+import (
+	"fmt"
+	"sync"
+
+	"tvsubscribe"
+	"tvsubscribe/storage"
+)
+
+// Store 订阅列表的持久化存储
+type Store interface {
+	// Load 读取全部订阅，migrated 表示旧版本的数据已补全字段，需要写回
+	Load() (subscribes []tvsubscribe.TVInfo, migrated bool, err error)
+	// Save 保存全部订阅，切片顺序即订阅列表的顺序。
+	// 数据在上次读取之后被外部修改时返回 storage.ErrConflict，不覆盖外部的修改
+	Save(subscribes []tvsubscribe.TVInfo) error
+	// String 返回存储位置，用于日志
+	String() string
+}
+
+// JSONStore 使用JSON订阅文件的存储，适合订阅数量不多的安装。
+// 记录上次读取或写入的文件版本，保存前检查文件是否被外部修改
+type JSONStore struct {
+	path    string
+	version string
+	loaded  bool
+	mu      sync.Mutex
+}
+
+// NewJSONStore 创建使用JSON订阅文件的存储
+func NewJSONStore(path string) *JSONStore {
+	return &JSONStore{path: path}
+}
+
+// Load 从订阅文件读取订阅列表，文件不存在时返回空列表。
+// 首次读取时文件损坏会从最新的有效备份恢复，之后重新加载时直接返回错误
+func (s *JSONStore) Load() ([]tvsubscribe.TVInfo, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subscribes, version, migrated, err := loadSubscribes(s.path, !s.loaded)
+	if err != nil {
+		return nil, false, err
+	}
+	s.version = version
+	s.loaded = true
+	return subscribes, migrated, nil
+}
+
+// Save 原子写入订阅文件，文件在上次读取之后被外部修改时返回 storage.ErrConflict
+func (s *JSONStore) Save(subscribes []tvsubscribe.TVInfo) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, err := storage.FileVersion(s.path)
+	if err != nil {
+		return fmt.Errorf("读取订阅文件失败: %v", err)
+	}
+	if current != s.version {
+		return fmt.Errorf("%w: %s", storage.ErrConflict, s.path)
+	}
+
+	version, err := saveSubscribes(s.path, subscribes)
+	if err != nil {
+		return err
+	}
+	s.version = version
+	return nil
+}
+
+// String 返回订阅文件路径
+func (s *JSONStore) String() string {
+	return s.path
+}
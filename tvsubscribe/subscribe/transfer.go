@@ -0,0 +1,439 @@
+package subscribe
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"tvsubscribe"
+	"tvsubscribe/scheduler"
+)
+
+// 导入导出的文件格式
+const (
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+)
+
+// 导入模式
+const (
+	ImportMerge   = "merge"   // 保留现有订阅，只添加不重复的订阅
+	ImportReplace = "replace" // 用导入的订阅替换全部现有订阅
+)
+
+// 导入时每条订阅的处理结果
+const (
+	ImportAdd     = "add"     // 添加
+	ImportSkip    = "skip"    // 与现有订阅或导入文件中前面的订阅重复，跳过
+	ImportInvalid = "invalid" // 校验失败，跳过
+)
+
+// csvHeader CSV 导出的列，导入时按列名读取，顺序无关
+var csvHeader = []string{"douban_id", "name", "resolution", "kind", "min_quality", "schedule", "active_hours", "enabled", "notes", "tags", "filters", "team_policy"}
+
+// ImportRecord 从导入文件中解析出的一条订阅，解析失败时 Err 不为空
+type ImportRecord struct {
+	TV  tvsubscribe.TVInfo
+	Err error
+}
+
+// ImportEntry 导入文件中一条订阅的处理结果
+type ImportEntry struct {
+	Index      int    `json:"index"` // 在导入文件中的序号，从1开始
+	DouBanID   string `json:"douban_id"`
+	Resolution int    `json:"resolution"`
+	Name       string `json:"name,omitempty"`
+	Action     string `json:"action"`            // add、skip 或 invalid
+	Message    string `json:"message,omitempty"` // 跳过或校验失败的原因、获取名称失败的提示
+}
+
+// ImportResult 导入结果，试运行时只预览不保存
+type ImportResult struct {
+	Mode    string        `json:"mode"`
+	DryRun  bool          `json:"dry_run"`
+	Added   int           `json:"added"`
+	Skipped int           `json:"skipped"`
+	Invalid int           `json:"invalid"`
+	Removed int           `json:"removed"` // replace 模式下被替换掉的现有订阅数量
+	Entries []ImportEntry `json:"entries"`
+}
+
+// ExportSubscribes 将订阅列表导出为指定格式，JSON 包含订阅的全部字段，CSV 只包含订阅设置
+func ExportSubscribes(format string, subscribes []tvsubscribe.TVInfo) ([]byte, error) {
+	switch format {
+	case FormatJSON, "":
+		data, err := json.MarshalIndent(subscribes, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("序列化订阅数据失败: %v", err)
+		}
+		return data, nil
+	case FormatCSV:
+		return exportCSV(subscribes)
+	}
+	return nil, fmt.Errorf("不支持的格式: %s，可选值为 json 或 csv", format)
+}
+
+// exportCSV 将订阅列表导出为CSV，过滤规则和制作组设置以JSON形式保存在单元格中
+func exportCSV(subscribes []tvsubscribe.TVInfo) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	if err := writer.Write(csvHeader); err != nil {
+		return nil, fmt.Errorf("写入CSV失败: %v", err)
+	}
+	for _, tv := range subscribes {
+		filters, err := marshalCell(tv.Filters)
+		if err != nil {
+			return nil, err
+		}
+		teamPolicy, err := marshalCell(tv.TeamPolicy)
+		if err != nil {
+			return nil, err
+		}
+		row := []string{
+			tv.DouBanID,
+			tv.Name,
+			strconv.Itoa(tv.Resolution),
+			tv.Kind,
+			tv.MinQuality,
+			tv.Schedule,
+			tv.ActiveHours,
+			strconv.FormatBool(tv.Enabled),
+			tv.Notes,
+			strings.Join(tv.Tags, ","),
+			filters,
+			teamPolicy,
+		}
+		if err := writer.Write(row); err != nil {
+			return nil, fmt.Errorf("写入CSV失败: %v", err)
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, fmt.Errorf("写入CSV失败: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// marshalCell 将可选的结构体序列化为CSV单元格，为 nil 时返回空字符串
+func marshalCell(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case *tvsubscribe.FilterRules:
+		if v == nil {
+			return "", nil
+		}
+	case *tvsubscribe.TeamPolicy:
+		if v == nil {
+			return "", nil
+		}
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("序列化订阅数据失败: %v", err)
+	}
+	return string(data), nil
+}
+
+// ParseImport 解析导入文件，文件格式错误时返回错误，单条订阅的解析错误记录在对应的 ImportRecord 中
+func ParseImport(format string, data []byte) ([]ImportRecord, error) {
+	switch format {
+	case FormatJSON, "":
+		return parseJSONImport(data)
+	case FormatCSV:
+		return parseCSVImport(data)
+	}
+	return nil, fmt.Errorf("不支持的格式: %s，可选值为 json 或 csv", format)
+}
+
+// parseJSONImport 解析导出的JSON订阅列表，也接受 subscribes.json 文件，缺少 enabled 字段的订阅视为启用
+func parseJSONImport(data []byte) ([]ImportRecord, error) {
+	if version, err := detectVersion(data); err == nil && version > 1 {
+		var file struct {
+			Subscribes json.RawMessage `json:"subscribes"`
+		}
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("解析JSON失败: %v", err)
+		}
+		data = file.Subscribes
+	}
+
+	var subscribes []tvsubscribe.TVInfo
+	if err := json.Unmarshal(data, &subscribes); err != nil {
+		return nil, fmt.Errorf("解析JSON失败: %v", err)
+	}
+	var flags []struct {
+		Enabled *bool `json:"enabled"`
+	}
+	if err := json.Unmarshal(data, &flags); err != nil {
+		return nil, fmt.Errorf("解析JSON失败: %v", err)
+	}
+
+	records := make([]ImportRecord, len(subscribes))
+	for i, tv := range subscribes {
+		if flags[i].Enabled == nil {
+			tv.Enabled = true
+		}
+		records[i] = ImportRecord{TV: tv}
+	}
+	return records, nil
+}
+
+// parseCSVImport 按列名解析CSV，必须包含 douban_id 列，resolution 为空时默认为1080P，enabled 为空时默认启用
+func parseCSVImport(data []byte) ([]ImportRecord, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("读取CSV表头失败: %v", err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+	if _, ok := columns["douban_id"]; !ok {
+		return nil, fmt.Errorf("CSV缺少 douban_id 列")
+	}
+
+	var records []ImportRecord
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("读取CSV失败: %v", err)
+		}
+		cell := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(row) {
+				return strings.TrimSpace(row[i])
+			}
+			return ""
+		}
+		tv, err := parseCSVRow(cell)
+		records = append(records, ImportRecord{TV: tv, Err: err})
+	}
+	return records, nil
+}
+
+// parseCSVRow 解析CSV中的一行订阅
+func parseCSVRow(cell func(name string) string) (tvsubscribe.TVInfo, error) {
+	tv := tvsubscribe.TVInfo{
+		DouBanID:    cell("douban_id"),
+		Name:        cell("name"),
+		Resolution:  tvsubscribe.RES_1080P,
+		Kind:        cell("kind"),
+		MinQuality:  cell("min_quality"),
+		Schedule:    cell("schedule"),
+		ActiveHours: cell("active_hours"),
+		Enabled:     true,
+		Notes:       cell("notes"),
+	}
+	if value := cell("resolution"); value != "" {
+		resolution, err := strconv.Atoi(value)
+		if err != nil {
+			return tv, fmt.Errorf("resolution 必须是整数: %s", value)
+		}
+		tv.Resolution = resolution
+	}
+	if value := cell("enabled"); value != "" {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return tv, fmt.Errorf("enabled 必须是 true 或 false: %s", value)
+		}
+		tv.Enabled = enabled
+	}
+	for _, tag := range strings.Split(cell("tags"), ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tv.Tags = append(tv.Tags, tag)
+		}
+	}
+	if value := cell("filters"); value != "" {
+		var filters tvsubscribe.FilterRules
+		if err := json.Unmarshal([]byte(value), &filters); err != nil {
+			return tv, fmt.Errorf("解析 filters 失败: %v", err)
+		}
+		tv.Filters = &filters
+	}
+	if value := cell("team_policy"); value != "" {
+		var policy tvsubscribe.TeamPolicy
+		if err := json.Unmarshal([]byte(value), &policy); err != nil {
+			return tv, fmt.Errorf("解析 team_policy 失败: %v", err)
+		}
+		tv.TeamPolicy = &policy
+	}
+	return tv, nil
+}
+
+// validateImport 校验导入的订阅并补全默认值
+func validateImport(tv *tvsubscribe.TVInfo) error {
+	tv.DouBanID = strings.TrimSpace(tv.DouBanID)
+	if tv.DouBanID == "" {
+		return fmt.Errorf("豆瓣ID不能为空")
+	}
+	if _, err := strconv.Atoi(tv.DouBanID); err != nil {
+		return fmt.Errorf("无效的豆瓣ID: %s", tv.DouBanID)
+	}
+	if !tvsubscribe.ValidResolution(tv.Resolution) {
+		return fmt.Errorf("无效的分辨率: %d", tv.Resolution)
+	}
+	if !tvsubscribe.ValidKind(tv.Kind) {
+		return fmt.Errorf("无效的订阅类型: %s", tv.Kind)
+	}
+	if tv.Kind == "" {
+		tv.Kind = tvsubscribe.KindSeries
+	}
+	if tv.MinQuality != "" {
+		if _, err := tvsubscribe.ParseQualityName(tv.MinQuality); err != nil {
+			return err
+		}
+	}
+	if tv.Schedule != "" {
+		if err := scheduler.ValidateCron(tv.Schedule); err != nil {
+			return err
+		}
+	}
+	if tv.ActiveHours != "" {
+		if _, _, err := scheduler.ParseActiveHours(tv.ActiveHours); err != nil {
+			return err
+		}
+	}
+	if tv.Filters != nil {
+		if err := tv.Filters.Validate(); err != nil {
+			return err
+		}
+		if tv.Filters.Empty() {
+			tv.Filters = nil
+		}
+	}
+	if tv.TeamPolicy != nil {
+		if tv.TeamPolicy.Mode == "" {
+			tv.TeamPolicy = nil
+		} else if err := tv.TeamPolicy.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// subscribeKey 用于去重的订阅标识：豆瓣ID+分辨率
+func subscribeKey(douBanID string, resolution int) string {
+	return fmt.Sprintf("%s/%d", douBanID, resolution)
+}
+
+// ImportSubscribes 导入订阅，按豆瓣ID+分辨率去重。
+// merge 模式跳过与现有订阅重复的条目；replace 模式用导入的订阅替换全部现有订阅。
+// 名称为空的订阅从豆瓣获取名称；dryRun 为 true 时只返回预览结果，不保存。
+func (m *SubscribeManager) ImportSubscribes(ctx context.Context, records []ImportRecord, mode string, dryRun bool) (ImportResult, error) {
+	if mode == "" {
+		mode = ImportMerge
+	}
+	if mode != ImportMerge && mode != ImportReplace {
+		return ImportResult{}, fmt.Errorf("无效的导入模式: %s，可选值为 merge 或 replace", mode)
+	}
+
+	result := ImportResult{Mode: mode, DryRun: dryRun, Entries: make([]ImportEntry, 0, len(records))}
+
+	// 与现有订阅比较重复，replace 模式下现有订阅都会被替换
+	existing := make(map[string]bool)
+	if mode == ImportMerge {
+		for _, tv := range m.GetSubscribes() {
+			existing[subscribeKey(tv.DouBanID, tv.Resolution)] = true
+		}
+	}
+
+	now := time.Now()
+	seen := make(map[string]bool)
+	var imported []tvsubscribe.TVInfo
+	for i, record := range records {
+		tv := record.TV
+		err := record.Err
+		if err == nil {
+			err = validateImport(&tv)
+		}
+		entry := ImportEntry{Index: i + 1, DouBanID: tv.DouBanID, Resolution: tv.Resolution, Name: tv.Name}
+
+		key := subscribeKey(tv.DouBanID, tv.Resolution)
+		switch {
+		case err != nil:
+			entry.Action = ImportInvalid
+			entry.Message = err.Error()
+			result.Invalid++
+		case seen[key]:
+			entry.Action = ImportSkip
+			entry.Message = "与导入文件中前面的订阅重复"
+			result.Skipped++
+		case existing[key]:
+			entry.Action = ImportSkip
+			entry.Message = "已存在相同豆瓣ID和分辨率的订阅"
+			result.Skipped++
+		default:
+			seen[key] = true
+			entry.Action = ImportAdd
+			result.Added++
+
+			// 名称为空时从豆瓣获取，失败时不阻止导入
+			if tv.Name == "" {
+				name, err := tvsubscribe.GetTVNameByDouBanID(ctx, tv.DouBanID)
+				if err != nil {
+					tv.Name = fmt.Sprintf("豆瓣ID: %s", tv.DouBanID)
+					entry.Message = fmt.Sprintf("获取名称失败: %v", err)
+				} else {
+					tv.Name = name
+				}
+				entry.Name = tv.Name
+			}
+
+			tv.ID = generateUniqueID()
+			if tv.CreatedAt.IsZero() {
+				tv.CreatedAt = now
+			}
+			tv.UpdatedAt = now
+			imported = append(imported, tv)
+		}
+		result.Entries = append(result.Entries, entry)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var newSubscribes []tvsubscribe.TVInfo
+	if mode == ImportReplace {
+		result.Removed = len(m.subscribes)
+		newSubscribes = imported
+	} else {
+		newSubscribes = make([]tvsubscribe.TVInfo, len(m.subscribes), len(m.subscribes)+len(imported))
+		copy(newSubscribes, m.subscribes)
+		newSubscribes = append(newSubscribes, imported...)
+	}
+	if newSubscribes == nil {
+		newSubscribes = []tvsubscribe.TVInfo{}
+	}
+
+	if dryRun {
+		return result, nil
+	}
+
+	// 获取名称期间订阅列表可能已被修改，保存前再次检查重复
+	if mode == ImportMerge {
+		keys := make(map[string]bool)
+		for _, tv := range m.subscribes {
+			keys[subscribeKey(tv.DouBanID, tv.Resolution)] = true
+		}
+		for _, tv := range imported {
+			if keys[subscribeKey(tv.DouBanID, tv.Resolution)] {
+				return ImportResult{}, fmt.Errorf("%w: 导入期间已添加豆瓣ID=%s, 分辨率=%d 的订阅，请重新导入", tvsubscribe.ErrSubscribeExists, tv.DouBanID, tv.Resolution)
+			}
+		}
+	}
+
+	if err := m.save(newSubscribes); err != nil {
+		return ImportResult{}, err
+	}
+	return result, nil
+}
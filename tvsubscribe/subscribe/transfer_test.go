@@ -0,0 +1,184 @@
+package subscribe
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tvsubscribe"
+)
+
+// TestExportImportRoundTrip 测试JSON和CSV导出后再导入，订阅设置保持不变
+func TestExportImportRoundTrip(t *testing.T) {
+	subscribes := []tvsubscribe.TVInfo{
+		{
+			ID:          "a",
+			DouBanID:    "36391902",
+			Name:        "庆余年, 第二季",
+			Resolution:  tvsubscribe.RES_1080P,
+			Kind:        tvsubscribe.KindSeries,
+			Schedule:    "0 */2 * * *",
+			ActiveHours: "08:00-02:00",
+			Enabled:     true,
+			Notes:       "带\"引号\"的备注",
+			Tags:        []string{"古装", "追更"},
+			Filters:     &tvsubscribe.FilterRules{Exclude: []string{"国语"}, IncludeRegex: []string{`S\d+E\d+`}},
+			TeamPolicy:  &tvsubscribe.TeamPolicy{Mode: tvsubscribe.TeamModePrefer, Teams: []string{"9"}, FallbackHours: 12},
+		},
+		{
+			ID:         "b",
+			DouBanID:   "1292052",
+			Name:       "肖申克的救赎",
+			Resolution: tvsubscribe.RES_2160P,
+			Kind:       tvsubscribe.KindMovie,
+			MinQuality: "bluray",
+		},
+	}
+
+	for _, format := range []string{FormatJSON, FormatCSV} {
+		data, err := ExportSubscribes(format, subscribes)
+		require.NoError(t, err, format)
+
+		records, err := ParseImport(format, data)
+		require.NoError(t, err, format)
+		require.Len(t, records, 2, format)
+		for i, record := range records {
+			require.NoError(t, record.Err, format)
+			want := subscribes[i]
+			got := record.TV
+			assert.Equal(t, want.DouBanID, got.DouBanID, format)
+			assert.Equal(t, want.Name, got.Name, format)
+			assert.Equal(t, want.Resolution, got.Resolution, format)
+			assert.Equal(t, want.Kind, got.Kind, format)
+			assert.Equal(t, want.MinQuality, got.MinQuality, format)
+			assert.Equal(t, want.Schedule, got.Schedule, format)
+			assert.Equal(t, want.ActiveHours, got.ActiveHours, format)
+			assert.Equal(t, want.Enabled, got.Enabled, format)
+			assert.Equal(t, want.Notes, got.Notes, format)
+			assert.Equal(t, want.Tags, got.Tags, format)
+			assert.Equal(t, want.Filters, got.Filters, format)
+			assert.Equal(t, want.TeamPolicy, got.TeamPolicy, format)
+		}
+	}
+
+	_, err := ExportSubscribes("xml", subscribes)
+	assert.Error(t, err)
+}
+
+// TestParseImport 测试导入文件的默认值和错误处理
+func TestParseImport(t *testing.T) {
+	// JSON 缺少 enabled 时视为启用
+	records, err := ParseImport(FormatJSON, []byte(`[{"douban_id": "1", "resolution": 1}, {"douban_id": "2", "resolution": 1, "enabled": false}]`))
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.True(t, records[0].TV.Enabled)
+	assert.False(t, records[1].TV.Enabled)
+
+	_, err = ParseImport(FormatJSON, []byte(`{"douban_id": "1"}`))
+	assert.Error(t, err)
+
+	// 也接受带版本号的 subscribes.json
+	records, err = ParseImport(FormatJSON, []byte(`{"version": 2, "subscribes": [{"douban_id": "1", "resolution": 1}]}`))
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "1", records[0].TV.DouBanID)
+	assert.True(t, records[0].TV.Enabled)
+
+	// CSV 按列名读取，缺少的列使用默认值，单行错误不影响其他行
+	csvData := "\xef\xbb\xbfname,douban_id\n琅琊榜,26798436\n"
+	records, err = ParseImport(FormatCSV, []byte(csvData))
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "26798436", records[0].TV.DouBanID)
+	assert.Equal(t, "琅琊榜", records[0].TV.Name)
+	assert.Equal(t, tvsubscribe.RES_1080P, records[0].TV.Resolution)
+	assert.True(t, records[0].TV.Enabled)
+
+	records, err = ParseImport(FormatCSV, []byte("douban_id,resolution,enabled\n1,abc,true\n2,1,maybe\n3,1,false\n"))
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Error(t, records[0].Err)
+	assert.Error(t, records[1].Err)
+	assert.NoError(t, records[2].Err)
+	assert.False(t, records[2].TV.Enabled)
+
+	_, err = ParseImport(FormatCSV, []byte("name,resolution\na,1\n"))
+	assert.Error(t, err)
+}
+
+// TestImportSubscribes 测试合并导入、试运行、去重、校验和替换导入
+func TestImportSubscribes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "subscribes.json")
+	require.NoError(t, os.WriteFile(path, []byte(`[{"id": "a", "douban_id": "1", "name": "a", "resolution": 1}]`), 0644))
+	manager, err := NewSubscribeManager(path)
+	require.NoError(t, err)
+
+	records := []ImportRecord{
+		{TV: tvsubscribe.TVInfo{DouBanID: "1", Name: "a", Resolution: 1, Enabled: true}},      // 与现有订阅重复
+		{TV: tvsubscribe.TVInfo{DouBanID: "2", Name: "b", Resolution: 1, Enabled: true}},      // 添加
+		{TV: tvsubscribe.TVInfo{DouBanID: "2", Name: "b", Resolution: 1, Enabled: true}},      // 与文件中前面的订阅重复
+		{TV: tvsubscribe.TVInfo{DouBanID: "2", Name: "b 4K", Resolution: 0, Enabled: true}},   // 分辨率不同，添加
+		{TV: tvsubscribe.TVInfo{DouBanID: "3", Name: "c", Resolution: 9, Enabled: true}},      // 无效分辨率
+		{TV: tvsubscribe.TVInfo{DouBanID: "4", Name: "d", Resolution: 1, Schedule: "bad"}},    // 无效调度规则
+		{TV: tvsubscribe.TVInfo{DouBanID: "abc", Name: "e", Resolution: 1, Enabled: true}},    // 无效豆瓣ID
+		{TV: tvsubscribe.TVInfo{DouBanID: "5"}, Err: assert.AnError},                          // 解析失败
+		{TV: tvsubscribe.TVInfo{DouBanID: "6", Name: "f", Resolution: 1, Kind: "anime"}},      // 无效类型
+		{TV: tvsubscribe.TVInfo{DouBanID: "7", Name: "g", Resolution: 1, MinQuality: "best"}}, // 无效质量
+	}
+
+	// 试运行只返回预览结果，不修改订阅
+	preview, err := manager.ImportSubscribes(context.Background(), records, "", true)
+	require.NoError(t, err)
+	assert.Equal(t, ImportMerge, preview.Mode)
+	assert.True(t, preview.DryRun)
+	assert.Equal(t, 2, preview.Added)
+	assert.Equal(t, 2, preview.Skipped)
+	assert.Equal(t, 6, preview.Invalid)
+	require.Len(t, preview.Entries, len(records))
+	actions := make([]string, len(preview.Entries))
+	for i, entry := range preview.Entries {
+		assert.Equal(t, i+1, entry.Index)
+		actions[i] = entry.Action
+	}
+	assert.Equal(t, []string{ImportSkip, ImportAdd, ImportSkip, ImportAdd, ImportInvalid, ImportInvalid, ImportInvalid, ImportInvalid, ImportInvalid, ImportInvalid}, actions)
+	assert.Len(t, manager.GetSubscribes(), 1)
+
+	result, err := manager.ImportSubscribes(context.Background(), records, ImportMerge, false)
+	require.NoError(t, err)
+	assert.Equal(t, preview.Added, result.Added)
+	subscribes := manager.GetSubscribes()
+	require.Len(t, subscribes, 3)
+	assert.Equal(t, "a", subscribes[0].ID)
+	assert.Equal(t, "b", subscribes[1].Name)
+	assert.Equal(t, tvsubscribe.KindSeries, subscribes[1].Kind)
+	assert.NotEmpty(t, subscribes[1].ID)
+	assert.NotEqual(t, subscribes[1].ID, subscribes[2].ID)
+	assert.False(t, subscribes[1].CreatedAt.IsZero())
+
+	// 已保存到文件
+	reloaded, err := NewSubscribeManager(path)
+	require.NoError(t, err)
+	assert.Len(t, reloaded.GetSubscribes(), 3)
+
+	// 再次合并导入时全部跳过
+	result, err = manager.ImportSubscribes(context.Background(), records[:4], ImportMerge, false)
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.Added)
+	assert.Equal(t, 4, result.Skipped)
+
+	// 替换导入时现有订阅全部被替换
+	result, err = manager.ImportSubscribes(context.Background(), []ImportRecord{{TV: tvsubscribe.TVInfo{DouBanID: "8", Name: "h", Resolution: 1, Enabled: true}}}, ImportReplace, false)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Added)
+	assert.Equal(t, 3, result.Removed)
+	subscribes = manager.GetSubscribes()
+	require.Len(t, subscribes, 1)
+	assert.Equal(t, "8", subscribes[0].DouBanID)
+
+	_, err = manager.ImportSubscribes(context.Background(), nil, "overwrite", false)
+	assert.Error(t, err)
+}
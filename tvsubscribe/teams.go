@@ -0,0 +1,129 @@
+package tvsubscribe
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 制作组搜索模式
+const (
+	TeamModeList   = "list"   // 只搜索列出的制作组
+	TeamModeAny    = "any"    // 不限制作组
+	TeamModePrefer = "prefer" // 按顺序优先使用列出的制作组，超过等待时间后回退到其他制作组
+)
+
+// DefaultTeamIDs 默认搜索的站点制作组ID，与之前固定使用的 team9 保持一致
+var DefaultTeamIDs = []int{9}
+
+// DefaultTeamFallbackHours prefer 模式默认等待首选制作组的时长（小时）
+const DefaultTeamFallbackHours = 24
+
+// TeamPolicy 订阅搜索的制作组设置
+type TeamPolicy struct {
+	Mode          string   `json:"mode"`                     // list、any 或 prefer
+	Teams         []string `json:"teams,omitempty"`          // 制作组名称（在 site_teams 中配置站点ID）或站点制作组ID，prefer 模式下按优先级排列
+	FallbackHours int      `json:"fallback_hours,omitempty"` // prefer 模式下其他制作组的种子出现多少小时后仍没有首选版本才下载，0 表示立即回退
+}
+
+// Validate 检查制作组设置的模式和列表，制作组名称是否已配置由 ResolveTeams 检查
+func (p TeamPolicy) Validate() error {
+	switch p.Mode {
+	case TeamModeAny:
+		return nil
+	case TeamModeList, TeamModePrefer:
+		if len(p.Teams) == 0 {
+			return fmt.Errorf("制作组模式 %s 需要至少指定一个制作组", p.Mode)
+		}
+		if p.FallbackHours < 0 {
+			return fmt.Errorf("回退等待时间不能为负数: %d", p.FallbackHours)
+		}
+		return nil
+	}
+	return fmt.Errorf("无效的制作组模式: %s，可选值为 list、any 或 prefer", p.Mode)
+}
+
+// FallbackDelay 返回 prefer 模式下回退到其他制作组前的等待时长
+func (p TeamPolicy) FallbackDelay() time.Duration {
+	return time.Duration(p.FallbackHours) * time.Hour
+}
+
+// ResolveTeams 将制作组名称或ID转换为站点制作组ID，名称不区分大小写
+func ResolveTeams(teams []string, siteTeams map[string]int) ([]int, error) {
+	ids := make([]int, 0, len(teams))
+	for _, team := range teams {
+		team = strings.TrimSpace(team)
+		id, ok := lookupTeam(siteTeams, team)
+		if !ok {
+			parsed, err := strconv.Atoi(team)
+			if err != nil || parsed <= 0 {
+				return nil, fmt.Errorf("未知的制作组: %s，请在 site_teams 中配置站点制作组ID", team)
+			}
+			id = parsed
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// lookupTeam 按名称查找站点制作组ID，不区分大小写
+func lookupTeam(siteTeams map[string]int, name string) (int, bool) {
+	for teamName, id := range siteTeams {
+		if strings.EqualFold(teamName, name) {
+			return id, true
+		}
+	}
+	return 0, false
+}
+
+// TeamCandidate 带有制作组优先级的候选种子
+type TeamCandidate struct {
+	Torrent  TorrentInfo
+	Rank     int  // 制作组在优先级列表中的位置，越小越优先
+	Fallback bool // 来自首选列表之外的制作组
+}
+
+// PreferTeams 按制作组优先级筛选种子：种子包含的集数都已有更优先制作组的版本时拒绝，
+// 其他制作组的种子在首次发现 delay 之后仍没有首选版本才接受。
+// candidates 应包含已下载过的种子，以便判断集数是否已有首选版本；
+// firstSeen 返回种子首次被发现的时间。返回被拒绝的种子ID及原因。
+func PreferTeams(candidates []TeamCandidate, delay time.Duration, now time.Time, firstSeen func(id string) time.Time) map[string]string {
+	// 每一集（电影或无法解析集数的种子视为整体）最优先的制作组
+	bestRank := make(map[int]int)
+	for _, candidate := range candidates {
+		for _, episode := range candidateEpisodes(candidate.Torrent) {
+			if rank, ok := bestRank[episode]; !ok || candidate.Rank < rank {
+				bestRank[episode] = candidate.Rank
+			}
+		}
+	}
+
+	rejected := make(map[string]string)
+	for _, candidate := range candidates {
+		covered := true
+		for _, episode := range candidateEpisodes(candidate.Torrent) {
+			if bestRank[episode] >= candidate.Rank {
+				covered = false
+				break
+			}
+		}
+		switch {
+		case covered:
+			rejected[candidate.Torrent.ID] = "已有更优先制作组的版本"
+		case candidate.Fallback:
+			if waited := now.Sub(firstSeen(candidate.Torrent.ID)); waited < delay {
+				rejected[candidate.Torrent.ID] = fmt.Sprintf("等待首选制作组，%s后回退", (delay - waited).Round(time.Minute))
+			}
+		}
+	}
+	return rejected
+}
+
+// candidateEpisodes 返回种子包含的集数，无法解析时用 0 表示整个条目
+func candidateEpisodes(torrentInfo TorrentInfo) []int {
+	if episodes := ParseEpisodes(torrentInfo.Info); len(episodes) > 0 {
+		return episodes
+	}
+	return []int{0}
+}
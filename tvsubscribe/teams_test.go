@@ -0,0 +1,75 @@
+package tvsubscribe
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildTeamSearchURL(t *testing.T) {
+	info := &TVInfo{DouBanID: "36391902", Resolution: RES_1080P}
+
+	// 不限制作组时不带 team 参数
+	assert.Equal(t, "https://springsunday.net/torrents.php?standard2=1&incldead=0&spstate=0&pick=0&inclbookmarked=0&search=36391902&search_area=5&search_mode=0",
+		buildTeamSearchURL(info, nil))
+	assert.Equal(t, "https://springsunday.net/torrents.php?standard2=1&team9=1&team12=1&incldead=0&spstate=0&pick=0&inclbookmarked=0&search=36391902&search_area=5&search_mode=0",
+		buildTeamSearchURL(info, []int{9, 12}))
+}
+
+func TestResolveTeams(t *testing.T) {
+	siteTeams := map[string]int{"GroupA": 9, "GroupB": 12}
+
+	ids, err := ResolveTeams([]string{"groupb", "GroupA", "31"}, siteTeams)
+	require.NoError(t, err)
+	assert.Equal(t, []int{12, 9, 31}, ids)
+
+	_, err = ResolveTeams([]string{"GroupC"}, siteTeams)
+	assert.Error(t, err)
+}
+
+func TestTeamPolicyValidate(t *testing.T) {
+	assert.NoError(t, TeamPolicy{Mode: TeamModeAny}.Validate())
+	assert.NoError(t, TeamPolicy{Mode: TeamModePrefer, Teams: []string{"GroupA"}, FallbackHours: 12}.Validate())
+	assert.Error(t, TeamPolicy{Mode: TeamModeList}.Validate())
+	assert.Error(t, TeamPolicy{Mode: TeamModePrefer, Teams: []string{"GroupA"}, FallbackHours: -1}.Validate())
+	assert.Error(t, TeamPolicy{Mode: "first"}.Validate())
+}
+
+func TestPreferTeams(t *testing.T) {
+	now := time.Date(2025, 6, 6, 22, 0, 0, 0, time.Local)
+	seen := map[string]time.Time{
+		"4": now.Add(-2 * time.Hour),
+		"5": now.Add(-30 * time.Hour),
+	}
+	firstSeen := func(id string) time.Time { return seen[id] }
+
+	candidates := []TeamCandidate{
+		{Torrent: TorrentInfo{ID: "1", Info: "第1-2集"}, Rank: 0},
+		{Torrent: TorrentInfo{ID: "2", Info: "第2集"}, Rank: 1},
+		{Torrent: TorrentInfo{ID: "3", Info: "第3集"}, Rank: 1},
+		{Torrent: TorrentInfo{ID: "4", Info: "第4集"}, Rank: 2, Fallback: true},
+		{Torrent: TorrentInfo{ID: "5", Info: "第5集"}, Rank: 2, Fallback: true},
+		{Torrent: TorrentInfo{ID: "6", Info: "第1集"}, Rank: 2, Fallback: true},
+	}
+	rejected := PreferTeams(candidates, 24*time.Hour, now, firstSeen)
+
+	// 第2集已有首选制作组的版本
+	assert.Equal(t, "已有更优先制作组的版本", rejected["2"])
+	assert.Equal(t, "已有更优先制作组的版本", rejected["6"])
+	// 第3集只有第二优先的制作组发布
+	assert.NotContains(t, rejected, "3")
+	// 其他制作组的第4集发现不久，继续等待；第5集已等待超过24小时，回退下载
+	assert.Equal(t, "等待首选制作组，22h0m0s后回退", rejected["4"])
+	assert.NotContains(t, rejected, "5")
+	assert.NotContains(t, rejected, "1")
+
+	// 电影等无法解析集数的种子整体比较
+	movies := []TeamCandidate{
+		{Torrent: TorrentInfo{ID: "m1", Title: "Movie.2023.1080p.WEB-DL"}, Rank: 0},
+		{Torrent: TorrentInfo{ID: "m2", Title: "Movie.2023.2160p.BluRay"}, Rank: 1, Fallback: true},
+	}
+	rejected = PreferTeams(movies, 0, now, firstSeen)
+	assert.Equal(t, map[string]string{"m2": "已有更优先制作组的版本"}, rejected)
+}
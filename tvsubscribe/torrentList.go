@@ -2,12 +2,15 @@ package tvsubscribe
 
 import (
 	"compress/gzip"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"net/url"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
@@ -29,15 +32,103 @@ const (
 	RES_1080P
 )
 
+// 订阅类型
+const (
+	KindSeries = "series" // 剧集，按集下载，全部剧集下载后完结
+	KindMovie  = "movie"  // 电影，下载一个最佳版本后完结
+)
+
+// SourceDouban 由豆瓣想看/在看列表同步创建的订阅
+const SourceDouban = "douban"
+
+// DefaultDoubanBaseURL 豆瓣电影默认地址
+const DefaultDoubanBaseURL = "https://movie.douban.com"
+
+// ErrCookieExpired 站点Cookie已失效，请求被重定向到登录页
+var ErrCookieExpired = errors.New("站点Cookie已失效，请重新登录后更新Cookie")
+
+// ErrSubscribeNotFound 订阅不存在
+var ErrSubscribeNotFound = errors.New("订阅不存在")
+
+// ErrSubscribeExists 已存在相同豆瓣ID和分辨率的订阅
+var ErrSubscribeExists = errors.New("订阅已存在")
+
 type TVInfo struct {
-	ID         string `json:"id"`         // 订阅唯一标识
-	DouBanID   string `json:"douban_id"`  // 豆瓣ID
-	Name       string `json:"name"`       // 电视剧名称
-	Resolution int    `json:"resolution"` // 分辨率
+	ID          string `json:"id"`                     // 订阅唯一标识
+	DouBanID    string `json:"douban_id"`              // 豆瓣ID
+	Name        string `json:"name"`                   // 电视剧名称
+	Resolution  int    `json:"resolution"`             // 分辨率
+	Kind        string `json:"kind,omitempty"`         // 订阅类型，KindMovie 或 KindSeries，为空表示剧集
+	MinQuality  string `json:"min_quality,omitempty"`  // 电影的最低片源质量，为空时使用全局设置
+	Schedule    string `json:"schedule,omitempty"`     // cron表达式，为空时使用全局调度规则
+	ActiveHours string `json:"active_hours,omitempty"` // 活跃时段（HH:MM-HH:MM），为空时使用全局设置
+
+	Filters    *FilterRules `json:"filters,omitempty"`     // 种子过滤规则，与全局默认规则合并使用
+	TeamPolicy *TeamPolicy  `json:"team_policy,omitempty"` // 搜索的制作组，为空时使用全局设置
+
+	Enabled       bool       `json:"enabled"`                   // 是否启用，暂停的订阅不参与定时处理
+	CreatedAt     time.Time  `json:"created_at"`                // 创建时间
+	UpdatedAt     time.Time  `json:"updated_at"`                // 最近一次修改订阅的时间
+	LastCheckedAt *time.Time `json:"last_checked_at,omitempty"` // 最近一次查询站点的时间
+	LastGrabAt    *time.Time `json:"last_grab_at,omitempty"`    // 最近一次下载到新种子的时间
+	LastError     string     `json:"last_error,omitempty"`      // 最近一次检查的错误，成功后清空
+	Notes         string     `json:"notes,omitempty"`           // 备注
+	Tags          []string   `json:"tags,omitempty"`            // 标签
+	Source        string     `json:"source,omitempty"`          // 订阅来源，SourceDouban 表示由豆瓣想看同步创建，为空表示手动添加
+
+	Episodes      []int      `json:"episodes,omitempty"`       // 分集账本：已下载的集数
+	TotalEpisodes int        `json:"total_episodes,omitempty"` // 豆瓣上的总集数
+	Completed     bool       `json:"completed,omitempty"`      // 已下载全部剧集，归档后不再定时处理
+	CompletedAt   *time.Time `json:"completed_at,omitempty"`   // 完结时间
+}
+
+// IsMovie 判断订阅是否为电影
+func (info TVInfo) IsMovie() bool {
+	return info.Kind == KindMovie
+}
+
+// Active 判断订阅是否参与定时处理：已启用且尚未完结
+func (info TVInfo) Active() bool {
+	return info.Enabled && !info.Completed
+}
+
+// TVInfoPatch 修改订阅时提交的字段，为 nil 的字段保持不变
+type TVInfoPatch struct {
+	DouBanID    *string   `json:"douban_id,omitempty"`
+	Name        *string   `json:"name,omitempty"` // 设置为空字符串时重新从豆瓣获取名称
+	Resolution  *int      `json:"resolution,omitempty"`
+	Schedule    *string   `json:"schedule,omitempty"`
+	ActiveHours *string   `json:"active_hours,omitempty"`
+	Enabled     *bool     `json:"enabled,omitempty"`
+	Notes       *string   `json:"notes,omitempty"`
+	Tags        *[]string `json:"tags,omitempty"`
+	Kind        *string   `json:"kind,omitempty"`
+	MinQuality  *string   `json:"min_quality,omitempty"` // 设置为空字符串时使用全局默认值
+
+	Filters    *FilterRules `json:"filters,omitempty"`     // 替换其中提供的规则列表，空列表表示清空该项
+	TeamPolicy *TeamPolicy  `json:"team_policy,omitempty"` // 整体替换制作组设置，mode 为空时恢复使用全局设置
+}
+
+// Empty 判断是否没有任何需要修改的字段
+func (p TVInfoPatch) Empty() bool {
+	return p.DouBanID == nil && p.Name == nil && p.Resolution == nil && p.Schedule == nil &&
+		p.ActiveHours == nil && p.Enabled == nil && p.Notes == nil && p.Tags == nil &&
+		p.Kind == nil && p.MinQuality == nil && p.Filters == nil && p.TeamPolicy == nil
+}
+
+// ValidResolution 判断分辨率是否受支持
+func ValidResolution(resolution int) bool {
+	return resolution == RES_2160P || resolution == RES_1080P
+}
+
+// ValidKind 判断订阅类型是否受支持，空值按电视剧处理
+func ValidKind(kind string) bool {
+	return kind == "" || kind == KindSeries || kind == KindMovie
 }
 
 type TorrentInfo struct {
 	ID           string // 种子id
+	Title        string // 种子标题，如 Sword.and.Beloved.S01E26-E27.2025.2160p
 	Info         string // 种子信息
 	DownloadLink string // 种子下载链接
 	Volume       string // 种子大小
@@ -50,6 +141,7 @@ type DoubanSearchResult struct {
 	Img     string `json:"img"`       // 图片URL
 	Year    string `json:"year"`      // 年份
 	Episode string `json:"episode"`   // 集数
+	Kind    string `json:"kind"`      // 类型，没有集数的条目为电影
 }
 
 // doubanAPIResponse 豆瓣API原始响应结构
@@ -68,7 +160,13 @@ type doubanAPIResponse struct {
 // 豆瓣ID 36391902 分辨率 2160P https://springsunday.net/torrents.php?standard1=1&team9=1&incldead=0&spstate=0&pick=0&inclbookmarked=0&search=36391902&search_area=5&search_mode=0
 // 豆瓣ID 36391902 分辨率 1080P https://springsunday.net/torrents.php?standard2=1&team9=1&incldead=0&spstate=0&pick=0&inclbookmarked=0&search=36391902&search_area=5&search_mode=0
 
-func QueryTorrentList(cookie string, info *TVInfo) ([]TorrentInfo, error) {
+// QueryTorrentList 使用默认制作组查询订阅在站点上的种子列表，ctx 取消时立即中止请求
+func QueryTorrentList(ctx context.Context, cookie string, info *TVInfo) ([]TorrentInfo, error) {
+	return QueryTorrentListWithTeams(ctx, cookie, info, DefaultTeamIDs)
+}
+
+// QueryTorrentListWithTeams 只在指定的站点制作组中查询种子列表，teamIDs 为空表示不限制作组
+func QueryTorrentListWithTeams(ctx context.Context, cookie string, info *TVInfo, teamIDs []int) ([]TorrentInfo, error) {
 	// 参数校验
 	if info == nil {
 		return nil, fmt.Errorf("TVInfo 参数不能为空")
@@ -81,10 +179,10 @@ func QueryTorrentList(cookie string, info *TVInfo) ([]TorrentInfo, error) {
 	}
 
 	// 构建搜索URL
-	searchURL := buildSearchURL(info)
+	searchURL := buildTeamSearchURL(info, teamIDs)
 
 	// 创建HTTP请求
-	req, err := http.NewRequest("GET", searchURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", searchURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("创建请求失败: %v", err)
 	}
@@ -95,7 +193,7 @@ func QueryTorrentList(cookie string, info *TVInfo) ([]TorrentInfo, error) {
 	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
 
 	// 发送请求
-	client := &http.Client{}
+	client := &http.Client{Timeout: 30 * time.Second}
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("请求失败: %v", err)
@@ -113,6 +211,11 @@ func QueryTorrentList(cookie string, info *TVInfo) ([]TorrentInfo, error) {
 		return nil, fmt.Errorf("读取响应失败: %v", err)
 	}
 
+	// 检查是否被重定向到登录页
+	if isLoginPage(resp.Request.URL, string(body)) {
+		return nil, ErrCookieExpired
+	}
+
 	// 检查响应是否为空
 	if len(body) == 0 {
 		return []TorrentInfo{}, nil
@@ -124,8 +227,21 @@ func QueryTorrentList(cookie string, info *TVInfo) ([]TorrentInfo, error) {
 	return torrentInfos, nil
 }
 
-// buildSearchURL 根据TVInfo构建搜索URL
+// isLoginPage 判断响应是否为站点登录页（Cookie失效时会被重定向到登录页）
+func isLoginPage(finalURL *url.URL, htmlContent string) bool {
+	if finalURL != nil && strings.Contains(finalURL.Path, "login") {
+		return true
+	}
+	return strings.Contains(htmlContent, "takelogin.php")
+}
+
+// buildSearchURL 根据TVInfo构建使用默认制作组的搜索URL
 func buildSearchURL(info *TVInfo) string {
+	return buildTeamSearchURL(info, DefaultTeamIDs)
+}
+
+// buildTeamSearchURL 根据TVInfo和站点制作组ID构建搜索URL，teamIDs 为空表示不限制作组
+func buildTeamSearchURL(info *TVInfo, teamIDs []int) string {
 	baseURL := "https://springsunday.net/torrents.php?"
 
 	// 根据分辨率选择standard参数
@@ -139,13 +255,28 @@ func buildSearchURL(info *TVInfo) string {
 		standardParam = "standard2=1" // 默认使用1080P
 	}
 
+	// 每个制作组对应一个 teamN=1 参数
+	var teamParams string
+	for _, teamID := range teamIDs {
+		teamParams += fmt.Sprintf("&team%d=1", teamID)
+	}
+
 	// 构建完整URL
-	url := fmt.Sprintf("%s%s&team9=1&incldead=0&spstate=0&pick=0&inclbookmarked=0&search=%s&search_area=5&search_mode=0",
-		baseURL, standardParam, info.DouBanID)
+	url := fmt.Sprintf("%s%s%s&incldead=0&spstate=0&pick=0&inclbookmarked=0&search=%s&search_area=5&search_mode=0",
+		baseURL, standardParam, teamParams, info.DouBanID)
 
 	return url
 }
 
+// SiteHost 返回订阅查询的站点域名，用于按站点限制并发
+func SiteHost(info *TVInfo) string {
+	searchURL, err := url.Parse(buildSearchURL(info))
+	if err != nil {
+		return ""
+	}
+	return searchURL.Host
+}
+
 // extractTorrentInfos 从HTML内容中提取种子详细信息
 func extractTorrentInfos(htmlContent string) []TorrentInfo {
 	if strings.TrimSpace(htmlContent) == "" {
@@ -216,8 +347,15 @@ func extractTorrentInfos(htmlContent string) []TorrentInfo {
 				}
 			})
 
+			// 提取种子标题
+			title := strings.TrimSpace(s.Find(".torrent-title a[href*='details.php?id']").First().Text())
+			if title == "" {
+				title = strings.TrimSpace(detailLink.First().Text())
+			}
+
 			torrentInfo := TorrentInfo{
 				ID:           torrentID,
+				Title:        title,
 				Info:         info,
 				DownloadLink: downloadLink,
 				Volume:       volume,
@@ -248,51 +386,94 @@ func extractTorrentIDFromURL(url string) string {
 	return ""
 }
 
+// DoubanSubject 豆瓣条目页中获取的基本信息
+type DoubanSubject struct {
+	Name string // 条目名称
+	Kind string // 条目类型，KindMovie 或 KindSeries，无法判断时为空
+}
+
 // GetTVNameByDouBanID 根据豆瓣ID获取电视剧名称
-func GetTVNameByDouBanID(douBanID string) (string, error) {
+func GetTVNameByDouBanID(ctx context.Context, douBanID string) (string, error) {
+	subject, err := GetDoubanSubject(ctx, douBanID)
+	if err != nil {
+		return "", err
+	}
+	return subject.Name, nil
+}
+
+// GetDoubanSubject 根据豆瓣ID获取条目名称和类型（电影或剧集）
+func GetDoubanSubject(ctx context.Context, douBanID string) (DoubanSubject, error) {
+	return GetDoubanSubjectFrom(ctx, DefaultDoubanBaseURL, douBanID)
+}
+
+// GetDoubanSubjectFrom 从指定的豆瓣地址获取条目名称和类型
+func GetDoubanSubjectFrom(ctx context.Context, baseURL, douBanID string) (DoubanSubject, error) {
 	if strings.TrimSpace(douBanID) == "" {
-		return "", fmt.Errorf("豆瓣ID不能为空")
+		return DoubanSubject{}, fmt.Errorf("豆瓣ID不能为空")
 	}
 
-	// 构建豆瓣API URL
-	url := fmt.Sprintf("https://movie.douban.com/subject/%s/", douBanID)
+	// 构建豆瓣条目页URL
+	subjectURL := fmt.Sprintf("%s/subject/%s/", strings.TrimRight(baseURL, "/"), douBanID)
+
+	doc, _, err := fetchDoubanPage(ctx, subjectURL, "")
+	if err != nil {
+		return DoubanSubject{}, err
+	}
+
+	subject := parseDoubanSubject(doc)
+	if subject.Name == "" {
+		return DoubanSubject{}, fmt.Errorf("无法从豆瓣页面获取电视剧名称")
+	}
 
+	return subject, nil
+}
+
+// fetchDoubanPage 请求豆瓣页面并使用goquery解析，返回文档和跳转后的最终地址
+func fetchDoubanPage(ctx context.Context, pageURL, cookie string) (*goquery.Document, *url.URL, error) {
 	// 创建HTTP请求
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", pageURL, nil)
 	if err != nil {
-		return "", fmt.Errorf("创建请求失败: %v", err)
+		return nil, nil, fmt.Errorf("创建请求失败: %v", err)
 	}
 
 	// 设置请求头
 	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36")
 	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
 	req.Header.Set("Accept-Language", "zh-CN,zh;q=0.9,en;q=0.8")
+	if cookie != "" {
+		req.Header.Set("Cookie", cookie)
+	}
 
 	// 发送请求
 	client := &http.Client{Timeout: 10 * time.Second}
 	resp, err := client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("请求豆瓣失败: %v", err)
+		return nil, nil, fmt.Errorf("请求豆瓣失败: %v", err)
 	}
 	defer resp.Body.Close()
 
 	// 检查HTTP状态码
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("豆瓣API请求失败，状态码: %d", resp.StatusCode)
+		return nil, nil, fmt.Errorf("豆瓣API请求失败，状态码: %d", resp.StatusCode)
 	}
 
 	// 读取响应
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("读取豆瓣响应失败: %v", err)
+		return nil, nil, fmt.Errorf("读取豆瓣响应失败: %v", err)
 	}
 
 	// 使用goquery解析HTML
 	doc, err := goquery.NewDocumentFromReader(strings.NewReader(string(body)))
 	if err != nil {
-		return "", fmt.Errorf("解析豆瓣HTML失败: %v", err)
+		return nil, nil, fmt.Errorf("解析豆瓣HTML失败: %v", err)
 	}
 
+	return doc, resp.Request.URL, nil
+}
+
+// parseDoubanSubject 从豆瓣条目页中解析名称和类型
+func parseDoubanSubject(doc *goquery.Document) DoubanSubject {
 	// 尝试多种方式获取标题
 	var title string
 
@@ -323,15 +504,103 @@ func GetTVNameByDouBanID(douBanID string) (string, error) {
 		}
 	}
 
-	if title == "" {
-		return "", fmt.Errorf("无法从豆瓣页面获取电视剧名称")
+	// 剧集的条目信息中有集数，电影没有
+	kind := ""
+	if info := doc.Find("#info").Text(); strings.TrimSpace(info) != "" {
+		kind = KindMovie
+		if strings.Contains(info, "集数") {
+			kind = KindSeries
+		}
+	}
+
+	return DoubanSubject{Name: title, Kind: kind}
+}
+
+// 豆瓣用户的电影/剧集列表
+const (
+	DoubanListWish = "wish" // 想看
+	DoubanListDo   = "do"   // 在看
+)
+
+// doubanListMaxPages 同步豆瓣列表时最多读取的页数，避免分页异常时无限请求
+const doubanListMaxPages = 50
+
+// DoubanListItem 豆瓣用户列表中的一个条目
+type DoubanListItem struct {
+	DouBanID string `json:"douban_id"`
+	Title    string `json:"title"`
+}
+
+// ValidDoubanList 判断豆瓣列表名称是否有效
+func ValidDoubanList(list string) bool {
+	return list == DoubanListWish || list == DoubanListDo
+}
+
+// GetDoubanUserList 获取豆瓣用户的想看或在看列表，自动读取全部分页。
+// 列表设为仅自己可见时需要提供豆瓣登录Cookie
+func GetDoubanUserList(ctx context.Context, baseURL, user, list, cookie string) ([]DoubanListItem, error) {
+	if strings.TrimSpace(user) == "" {
+		return nil, fmt.Errorf("豆瓣用户不能为空")
+	}
+	if !ValidDoubanList(list) {
+		return nil, fmt.Errorf("无效的豆瓣列表: %s，可选值为 wish 或 do", list)
+	}
+
+	pageURL := fmt.Sprintf("%s/people/%s/%s?sort=time&mode=grid", strings.TrimRight(baseURL, "/"), url.PathEscape(user), list)
+	var items []DoubanListItem
+	seen := make(map[string]bool)
+	for page := 0; page < doubanListMaxPages && pageURL != ""; page++ {
+		doc, finalURL, err := fetchDoubanPage(ctx, pageURL, cookie)
+		if err != nil {
+			return nil, err
+		}
+		// 未登录访问私密列表时被重定向到登录页
+		if strings.Contains(finalURL.Host, "accounts.") || strings.Contains(finalURL.Path, "/login") {
+			return nil, fmt.Errorf("豆瓣列表需要登录才能访问，请配置豆瓣Cookie")
+		}
+
+		for _, item := range parseDoubanList(doc) {
+			if !seen[item.DouBanID] {
+				seen[item.DouBanID] = true
+				items = append(items, item)
+			}
+		}
+
+		pageURL = ""
+		if next, ok := doc.Find(".paginator .next a").Attr("href"); ok && next != "" {
+			if nextURL, err := finalURL.Parse(next); err == nil {
+				pageURL = nextURL.String()
+			}
+		}
 	}
+	return items, nil
+}
 
-	return title, nil
+// parseDoubanList 从豆瓣用户列表页中解析条目的豆瓣ID和标题
+func parseDoubanList(doc *goquery.Document) []DoubanListItem {
+	var items []DoubanListItem
+	doc.Find(".grid-view .item, .list-view .item").Each(func(i int, s *goquery.Selection) {
+		link := s.Find(".title a").First()
+		href, _ := link.Attr("href")
+		m := doubanSubjectPattern.FindStringSubmatch(href)
+		if m == nil {
+			return
+		}
+		// 标题格式为“中文名 / 其他名称”，只保留中文名
+		title := strings.TrimSpace(link.Find("em").Text())
+		if title == "" {
+			title = strings.TrimSpace(strings.Split(link.Text(), "/")[0])
+		}
+		items = append(items, DoubanListItem{DouBanID: m[1], Title: title})
+	})
+	return items
 }
 
+// doubanSubjectPattern 从条目链接中提取豆瓣ID
+var doubanSubjectPattern = regexp.MustCompile(`/subject/(\d+)`)
+
 // SearchDouBan 搜索豆瓣
-func SearchDouBan(name string) ([]DoubanSearchResult, error) {
+func SearchDouBan(ctx context.Context, name string) ([]DoubanSearchResult, error) {
 	if strings.TrimSpace(name) == "" {
 		return nil, fmt.Errorf("搜索名称不能为空")
 	}
@@ -348,7 +617,7 @@ func SearchDouBan(name string) ([]DoubanSearchResult, error) {
 	}
 
 	// 创建请求
-	req, err := http.NewRequest("GET", searchURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", searchURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("创建请求失败: %v", err)
 	}
@@ -419,6 +688,11 @@ func SearchDouBan(name string) ([]DoubanSearchResult, error) {
 				Img:     item.Img,
 				Year:    item.Year,
 				Episode: item.Episode,
+				Kind:    KindSeries,
+			}
+			// 豆瓣的电影和剧集类型都是movie，电影没有集数
+			if strings.TrimSpace(item.Episode) == "" {
+				result.Kind = KindMovie
 			}
 			results = append(results, result)
 		}
@@ -1,6 +1,7 @@
 package tvsubscribe
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -8,6 +9,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/PuerkitoBio/goquery"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -83,7 +85,7 @@ func TestExtractTorrentInfos(t *testing.T) {
 			name: "单个种子信息（在outer表格内）",
 			html: `<html><body><div id="outer"><div><table><tr><td class="embedded"><div class="torrent-title"><a href="details.php?id=123456&hit=1">Sword.and.Beloved.S01E26-E27.2025.2160p</a></div><div class="torrent-smalldescr"><span title="天地剑心 / 狐妖小红娘·王权篇 / 狐妖小红娘之王权篇 | 第26-27集 | 成毅 / 李一桐 / 郭俊辰 [国语] [简繁英字幕]">详细信息</span></div></td><td width="110"><a href="download.php?id=123456&passkey=test">下载</a></td><td>1.5 GB</td></tr></table></div></div></body></html>`,
 			expected: []TorrentInfo{
-				{ID: "123456", Info: "天地剑心 / 狐妖小红娘·王权篇 / 狐妖小红娘之王权篇 | 第26-27集 | 成毅 / 李一桐 / 郭俊辰 [国语] [简繁英字幕]", DownloadLink: "https://springsunday.net/download.php?id=123456&passkey=test", Volume: "1.5 GB"},
+				{ID: "123456", Title: "Sword.and.Beloved.S01E26-E27.2025.2160p", Info: "天地剑心 / 狐妖小红娘·王权篇 / 狐妖小红娘之王权篇 | 第26-27集 | 成毅 / 李一桐 / 郭俊辰 [国语] [简繁英字幕]", DownloadLink: "https://springsunday.net/download.php?id=123456&passkey=test", Volume: "1.5 GB"},
 			},
 		},
 		{
@@ -93,8 +95,8 @@ func TestExtractTorrentInfos(t *testing.T) {
 				<tr><td class="embedded"><div class="torrent-title"><a href="details.php?id=789012&hit=1">Sword.and.Beloved.S01E24-E25.2025.2160p</a></div><div class="torrent-smalldescr"><span title="天地剑心 / 狐妖小红娘·王权篇 / 狐妖小红娘之王权篇 | 第24-25集 | 成毅 / 李一桐 / 郭俊辰 [国语] [简繁英字幕]">详细信息</span></div></td><td width="110"><a href="download.php?id=789012&passkey=test">下载</a></td><td>2.1 GB</td></tr>
 			</table></div></div></body></html>`,
 			expected: []TorrentInfo{
-				{ID: "123456", Info: "天地剑心 / 狐妖小红娘·王权篇 / 狐妖小红娘之王权篇 | 第26-27集 | 成毅 / 李一桐 / 郭俊辰 [国语] [简繁英字幕]", DownloadLink: "https://springsunday.net/download.php?id=123456&passkey=test", Volume: "1.5 GB"},
-				{ID: "789012", Info: "天地剑心 / 狐妖小红娘·王权篇 / 狐妖小红娘之王权篇 | 第24-25集 | 成毅 / 李一桐 / 郭俊辰 [国语] [简繁英字幕]", DownloadLink: "https://springsunday.net/download.php?id=789012&passkey=test", Volume: "2.1 GB"},
+				{ID: "123456", Title: "Sword.and.Beloved.S01E26-E27.2025.2160p", Info: "天地剑心 / 狐妖小红娘·王权篇 / 狐妖小红娘之王权篇 | 第26-27集 | 成毅 / 李一桐 / 郭俊辰 [国语] [简繁英字幕]", DownloadLink: "https://springsunday.net/download.php?id=123456&passkey=test", Volume: "1.5 GB"},
+				{ID: "789012", Title: "Sword.and.Beloved.S01E24-E25.2025.2160p", Info: "天地剑心 / 狐妖小红娘·王权篇 / 狐妖小红娘之王权篇 | 第24-25集 | 成毅 / 李一桐 / 郭俊辰 [国语] [简繁英字幕]", DownloadLink: "https://springsunday.net/download.php?id=789012&passkey=test", Volume: "2.1 GB"},
 			},
 		},
 	}
@@ -108,6 +110,9 @@ func TestExtractTorrentInfos(t *testing.T) {
 			}
 
 			for i, info := range result {
+				if info.Title != tt.expected[i].Title {
+					t.Errorf("extractTorrentInfos()[%d].Title = %v, expected %v", i, info.Title, tt.expected[i].Title)
+				}
 				if info.Info != tt.expected[i].Info {
 					t.Errorf("extractTorrentInfos()[%d].Info = %v, expected %v", i, info.Info, tt.expected[i].Info)
 				}
@@ -122,6 +127,43 @@ func TestExtractTorrentInfos(t *testing.T) {
 	}
 }
 
+// TestParseDoubanSubject 测试从豆瓣条目页解析名称和类型
+func TestParseDoubanSubject(t *testing.T) {
+	tests := []struct {
+		name     string
+		html     string
+		expected DoubanSubject
+	}{
+		{
+			name:     "剧集",
+			html:     `<html><body><h1><span property="v:itemreviewed">庆余年 第二季</span><span class="year">(2024)</span></h1><div id="info"><span class="pl">类型:</span> 剧情<br><span class="pl">集数:</span> 36<br></div></body></html>`,
+			expected: DoubanSubject{Name: "庆余年 第二季", Kind: KindSeries},
+		},
+		{
+			name:     "电影",
+			html:     `<html><body><h1><span property="v:itemreviewed">流浪地球2</span><span class="year">(2023)</span></h1><div id="info"><span class="pl">类型:</span> 科幻<br><span class="pl">片长:</span> 173分钟<br></div></body></html>`,
+			expected: DoubanSubject{Name: "流浪地球2", Kind: KindMovie},
+		},
+		{
+			name:     "没有条目信息",
+			html:     `<html><head><title>琅琊榜 (豆瓣)</title></head><body></body></html>`,
+			expected: DoubanSubject{Name: "琅琊榜"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader(tt.html))
+			if err != nil {
+				t.Fatalf("解析HTML失败: %v", err)
+			}
+			if result := parseDoubanSubject(doc); result != tt.expected {
+				t.Errorf("parseDoubanSubject() = %+v, expected %+v", result, tt.expected)
+			}
+		})
+	}
+}
+
 // TestQueryTorrentList_ParameterValidation 测试 QueryTorrentList 参数校验
 func TestQueryTorrentList_ParameterValidation(t *testing.T) {
 	tests := []struct {
@@ -170,7 +212,7 @@ func TestQueryTorrentList_ParameterValidation(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := QueryTorrentList(tt.cookie, tt.info)
+			_, err := QueryTorrentList(context.Background(), tt.cookie, tt.info)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("QueryTorrentList() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -268,7 +310,7 @@ func TestQueryTorrentListInReality(t *testing.T) {
 		Resolution: RES_1080P,
 	}
 	cookie := os.Getenv("SSD_COOKIE")
-	torrentInfos, err := QueryTorrentList(cookie, info)
+	torrentInfos, err := QueryTorrentList(context.Background(), cookie, info)
 	if err != nil {
 	    t.Fatalf("查询失败: %v\n", err)
 	}
@@ -2,6 +2,7 @@ import { createApp } from 'vue'
 import { createRouter, createWebHistory } from 'vue-router'
 import ElementPlus from 'element-plus'
 import 'element-plus/dist/index.css'
+import axios from 'axios'
 import App from './App.vue'
 
 // 导入路由配置
@@ -12,6 +13,18 @@ const router = createRouter({
   routes
 })
 
+// 未登录或会话过期时跳转到登录页，登录后返回原来的页面
+axios.interceptors.response.use(
+  response => response,
+  error => {
+    const route = router.currentRoute.value
+    if (error.response && error.response.status === 401 && route.path !== '/login') {
+      router.push({ path: '/login', query: { redirect: route.fullPath } })
+    }
+    return Promise.reject(error)
+  }
+)
+
 const app = createApp(App)
 app.use(router)
 app.use(ElementPlus)
@@ -1,5 +1,8 @@
 import Config from '../views/Config.vue'
 import Subscribe from '../views/Subscribe.vue'
+import Runs from '../views/Runs.vue'
+import Login from '../views/Login.vue'
+import Account from '../views/Account.vue'
 
 const routes = [
   {
@@ -15,6 +18,21 @@ const routes = [
     path: '/subscribe',
     name: 'Subscribe',
     component: Subscribe
+  },
+  {
+    path: '/runs',
+    name: 'Runs',
+    component: Runs
+  },
+  {
+    path: '/account',
+    name: 'Account',
+    component: Account
+  },
+  {
+    path: '/login',
+    name: 'Login',
+    component: Login
   }
 ]
 
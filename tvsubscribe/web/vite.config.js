@@ -6,6 +6,10 @@ export default defineConfig({
   server: {
     port: 3000,
     proxy: {
+      '^/api/(runs|subscribes|wishlist|auth|config)': {
+        target: 'http://localhost:8443',
+        changeOrigin: true
+      },
       '/api': {
         target: 'http://localhost:8443',
         changeOrigin: true,
@@ -0,0 +1,225 @@
+package wishlist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"tvsubscribe"
+)
+
+// DefaultIntervalMinutes 默认同步间隔（分钟）
+const DefaultIntervalMinutes = 360
+
+// Options 豆瓣列表同步设置
+type Options struct {
+	BaseURL    string        // 豆瓣地址，为空时使用默认地址
+	User       string        // 豆瓣用户ID，为空时不同步
+	Cookie     string        // 豆瓣登录Cookie，列表仅自己可见时需要
+	Lists      []string      // 同步的列表，wish 想看、do 在看
+	Interval   time.Duration // 定时同步间隔
+	Resolution int           // 新建订阅的分辨率
+	Tags       []string      // 新建订阅的标签
+	Movies     bool          // 是否同时为电影创建订阅，默认只同步剧集
+	Archive    bool          // 是否归档已从列表中移除的同步订阅
+}
+
+// Manager 同步使用的订阅管理操作
+type Manager interface {
+	GetSubscribes() []tvsubscribe.TVInfo
+	AddSubscribe(ctx context.Context, tvInfo tvsubscribe.TVInfo) error
+	CompleteSubscribe(id string) (bool, error)
+}
+
+// Result 一次同步的结果
+type Result struct {
+	Listed   int                  `json:"listed"`   // 列表中的条目数
+	Added    []tvsubscribe.TVInfo `json:"added"`    // 新建的订阅
+	Archived []tvsubscribe.TVInfo `json:"archived"` // 已从列表中移除而归档的订阅
+	Skipped  []string             `json:"skipped"`  // 不同步的电影条目
+	Errors   []string             `json:"errors"`   // 单个条目处理失败的原因，下次同步时重试
+}
+
+// Changed 判断同步是否新建或归档了订阅
+func (r Result) Changed() bool {
+	return len(r.Added) > 0 || len(r.Archived) > 0
+}
+
+// Sync 同步豆瓣用户的想看/在看列表：为列表中新的剧集创建订阅，
+// 启用归档时将已不在列表中的同步订阅标记为完结。
+// 已存在相同豆瓣ID订阅（任意分辨率，包括已完结的）的条目不会重复创建。
+func Sync(ctx context.Context, manager Manager, opts Options) (Result, error) {
+	if opts.User == "" {
+		return Result{}, fmt.Errorf("未配置豆瓣用户")
+	}
+	baseURL := opts.BaseURL
+	if baseURL == "" {
+		baseURL = tvsubscribe.DefaultDoubanBaseURL
+	}
+	lists := opts.Lists
+	if len(lists) == 0 {
+		lists = []string{tvsubscribe.DoubanListWish, tvsubscribe.DoubanListDo}
+	}
+
+	// 任一列表获取失败时不做任何修改，避免误归档
+	var items []tvsubscribe.DoubanListItem
+	listed := make(map[string]bool)
+	for _, list := range lists {
+		listItems, err := tvsubscribe.GetDoubanUserList(ctx, baseURL, opts.User, list, opts.Cookie)
+		if err != nil {
+			return Result{}, fmt.Errorf("获取豆瓣列表 %s 失败: %v", list, err)
+		}
+		for _, item := range listItems {
+			if !listed[item.DouBanID] {
+				listed[item.DouBanID] = true
+				items = append(items, item)
+			}
+		}
+	}
+
+	result := Result{Listed: len(items)}
+	existing := make(map[string]bool)
+	for _, tv := range manager.GetSubscribes() {
+		existing[tv.DouBanID] = true
+	}
+
+	for _, item := range items {
+		if existing[item.DouBanID] {
+			continue
+		}
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+
+		// 列表页不区分电影和剧集，需要从条目页判断
+		subject, err := tvsubscribe.GetDoubanSubjectFrom(ctx, baseURL, item.DouBanID)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s (豆瓣ID: %s): %v", item.Title, item.DouBanID, err))
+			continue
+		}
+		if subject.Kind == tvsubscribe.KindMovie && !opts.Movies {
+			result.Skipped = append(result.Skipped, subject.Name)
+			continue
+		}
+
+		tvInfo := tvsubscribe.TVInfo{
+			DouBanID:   item.DouBanID,
+			Name:       subject.Name,
+			Resolution: opts.Resolution,
+			Kind:       subject.Kind,
+			Tags:       append([]string(nil), opts.Tags...),
+			Source:     tvsubscribe.SourceDouban,
+		}
+		if err := manager.AddSubscribe(ctx, tvInfo); err != nil {
+			if !errors.Is(err, tvsubscribe.ErrSubscribeExists) {
+				result.Errors = append(result.Errors, fmt.Sprintf("%s (豆瓣ID: %s): %v", subject.Name, item.DouBanID, err))
+			}
+			continue
+		}
+		existing[item.DouBanID] = true
+		// 取回分配了ID的订阅
+		for _, tv := range manager.GetSubscribes() {
+			if tv.DouBanID == tvInfo.DouBanID && tv.Resolution == tvInfo.Resolution {
+				tvInfo = tv
+				break
+			}
+		}
+		result.Added = append(result.Added, tvInfo)
+	}
+
+	if !opts.Archive {
+		return result, nil
+	}
+	// 列表为空通常是豆瓣页面异常，不归档
+	if len(items) == 0 {
+		log.Printf("豆瓣列表为空，跳过归档 (用户: %s)", opts.User)
+		return result, nil
+	}
+
+	for _, tv := range manager.GetSubscribes() {
+		if tv.Source != tvsubscribe.SourceDouban || tv.Completed || listed[tv.DouBanID] {
+			continue
+		}
+		archived, err := manager.CompleteSubscribe(tv.ID)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("归档 %s 失败: %v", tv.Name, err))
+			continue
+		}
+		if archived {
+			result.Archived = append(result.Archived, tv)
+		}
+	}
+	return result, nil
+}
+
+// Syncer 定时同步豆瓣列表，同一时间只进行一次同步
+type Syncer struct {
+	manager  Manager
+	options  func() Options
+	onResult func(Result) // 每次同步成功后调用
+	mu       sync.Mutex
+}
+
+// NewSyncer 创建豆瓣列表同步器，options 在每次同步时读取，修改配置后下一次同步生效
+func NewSyncer(manager Manager, options func() Options, onResult func(Result)) *Syncer {
+	return &Syncer{
+		manager:  manager,
+		options:  options,
+		onResult: onResult,
+	}
+}
+
+// Sync 立即同步一次
+func (s *Syncer) Sync(ctx context.Context) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result, err := Sync(ctx, s.manager, s.options())
+	if err != nil {
+		return result, err
+	}
+	if s.onResult != nil {
+		s.onResult(result)
+	}
+	return result, nil
+}
+
+// Run 启动后立即同步一次，之后按配置的间隔定时同步，直到 stop 被关闭。未配置豆瓣用户时跳过同步
+func (s *Syncer) Run(stop <-chan struct{}) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-stop
+		cancel()
+	}()
+
+	for {
+		opts := s.options()
+		if opts.User != "" {
+			result, err := s.Sync(ctx)
+			if err != nil {
+				log.Printf("同步豆瓣列表失败: %v", err)
+			} else {
+				log.Printf("同步豆瓣列表完成: 列表 %d 个条目，新增 %d 个订阅，归档 %d 个订阅", result.Listed, len(result.Added), len(result.Archived))
+				for _, message := range result.Errors {
+					log.Printf("同步豆瓣列表条目失败: %s", message)
+				}
+			}
+		}
+
+		interval := opts.Interval
+		if interval <= 0 {
+			interval = DefaultIntervalMinutes * time.Minute
+		}
+		timer := time.NewTimer(interval)
+		select {
+		case <-stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package wishlist
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tvsubscribe"
+	"tvsubscribe/subscribe"
+)
+
+// subjectNames 替身服务中条目的名称和类型
+var subjectNames = map[string]struct {
+	name  string
+	movie bool
+}{
+	"34937650": {name: "庆余年 第二季"},
+	"35243442": {name: "繁花"},
+	"1292052":  {name: "肖申克的救赎", movie: true},
+}
+
+// doubanStub 使用 testdata 中的页面模拟豆瓣，wish 为想看列表返回的页面，为空时返回空列表
+type doubanStub struct {
+	mu     sync.Mutex
+	wish   []string
+	login  bool // 模拟列表需要登录，重定向到登录页
+	server *httptest.Server
+}
+
+// newDoubanStub 创建豆瓣替身服务
+func newDoubanStub(t *testing.T) *doubanStub {
+	stub := &doubanStub{wish: []string{"wish_page1.html", "wish_page2.html"}}
+	stub.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stub.mu.Lock()
+		defer stub.mu.Unlock()
+
+		switch {
+		case r.URL.Path == "/accounts/login":
+			w.Write([]byte(`<html><body><form id="login"></form></body></html>`))
+		case stub.login && strings.HasPrefix(r.URL.Path, "/people/"):
+			http.Redirect(w, r, "/accounts/login?redir=/people/alice/wish", http.StatusFound)
+		case r.URL.Path == "/people/alice/wish":
+			page := 0
+			if r.URL.Query().Get("start") == "15" {
+				page = 1
+			}
+			if page >= len(stub.wish) {
+				w.Write([]byte(`<html><body><div class="grid-view"></div></body></html>`))
+				return
+			}
+			writeFixture(t, w, stub.wish[page], "")
+		case r.URL.Path == "/people/alice/do":
+			writeFixture(t, w, "do.html", "")
+		case strings.HasPrefix(r.URL.Path, "/subject/"):
+			id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/subject/"), "/")
+			subject, ok := subjectNames[id]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			fixture := "subject_series.html"
+			if subject.movie {
+				fixture = "subject_movie.html"
+			}
+			writeFixture(t, w, fixture, subject.name)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(stub.server.Close)
+	return stub
+}
+
+// set 修改替身服务的行为
+func (s *doubanStub) set(wish []string, login bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.wish = wish
+	s.login = login
+}
+
+// writeFixture 输出 testdata 中的页面，{{NAME}} 替换为条目名称
+func writeFixture(t *testing.T, w http.ResponseWriter, name, subjectName string) {
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(strings.ReplaceAll(string(data), "{{NAME}}", subjectName)))
+}
+
+// newManager 创建使用临时文件的订阅管理器
+func newManager(t *testing.T, content string) *subscribe.SubscribeManager {
+	path := filepath.Join(t.TempDir(), "subscribes.json")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	manager, err := subscribe.NewSubscribeManager(path)
+	require.NoError(t, err)
+	return manager
+}
+
+// TestGetDoubanUserList 测试解析豆瓣列表页并读取全部分页
+func TestGetDoubanUserList(t *testing.T) {
+	stub := newDoubanStub(t)
+
+	items, err := tvsubscribe.GetDoubanUserList(context.Background(), stub.server.URL, "alice", tvsubscribe.DoubanListWish, "")
+	require.NoError(t, err)
+	assert.Equal(t, []tvsubscribe.DoubanListItem{
+		{DouBanID: "34937650", Title: "庆余年 第二季"},
+		{DouBanID: "1292052", Title: "肖申克的救赎"},
+		{DouBanID: "35243442", Title: "繁花"},
+	}, items)
+
+	_, err = tvsubscribe.GetDoubanUserList(context.Background(), stub.server.URL, "alice", "collect", "")
+	assert.Error(t, err)
+
+	// 私密列表被重定向到登录页
+	stub.set(nil, true)
+	_, err = tvsubscribe.GetDoubanUserList(context.Background(), stub.server.URL, "alice", tvsubscribe.DoubanListWish, "")
+	assert.ErrorContains(t, err, "登录")
+}
+
+// TestSync 测试同步创建剧集订阅、跳过电影和已有订阅，以及归档已移出列表的同步订阅
+func TestSync(t *testing.T) {
+	stub := newDoubanStub(t)
+	// 已手动订阅了繁花的4K版本
+	manager := newManager(t, `[{"id": "manual", "douban_id": "35243442", "name": "繁花", "resolution": 0, "enabled": true}]`)
+	opts := Options{
+		BaseURL:    stub.server.URL,
+		User:       "alice",
+		Resolution: tvsubscribe.RES_1080P,
+		Tags:       []string{"豆瓣想看"},
+		Archive:    true,
+	}
+
+	result, err := Sync(context.Background(), manager, opts)
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.Listed)
+	assert.Empty(t, result.Errors)
+	assert.Equal(t, []string{"肖申克的救赎"}, result.Skipped)
+	require.Len(t, result.Added, 1)
+	added := result.Added[0]
+	assert.NotEmpty(t, added.ID)
+	assert.Equal(t, "34937650", added.DouBanID)
+	assert.Equal(t, "庆余年 第二季", added.Name)
+	assert.Equal(t, tvsubscribe.KindSeries, added.Kind)
+	assert.Equal(t, tvsubscribe.RES_1080P, added.Resolution)
+	assert.Equal(t, []string{"豆瓣想看"}, added.Tags)
+	assert.Equal(t, tvsubscribe.SourceDouban, added.Source)
+	assert.True(t, added.Enabled)
+	assert.Empty(t, result.Archived)
+	assert.Len(t, manager.GetSubscribes(), 2)
+
+	// 再次同步不会重复创建
+	result, err = Sync(context.Background(), manager, opts)
+	require.NoError(t, err)
+	assert.Empty(t, result.Added)
+	assert.False(t, result.Changed())
+
+	// 列表获取失败时不做任何修改
+	stub.set([]string{"wish_page2.html"}, true)
+	_, err = Sync(context.Background(), manager, opts)
+	assert.Error(t, err)
+	sub, err := manager.GetSubscribeByID(added.ID)
+	require.NoError(t, err)
+	assert.False(t, sub.Completed)
+
+	// 庆余年移出想看列表后归档，手动添加的订阅不受影响
+	stub.set([]string{"wish_page2.html"}, false)
+	result, err = Sync(context.Background(), manager, opts)
+	require.NoError(t, err)
+	require.Len(t, result.Archived, 1)
+	assert.Equal(t, added.ID, result.Archived[0].ID)
+	sub, err = manager.GetSubscribeByID(added.ID)
+	require.NoError(t, err)
+	assert.True(t, sub.Completed)
+	manual, err := manager.GetSubscribeByID("manual")
+	require.NoError(t, err)
+	assert.False(t, manual.Completed)
+
+	// 列表全部为空时视为页面异常，不归档
+	stub.set(nil, false)
+	opts.Lists = []string{tvsubscribe.DoubanListWish}
+	result, err = Sync(context.Background(), manager, opts)
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.Listed)
+	assert.Empty(t, result.Archived)
+
+	_, err = Sync(context.Background(), manager, Options{})
+	assert.Error(t, err)
+}
+
+// TestSyncMovies 测试开启电影同步后为电影创建订阅
+func TestSyncMovies(t *testing.T) {
+	stub := newDoubanStub(t)
+	manager := newManager(t, `[]`)
+
+	result, err := Sync(context.Background(), manager, Options{
+		BaseURL:    stub.server.URL,
+		User:       "alice",
+		Lists:      []string{tvsubscribe.DoubanListWish},
+		Resolution: tvsubscribe.RES_2160P,
+		Movies:     true,
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Added, 3)
+	assert.Empty(t, result.Skipped)
+	assert.Equal(t, tvsubscribe.KindMovie, result.Added[1].Kind)
+	assert.Equal(t, tvsubscribe.RES_2160P, result.Added[1].Resolution)
+}